@@ -0,0 +1,276 @@
+package api
+
+import (
+	"errors"
+	"go_api_product_review/db"
+	"go_api_product_review/middleware"
+	"go_api_product_review/models"
+	"go_api_product_review/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterAPIKeyRoutes initializes the routes for API keys
+// API keys are managed by administrators only, API keys also need the api_keys:manage scope.
+// The middlewares, such as rate limits, apply to every API key route.
+// @Summary Register API key routes
+// @Description Initializes the API endpoints for managing API keys
+// @Tags api-keys
+// @Security ApiKeyAuth
+// @Security APIKeyHeader
+func RegisterAPIKeyRoutes(router *gin.Engine, middlewares ...gin.HandlerFunc) {
+	apiKeyGroup := router.Group("/api-keys", middlewares...)
+	apiKeyGroup.Use(middleware.Authorize(middleware.Policy{
+		Roles: []string{middleware.RoleAdmin},
+		Scope: middleware.ScopeAPIKeysManage,
+	}))
+	{
+		apiKeyGroup.POST("/", CreateAPIKey)
+		apiKeyGroup.GET("/", ListAPIKeys)
+		apiKeyGroup.POST("/:id/rotate", RotateAPIKey)
+		apiKeyGroup.DELETE("/:id", RevokeAPIKey)
+	}
+}
+
+// AuthenticateAPIKey returns the caller owning an API key, it is the
+// middleware.APIKeyAuthenticator of the API. The subject of the caller is "api-key:<id>".
+func AuthenticateAPIKey(key string) (*middleware.Principal, error) {
+	apiKey, err := service.AuthenticateAPIKey(db.GetDB(), key)
+	if errors.Is(err, service.ErrInvalidAPIKey) {
+		return nil, middleware.ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &middleware.Principal{
+		Subject: "api-key:" + strconv.FormatUint(uint64(apiKey.ID), 10),
+		Name:    apiKey.Name,
+		Roles:   apiKey.RoleList(),
+		Scoped:  true,
+		Scopes:  apiKey.ScopeList(),
+	}, nil
+}
+
+// CreateAPIKey issues a new API key
+// @Summary Create an API key
+// @Description Issues an API key with the given scopes and roles. The key is only returned in this response.
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Param apiKey body models.APIKeyRequest true "API key details"
+// @Success 201 {object} models.IssuedAPIKeyResponse "Successfully created API key"
+// @Failure 400 {object} models.ErrorResponse "Invalid API key data"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to manage API keys or to grant the scopes and roles"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api-keys [post]
+func CreateAPIKey(c *gin.Context) {
+	var request models.APIKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid API key data",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	// Validate using the model's method, then check the grants against the policies
+	err := request.Validate()
+	if err == nil {
+		err = middleware.ValidateGrants(request.Roles, request.Scopes)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid API key data",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if !checkGrants(c, request.Roles, request.Scopes) {
+		return
+	}
+
+	apiKey := request.ToAPIKey()
+	createdKey, plaintext, err := service.CreateAPIKey(db.GetDB(), &apiKey)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to create API key",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.IssuedAPIKeyResponse{
+		APIKeyResponse: models.NewAPIKeyResponse(*createdKey),
+		Key:            plaintext,
+	})
+}
+
+// ListAPIKeys lists all API keys
+// @Summary List API keys
+// @Description Fetches all API keys, including revoked ones, without the keys themselves
+// @Tags api-keys
+// @Produce json
+// @Success 200 {array} models.APIKeyResponse "List of API keys"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to manage API keys"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /api-keys [get]
+func ListAPIKeys(c *gin.Context) {
+	keys, err := service.ListAPIKeys(db.GetDB())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to list API keys",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	response := make([]models.APIKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		response = append(response, models.NewAPIKeyResponse(key))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// RotateAPIKey replaces an API key with a new one
+// @Summary Rotate an API key
+// @Description Issues a new key keeping the name, scopes, roles and expiry. The previous key stops working immediately.
+// @Tags api-keys
+// @Produce json
+// @Param id path int true "API key ID"
+// @Success 200 {object} models.IssuedAPIKeyResponse "Successfully rotated API key"
+// @Failure 400 {object} models.ErrorResponse "Invalid API key ID"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to manage API keys or to grant the scopes and roles of the key"
+// @Failure 404 {object} models.ErrorResponse "API key not found"
+// @Failure 409 {object} models.ErrorResponse "API key is revoked"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Failed to rotate API key"
+// @Router /api-keys/{id}/rotate [post]
+func RotateAPIKey(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid API key ID",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	// The new key is returned to the caller, who must hold what it grants
+	apiKey, err := service.GetAPIKey(db.GetDB(), uint(id))
+	if errors.Is(err, service.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Message: "API key not found",
+			Details: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to rotate API key",
+			Details: err.Error(),
+		})
+		return
+	}
+	if !checkGrants(c, apiKey.RoleList(), apiKey.ScopeList()) {
+		return
+	}
+
+	rotatedKey, plaintext, err := service.RotateAPIKey(db.GetDB(), uint(id))
+	if errors.Is(err, service.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Message: "API key not found",
+			Details: err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrAPIKeyRevoked) {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Message: "API key is revoked",
+			Details: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to rotate API key",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.IssuedAPIKeyResponse{
+		APIKeyResponse: models.NewAPIKeyResponse(*rotatedKey),
+		Key:            plaintext,
+	})
+}
+
+// RevokeAPIKey revokes an API key
+// @Summary Revoke an API key
+// @Description Revokes an API key, which stops working immediately. The key remains listed as revoked.
+// @Tags api-keys
+// @Produce json
+// @Param id path int true "API key ID"
+// @Success 200 {object} models.APIKeyResponse "Revoked API key"
+// @Failure 400 {object} models.ErrorResponse "Invalid API key ID"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to manage API keys"
+// @Failure 404 {object} models.ErrorResponse "API key not found"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Failed to revoke API key"
+// @Router /api-keys/{id} [delete]
+func RevokeAPIKey(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid API key ID",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	revokedKey, err := service.RevokeAPIKey(db.GetDB(), uint(id))
+	if errors.Is(err, service.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Message: "API key not found",
+			Details: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to revoke API key",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewAPIKeyResponse(*revokedKey))
+}
+
+// checkGrants responds with 403 unless the caller holds the roles and scopes granted to an API key,
+// and reports whether the request can go on.
+func checkGrants(c *gin.Context, roles []string, scopes []string) bool {
+	principal, ok := middleware.PrincipalFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Message: "Not authenticated",
+			Details: "No authenticated caller",
+		})
+		return false
+	}
+	if err := principal.CheckGrants(roles, scopes); err != nil {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Message: "Forbidden",
+			Details: err.Error(),
+		})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,367 @@
+package api
+
+import (
+	"errors"
+	"go_api_product_review/db"
+	"go_api_product_review/middleware"
+	"go_api_product_review/models"
+	"go_api_product_review/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterCategoryRoutes initializes the routes for the category tree
+// Categories and their products are read like products, categories are edited by catalog editors.
+// The middlewares, such as rate limits, apply to every category route.
+// @Summary Register category routes
+// @Description Initializes the API endpoints for managing the category tree, the aspects of the categories and browsing their products
+// @Tags categories
+// @Security ApiKeyAuth
+// @Security APIKeyHeader
+func RegisterCategoryRoutes(router *gin.Engine, middlewares ...gin.HandlerFunc) {
+	readCategories := middleware.Authorize(middleware.Policy{Scope: middleware.ScopeProductsRead})
+	editCategories := middleware.Authorize(middleware.Policy{
+		Roles: []string{middleware.RoleCatalogEditor},
+		Scope: middleware.ScopeProductsWrite,
+	})
+
+	categoryGroup := router.Group("/categories", middlewares...)
+	{
+		categoryGroup.POST("/", editCategories, CreateCategory)
+		categoryGroup.GET("/", readCategories, ListCategories)
+		categoryGroup.GET("/:id", readCategories, GetCategory)
+		categoryGroup.PUT("/:id", editCategories, UpdateCategory)
+		categoryGroup.DELETE("/:id", editCategories, DeleteCategory)
+		categoryGroup.GET("/:id/products", readCategories, ListCategoryProducts)
+	}
+}
+
+// CreateCategory creates a new category
+// @Summary Create a new category
+// @Description Creates a category of products under its parent, or at the top level without parent_id, along with the aspects their reviews rate from 1 to 5, such as the battery life or the fit. The slug is derived from the name when empty.
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param category body models.Category true "Category details"
+// @Success 201 {object} models.Category "Successfully created category"
+// @Failure 400 {object} models.ErrorResponse "Invalid category data"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to perform the operation"
+// @Failure 409 {object} models.ErrorResponse "Slug already used under the parent"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Failed to create category"
+// @Router /categories [post]
+func CreateCategory(c *gin.Context) {
+	var category models.Category
+	if err := c.ShouldBindJSON(&category); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid category data",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	// Validate using the model's method
+	if err := category.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid category data",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	createdCategory, err := service.CreateCategory(db.GetDB(), &category)
+	if errors.Is(err, service.ErrInvalidCategoryParent) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid category data",
+			Details: err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrDuplicateCategory) {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Message: "Category already exists",
+			Details: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to create category",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, createdCategory)
+}
+
+// ListCategories retrieves every category
+// @Summary List categories
+// @Description Fetches every category with its aspects and rating aggregates, ordered by path so each category follows its ancestors
+// @Tags categories
+// @Produce json
+// @Success 200 {array} models.Category "Categories"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to perform the operation"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Failed to list categories"
+// @Router /categories [get]
+func ListCategories(c *gin.Context) {
+	categories, err := service.ListCategories(db.GetDB())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to list categories",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, categories)
+}
+
+// GetCategory retrieves a category by its ID
+// @Summary Get category by ID
+// @Description Fetches a category with the aspects rated by the reviews of its products and the rating aggregates rolled up from the products of the category and its descendants
+// @Tags categories
+// @Produce json
+// @Param id path int true "Category ID"
+// @Success 200 {object} models.Category "Category found"
+// @Failure 400 {object} models.ErrorResponse "Invalid category ID"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to perform the operation"
+// @Failure 404 {object} models.ErrorResponse "Category not found"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Failed to get category"
+// @Router /categories/{id} [get]
+func GetCategory(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid category ID",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	category, err := service.GetCategory(db.GetDB(), uint(id))
+	if errors.Is(err, service.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Message: "Category not found",
+			Details: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to get category",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+// UpdateCategory updates an existing category
+// @Summary Update category
+// @Description Renames a category, moves it under another parent and replaces its aspects. The paths of its descendants follow its slug and parent. The ratings already given to removed aspects are kept, but new reviews can no longer rate them.
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param id path int true "Category ID"
+// @Param category body models.Category true "Updated category details"
+// @Success 200 {object} models.Category "Updated category"
+// @Failure 400 {object} models.ErrorResponse "Invalid category data"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to perform the operation"
+// @Failure 404 {object} models.ErrorResponse "Category not found"
+// @Failure 409 {object} models.ErrorResponse "Slug already used under the parent"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Failed to update category"
+// @Router /categories/{id} [put]
+func UpdateCategory(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid category ID",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	var category models.Category
+	if err := c.ShouldBindJSON(&category); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid category data",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	// Validate using the model's method
+	if err := category.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid category data",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	updatedCategory, err := service.UpdateCategory(db.GetDB(), uint(id), &category)
+	if errors.Is(err, service.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Message: "Category not found",
+			Details: err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrInvalidCategoryParent) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid category data",
+			Details: err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrDuplicateCategory) {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Message: "Category already exists",
+			Details: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to update category",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, updatedCategory)
+}
+
+// DeleteCategory deletes a category by its ID
+// @Summary Delete category
+// @Description Deletes a category without subcategories along with its aspects. Its products stay in the catalog and leave the category.
+// @Tags categories
+// @Param id path int true "Category ID"
+// @Success 204 "Category deleted"
+// @Failure 400 {object} models.ErrorResponse "Invalid category ID"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to perform the operation"
+// @Failure 404 {object} models.ErrorResponse "Category not found"
+// @Failure 409 {object} models.ErrorResponse "Category has subcategories"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Failed to delete category"
+// @Router /categories/{id} [delete]
+func DeleteCategory(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid category ID",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	err = service.DeleteCategory(db.GetDB(), uint(id))
+	if errors.Is(err, service.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Message: "Category not found",
+			Details: err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrCategoryHasChildren) {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Message: "Category has subcategories",
+			Details: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to delete category",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// ListCategoryProducts lists the products of a category page by page
+// @Summary List category products
+// @Description Fetches a page of the summaries of the products assigned to a category or one of its descendants, with the filters and sort order of the product listing
+// @Tags categories
+// @Produce json
+// @Param id path int true "Category ID"
+// @Param limit query int false "Maximum number of products to return (1-100, default 20)"
+// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+// @Param min_price query number false "Minimum price"
+// @Param max_price query number false "Maximum price"
+// @Param min_rating query number false "Minimum average rating"
+// @Param name query string false "Case-insensitive substring of the product name"
+// @Param sort query string false "Sort key: price, average_rating, ranking_score or created_at, prefixed with - for descending order (default -created_at)"
+// @Param include_total query bool false "Include the total number of matching products"
+// @Param include query string false "Set to reviews to embed the reviews of each product"
+// @Success 200 {object} models.ProductPage "Page of products"
+// @Failure 400 {object} models.ErrorResponse "Invalid query parameters"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to perform the operation"
+// @Failure 404 {object} models.ErrorResponse "Category not found"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Failed to list products"
+// @Router /categories/{id}/products [get]
+func ListCategoryProducts(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid category ID",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	var query models.ProductQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid query parameters",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	// Validate using the model's method
+	if err := query.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid query parameters",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	page, err := service.ListCategoryProducts(db.GetDB(), uint(id), query)
+	if errors.Is(err, service.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Message: "Category not found",
+			Details: err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid query parameters",
+			Details: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to list products",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
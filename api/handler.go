@@ -1,11 +1,14 @@
 package api
 
 import (
+	"errors"
 	"go_api_product_review/db"
+	"go_api_product_review/middleware"
 	"go_api_product_review/models"
 	"go_api_product_review/service"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,33 +16,58 @@ import (
 // @securityDefinitions.apikey ApiKeyAuth
 // @in header
 // @name Authorization
-// @description Bearer token authentication. Example: 'Bearer token'
+// @description Bearer JWT authentication. Example: 'Bearer eyJhbGciOiJIUzI1NiIs...'
+
+// @securityDefinitions.apikey APIKeyHeader
+// @in header
+// @name X-API-Key
+// @description API key issued by an administrator. Example: 'prk_4Fh2kQ9xT0mJ8sLw...'
 
 // RegisterProductRoutes initializes the routes for products
+// Products can be read by every caller, created and updated by catalog editors,
+// and deleted by administrators only. API keys also need the scope of the route.
+// The middlewares, such as rate limits, apply to every product route.
 // @Summary Register product routes
 // @Description Initializes the API endpoints for managing products
 // @Tags products
 // @Security ApiKeyAuth
-func RegisterProductRoutes(router *gin.Engine) {
-	productGroup := router.Group("/products")
+// @Security APIKeyHeader
+func RegisterProductRoutes(router *gin.Engine, middlewares ...gin.HandlerFunc) {
+	readProducts := middleware.Authorize(middleware.Policy{Scope: middleware.ScopeProductsRead})
+	readReviews := middleware.Authorize(middleware.Policy{Scope: middleware.ScopeReviewsRead})
+	editProducts := middleware.Authorize(middleware.Policy{
+		Roles: []string{middleware.RoleCatalogEditor},
+		Scope: middleware.ScopeProductsWrite,
+	})
+	deleteProducts := middleware.Authorize(middleware.Policy{
+		Roles: []string{middleware.RoleAdmin},
+		Scope: middleware.ScopeProductsWrite,
+	})
+
+	productGroup := router.Group("/products", middlewares...)
 	{
-		productGroup.POST("/", CreateProduct)
-		productGroup.GET("/", ListProducts)
-		productGroup.GET("/:id", GetProductByID)
-		productGroup.PUT("/:id", UpdateProduct)
-		productGroup.DELETE("/:id", DeleteProduct)
+		productGroup.POST("/", editProducts, CreateProduct)
+		productGroup.GET("/", readProducts, ListProducts)
+		productGroup.GET("/:id", readProducts, GetProductByID)
+		productGroup.GET("/:id/reviews", readReviews, ListProductReviews)
+		productGroup.GET("/:id/rating-summary", readReviews, GetRatingSummary)
+		productGroup.GET("/:id/rating-trend", readReviews, GetRatingTrend)
+		productGroup.PUT("/:id", editProducts, UpdateProduct)
+		productGroup.DELETE("/:id", deleteProducts, DeleteProduct)
 	}
 }
 
 // CreateProduct creates a new product
 // @Summary Create a new product
-// @Description Creates a new product in the catalog
+// @Description Creates a new product in the catalog, assigned to the existing categories listed in category_ids and to category_id, whose aspects its reviews rate
 // @Tags products
 // @Accept json
 // @Produce json
 // @Param product body models.Product true "Product details"
 // @Success 201 {object} models.Product "Successfully created product"
 // @Failure 400 {object} models.ErrorResponse "Invalid product data"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to perform the operation"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
 // @Failure 500 {object} models.ErrorResponse "Internal server error"
 // @Router /products [post]
 func CreateProduct(c *gin.Context) {
@@ -62,6 +90,13 @@ func CreateProduct(c *gin.Context) {
 	}
 
 	createdProduct, err := service.CreateProduct(db.GetDB(), &product)
+	if errors.Is(err, service.ErrUnknownCategory) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid product data",
+			Details: err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Message: "Failed to create product",
@@ -73,16 +108,53 @@ func CreateProduct(c *gin.Context) {
 	c.JSON(http.StatusCreated, createdProduct)
 }
 
-// ListProducts lists all products
-// @Summary List all products
-// @Description Fetches all products from the catalog
+// ListProducts lists products page by page
+// @Summary List products
+// @Description Fetches a page of product summaries, optionally filtered by price range, minimum average rating, name and category
 // @Tags products
 // @Produce json
-// @Success 200 {array} models.Product "List of products"
+// @Param limit query int false "Maximum number of products to return (1-100, default 20)"
+// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+// @Param min_price query number false "Minimum price"
+// @Param max_price query number false "Maximum price"
+// @Param min_rating query number false "Minimum average rating"
+// @Param name query string false "Case-insensitive substring of the product name"
+// @Param category_id query int false "Only products assigned to this category or one of its descendants"
+// @Param sort query string false "Sort key: price, average_rating, ranking_score or created_at, prefixed with - for descending order (default -created_at)"
+// @Param include_total query bool false "Include the total number of matching products"
+// @Param include query string false "Set to reviews to embed the reviews of each product"
+// @Success 200 {object} models.ProductPage "Page of products"
+// @Failure 400 {object} models.ErrorResponse "Invalid query parameters"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
 // @Failure 500 {object} models.ErrorResponse "Internal server error"
 // @Router /products [get]
 func ListProducts(c *gin.Context) {
-	products, err := service.ListProducts(db.GetDB())
+	var query models.ProductQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid query parameters",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	// Validate using the model's method
+	if err := query.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid query parameters",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	page, err := service.ListProducts(db.GetDB(), query)
+	if errors.Is(err, service.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid query parameters",
+			Details: err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Message: "Failed to list products",
@@ -90,7 +162,7 @@ func ListProducts(c *gin.Context) {
 		})
 		return
 	}
-	c.JSON(http.StatusOK, products)
+	c.JSON(http.StatusOK, page)
 }
 
 // GetProductByID retrieves a product by its ID
@@ -99,8 +171,11 @@ func ListProducts(c *gin.Context) {
 // @Tags products
 // @Produce json
 // @Param id path int true "Product ID"
-// @Success 200 {object} models.Product "Product found"
+// @Param include query string false "Set to reviews to embed the product reviews"
+// @Success 200 {object} models.ProductDetail "Product found"
 // @Failure 400 {object} models.ErrorResponse "Invalid product id"
+// @Failure 404 {object} models.ErrorResponse "Product not found"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
 // @Failure 500 {object} models.ErrorResponse "Failed to retrieve product"
 // @Router /products/{id} [get]
 func GetProductByID(c *gin.Context) {
@@ -114,8 +189,24 @@ func GetProductByID(c *gin.Context) {
 		return
 	}
 
+	includeReviews, err := models.ParseInclude(c.Query("include"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid query parameters",
+			Details: err.Error(),
+		})
+		return
+	}
+
 	productID := uint(id)
-	product, err := service.GetProductByID(db.GetDB(), productID)
+	product, err := service.GetProductByID(db.GetDB(), productID, includeReviews)
+	if errors.Is(err, service.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Message: "Product not found",
+			Details: err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Message: "Failed to get product",
@@ -129,7 +220,7 @@ func GetProductByID(c *gin.Context) {
 
 // UpdateProduct updates an existing product
 // @Summary Update product
-// @Description Updates an existing product by its ID
+// @Description Updates an existing product by its ID, replacing its categories with the existing ones listed in category_ids and category_id
 // @Tags products
 // @Accept json
 // @Produce json
@@ -138,6 +229,8 @@ func GetProductByID(c *gin.Context) {
 // @Success 200 {object} models.Product "Updated product"
 // @Failure 400 {object} models.ErrorResponse "Invalid product data"
 // @Failure 400 {object} models.ErrorResponse "Invalid product ID"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to perform the operation"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
 // @Failure 500 {object} models.ErrorResponse "Failed to update product"
 // @Router /products/{id} [put]
 func UpdateProduct(c *gin.Context) {
@@ -171,6 +264,13 @@ func UpdateProduct(c *gin.Context) {
 	}
 
 	updatedProduct, err := service.UpdateProduct(db.GetDB(), productID, &product)
+	if errors.Is(err, service.ErrUnknownCategory) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid product data",
+			Details: err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Message: "Failed to update product",
@@ -189,6 +289,8 @@ func UpdateProduct(c *gin.Context) {
 // @Param id path int true "Product ID"
 // @Success 204 "Product deleted"
 // @Failure 400 {object} models.ErrorResponse "Invalid product ID"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to perform the operation"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
 // @Failure 500 {object} models.ErrorResponse "Failed to delete product"
 // @Router /products/{id} [delete]
 func DeleteProduct(c *gin.Context) {
@@ -213,29 +315,246 @@ func DeleteProduct(c *gin.Context) {
 	c.JSON(http.StatusNoContent, nil)
 }
 
+// ListProductReviews lists the reviews of a product page by page
+// @Summary List product reviews
+// @Description Fetches a page of reviews of a product, optionally filtered by rating and to verified purchases
+// @Tags products
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param limit query int false "Maximum number of reviews to return (1-100, default 20)"
+// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+// @Param sort query string false "Sort order: newest, highest, lowest or helpful (default newest)"
+// @Param rating query int false "Only return reviews with this rating"
+// @Param verified query bool false "Only return reviews of verified purchases"
+// @Success 200 {object} models.ReviewPage "Page of reviews"
+// @Failure 400 {object} models.ErrorResponse "Invalid query parameters"
+// @Failure 404 {object} models.ErrorResponse "Product not found"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Failed to list reviews"
+// @Router /products/{id}/reviews [get]
+func ListProductReviews(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid product ID",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	var query models.ReviewQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid query parameters",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	// Validate using the model's method
+	if err := query.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid query parameters",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	page, err := service.ListProductReviews(db.GetDB(), uint(id), query)
+	if errors.Is(err, service.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid query parameters",
+			Details: err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Message: "Product not found",
+			Details: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to list reviews",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// GetRatingSummary retrieves the rating distribution of a product
+// @Summary Get product rating summary
+// @Description Fetches the number and share of reviews with each rating, along with the total, average and median rating
+// @Tags products
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {object} models.RatingSummary "Rating summary"
+// @Failure 400 {object} models.ErrorResponse "Invalid product ID"
+// @Failure 404 {object} models.ErrorResponse "Product not found"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Failed to get rating summary"
+// @Router /products/{id}/rating-summary [get]
+func GetRatingSummary(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid product ID",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	summary, err := service.GetRatingSummary(db.GetDB(), uint(id))
+	if errors.Is(err, service.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Message: "Product not found",
+			Details: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to get rating summary",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// GetRatingTrend retrieves the evolution of the ratings of a product over time
+// @Summary Get product rating trend
+// @Description Fetches the number and average rating of the reviews of a product per week or month, along with its recent and time-decayed average ratings
+// @Tags products
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param interval query string false "Length of the periods: week or month (default week)"
+// @Param periods query int false "Number of periods to return, ending with the current one (1-52, default 12)"
+// @Success 200 {object} models.RatingTrend "Rating trend"
+// @Failure 400 {object} models.ErrorResponse "Invalid query parameters"
+// @Failure 404 {object} models.ErrorResponse "Product not found"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Failed to get rating trend"
+// @Router /products/{id}/rating-trend [get]
+func GetRatingTrend(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid product ID",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	var query models.RatingTrendQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid query parameters",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	// Validate using the model's method
+	if err := query.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid query parameters",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	trend, err := service.GetRatingTrend(db.GetDB(), uint(id), query)
+	if errors.Is(err, service.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Message: "Product not found",
+			Details: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to get rating trend",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, trend)
+}
+
 // RegisterReviewRoutes initializes the routes for reviews
+// Reviews can be read by every caller and written and voted on by reviewers,
+// who may only change the reviews they authored unless they are administrators.
+// Merchants reply to reviews and may only change the replies they wrote.
+// The history of a review is read by its author and moderators, and restored by administrators.
+// API keys also need the scope of the route.
+// The middlewares, such as rate limits, apply to every review route.
 // @Summary Register review routes
 // @Description Initializes the API endpoints for managing reviews
 // @Tags reviews
 // @Security ApiKeyAuth
-func RegisterReviewRoutes(router *gin.Engine) {
-	reviewGroup := router.Group("/reviews")
+// @Security APIKeyHeader
+func RegisterReviewRoutes(router *gin.Engine, middlewares ...gin.HandlerFunc) {
+	readReviews := middleware.Authorize(middleware.Policy{Scope: middleware.ScopeReviewsRead})
+	writeReviews := middleware.Authorize(middleware.Policy{
+		Roles: []string{middleware.RoleReviewer},
+		Scope: middleware.ScopeReviewsWrite,
+	})
+	replyReviews := middleware.Authorize(middleware.Policy{
+		Roles: []string{middleware.RoleMerchant},
+		Scope: middleware.ScopeReviewsReply,
+	})
+	restoreReviews := middleware.Authorize(middleware.Policy{
+		Roles: []string{middleware.RoleAdmin},
+		Scope: middleware.ScopeReviewsModerate,
+	})
+
+	reviewGroup := router.Group("/reviews", middlewares...)
 	{
-		reviewGroup.POST("/", CreateReview)
-		reviewGroup.PUT("/:id", UpdateReview)
-		reviewGroup.DELETE("/:id", DeleteReview)
+		reviewGroup.POST("/", writeReviews, CreateReview)
+		reviewGroup.GET("/:id", readReviews, GetReview)
+		// Reviewers may only change their own reviews, which the handlers check
+		reviewGroup.PUT("/:id", writeReviews, UpdateReview)
+		reviewGroup.DELETE("/:id", writeReviews, DeleteReview)
+		reviewGroup.POST("/:id/votes", writeReviews, CastVote)
+		reviewGroup.DELETE("/:id/votes", writeReviews, DeleteVote)
+		// Merchants may only change their own replies, which the handlers check
+		reviewGroup.POST("/:id/replies", replyReviews, CreateReply)
+		reviewGroup.PUT("/:id/replies/:replyId", replyReviews, UpdateReply)
+		reviewGroup.DELETE("/:id/replies/:replyId", replyReviews, DeleteReply)
+		// Only the author and moderators may read the history, which the handler checks
+		reviewGroup.GET("/:id/history", readReviews, GetReviewHistory)
+		reviewGroup.POST("/:id/history/:version/restore", restoreReviews, RestoreReviewRevision)
+		// Reviewers may only attach photos to their own reviews, which the handlers check
+		reviewGroup.POST("/:id/media", writeReviews, UploadReviewMedia)
+		reviewGroup.GET("/:id/media/:mediaId", readReviews, GetReviewMedia)
+		reviewGroup.GET("/:id/media/:mediaId/thumbnail", readReviews, GetReviewMediaThumbnail)
+		reviewGroup.DELETE("/:id/media/:mediaId", writeReviews, DeleteReviewMedia)
 	}
 }
 
 // CreateReview creates a new review
 // @Summary Create a new review
-// @Description Creates a new review for a product
+// @Description Creates a new review for a product. The review is pending until a moderator approves it, only approved reviews are public and count toward the ratings. The content screening and the duplicate detection may reject the review or flag it for moderation.
 // @Tags reviews
 // @Accept json
 // @Produce json
 // @Param review body models.Review true "Review details"
 // @Success 201 {object} models.Review "Successfully created review"
 // @Failure 400 {object} models.ErrorResponse "Invalid review data"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to perform the operation"
+// @Failure 409 {object} models.ErrorResponse "Review duplicates another review"
+// @Failure 422 {object} models.ScreeningErrorResponse "Review rejected by content screening"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
 // @Failure 500 {object} models.ErrorResponse "Internal server error"
 // @Router /reviews [post]
 func CreateReview(c *gin.Context) {
@@ -257,7 +576,54 @@ func CreateReview(c *gin.Context) {
 		return
 	}
 
+	// Reviews are dated by the server, the rating trends rely on it
+	review.CreatedAt = time.Time{}
+
+	// Reviews are only published once a moderator approves them
+	review.Status = models.ReviewPending
+
+	// Replies are added by merchants through their own endpoints
+	review.Replies = nil
+
+	// Photos are uploaded through their own endpoints
+	review.Media = nil
+
+	// Purchases are verified by the server
+	review.Verified = false
+
+	// The author is always the authenticated caller
+	review.AuthorSubject = ""
+	if principal, ok := middleware.PrincipalFromContext(c); ok {
+		review.AuthorSubject = principal.Subject
+	}
+
 	createdReview, err := service.CreateReview(db.GetDB(), &review)
+	var screeningErr *service.ScreeningError
+	if errors.As(err, &screeningErr) {
+		c.JSON(http.StatusUnprocessableEntity, models.ScreeningErrorResponse{
+			ErrorResponse: models.ErrorResponse{
+				Message: "Review rejected by content screening",
+				Details: err.Error(),
+			},
+			Score:    screeningErr.Result.Score,
+			Findings: screeningErr.Result.Findings,
+		})
+		return
+	}
+	if errors.Is(err, service.ErrDuplicateReview) {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Message: "Review duplicates another review",
+			Details: err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrInvalidAspectRating) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid review data",
+			Details: err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Message: "Failed creating review",
@@ -269,9 +635,54 @@ func CreateReview(c *gin.Context) {
 	c.JSON(http.StatusCreated, createdReview)
 }
 
+// GetReview retrieves a review by its ID
+// @Summary Get review by ID
+// @Description Fetches a review by its unique ID. Reviews that are not approved are only visible to their author and moderators.
+// @Tags reviews
+// @Produce json
+// @Param id path int true "Review ID"
+// @Success 200 {object} models.ReviewResponse "Review found"
+// @Failure 400 {object} models.ErrorResponse "Invalid review ID"
+// @Failure 404 {object} models.ErrorResponse "Review not found"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Failed to get review"
+// @Router /reviews/{id} [get]
+func GetReview(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid review ID",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	review, err := service.GetReview(db.GetDB(), uint(id))
+	if err == nil && !canSeeReview(c, review) {
+		err = service.ErrNotFound
+	}
+	if errors.Is(err, service.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Message: "Review not found",
+			Details: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to get review",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewReviewResponse(*review))
+}
+
 // UpdateReview updates an existing review
 // @Summary Update review
-// @Description Updates an existing review by its ID
+// @Description Updates an existing review by its ID. The new text goes through the content screening, which rejects it or takes the review down for moderation. The aspect ratings are replaced with the updated ones.
 // @Tags reviews
 // @Accept json
 // @Produce json
@@ -280,6 +691,12 @@ func CreateReview(c *gin.Context) {
 // @Success 200 {object} models.Review "Updated review"
 // @Failure 400 {object} models.ErrorResponse "Invalid review ID"
 // @Failure 400 {object} models.ErrorResponse "Invalid review Data"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to perform the operation"
+// @Failure 404 {object} models.ErrorResponse "Review not found"
+// @Failure 409 {object} models.ErrorResponse "Review was moderated meanwhile"
+// @Failure 409 {object} models.ErrorResponse "Review duplicates another review"
+// @Failure 422 {object} models.ScreeningErrorResponse "Review rejected by content screening"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
 // @Failure 500 {object} models.ErrorResponse "Failed to update review"
 // @Router /reviews/{id} [put]
 func UpdateReview(c *gin.Context) {
@@ -293,6 +710,10 @@ func UpdateReview(c *gin.Context) {
 		return
 	}
 
+	if !authorizeReviewChange(c, uint(id)) {
+		return
+	}
+
 	var review models.Review
 	if err := c.ShouldBindJSON(&review); err != nil {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{
@@ -311,7 +732,47 @@ func UpdateReview(c *gin.Context) {
 		return
 	}
 
-	updatedReview, err := service.UpdateReview(db.GetDB(), uint(id), &review)
+	updatedReview, err := service.UpdateReview(db.GetDB(), uint(id), &review, callerSubject(c))
+	var screeningErr *service.ScreeningError
+	if errors.As(err, &screeningErr) {
+		c.JSON(http.StatusUnprocessableEntity, models.ScreeningErrorResponse{
+			ErrorResponse: models.ErrorResponse{
+				Message: "Review rejected by content screening",
+				Details: err.Error(),
+			},
+			Score:    screeningErr.Result.Score,
+			Findings: screeningErr.Result.Findings,
+		})
+		return
+	}
+	if errors.Is(err, service.ErrDuplicateReview) {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Message: "Review duplicates another review",
+			Details: err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrInvalidAspectRating) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid review data",
+			Details: err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Message: "Review not found",
+			Details: err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrReviewModerated) {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Message: "Review was moderated meanwhile",
+			Details: err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Message: "Failed to update review",
@@ -330,6 +791,10 @@ func UpdateReview(c *gin.Context) {
 // @Param id path int true "Review ID"
 // @Success 204 "Review deleted"
 // @Failure 400 {object} models.ErrorResponse "Invalid review ID"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to perform the operation"
+// @Failure 404 {object} models.ErrorResponse "Review not found"
+// @Failure 409 {object} models.ErrorResponse "Review was moderated meanwhile"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
 // @Failure 500 {object} models.ErrorResponse "Failed to delete review"
 // @Router /reviews/{id} [delete]
 func DeleteReview(c *gin.Context) {
@@ -343,7 +808,25 @@ func DeleteReview(c *gin.Context) {
 		return
 	}
 
-	err = service.DeleteReview(db.GetDB(), uint(id))
+	if !authorizeReviewChange(c, uint(id)) {
+		return
+	}
+
+	err = service.DeleteReview(db.GetDB(), uint(id), callerSubject(c))
+	if errors.Is(err, service.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Message: "Review not found",
+			Details: err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrReviewModerated) {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Message: "Review was moderated meanwhile",
+			Details: err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Message: "Failed to delete review",
@@ -354,3 +837,59 @@ func DeleteReview(c *gin.Context) {
 
 	c.JSON(http.StatusNoContent, nil)
 }
+
+// callerSubject returns the subject of the authenticated caller, empty if there is none.
+func callerSubject(c *gin.Context) string {
+	if principal, ok := middleware.PrincipalFromContext(c); ok {
+		return principal.Subject
+	}
+	return ""
+}
+
+// canSeeReview reports whether the caller may read the review:
+// approved reviews are public, the others are only visible to their author and moderators.
+func canSeeReview(c *gin.Context, review *models.Review) bool {
+	if review.Status == models.ReviewApproved {
+		return true
+	}
+	principal, ok := middleware.PrincipalFromContext(c)
+	if !ok {
+		return false
+	}
+	return principal.IsModerator() || (review.AuthorSubject != "" && review.AuthorSubject == principal.Subject)
+}
+
+// authorizeReviewChange checks that the caller may change the review:
+// administrators may change any review, other callers only the ones they authored.
+// It writes the error response and returns false when the change is not allowed.
+func authorizeReviewChange(c *gin.Context, id uint) bool {
+	principal, ok := middleware.PrincipalFromContext(c)
+	if ok && principal.IsAdmin() {
+		return true
+	}
+
+	review, err := service.GetReview(db.GetDB(), id)
+	if errors.Is(err, service.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Message: "Review not found",
+			Details: err.Error(),
+		})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to get review",
+			Details: err.Error(),
+		})
+		return false
+	}
+
+	if !ok || review.AuthorSubject == "" || review.AuthorSubject != principal.Subject {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Message: "Forbidden",
+			Details: "Only the author of the review can change it",
+		})
+		return false
+	}
+	return true
+}
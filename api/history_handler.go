@@ -0,0 +1,125 @@
+package api
+
+import (
+	"errors"
+	"go_api_product_review/db"
+	"go_api_product_review/middleware"
+	"go_api_product_review/models"
+	"go_api_product_review/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetReviewHistory lists the prior versions of a review
+// @Summary Get review history
+// @Description Fetches the prior versions of a review, oldest first, with who changed the review and when. Every update and delete of a review is recorded, and the history of deleted reviews is kept. Only the author of the review and moderators may read it.
+// @Tags reviews
+// @Produce json
+// @Param id path int true "Review ID"
+// @Success 200 {object} models.ReviewHistory "History of the review"
+// @Failure 400 {object} models.ErrorResponse "Invalid review ID"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to perform the operation"
+// @Failure 404 {object} models.ErrorResponse "Review not found"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Failed to get review history"
+// @Router /reviews/{id}/history [get]
+func GetReviewHistory(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid review ID",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	history, err := service.GetReviewHistory(db.GetDB(), uint(id))
+	if errors.Is(err, service.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Message: "Review not found",
+			Details: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to get review history",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	// The history keeps what was taken down, so it is not public
+	principal, ok := middleware.PrincipalFromContext(c)
+	if !ok || !(principal.IsModerator() || (history.AuthorSubject != "" && history.AuthorSubject == principal.Subject)) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Message: "Forbidden",
+			Details: "Only the author of the review and moderators can read its history",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// RestoreReviewRevision restores a prior version of a review
+// @Summary Restore review revision
+// @Description Brings a review back to the names, text, rating and aspect ratings of one of its prior versions, undeleting it if it was deleted, and recomputes the ratings of the product. The moderation status of the review is kept. The current version is recorded in the history first. Administrators only.
+// @Tags reviews
+// @Produce json
+// @Param id path int true "Review ID"
+// @Param version path int true "Version to restore"
+// @Success 200 {object} models.ReviewResponse "Restored review"
+// @Failure 400 {object} models.ErrorResponse "Invalid review ID or version"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to perform the operation"
+// @Failure 404 {object} models.ErrorResponse "Review or version not found"
+// @Failure 409 {object} models.ErrorResponse "Version rates aspects the category of the product no longer defines"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Failed to restore review"
+// @Router /reviews/{id}/history/{version}/restore [post]
+func RestoreReviewRevision(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid review ID",
+			Details: err.Error(),
+		})
+		return
+	}
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid version",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	review, err := service.RestoreReviewRevision(db.GetDB(), uint(id), version, callerSubject(c))
+	if errors.Is(err, service.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Message: "Review or version not found",
+			Details: err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrInvalidAspectRating) {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Message: "Version cannot be restored",
+			Details: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to restore review",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewReviewResponse(*review))
+}
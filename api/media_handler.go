@@ -0,0 +1,277 @@
+package api
+
+import (
+	"errors"
+	"go_api_product_review/db"
+	"go_api_product_review/media"
+	"go_api_product_review/models"
+	"go_api_product_review/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UploadReviewMedia attaches a photo to a review
+// @Summary Upload review photo
+// @Description Attaches a JPEG, PNG or GIF photo to a review, sent as the file field of a multipart form. The type is sniffed from the content of the file, and the size, dimensions and number of photos of a review are limited. A thumbnail is generated, and the photos are embedded in the review reads. Reviewers may only attach photos to their own reviews.
+// @Tags reviews
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Review ID"
+// @Param file formData file true "Photo"
+// @Success 201 {object} models.ReviewMediaResponse "Attached photo"
+// @Failure 400 {object} models.ErrorResponse "Invalid upload"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to perform the operation"
+// @Failure 404 {object} models.ErrorResponse "Review not found"
+// @Failure 409 {object} models.ErrorResponse "Review has too many photos"
+// @Failure 413 {object} models.ErrorResponse "Photo too large"
+// @Failure 415 {object} models.ErrorResponse "Unsupported file type"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Failed to upload photo"
+// @Failure 503 {object} models.ErrorResponse "Media storage not configured"
+// @Router /reviews/{id}/media [post]
+func UploadReviewMedia(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid review ID",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if !authorizeReviewChange(c, uint(id)) {
+		return
+	}
+
+	// Stop reading the body past the limit instead of spooling it to disk
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, service.MaxMediaUploadBytes())
+	header, err := c.FormFile("file")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, models.ErrorResponse{
+			Message: "Photo too large",
+			Details: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid upload",
+			Details: err.Error(),
+		})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid upload",
+			Details: err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+
+	item, err := service.AddReviewMedia(db.GetDB(), uint(id), callerSubject(c), file)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusCreated, models.NewReviewMediaResponse(*item))
+	case errors.Is(err, service.ErrNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Message: "Review not found",
+			Details: err.Error(),
+		})
+	case errors.Is(err, service.ErrTooManyMedia):
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Message: "Review has too many photos",
+			Details: err.Error(),
+		})
+	case errors.Is(err, media.ErrTooLarge), errors.Is(err, media.ErrTooManyPixels):
+		c.JSON(http.StatusRequestEntityTooLarge, models.ErrorResponse{
+			Message: "Photo too large",
+			Details: err.Error(),
+		})
+	case errors.Is(err, media.ErrUnsupportedType):
+		c.JSON(http.StatusUnsupportedMediaType, models.ErrorResponse{
+			Message: "Unsupported file type",
+			Details: err.Error(),
+		})
+	case errors.Is(err, service.ErrMediaStorageDisabled):
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{
+			Message: "Media storage not configured",
+			Details: err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to upload photo",
+			Details: err.Error(),
+		})
+	}
+}
+
+// GetReviewMedia serves a photo attached to a review
+// @Summary Get review photo
+// @Description Serves a photo attached to a review, with the content type sniffed at upload
+// @Tags reviews
+// @Produce image/jpeg,image/png,image/gif
+// @Param id path int true "Review ID"
+// @Param mediaId path int true "Photo ID"
+// @Success 200 {file} file "Photo"
+// @Failure 400 {object} models.ErrorResponse "Invalid photo ID"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to perform the operation"
+// @Failure 404 {object} models.ErrorResponse "Photo not found"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Failed to get photo"
+// @Router /reviews/{id}/media/{mediaId} [get]
+func GetReviewMedia(c *gin.Context) {
+	serveReviewMedia(c, false)
+}
+
+// GetReviewMediaThumbnail serves the thumbnail of a photo attached to a review
+// @Summary Get review photo thumbnail
+// @Description Serves the thumbnail of a photo attached to a review, a JPEG for JPEG photos and a PNG otherwise
+// @Tags reviews
+// @Produce image/jpeg,image/png
+// @Param id path int true "Review ID"
+// @Param mediaId path int true "Photo ID"
+// @Success 200 {file} file "Thumbnail"
+// @Failure 400 {object} models.ErrorResponse "Invalid photo ID"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to perform the operation"
+// @Failure 404 {object} models.ErrorResponse "Photo not found"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Failed to get photo"
+// @Router /reviews/{id}/media/{mediaId}/thumbnail [get]
+func GetReviewMediaThumbnail(c *gin.Context) {
+	serveReviewMedia(c, true)
+}
+
+// DeleteReviewMedia removes a photo from a review
+// @Summary Delete review photo
+// @Description Removes a photo from a review along with its thumbnail. Reviewers may only remove the photos of their own reviews.
+// @Tags reviews
+// @Param id path int true "Review ID"
+// @Param mediaId path int true "Photo ID"
+// @Success 204 "Photo deleted"
+// @Failure 400 {object} models.ErrorResponse "Invalid photo ID"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to perform the operation"
+// @Failure 404 {object} models.ErrorResponse "Photo not found"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Failed to delete photo"
+// @Router /reviews/{id}/media/{mediaId} [delete]
+func DeleteReviewMedia(c *gin.Context) {
+	reviewID, mediaID, ok := mediaIDs(c)
+	if !ok {
+		return
+	}
+
+	if !authorizeReviewChange(c, reviewID) {
+		return
+	}
+
+	err := service.DeleteReviewMedia(db.GetDB(), reviewID, mediaID)
+	if errors.Is(err, service.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Message: "Photo not found",
+			Details: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to delete photo",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// serveReviewMedia streams a photo attached to a review, or its thumbnail,
+// to the callers who may read the review.
+func serveReviewMedia(c *gin.Context, thumbnail bool) {
+	reviewID, mediaID, ok := mediaIDs(c)
+	if !ok {
+		return
+	}
+
+	review, err := service.GetReview(db.GetDB(), reviewID)
+	if err == nil && !canSeeReview(c, review) {
+		err = service.ErrNotFound
+	}
+	var item *models.ReviewMedia
+	if err == nil {
+		item, err = service.GetReviewMedia(db.GetDB(), reviewID, mediaID)
+	}
+	if errors.Is(err, service.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Message: "Photo not found",
+			Details: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to get photo",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	file, err := service.OpenReviewMedia(item, thumbnail)
+	if errors.Is(err, service.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Message: "Photo not found",
+			Details: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to get photo",
+			Details: err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+
+	contentType, size := item.ContentType, item.Size
+	if thumbnail {
+		contentType, size = item.ThumbnailContentType, -1
+	}
+	// Shared caches keep the photos of public reviews for a few minutes only, so taking down
+	// the review or the photo takes effect quickly. The photos of reviews that are not public
+	// are only served to their author and moderators, and never cached
+	cacheControl := "public, max-age=300"
+	if review.Status != models.ReviewApproved {
+		cacheControl = "private, no-store"
+	}
+	c.DataFromReader(http.StatusOK, size, contentType, file, map[string]string{
+		"Cache-Control":          cacheControl,
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+// mediaIDs parses the review and photo IDs of the request.
+// It responds with 400 and returns false if either is invalid.
+func mediaIDs(c *gin.Context) (uint, uint, bool) {
+	reviewID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid review ID",
+			Details: err.Error(),
+		})
+		return 0, 0, false
+	}
+	mediaID, err := strconv.Atoi(c.Param("mediaId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid photo ID",
+			Details: err.Error(),
+		})
+		return 0, 0, false
+	}
+	return uint(reviewID), uint(mediaID), true
+}
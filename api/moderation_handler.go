@@ -0,0 +1,211 @@
+package api
+
+import (
+	"errors"
+	"go_api_product_review/db"
+	"go_api_product_review/middleware"
+	"go_api_product_review/models"
+	"go_api_product_review/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterModerationRoutes initializes the routes for review moderation
+// Reviews are moderated by moderators and administrators, API keys also need the reviews:moderate scope.
+// The middlewares, such as rate limits, apply to every moderation route.
+// @Summary Register moderation routes
+// @Description Initializes the API endpoints for moderating reviews
+// @Tags moderation
+// @Security ApiKeyAuth
+// @Security APIKeyHeader
+func RegisterModerationRoutes(router *gin.Engine, middlewares ...gin.HandlerFunc) {
+	moderationGroup := router.Group("/moderation", middlewares...)
+	moderationGroup.Use(middleware.Authorize(middleware.Policy{
+		Roles: []string{middleware.RoleModerator},
+		Scope: middleware.ScopeReviewsModerate,
+	}))
+	{
+		moderationGroup.GET("/reviews", ListModerationQueue)
+		moderationGroup.POST("/reviews/:id/approve", ApproveReview)
+		moderationGroup.POST("/reviews/:id/reject", RejectReview)
+		moderationGroup.POST("/reviews/:id/flag", FlagReview)
+	}
+}
+
+// ListModerationQueue lists the reviews with a moderation status page by page
+// @Summary List the moderation queue
+// @Description Fetches a page of the reviews with the requested status, oldest first
+// @Tags moderation
+// @Produce json
+// @Param status query string false "Status of the reviews: pending, flagged, rejected or approved (default pending)"
+// @Param limit query int false "Maximum number of reviews to return (1-100, default 20)"
+// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+// @Success 200 {object} models.ReviewPage "Page of reviews"
+// @Failure 400 {object} models.ErrorResponse "Invalid query parameters"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to perform the operation"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Failed to list reviews"
+// @Router /moderation/reviews [get]
+func ListModerationQueue(c *gin.Context) {
+	var query models.ModerationQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid query parameters",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	// Validate using the model's method
+	if err := query.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid query parameters",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	page, err := service.ListModerationQueue(db.GetDB(), query)
+	if errors.Is(err, service.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid query parameters",
+			Details: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to list reviews",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// ApproveReview publishes a review
+// @Summary Approve review
+// @Description Publishes a pending, flagged or rejected review, which then counts toward the ratings of its product
+// @Tags moderation
+// @Accept json
+// @Produce json
+// @Param id path int true "Review ID"
+// @Param request body models.ModerationRequest false "Reason of the decision"
+// @Success 200 {object} models.ReviewResponse "Moderated review"
+// @Failure 400 {object} models.ErrorResponse "Invalid moderation request"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to perform the operation"
+// @Failure 404 {object} models.ErrorResponse "Review not found"
+// @Failure 409 {object} models.ErrorResponse "Review cannot be moved to this status"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Failed to moderate review"
+// @Router /moderation/reviews/{id}/approve [post]
+func ApproveReview(c *gin.Context) {
+	moderateReview(c, models.ReviewApproved)
+}
+
+// RejectReview turns down a review
+// @Summary Reject review
+// @Description Rejects a pending, flagged or approved review with a reason, removing it from the ratings of its product
+// @Tags moderation
+// @Accept json
+// @Produce json
+// @Param id path int true "Review ID"
+// @Param request body models.ModerationRequest true "Reason of the decision"
+// @Success 200 {object} models.ReviewResponse "Moderated review"
+// @Failure 400 {object} models.ErrorResponse "Invalid moderation request"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to perform the operation"
+// @Failure 404 {object} models.ErrorResponse "Review not found"
+// @Failure 409 {object} models.ErrorResponse "Review cannot be moved to this status"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Failed to moderate review"
+// @Router /moderation/reviews/{id}/reject [post]
+func RejectReview(c *gin.Context) {
+	moderateReview(c, models.ReviewRejected)
+}
+
+// FlagReview takes a review down for another look
+// @Summary Flag review
+// @Description Flags a pending or approved review with a reason, taking it down until a moderator approves or rejects it
+// @Tags moderation
+// @Accept json
+// @Produce json
+// @Param id path int true "Review ID"
+// @Param request body models.ModerationRequest true "Reason of the decision"
+// @Success 200 {object} models.ReviewResponse "Moderated review"
+// @Failure 400 {object} models.ErrorResponse "Invalid moderation request"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to perform the operation"
+// @Failure 404 {object} models.ErrorResponse "Review not found"
+// @Failure 409 {object} models.ErrorResponse "Review cannot be moved to this status"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Failed to moderate review"
+// @Router /moderation/reviews/{id}/flag [post]
+func FlagReview(c *gin.Context) {
+	moderateReview(c, models.ReviewFlagged)
+}
+
+// moderateReview moves the review of the request to the status, on behalf of the caller.
+func moderateReview(c *gin.Context, status string) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid review ID",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	// The reason is optional to approve a review, so is the body
+	var request models.ModerationRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Message: "Invalid moderation request",
+				Details: err.Error(),
+			})
+			return
+		}
+	}
+
+	// Validate using the model's method
+	if err := request.Validate(status); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid moderation request",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	moderator := ""
+	if principal, ok := middleware.PrincipalFromContext(c); ok {
+		moderator = principal.Subject
+	}
+
+	review, err := service.ModerateReview(db.GetDB(), uint(id), status, request.Reason, moderator)
+	if errors.Is(err, service.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Message: "Review not found",
+			Details: err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrInvalidTransition) {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Message: "Review cannot be moved to this status",
+			Details: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to moderate review",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewReviewResponse(*review))
+}
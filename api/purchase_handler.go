@@ -0,0 +1,74 @@
+package api
+
+import (
+	"go_api_product_review/db"
+	"go_api_product_review/middleware"
+	"go_api_product_review/models"
+	"go_api_product_review/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterPurchaseRoutes initializes the routes for purchase ingestion
+// Purchases are imported by administrators only, API keys also need the purchases:write scope.
+// The middlewares, such as rate limits, apply to every purchase route.
+// @Summary Register purchase routes
+// @Description Initializes the API endpoints for importing purchases
+// @Tags purchases
+// @Security ApiKeyAuth
+// @Security APIKeyHeader
+func RegisterPurchaseRoutes(router *gin.Engine, middlewares ...gin.HandlerFunc) {
+	purchaseGroup := router.Group("/purchases", middlewares...)
+	purchaseGroup.Use(middleware.Authorize(middleware.Policy{
+		Roles: []string{middleware.RoleAdmin},
+		Scope: middleware.ScopePurchasesWrite,
+	}))
+	{
+		purchaseGroup.POST("/", ImportPurchases)
+	}
+}
+
+// ImportPurchases records purchases from the order system
+// @Summary Import purchases
+// @Description Records up to 1000 purchases, keyed by customer and product. Purchases already recorded for the same order are skipped. Reviews of the customers on the bought products are marked as verified purchases, the ones written before the import included.
+// @Tags purchases
+// @Accept json
+// @Produce json
+// @Param purchases body models.PurchaseImport true "Purchases"
+// @Success 200 {object} models.PurchaseImportResult "Outcome of the import"
+// @Failure 400 {object} models.ErrorResponse "Invalid purchases"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to import purchases"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Failed to import purchases"
+// @Router /purchases [post]
+func ImportPurchases(c *gin.Context) {
+	var request models.PurchaseImport
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid purchases",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	// Validate using the model's method
+	if err := request.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid purchases",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	result, err := service.ImportPurchases(db.GetDB(), request.Purchases)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to import purchases",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,247 @@
+package api
+
+import (
+	"errors"
+	"go_api_product_review/db"
+	"go_api_product_review/middleware"
+	"go_api_product_review/models"
+	"go_api_product_review/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateReply replies to a review
+// @Summary Reply to review
+// @Description Publishes the reply of a merchant to an approved review, or to another reply of the review with parent_id. Replies are embedded in the review reads.
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Param id path int true "Review ID"
+// @Param reply body models.ReplyRequest true "Reply"
+// @Success 201 {object} models.ReviewReplyResponse "Created reply"
+// @Failure 400 {object} models.ErrorResponse "Invalid reply"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to perform the operation"
+// @Failure 404 {object} models.ErrorResponse "Review not found"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Failed to create reply"
+// @Router /reviews/{id}/replies [post]
+func CreateReply(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid review ID",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	request, ok := bindReplyRequest(c)
+	if !ok {
+		return
+	}
+
+	// The author is always the authenticated caller
+	author := ""
+	if principal, ok := middleware.PrincipalFromContext(c); ok {
+		author = principal.Subject
+	}
+
+	reply, err := service.CreateReply(db.GetDB(), uint(id), request.ParentID, author, request.Body)
+	if errors.Is(err, service.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Message: "Review not found",
+			Details: err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrInvalidReplyParent) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid reply",
+			Details: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to create reply",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.NewReviewReplyResponse(*reply))
+}
+
+// UpdateReply edits a reply to a review
+// @Summary Update reply
+// @Description Changes the text of a reply. Merchants may only change the replies they wrote.
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Param id path int true "Review ID"
+// @Param replyId path int true "Reply ID"
+// @Param reply body models.ReplyRequest true "Reply"
+// @Success 200 {object} models.ReviewReplyResponse "Updated reply"
+// @Failure 400 {object} models.ErrorResponse "Invalid reply"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to perform the operation"
+// @Failure 404 {object} models.ErrorResponse "Reply not found"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Failed to update reply"
+// @Router /reviews/{id}/replies/{replyId} [put]
+func UpdateReply(c *gin.Context) {
+	reviewID, replyID, ok := replyIDs(c)
+	if !ok {
+		return
+	}
+
+	if !authorizeReplyChange(c, reviewID, replyID) {
+		return
+	}
+
+	request, ok := bindReplyRequest(c)
+	if !ok {
+		return
+	}
+
+	reply, err := service.UpdateReply(db.GetDB(), reviewID, replyID, request.Body)
+	if errors.Is(err, service.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Message: "Reply not found",
+			Details: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to update reply",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewReviewReplyResponse(*reply))
+}
+
+// DeleteReply deletes a reply to a review
+// @Summary Delete reply
+// @Description Deletes a reply along with the replies answering it. Merchants may only delete the replies they wrote.
+// @Tags reviews
+// @Param id path int true "Review ID"
+// @Param replyId path int true "Reply ID"
+// @Success 204 "Reply deleted"
+// @Failure 400 {object} models.ErrorResponse "Invalid reply ID"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to perform the operation"
+// @Failure 404 {object} models.ErrorResponse "Reply not found"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Failed to delete reply"
+// @Router /reviews/{id}/replies/{replyId} [delete]
+func DeleteReply(c *gin.Context) {
+	reviewID, replyID, ok := replyIDs(c)
+	if !ok {
+		return
+	}
+
+	if !authorizeReplyChange(c, reviewID, replyID) {
+		return
+	}
+
+	err := service.DeleteReply(db.GetDB(), reviewID, replyID)
+	if errors.Is(err, service.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Message: "Reply not found",
+			Details: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to delete reply",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// replyIDs parses the review and reply IDs of the request.
+// It responds with 400 and returns false if either is invalid.
+func replyIDs(c *gin.Context) (uint, uint, bool) {
+	reviewID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid review ID",
+			Details: err.Error(),
+		})
+		return 0, 0, false
+	}
+	replyID, err := strconv.Atoi(c.Param("replyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid reply ID",
+			Details: err.Error(),
+		})
+		return 0, 0, false
+	}
+	return uint(reviewID), uint(replyID), true
+}
+
+// bindReplyRequest binds and validates the reply of the request.
+// It responds with 400 and returns false if the reply is invalid.
+func bindReplyRequest(c *gin.Context) (models.ReplyRequest, bool) {
+	var request models.ReplyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid reply",
+			Details: err.Error(),
+		})
+		return request, false
+	}
+
+	// Validate using the model's method
+	if err := request.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid reply",
+			Details: err.Error(),
+		})
+		return request, false
+	}
+	return request, true
+}
+
+// authorizeReplyChange lets administrators and the merchant who wrote the reply change it.
+// It responds with the appropriate error and returns false otherwise.
+func authorizeReplyChange(c *gin.Context, reviewID uint, replyID uint) bool {
+	principal, ok := middleware.PrincipalFromContext(c)
+	if ok && principal.IsAdmin() {
+		return true
+	}
+
+	reply, err := service.GetReply(db.GetDB(), reviewID, replyID)
+	if errors.Is(err, service.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Message: "Reply not found",
+			Details: err.Error(),
+		})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to get reply",
+			Details: err.Error(),
+		})
+		return false
+	}
+
+	if !ok || reply.AuthorSubject == "" || reply.AuthorSubject != principal.Subject {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Message: "Forbidden",
+			Details: "Only the author of the reply can change it",
+		})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"errors"
+	"go_api_product_review/db"
+	"go_api_product_review/middleware"
+	"go_api_product_review/models"
+	"go_api_product_review/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CastVote votes a review helpful or unhelpful
+// @Summary Vote on review
+// @Description Votes a review helpful or unhelpful on behalf of the caller, replacing the previous vote of the caller. Each caller has a single vote per review and cannot vote on their own reviews.
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Param id path int true "Review ID"
+// @Param vote body models.VoteRequest true "Vote"
+// @Success 200 {object} models.VoteTally "Votes of the review"
+// @Failure 400 {object} models.ErrorResponse "Invalid vote"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to perform the operation"
+// @Failure 404 {object} models.ErrorResponse "Review not found"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Failed to vote on review"
+// @Router /reviews/{id}/votes [post]
+func CastVote(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid review ID",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	var request models.VoteRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid vote",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	// Validate using the model's method
+	if err := request.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid vote",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	voter, ok := voterFromContext(c)
+	if !ok {
+		return
+	}
+
+	tally, err := service.CastVote(db.GetDB(), uint(id), voter, *request.Helpful)
+	if errors.Is(err, service.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Message: "Review not found",
+			Details: err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, service.ErrSelfVote) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Message: "Forbidden",
+			Details: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to vote on review",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, tally)
+}
+
+// DeleteVote withdraws the vote of the caller on a review
+// @Summary Withdraw vote
+// @Description Withdraws the vote of the caller on a review
+// @Tags reviews
+// @Produce json
+// @Param id path int true "Review ID"
+// @Success 200 {object} models.VoteTally "Votes of the review"
+// @Failure 400 {object} models.ErrorResponse "Invalid review ID"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to perform the operation"
+// @Failure 404 {object} models.ErrorResponse "Vote not found"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Failed to withdraw vote"
+// @Router /reviews/{id}/votes [delete]
+func DeleteVote(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid review ID",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	voter, ok := voterFromContext(c)
+	if !ok {
+		return
+	}
+
+	tally, err := service.DeleteVote(db.GetDB(), uint(id), voter)
+	if errors.Is(err, service.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Message: "Vote not found",
+			Details: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to withdraw vote",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, tally)
+}
+
+// voterFromContext returns the subject of the authenticated caller, who votes.
+// It responds with 403 and returns false for anonymous callers.
+func voterFromContext(c *gin.Context) (string, bool) {
+	principal, ok := middleware.PrincipalFromContext(c)
+	if !ok || principal.Subject == "" {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Message: "Forbidden",
+			Details: "Votes require an authenticated caller",
+		})
+		return "", false
+	}
+	return principal.Subject, true
+}
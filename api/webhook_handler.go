@@ -0,0 +1,196 @@
+package api
+
+import (
+	"errors"
+	"go_api_product_review/db"
+	"go_api_product_review/middleware"
+	"go_api_product_review/models"
+	"go_api_product_review/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterWebhookRoutes initializes the routes for webhook subscriptions
+// Webhooks are managed by administrators only, API keys also need the webhooks:manage scope.
+// The middlewares, such as rate limits, apply to every webhook route.
+// @Summary Register webhook routes
+// @Description Initializes the API endpoints for managing webhook subscriptions
+// @Tags webhooks
+// @Security ApiKeyAuth
+// @Security APIKeyHeader
+func RegisterWebhookRoutes(router *gin.Engine, middlewares ...gin.HandlerFunc) {
+	webhookGroup := router.Group("/webhooks", middlewares...)
+	webhookGroup.Use(middleware.Authorize(middleware.Policy{
+		Roles: []string{middleware.RoleAdmin},
+		Scope: middleware.ScopeWebhooksManage,
+	}))
+	{
+		webhookGroup.POST("/", CreateWebhook)
+		webhookGroup.GET("/", ListWebhooks)
+		webhookGroup.DELETE("/:id", DeleteWebhook)
+		webhookGroup.GET("/:id/deliveries", ListWebhookDeliveries)
+	}
+}
+
+// CreateWebhook creates a new webhook subscription
+// @Summary Create a webhook subscription
+// @Description Subscribes an endpoint to review and product events. Deliveries are signed with HMAC-SHA256 in the X-Webhook-Signature header.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param webhook body models.WebhookRequest true "Webhook details"
+// @Success 201 {object} models.WebhookResponse "Successfully created webhook"
+// @Failure 400 {object} models.ErrorResponse "Invalid webhook data"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to manage webhooks"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /webhooks [post]
+func CreateWebhook(c *gin.Context) {
+	var request models.WebhookRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid webhook data",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	// Validate using the model's method
+	if err := request.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid webhook data",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	subscription := request.ToSubscription()
+	createdSubscription, err := service.CreateWebhook(db.GetDB(), &subscription)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to create webhook",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.NewWebhookResponse(*createdSubscription))
+}
+
+// ListWebhooks lists all webhook subscriptions
+// @Summary List webhook subscriptions
+// @Description Fetches all webhook subscriptions, without their secrets
+// @Tags webhooks
+// @Produce json
+// @Success 200 {array} models.WebhookResponse "List of webhooks"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to manage webhooks"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Router /webhooks [get]
+func ListWebhooks(c *gin.Context) {
+	subscriptions, err := service.ListWebhooks(db.GetDB())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to list webhooks",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	webhooks := make([]models.WebhookResponse, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		webhooks = append(webhooks, models.NewWebhookResponse(subscription))
+	}
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// DeleteWebhook deletes a webhook subscription by its ID
+// @Summary Delete webhook subscription
+// @Description Deletes a webhook subscription, its pending deliveries are abandoned
+// @Tags webhooks
+// @Param id path int true "Webhook ID"
+// @Success 204 "Webhook deleted"
+// @Failure 400 {object} models.ErrorResponse "Invalid webhook ID"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to manage webhooks"
+// @Failure 404 {object} models.ErrorResponse "Webhook not found"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Failed to delete webhook"
+// @Router /webhooks/{id} [delete]
+func DeleteWebhook(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid webhook ID",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	err = service.DeleteWebhook(db.GetDB(), uint(id))
+	if errors.Is(err, service.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Message: "Webhook not found",
+			Details: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to delete webhook",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// ListWebhookDeliveries lists the latest deliveries of a webhook subscription
+// @Summary List webhook deliveries
+// @Description Fetches the latest 100 deliveries of a webhook subscription, newest first
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 200 {array} models.WebhookDeliveryResponse "List of deliveries"
+// @Failure 400 {object} models.ErrorResponse "Invalid webhook ID"
+// @Failure 403 {object} models.ErrorResponse "Caller not allowed to manage webhooks"
+// @Failure 404 {object} models.ErrorResponse "Webhook not found"
+// @Failure 429 {object} models.ErrorResponse "Too many requests"
+// @Failure 500 {object} models.ErrorResponse "Failed to list deliveries"
+// @Router /webhooks/{id}/deliveries [get]
+func ListWebhookDeliveries(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Invalid webhook ID",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	deliveries, err := service.ListWebhookDeliveries(db.GetDB(), uint(id), 100)
+	if errors.Is(err, service.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Message: "Webhook not found",
+			Details: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to list deliveries",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	response := make([]models.WebhookDeliveryResponse, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		response = append(response, models.NewWebhookDeliveryResponse(delivery))
+	}
+	c.JSON(http.StatusOK, response)
+}
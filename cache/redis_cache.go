@@ -14,7 +14,15 @@ type RedisClient interface {
 	Ping(ctx context.Context) *redis.StatusCmd
 	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
 	Get(ctx context.Context, key string) *redis.StringCmd
+	SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd
 	Del(ctx context.Context, keys ...string) *redis.IntCmd
+	Incr(ctx context.Context, key string) *redis.IntCmd
+	IncrBy(ctx context.Context, key string, value int64) *redis.IntCmd
+	SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	SPopN(ctx context.Context, key string, count int64) *redis.StringSliceCmd
+	Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd
+	Publish(ctx context.Context, channel string, message interface{}) *redis.IntCmd
 }
 
 // Rdb is the global Redis client instance.
@@ -44,3 +52,17 @@ func InitRedis(client RedisClient) {
 		log.Fatalf("failed to connect to redis: %v", err)
 	}
 }
+
+// IncrWithExpireScript increments the counter at KEYS[1] and, when the increment created it,
+// sets its expiration to ARGV[1] milliseconds, in one atomic step.
+const IncrWithExpireScript = `local count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return count`
+
+// IncrWithExpire increments the counter of the key and sets its expiration when it is created.
+// Both run in a single script, so a counter never outlives its expiration.
+func IncrWithExpire(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	return Rdb.Eval(ctx, IncrWithExpireScript, []string{key}, expiration.Milliseconds()).Int64()
+}
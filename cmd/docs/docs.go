@@ -15,26 +15,38 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/products": {
+        "/api-keys": {
             "get": {
-                "description": "Fetches all products from the catalog",
+                "description": "Fetches all API keys, including revoked ones, without the keys themselves",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "api-keys"
                 ],
-                "summary": "List all products",
+                "summary": "List API keys",
                 "responses": {
                     "200": {
-                        "description": "List of products",
+                        "description": "List of API keys",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.Product"
+                                "$ref": "#/definitions/models.APIKeyResponse"
                             }
                         }
                     },
+                    "403": {
+                        "description": "Caller not allowed to manage API keys",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
@@ -44,7 +56,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Creates a new product in the catalog",
+                "description": "Issues an API key with the given scopes and roles. The key is only returned in this response.",
                 "consumes": [
                     "application/json"
                 ],
@@ -52,29 +64,41 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "api-keys"
                 ],
-                "summary": "Create a new product",
+                "summary": "Create an API key",
                 "parameters": [
                     {
-                        "description": "Product details",
-                        "name": "product",
+                        "description": "API key details",
+                        "name": "apiKey",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Product"
+                            "$ref": "#/definitions/models.APIKeyRequest"
                         }
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "Successfully created product",
+                        "description": "Successfully created API key",
                         "schema": {
-                            "$ref": "#/definitions/models.Product"
+                            "$ref": "#/definitions/models.IssuedAPIKeyResponse"
                         }
                     },
                     "400": {
-                        "description": "Invalid product data",
+                        "description": "Invalid API key data",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to manage API keys or to grant the scopes and roles",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -88,20 +112,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/products/{id}": {
-            "get": {
-                "description": "Fetches a product by its unique ID",
+        "/api-keys/{id}": {
+            "delete": {
+                "description": "Revokes an API key, which stops working immediately. The key remains listed as revoked.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "api-keys"
                 ],
-                "summary": "Get product by ID",
+                "summary": "Revoke an API key",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Product ID",
+                        "description": "API key ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -109,113 +133,151 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Product found",
+                        "description": "Revoked API key",
                         "schema": {
-                            "$ref": "#/definitions/models.Product"
+                            "$ref": "#/definitions/models.APIKeyResponse"
                         }
                     },
                     "400": {
-                        "description": "Invalid product id",
+                        "description": "Invalid API key ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to manage API keys",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "API key not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to retrieve product",
+                        "description": "Failed to revoke API key",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
-            },
-            "put": {
-                "description": "Updates an existing product by its ID",
-                "consumes": [
-                    "application/json"
-                ],
+            }
+        },
+        "/api-keys/{id}/rotate": {
+            "post": {
+                "description": "Issues a new key keeping the name, scopes, roles and expiry. The previous key stops working immediately.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "api-keys"
                 ],
-                "summary": "Update product",
+                "summary": "Rotate an API key",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Product ID",
+                        "description": "API key ID",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Updated product details",
-                        "name": "product",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.Product"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Updated product",
+                        "description": "Successfully rotated API key",
                         "schema": {
-                            "$ref": "#/definitions/models.Product"
+                            "$ref": "#/definitions/models.IssuedAPIKeyResponse"
                         }
                     },
                     "400": {
-                        "description": "Invalid product ID",
+                        "description": "Invalid API key ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to manage API keys or to grant the scopes and roles of the key",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "API key not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "API key is revoked",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to update product",
+                        "description": "Failed to rotate API key",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Deletes a product by its ID",
-                "tags": [
-                    "products"
+            }
+        },
+        "/categories": {
+            "get": {
+                "description": "Fetches every category with its aspects and rating aggregates, ordered by path so each category follows its ancestors",
+                "produces": [
+                    "application/json"
                 ],
-                "summary": "Delete product",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Product ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                "tags": [
+                    "categories"
                 ],
+                "summary": "List categories",
                 "responses": {
-                    "204": {
-                        "description": "Product deleted"
+                    "200": {
+                        "description": "Categories",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Category"
+                            }
+                        }
                     },
-                    "400": {
-                        "description": "Invalid product ID",
+                    "403": {
+                        "description": "Caller not allowed to perform the operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to delete product",
+                        "description": "Failed to list categories",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/reviews": {
+            },
             "post": {
-                "description": "Creates a new review for a product",
+                "description": "Creates a category of products under its parent, or at the top level without parent_id, along with the aspects their reviews rate from 1 to 5, such as the battery life or the fit. The slug is derived from the name when empty.",
                 "consumes": [
                     "application/json"
                 ],
@@ -223,35 +285,53 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "reviews"
+                    "categories"
                 ],
-                "summary": "Create a new review",
+                "summary": "Create a new category",
                 "parameters": [
                     {
-                        "description": "Review details",
-                        "name": "review",
+                        "description": "Category details",
+                        "name": "category",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Review"
+                            "$ref": "#/definitions/models.Category"
                         }
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "Successfully created review",
+                        "description": "Successfully created category",
                         "schema": {
-                            "$ref": "#/definitions/models.Review"
+                            "$ref": "#/definitions/models.Category"
                         }
                     },
                     "400": {
-                        "description": "Invalid review data",
+                        "description": "Invalid category data",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to perform the operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Slug already used under the parent",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal server error",
+                        "description": "Failed to create category",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -259,9 +339,66 @@ const docTemplate = `{
                 }
             }
         },
-        "/reviews/{id}": {
+        "/categories/{id}": {
+            "get": {
+                "description": "Fetches a category with the aspects rated by the reviews of its products and the rating aggregates rolled up from the products of the category and its descendants",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "categories"
+                ],
+                "summary": "Get category by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Category ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Category found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Category"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid category ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to perform the operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Category not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get category",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
             "put": {
-                "description": "Updates an existing review by its ID",
+                "description": "Renames a category, moves it under another parent and replaces its aspects. The paths of its descendants follow its slug and parent. The ratings already given to removed aspects are kept, but new reviews can no longer rate them.",
                 "consumes": [
                     "application/json"
                 ],
@@ -269,42 +406,66 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "reviews"
+                    "categories"
                 ],
-                "summary": "Update review",
+                "summary": "Update category",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Review ID",
+                        "description": "Category ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Updated review details",
-                        "name": "review",
+                        "description": "Updated category details",
+                        "name": "category",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Review"
+                            "$ref": "#/definitions/models.Category"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Updated review",
+                        "description": "Updated category",
                         "schema": {
-                            "$ref": "#/definitions/models.Review"
+                            "$ref": "#/definitions/models.Category"
                         }
                     },
                     "400": {
-                        "description": "Invalid review Data",
+                        "description": "Invalid category data",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to perform the operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Category not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Slug already used under the parent",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to update review",
+                        "description": "Failed to update category",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -312,15 +473,15 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Deletes a review by its ID",
+                "description": "Deletes a category without subcategories along with its aspects. Its products stay in the catalog and leave the category.",
                 "tags": [
-                    "reviews"
+                    "categories"
                 ],
-                "summary": "Delete review",
+                "summary": "Delete category",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Review ID",
+                        "description": "Category ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -328,91 +489,3598 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "204": {
-                        "description": "Review deleted"
+                        "description": "Category deleted"
                     },
                     "400": {
-                        "description": "Invalid review ID",
+                        "description": "Invalid category ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to perform the operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Category not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Category has subcategories",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to delete review",
+                        "description": "Failed to delete category",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "models.ErrorResponse": {
-            "description": "Standard error response format for all API errors",
-            "type": "object",
-            "properties": {
-                "details": {
-                    "description": "Details contains additional information (optional)\n@example \"The product ID provided was not an integer.\"",
-                    "type": "string"
-                },
-                "message": {
-                    "description": "Message contains a human-readable error message\n@example \"Invalid product ID\"",
-                    "type": "string"
+        },
+        "/categories/{id}/products": {
+            "get": {
+                "description": "Fetches a page of the summaries of the products assigned to a category or one of its descendants, with the filters and sort order of the product listing",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "categories"
+                ],
+                "summary": "List category products",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Category ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of products to return (1-100, default 20)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor returned as next_cursor by the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Minimum price",
+                        "name": "min_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Maximum price",
+                        "name": "max_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Minimum average rating",
+                        "name": "min_rating",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Case-insensitive substring of the product name",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort key: price, average_rating, ranking_score or created_at, prefixed with - for descending order (default -created_at)",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include the total number of matching products",
+                        "name": "include_total",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Set to reviews to embed the reviews of each product",
+                        "name": "include",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Page of products",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProductPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to perform the operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Category not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to list products",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/moderation/reviews": {
+            "get": {
+                "description": "Fetches a page of the reviews with the requested status, oldest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "moderation"
+                ],
+                "summary": "List the moderation queue",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Status of the reviews: pending, flagged, rejected or approved (default pending)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of reviews to return (1-100, default 20)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor returned as next_cursor by the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Page of reviews",
+                        "schema": {
+                            "$ref": "#/definitions/models.ReviewPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to perform the operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to list reviews",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/moderation/reviews/{id}/approve": {
+            "post": {
+                "description": "Publishes a pending, flagged or rejected review, which then counts toward the ratings of its product",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "moderation"
+                ],
+                "summary": "Approve review",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Review ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reason of the decision",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.ModerationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Moderated review",
+                        "schema": {
+                            "$ref": "#/definitions/models.ReviewResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid moderation request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to perform the operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Review not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Review cannot be moved to this status",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to moderate review",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/moderation/reviews/{id}/flag": {
+            "post": {
+                "description": "Flags a pending or approved review with a reason, taking it down until a moderator approves or rejects it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "moderation"
+                ],
+                "summary": "Flag review",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Review ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reason of the decision",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ModerationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Moderated review",
+                        "schema": {
+                            "$ref": "#/definitions/models.ReviewResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid moderation request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to perform the operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Review not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Review cannot be moved to this status",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to moderate review",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/moderation/reviews/{id}/reject": {
+            "post": {
+                "description": "Rejects a pending, flagged or approved review with a reason, removing it from the ratings of its product",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "moderation"
+                ],
+                "summary": "Reject review",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Review ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reason of the decision",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ModerationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Moderated review",
+                        "schema": {
+                            "$ref": "#/definitions/models.ReviewResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid moderation request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to perform the operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Review not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Review cannot be moved to this status",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to moderate review",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/products": {
+            "get": {
+                "description": "Fetches a page of product summaries, optionally filtered by price range, minimum average rating, name and category",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "List products",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of products to return (1-100, default 20)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor returned as next_cursor by the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Minimum price",
+                        "name": "min_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Maximum price",
+                        "name": "max_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Minimum average rating",
+                        "name": "min_rating",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Case-insensitive substring of the product name",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only products assigned to this category or one of its descendants",
+                        "name": "category_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort key: price, average_rating, ranking_score or created_at, prefixed with - for descending order (default -created_at)",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include the total number of matching products",
+                        "name": "include_total",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Set to reviews to embed the reviews of each product",
+                        "name": "include",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Page of products",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProductPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Creates a new product in the catalog, assigned to the existing categories listed in category_ids and to category_id, whose aspects its reviews rate",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Create a new product",
+                "parameters": [
+                    {
+                        "description": "Product details",
+                        "name": "product",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Product"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Successfully created product",
+                        "schema": {
+                            "$ref": "#/definitions/models.Product"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid product data",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to perform the operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}": {
+            "get": {
+                "description": "Fetches a product by its unique ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Get product by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Set to reviews to embed the product reviews",
+                        "name": "include",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Product found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProductDetail"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid product id",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Product not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to retrieve product",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Updates an existing product by its ID, replacing its categories with the existing ones listed in category_ids and category_id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Update product",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated product details",
+                        "name": "product",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Product"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated product",
+                        "schema": {
+                            "$ref": "#/definitions/models.Product"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid product ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to perform the operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to update product",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes a product by its ID",
+                "tags": [
+                    "products"
+                ],
+                "summary": "Delete product",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Product deleted"
+                    },
+                    "400": {
+                        "description": "Invalid product ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to perform the operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to delete product",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}/rating-summary": {
+            "get": {
+                "description": "Fetches the number and share of reviews with each rating, along with the total, average and median rating",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Get product rating summary",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Rating summary",
+                        "schema": {
+                            "$ref": "#/definitions/models.RatingSummary"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid product ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Product not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get rating summary",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}/rating-trend": {
+            "get": {
+                "description": "Fetches the number and average rating of the reviews of a product per week or month, along with its recent and time-decayed average ratings",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Get product rating trend",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Length of the periods: week or month (default week)",
+                        "name": "interval",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of periods to return, ending with the current one (1-52, default 12)",
+                        "name": "periods",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Rating trend",
+                        "schema": {
+                            "$ref": "#/definitions/models.RatingTrend"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Product not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get rating trend",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}/reviews": {
+            "get": {
+                "description": "Fetches a page of reviews of a product, optionally filtered by rating and to verified purchases",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "List product reviews",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of reviews to return (1-100, default 20)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor returned as next_cursor by the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort order: newest, highest, lowest or helpful (default newest)",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only return reviews with this rating",
+                        "name": "rating",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only return reviews of verified purchases",
+                        "name": "verified",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Page of reviews",
+                        "schema": {
+                            "$ref": "#/definitions/models.ReviewPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Product not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to list reviews",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/purchases": {
+            "post": {
+                "description": "Records up to 1000 purchases, keyed by customer and product. Purchases already recorded for the same order are skipped. Reviews of the customers on the bought products are marked as verified purchases, the ones written before the import included.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "purchases"
+                ],
+                "summary": "Import purchases",
+                "parameters": [
+                    {
+                        "description": "Purchases",
+                        "name": "purchases",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.PurchaseImport"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Outcome of the import",
+                        "schema": {
+                            "$ref": "#/definitions/models.PurchaseImportResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid purchases",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to import purchases",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to import purchases",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/reviews": {
+            "post": {
+                "description": "Creates a new review for a product. The review is pending until a moderator approves it, only approved reviews are public and count toward the ratings. The content screening and the duplicate detection may reject the review or flag it for moderation.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reviews"
+                ],
+                "summary": "Create a new review",
+                "parameters": [
+                    {
+                        "description": "Review details",
+                        "name": "review",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Review"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Successfully created review",
+                        "schema": {
+                            "$ref": "#/definitions/models.Review"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid review data",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to perform the operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Review duplicates another review",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Review rejected by content screening",
+                        "schema": {
+                            "$ref": "#/definitions/models.ScreeningErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/reviews/{id}": {
+            "get": {
+                "description": "Fetches a review by its unique ID. Reviews that are not approved are only visible to their author and moderators.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reviews"
+                ],
+                "summary": "Get review by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Review ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Review found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ReviewResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid review ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Review not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get review",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Updates an existing review by its ID. The new text goes through the content screening, which rejects it or takes the review down for moderation. The aspect ratings are replaced with the updated ones.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reviews"
+                ],
+                "summary": "Update review",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Review ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated review details",
+                        "name": "review",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Review"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated review",
+                        "schema": {
+                            "$ref": "#/definitions/models.Review"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid review Data",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to perform the operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Review not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Review duplicates another review",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Review rejected by content screening",
+                        "schema": {
+                            "$ref": "#/definitions/models.ScreeningErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to update review",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes a review by its ID",
+                "tags": [
+                    "reviews"
+                ],
+                "summary": "Delete review",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Review ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Review deleted"
+                    },
+                    "400": {
+                        "description": "Invalid review ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to perform the operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Review not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Review was moderated meanwhile",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to delete review",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/reviews/{id}/history": {
+            "get": {
+                "description": "Fetches the prior versions of a review, oldest first, with who changed the review and when. Every update and delete of a review is recorded, and the history of deleted reviews is kept. Only the author of the review and moderators may read it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reviews"
+                ],
+                "summary": "Get review history",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Review ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "History of the review",
+                        "schema": {
+                            "$ref": "#/definitions/models.ReviewHistory"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid review ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to perform the operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Review not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get review history",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/reviews/{id}/history/{version}/restore": {
+            "post": {
+                "description": "Brings a review back to the names, text, rating and aspect ratings of one of its prior versions, undeleting it if it was deleted, and recomputes the ratings of the product. The moderation status of the review is kept. The current version is recorded in the history first. Administrators only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reviews"
+                ],
+                "summary": "Restore review revision",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Review ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Version to restore",
+                        "name": "version",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Restored review",
+                        "schema": {
+                            "$ref": "#/definitions/models.ReviewResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid review ID or version",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to perform the operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Review or version not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Version rates aspects the category of the product no longer defines",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to restore review",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/reviews/{id}/media": {
+            "post": {
+                "description": "Attaches a JPEG, PNG or GIF photo to a review, sent as the file field of a multipart form. The type is sniffed from the content of the file, and the size, dimensions and number of photos of a review are limited. A thumbnail is generated, and the photos are embedded in the review reads. Reviewers may only attach photos to their own reviews.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reviews"
+                ],
+                "summary": "Upload review photo",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Review ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Photo",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Attached photo",
+                        "schema": {
+                            "$ref": "#/definitions/models.ReviewMediaResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid upload",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to perform the operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Review not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Review has too many photos",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Photo too large",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported file type",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to upload photo",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Media storage not configured",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/reviews/{id}/media/{mediaId}": {
+            "get": {
+                "description": "Serves a photo attached to a review, with the content type sniffed at upload",
+                "produces": [
+                    "image/jpeg",
+                    "image/png",
+                    "image/gif"
+                ],
+                "tags": [
+                    "reviews"
+                ],
+                "summary": "Get review photo",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Review ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Photo ID",
+                        "name": "mediaId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Photo",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid photo ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to perform the operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Photo not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get photo",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Removes a photo from a review along with its thumbnail. Reviewers may only remove the photos of their own reviews.",
+                "tags": [
+                    "reviews"
+                ],
+                "summary": "Delete review photo",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Review ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Photo ID",
+                        "name": "mediaId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Photo deleted"
+                    },
+                    "400": {
+                        "description": "Invalid photo ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to perform the operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Photo not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to delete photo",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/reviews/{id}/media/{mediaId}/thumbnail": {
+            "get": {
+                "description": "Serves the thumbnail of a photo attached to a review, a JPEG for JPEG photos and a PNG otherwise",
+                "produces": [
+                    "image/jpeg",
+                    "image/png"
+                ],
+                "tags": [
+                    "reviews"
+                ],
+                "summary": "Get review photo thumbnail",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Review ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Photo ID",
+                        "name": "mediaId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Thumbnail",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid photo ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to perform the operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Photo not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get photo",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/reviews/{id}/replies": {
+            "post": {
+                "description": "Publishes the reply of a merchant to an approved review, or to another reply of the review with parent_id. Replies are embedded in the review reads.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reviews"
+                ],
+                "summary": "Reply to review",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Review ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reply",
+                        "name": "reply",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ReplyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created reply",
+                        "schema": {
+                            "$ref": "#/definitions/models.ReviewReplyResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid reply",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to perform the operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Review not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to create reply",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/reviews/{id}/replies/{replyId}": {
+            "put": {
+                "description": "Changes the text of a reply. Merchants may only change the replies they wrote.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reviews"
+                ],
+                "summary": "Update reply",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Review ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Reply ID",
+                        "name": "replyId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reply",
+                        "name": "reply",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ReplyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated reply",
+                        "schema": {
+                            "$ref": "#/definitions/models.ReviewReplyResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid reply",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to perform the operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Reply not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to update reply",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes a reply along with the replies answering it. Merchants may only delete the replies they wrote.",
+                "tags": [
+                    "reviews"
+                ],
+                "summary": "Delete reply",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Review ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Reply ID",
+                        "name": "replyId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Reply deleted"
+                    },
+                    "400": {
+                        "description": "Invalid reply ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to perform the operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Reply not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to delete reply",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/reviews/{id}/votes": {
+            "post": {
+                "description": "Votes a review helpful or unhelpful on behalf of the caller, replacing the previous vote of the caller. Each caller has a single vote per review and cannot vote on their own reviews.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reviews"
+                ],
+                "summary": "Vote on review",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Review ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Vote",
+                        "name": "vote",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.VoteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Votes of the review",
+                        "schema": {
+                            "$ref": "#/definitions/models.VoteTally"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid vote",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to perform the operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Review not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to vote on review",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Withdraws the vote of the caller on a review",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reviews"
+                ],
+                "summary": "Withdraw vote",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Review ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Votes of the review",
+                        "schema": {
+                            "$ref": "#/definitions/models.VoteTally"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid review ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to perform the operation",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Vote not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to withdraw vote",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks": {
+            "get": {
+                "description": "Fetches all webhook subscriptions, without their secrets",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List webhook subscriptions",
+                "responses": {
+                    "200": {
+                        "description": "List of webhooks",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.WebhookResponse"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to manage webhooks",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Subscribes an endpoint to review and product events. Deliveries are signed with HMAC-SHA256 in the X-Webhook-Signature header.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Create a webhook subscription",
+                "parameters": [
+                    {
+                        "description": "Webhook details",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.WebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Successfully created webhook",
+                        "schema": {
+                            "$ref": "#/definitions/models.WebhookResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid webhook data",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to manage webhooks",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}": {
+            "delete": {
+                "description": "Deletes a webhook subscription, its pending deliveries are abandoned",
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Delete webhook subscription",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Webhook deleted"
+                    },
+                    "400": {
+                        "description": "Invalid webhook ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to manage webhooks",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to delete webhook",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/deliveries": {
+            "get": {
+                "description": "Fetches the latest 100 deliveries of a webhook subscription, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List webhook deliveries",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of deliveries",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.WebhookDeliveryResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid webhook ID",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller not allowed to manage webhooks",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to list deliveries",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "models.APIKeyRequest": {
+            "description": "API key details",
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "description": "Expiration date of the key, omit for a key that never expires",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Name of the integration using the key\n@example \"Partner storefront\"",
+                    "type": "string"
+                },
+                "roles": {
+                    "description": "Roles granted to the key\n@example [\"reviewer\"]",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "scopes": {
+                    "description": "Scopes granted to the key\n@example [\"products:read\",\"reviews:write\"]",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.APIKeyResponse": {
+            "description": "API key",
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "Date the key was created",
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "Expiration date of the key",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "Unique identifier of the key\n@example 5",
+                    "type": "integer"
+                },
+                "last_used_at": {
+                    "description": "Date the key was last used",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Name of the integration using the key\n@example \"Partner storefront\"",
+                    "type": "string"
+                },
+                "prefix": {
+                    "description": "Beginning of the key, to identify it\n@example \"prk_4Fh2kQ9x\"",
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "description": "Date the key was revoked",
+                    "type": "string"
+                },
+                "roles": {
+                    "description": "Roles granted to the key\n@example [\"reviewer\"]",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "scopes": {
+                    "description": "Scopes granted to the key\n@example [\"products:read\",\"reviews:write\"]",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.AspectRatingResponse": {
+            "description": "Average rating of an aspect of a product over the reviews rating it",
+            "type": "object",
+            "properties": {
+                "aspect": {
+                    "description": "Key of the aspect\n@example \"battery_life\"",
+                    "type": "string"
+                },
+                "average_rating": {
+                    "description": "Average rating of the aspect\n@example 4.2",
+                    "type": "number"
+                },
+                "review_count": {
+                    "description": "Number of reviews rating the aspect\n@example 9",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Category": {
+            "description": "Category of products in the category tree and the aspects their reviews rate",
+            "type": "object",
+            "properties": {
+                "aspects": {
+                    "description": "Aspects rated by the reviews of the products of the category, the products of its\ndescendants rate them too",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.CategoryAspect"
+                    }
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "deletedAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "description": "Name of the category\n@example \"Headphones\"",
+                    "type": "string"
+                },
+                "parent_id": {
+                    "description": "ID of the parent category, top-level categories have none\n@example 2",
+                    "type": "integer"
+                },
+                "path": {
+                    "description": "Slugs of the ancestors of the category and its own, joined by slashes, maintained by the service\n@example \"electronics/audio/headphones\"",
+                    "type": "string"
+                },
+                "ratings": {
+                    "description": "Rating aggregates rolled up from the products of the category and its descendants, only set in responses",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.CategoryRatings"
+                        }
+                    ]
+                },
+                "slug": {
+                    "description": "Slug identifying the category among its siblings, derived from the name when empty\n@example \"headphones\"",
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CategoryAspect": {
+            "type": "object",
+            "properties": {
+                "key": {
+                    "description": "Key identifying the aspect in the aspect ratings of the reviews\n@example \"battery_life\"",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Name of the aspect shown to the reviewers\n@example \"Battery life\"",
+                    "type": "string"
+                }
+            }
+        },
+        "models.CategoryRatings": {
+            "description": "Rating aggregates of a category rolled up from the products of the category and its descendants",
+            "type": "object",
+            "properties": {
+                "average_rating": {
+                    "description": "Mean of the average ratings of the reviewed products\n@example 4.2",
+                    "type": "number"
+                },
+                "product_count": {
+                    "description": "Number of products in the category and its descendants\n@example 14",
+                    "type": "integer"
+                },
+                "rated_product_count": {
+                    "description": "Number of those products with at least one approved review\n@example 9",
+                    "type": "integer"
+                },
+                "review_count": {
+                    "description": "Number of approved reviews of those products\n@example 120",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ErrorResponse": {
+            "description": "Standard error response format for all API errors",
+            "type": "object",
+            "properties": {
+                "details": {
+                    "description": "Details contains additional information (optional)\n@example \"The product ID provided was not an integer.\"",
+                    "type": "string"
+                },
+                "message": {
+                    "description": "Message contains a human-readable error message\n@example \"Invalid product ID\"",
+                    "type": "string"
+                }
+            }
+        },
+        "models.IssuedAPIKeyResponse": {
+            "description": "Newly issued API key",
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "Date the key was created",
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "Expiration date of the key",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "Unique identifier of the key\n@example 5",
+                    "type": "integer"
+                },
+                "key": {
+                    "description": "The API key, to be sent in the X-API-Key header. It cannot be retrieved again.\n@example \"prk_4Fh2kQ9xT0mJ8sLw1ZbYcVdR3nE6uA5pGiHkOq7fXy2\"",
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "description": "Date the key was last used",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Name of the integration using the key\n@example \"Partner storefront\"",
+                    "type": "string"
+                },
+                "prefix": {
+                    "description": "Beginning of the key, to identify it\n@example \"prk_4Fh2kQ9x\"",
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "description": "Date the key was revoked",
+                    "type": "string"
+                },
+                "roles": {
+                    "description": "Roles granted to the key\n@example [\"reviewer\"]",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "scopes": {
+                    "description": "Scopes granted to the key\n@example [\"products:read\",\"reviews:write\"]",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.ModerationRequest": {
+            "description": "Reason of a moderation decision",
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "description": "Reason of the decision, required to reject or flag a review\n@example \"Contains personal information\"",
+                    "type": "string"
+                }
+            }
+        },
+        "models.Product": {
+            "description": "Represents a product in the store or catalog",
+            "type": "object",
+            "required": [
+                "name",
+                "price"
+            ],
+            "properties": {
+                "average_rating": {
+                    "description": "Average rating of the product based on reviews\n@example 4.5",
+                    "type": "number"
+                },
+                "category_id": {
+                    "description": "ID of the category of the product, whose aspects the reviews of the product rate\n@example 3",
+                    "type": "integer"
+                },
+                "category_ids": {
+                    "description": "IDs of every category the product is assigned to, including category_id,\nloaded from the product_categories table\n@example [3,7]",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "decayed_average_rating": {
+                    "description": "Average rating with every review weighted by its age, halving every decay half-life\n@example 4.6",
+                    "type": "number"
+                },
+                "deletedAt": {
+                    "type": "string"
+                },
+                "description": {
+                    "description": "Description of the product\n@example \"Bananas from Argentina\"",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "description": "Name of the product\n@example \"Bananas\"",
+                    "type": "string"
+                },
+                "price": {
+                    "description": "Price of the product\n@example 20.00",
+                    "type": "number"
+                },
+                "ranking_score": {
+                    "description": "Score used to rank the product, an average rating weighted by the confidence in it\n@example 4.31",
+                    "type": "number"
+                },
+                "recent_average_rating": {
+                    "description": "Average rating of the reviews created within the recent window\n@example 4.8",
+                    "type": "number"
+                },
+                "recent_review_count": {
+                    "description": "Number of reviews created within the recent window\n@example 3",
+                    "type": "integer"
+                },
+                "review_count": {
+                    "description": "Number of reviews of the product, maintained along with every review change\n@example 12",
+                    "type": "integer"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "verified_average_rating": {
+                    "description": "Average rating of the reviews of verified purchases\n@example 4.7",
+                    "type": "number"
+                },
+                "verified_review_count": {
+                    "description": "Number of reviews of verified purchases, maintained along with every review change\n@example 5",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ProductDetail": {
+            "description": "Product with its average rating and record timestamps, reviews are only embedded when requested with include=reviews",
+            "type": "object",
+            "properties": {
+                "aspect_ratings": {
+                    "description": "Average ratings of the aspects rated by the reviews, such as the battery life or the fit",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.AspectRatingResponse"
+                    }
+                },
+                "average_rating": {
+                    "description": "Average rating of the product based on reviews\n@example 4.5",
+                    "type": "number"
+                },
+                "category_id": {
+                    "description": "ID of the category of the product\n@example 3",
+                    "type": "integer"
+                },
+                "category_ids": {
+                    "description": "IDs of every category the product is assigned to\n@example [3,7]",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "created_at": {
+                    "description": "Date the product was created",
+                    "type": "string"
+                },
+                "decayed_average_rating": {
+                    "description": "Average rating with every review weighted by its age, recent reviews weighing the most\n@example 4.6",
+                    "type": "number"
+                },
+                "description": {
+                    "description": "Description of the product\n@example \"Bananas from Argentina\"",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "Unique identifier of the product\n@example 1",
+                    "type": "integer"
+                },
+                "name": {
+                    "description": "Name of the product\n@example \"Bananas\"",
+                    "type": "string"
+                },
+                "price": {
+                    "description": "Price of the product\n@example 20.00",
+                    "type": "number"
+                },
+                "ranking_score": {
+                    "description": "Score used to rank the product, an average rating weighted by the confidence in it\n@example 4.31",
+                    "type": "number"
+                },
+                "recent_average_rating": {
+                    "description": "Average rating of the reviews created within the recent window\n@example 4.8",
+                    "type": "number"
+                },
+                "recent_review_count": {
+                    "description": "Number of reviews created within the recent window\n@example 3",
+                    "type": "integer"
+                },
+                "review_count": {
+                    "description": "Number of reviews of the product\n@example 12",
+                    "type": "integer"
+                },
+                "reviews": {
+                    "description": "Reviews of the product, only set when include=reviews",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ReviewResponse"
+                    }
+                },
+                "updated_at": {
+                    "description": "Date the product was last updated",
+                    "type": "string"
+                },
+                "verified_average_rating": {
+                    "description": "Average rating of the reviews of verified purchases\n@example 4.7",
+                    "type": "number"
+                },
+                "verified_review_count": {
+                    "description": "Number of reviews of verified purchases\n@example 5",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ProductPage": {
+            "description": "Paginated list of products",
+            "type": "object",
+            "properties": {
+                "items": {
+                    "description": "Products in this page",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ProductSummary"
+                    }
+                },
+                "next_cursor": {
+                    "description": "Cursor to request the next page, empty on the last page\n@example \"eyJzIjoiY3JlYXRlZF9hdCJ9\"",
+                    "type": "string"
+                },
+                "total": {
+                    "description": "Total number of products matching the filters, only set when include_total=true\n@example 120",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ProductSummary": {
+            "description": "Product with its average rating, reviews are only embedded when requested with include=reviews",
+            "type": "object",
+            "properties": {
+                "aspect_ratings": {
+                    "description": "Average ratings of the aspects rated by the reviews, such as the battery life or the fit",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.AspectRatingResponse"
+                    }
+                },
+                "average_rating": {
+                    "description": "Average rating of the product based on reviews\n@example 4.5",
+                    "type": "number"
+                },
+                "category_id": {
+                    "description": "ID of the category of the product\n@example 3",
+                    "type": "integer"
+                },
+                "category_ids": {
+                    "description": "IDs of every category the product is assigned to\n@example [3,7]",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "decayed_average_rating": {
+                    "description": "Average rating with every review weighted by its age, recent reviews weighing the most\n@example 4.6",
+                    "type": "number"
+                },
+                "description": {
+                    "description": "Description of the product\n@example \"Bananas from Argentina\"",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "Unique identifier of the product\n@example 1",
+                    "type": "integer"
+                },
+                "name": {
+                    "description": "Name of the product\n@example \"Bananas\"",
+                    "type": "string"
+                },
+                "price": {
+                    "description": "Price of the product\n@example 20.00",
+                    "type": "number"
+                },
+                "ranking_score": {
+                    "description": "Score used to rank the product, an average rating weighted by the confidence in it\n@example 4.31",
+                    "type": "number"
+                },
+                "recent_average_rating": {
+                    "description": "Average rating of the reviews created within the recent window\n@example 4.8",
+                    "type": "number"
+                },
+                "recent_review_count": {
+                    "description": "Number of reviews created within the recent window\n@example 3",
+                    "type": "integer"
+                },
+                "review_count": {
+                    "description": "Number of reviews of the product\n@example 12",
+                    "type": "integer"
+                },
+                "reviews": {
+                    "description": "Reviews of the product, only set when include=reviews",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ReviewResponse"
+                    }
+                },
+                "verified_average_rating": {
+                    "description": "Average rating of the reviews of verified purchases\n@example 4.7",
+                    "type": "number"
+                },
+                "verified_review_count": {
+                    "description": "Number of reviews of verified purchases\n@example 5",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Purchase": {
+            "type": "object",
+            "properties": {
+                "customer_id": {
+                    "description": "Subject the customer authenticates with, the author subject of their reviews\n@example \"alice\"",
+                    "type": "string"
+                },
+                "order_id": {
+                    "description": "ID of the order in the order system\n@example \"order-1042\"",
+                    "type": "string"
+                },
+                "product_id": {
+                    "description": "ID of the bought product\n@example 1",
+                    "type": "integer"
+                },
+                "purchased_at": {
+                    "description": "Date of the purchase, the import date when omitted",
+                    "type": "string"
+                }
+            }
+        },
+        "models.PurchaseImport": {
+            "description": "Purchases to record, at most 1000 per import",
+            "type": "object",
+            "properties": {
+                "purchases": {
+                    "description": "Purchases to record, purchases already recorded are skipped",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Purchase"
+                    }
+                }
+            }
+        },
+        "models.PurchaseImportResult": {
+            "description": "Outcome of a purchase import",
+            "type": "object",
+            "properties": {
+                "recorded": {
+                    "description": "Number of purchases recorded\n@example 98",
+                    "type": "integer"
+                },
+                "skipped": {
+                    "description": "Number of purchases skipped because they were already recorded\n@example 2",
+                    "type": "integer"
+                },
+                "verified_reviews": {
+                    "description": "Number of existing reviews marked as verified purchases\n@example 4",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.RatingBucket": {
+            "description": "Number and share of the reviews with a rating",
+            "type": "object",
+            "properties": {
+                "count": {
+                    "description": "Number of reviews with this rating\n@example 8",
+                    "type": "integer"
+                },
+                "percentage": {
+                    "description": "Share of the reviews with this rating, in percent with one decimal\n@example 66.7",
+                    "type": "number"
+                },
+                "rating": {
+                    "description": "Rating of the reviews (1-5)\n@example 5",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.RatingSummary": {
+            "description": "Distribution of the ratings of a product",
+            "type": "object",
+            "properties": {
+                "average_rating": {
+                    "description": "Average rating of the reviews, 0 without reviews\n@example 4.25",
+                    "type": "number"
+                },
+                "distribution": {
+                    "description": "Number of reviews with each rating, from 5 down to 1",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.RatingBucket"
+                    }
+                },
+                "median": {
+                    "description": "Median rating of the reviews, 0 without reviews\n@example 4.5",
+                    "type": "number"
+                },
+                "product_id": {
+                    "description": "ID of the product\n@example 1",
+                    "type": "integer"
+                },
+                "total": {
+                    "description": "Total number of reviews\n@example 12",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.RatingTrend": {
+            "description": "Ratings of a product bucketed by week or month, oldest period first",
+            "type": "object",
+            "properties": {
+                "average_rating": {
+                    "description": "All-time average rating of the product\n@example 4.5",
+                    "type": "number"
+                },
+                "decayed_average_rating": {
+                    "description": "Average rating with every review weighted by its age\n@example 4.6",
+                    "type": "number"
+                },
+                "interval": {
+                    "description": "Length of the periods: week or month\n@example \"week\"",
+                    "type": "string"
+                },
+                "points": {
+                    "description": "Reviews of every period, oldest first, including periods without reviews",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.RatingTrendPoint"
+                    }
+                },
+                "product_id": {
+                    "description": "Unique identifier of the product\n@example 1",
+                    "type": "integer"
+                },
+                "recent_average_rating": {
+                    "description": "Average rating of the reviews created within the recent window\n@example 4.8",
+                    "type": "number"
+                }
+            }
+        },
+        "models.RatingTrendPoint": {
+            "description": "Number and average rating of the reviews created during a period",
+            "type": "object",
+            "properties": {
+                "average_rating": {
+                    "description": "Average rating of the reviews created during the period, 0 without reviews\n@example 4.25",
+                    "type": "number"
+                },
+                "period_start": {
+                    "description": "Start of the period, in UTC\n@example \"2024-05-06T00:00:00Z\"",
+                    "type": "string"
+                },
+                "review_count": {
+                    "description": "Number of reviews created during the period\n@example 4",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ReplyRequest": {
+            "description": "Text of a reply to a review, and the reply it answers",
+            "type": "object",
+            "properties": {
+                "body": {
+                    "description": "Text of the reply\n@example \"Thank you for your feedback, a replacement is on its way.\"",
+                    "type": "string"
+                },
+                "parent_id": {
+                    "description": "ID of the answered reply, omitted to answer the review itself. Ignored on edits\n@example 3",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Review": {
+            "description": "Represents a review for a specific product, including the reviewer's name, review text, and rating.",
+            "type": "object",
+            "required": [
+                "product_id"
+            ],
+            "properties": {
+                "aspect_ratings": {
+                    "description": "Ratings of the aspects of the product defined by its category (1-5), such as the battery life or the fit",
+                    "type": "array",
+                    "maxItems": 20,
+                    "items": {
+                        "$ref": "#/definitions/models.ReviewAspectRating"
+                    }
+                },
+                "author_subject": {
+                    "description": "AuthorSubject is the subject of the authenticated caller who wrote the review,\nset by the server from the caller's credentials\n@readOnly",
+                    "type": "string"
+                },
+                "cons": {
+                    "description": "What the reviewer disliked\n@example \"Bruised easily\"",
+                    "type": "string",
+                    "maxLength": 1000
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "deletedAt": {
+                    "type": "string"
+                },
+                "first_name": {
+                    "description": "First name of the reviewer\n@example \"Miguel\"",
+                    "type": "string"
+                },
+                "helpful_count": {
+                    "description": "Number of helpful votes, persisted periodically from the live tallies\n@readOnly\n@example 12",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_name": {
+                    "description": "Last name of the reviewer\n@example \"Filip\"",
+                    "type": "string"
+                },
+                "media": {
+                    "description": "Photos attached to the review by its author, oldest first, loaded with the review reads\n@readOnly",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ReviewMedia"
+                    }
+                },
+                "moderated_at": {
+                    "description": "Date of the last status change by a moderator\n@readOnly",
+                    "type": "string"
+                },
+                "moderated_by": {
+                    "description": "Subject of the moderator who last changed the status\n@readOnly",
+                    "type": "string"
+                },
+                "moderation_reason": {
+                    "description": "Reason given by the moderator for the last status change\n@readOnly",
+                    "type": "string"
+                },
+                "product_id": {
+                    "description": "ProductID is the foreign key that links to the product being reviewed\n@example 999",
+                    "type": "integer"
+                },
+                "pros": {
+                    "description": "What the reviewer liked\n@example \"Ripe and sweet\"",
+                    "type": "string",
+                    "maxLength": 1000
+                },
+                "rating": {
+                    "description": "Rating given by the reviewer (1-5)\n@example 4",
+                    "type": "integer",
+                    "maximum": 5,
+                    "minimum": 1
+                },
+                "replies": {
+                    "description": "Replies of merchants to the review, oldest first, loaded with the review reads\n@readOnly",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ReviewReply"
+                    }
+                },
+                "review_text": {
+                    "description": "Text content of the review\n@example \"This bananas are amazing!\"",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Moderation status of the review: pending, approved, rejected or flagged.\nOnly approved reviews are public and count toward the ratings of the product\n@readOnly\n@example \"approved\"",
+                    "type": "string"
+                },
+                "unhelpful_count": {
+                    "description": "Number of unhelpful votes, persisted periodically from the live tallies\n@readOnly\n@example 3",
+                    "type": "integer"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "verified": {
+                    "description": "Whether the author bought the product, set by the server from the ingested purchases\n@readOnly\n@example true",
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.ReviewAspectRating": {
+            "type": "object",
+            "required": [
+                "aspect"
+            ],
+            "properties": {
+                "aspect": {
+                    "description": "Key of the rated aspect, defined by the category of the product\n@example \"battery_life\"",
+                    "type": "string"
+                },
+                "rating": {
+                    "description": "Rating of the aspect (1-5)\n@example 4",
+                    "type": "integer",
+                    "maximum": 5,
+                    "minimum": 1
+                }
+            }
+        },
+        "models.ReviewHistory": {
+            "description": "Prior versions of a review, oldest first",
+            "type": "object",
+            "properties": {
+                "author_subject": {
+                    "description": "Subject of the author of the review\n@example \"alice\"",
+                    "type": "string"
+                },
+                "deleted": {
+                    "description": "Whether the review is deleted, deleted reviews can be restored by administrators\n@example false",
+                    "type": "boolean"
+                },
+                "review_id": {
+                    "description": "ID of the review\n@example 7",
+                    "type": "integer"
+                },
+                "revisions": {
+                    "description": "Prior versions of the review, oldest first. The current version is the review itself",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ReviewRevision"
+                    }
+                }
+            }
+        },
+        "models.ReviewMedia": {
+            "type": "object",
+            "properties": {
+                "content_type": {
+                    "description": "ContentType is the type sniffed from the content of the photo",
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "deletedAt": {
+                    "type": "string"
+                },
+                "height": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "review_id": {
+                    "description": "ReviewID is the review the photo is attached to",
+                    "type": "integer"
+                },
+                "size": {
+                    "description": "Size is the size of the photo in bytes",
+                    "type": "integer"
+                },
+                "thumbnail_content_type": {
+                    "description": "ThumbnailContentType, ThumbnailWidth and ThumbnailHeight describe the thumbnail of the photo",
+                    "type": "string"
+                },
+                "thumbnail_height": {
+                    "type": "integer"
+                },
+                "thumbnail_width": {
+                    "type": "integer"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "uploader_subject": {
+                    "description": "UploaderSubject is the subject of the caller who uploaded the photo",
+                    "type": "string"
+                },
+                "width": {
+                    "description": "Width and Height are the dimensions of the photo in pixels",
+                    "type": "integer"
                 }
             }
         },
-        "models.Product": {
-            "description": "Represents a product in the store or catalog",
+        "models.ReviewMediaResponse": {
+            "description": "Photo attached to a review, with the URLs of the photo and its thumbnail",
             "type": "object",
             "properties": {
-                "average_rating": {
-                    "description": "Average rating of the product based on reviews\n@example 4.5",
-                    "type": "number"
+                "content_type": {
+                    "description": "Type of the photo\n@example \"image/jpeg\"",
+                    "type": "string"
                 },
-                "description": {
-                    "description": "Description of the product\n@example \"Bananas from Argentina\"",
+                "created_at": {
+                    "description": "Date the photo was uploaded",
                     "type": "string"
                 },
-                "name": {
-                    "description": "Name of the product\n@example \"Bananas\"",
+                "height": {
+                    "description": "Height of the photo in pixels\n@example 1200",
+                    "type": "integer"
+                },
+                "id": {
+                    "description": "Unique identifier of the photo\n@example 4",
+                    "type": "integer"
+                },
+                "size": {
+                    "description": "Size of the photo in bytes\n@example 284133",
+                    "type": "integer"
+                },
+                "thumbnail_height": {
+                    "description": "Height of the thumbnail in pixels\n@example 240",
+                    "type": "integer"
+                },
+                "thumbnail_url": {
+                    "description": "URL of the thumbnail of the photo\n@example \"/reviews/7/media/4/thumbnail\"",
                     "type": "string"
                 },
-                "price": {
-                    "description": "Price of the product\n@example 20.00",
-                    "type": "number"
+                "thumbnail_width": {
+                    "description": "Width of the thumbnail in pixels\n@example 320",
+                    "type": "integer"
                 },
-                "reviews": {
-                    "description": "Reviews associated with this product\n@example []Review\n@readOnly",
+                "url": {
+                    "description": "URL of the photo\n@example \"/reviews/7/media/4\"",
+                    "type": "string"
+                },
+                "width": {
+                    "description": "Width of the photo in pixels\n@example 1600",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ReviewPage": {
+            "description": "Paginated list of reviews",
+            "type": "object",
+            "properties": {
+                "items": {
+                    "description": "Reviews in this page",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/models.Review"
+                        "$ref": "#/definitions/models.ReviewResponse"
                     }
+                },
+                "next_cursor": {
+                    "description": "Cursor to request the next page, empty on the last page\n@example \"eyJzIjoibmV3ZXN0In0\"",
+                    "type": "string"
                 }
             }
         },
-        "models.Review": {
-            "description": "Represents a review for a specific product, including the reviewer's name, review text, and rating.",
+        "models.ReviewReply": {
+            "type": "object",
+            "properties": {
+                "author_subject": {
+                    "description": "AuthorSubject is the subject of the merchant who wrote the reply",
+                    "type": "string"
+                },
+                "body": {
+                    "description": "Body is the text of the reply",
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "deletedAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "parent_id": {
+                    "description": "ParentID is the answered reply, nil for replies to the review itself",
+                    "type": "integer"
+                },
+                "review_id": {
+                    "description": "ReviewID is the answered review",
+                    "type": "integer"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ReviewReplyResponse": {
+            "description": "Reply of a merchant to a review, with the replies answering it",
+            "type": "object",
+            "properties": {
+                "author_subject": {
+                    "description": "Subject of the merchant who wrote the reply\n@example \"bananas-shop\"",
+                    "type": "string"
+                },
+                "body": {
+                    "description": "Text of the reply\n@example \"Thank you for your feedback, a replacement is on its way.\"",
+                    "type": "string"
+                },
+                "created_at": {
+                    "description": "Date the reply was created",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "Unique identifier of the reply\n@example 3",
+                    "type": "integer"
+                },
+                "parent_id": {
+                    "description": "ID of the answered reply, omitted for replies to the review itself\n@example 2",
+                    "type": "integer"
+                },
+                "replies": {
+                    "description": "Replies answering this reply, oldest first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ReviewReplyResponse"
+                    }
+                },
+                "review_id": {
+                    "description": "ID of the answered review\n@example 7",
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "description": "Date the reply was last updated",
+                    "type": "string"
+                }
+            }
+        },
+        "models.ReviewResponse": {
+            "description": "Review of a product",
             "type": "object",
             "properties": {
+                "aspect_ratings": {
+                    "description": "Ratings of the aspects of the product defined by its category (1-5)",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ReviewAspectRating"
+                    }
+                },
+                "cons": {
+                    "description": "What the reviewer disliked\n@example \"Bruised easily\"",
+                    "type": "string"
+                },
+                "created_at": {
+                    "description": "Date the review was created",
+                    "type": "string"
+                },
                 "first_name": {
                     "description": "First name of the reviewer\n@example \"Miguel\"",
                     "type": "string"
                 },
+                "helpful_count": {
+                    "description": "Number of helpful votes\n@example 12",
+                    "type": "integer"
+                },
+                "id": {
+                    "description": "Unique identifier of the review\n@example 7",
+                    "type": "integer"
+                },
                 "last_name": {
                     "description": "Last name of the reviewer\n@example \"Filip\"",
                     "type": "string"
                 },
+                "media": {
+                    "description": "Photos attached to the review, oldest first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ReviewMediaResponse"
+                    }
+                },
+                "moderation_reason": {
+                    "description": "Reason given by the moderator for the last status change\n@example \"Contains personal information\"",
+                    "type": "string"
+                },
                 "product_id": {
-                    "description": "ProductID is the foreign key that links to the product being reviewed\n@example 999",
+                    "description": "ID of the reviewed product\n@example 1",
                     "type": "integer"
                 },
+                "pros": {
+                    "description": "What the reviewer liked\n@example \"Ripe and sweet\"",
+                    "type": "string"
+                },
                 "rating": {
                     "description": "Rating given by the reviewer (1-5)\n@example 4",
                     "type": "integer"
                 },
+                "replies": {
+                    "description": "Threads of the replies of merchants to the review, oldest first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ReviewReplyResponse"
+                    }
+                },
                 "review_text": {
                     "description": "Text content of the review\n@example \"This bananas are amazing!\"",
                     "type": "string"
+                },
+                "status": {
+                    "description": "Moderation status of the review, only approved reviews are public\n@example \"approved\"",
+                    "type": "string"
+                },
+                "unhelpful_count": {
+                    "description": "Number of unhelpful votes\n@example 3",
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "description": "Date the review was last updated",
+                    "type": "string"
+                },
+                "verified": {
+                    "description": "Whether the reviewer bought the product\n@example true",
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.ReviewRevision": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "description": "Change that ended this version: update, delete or restore\n@example \"update\"",
+                    "type": "string"
+                },
+                "aspect_ratings": {
+                    "description": "Comma-separated aspect ratings of the review in this version, as aspect=rating pairs\n@example \"fit=4,comfort=5\"",
+                    "type": "string"
+                },
+                "changed_by": {
+                    "description": "Subject of the caller who made the change\n@example \"alice\"",
+                    "type": "string"
+                },
+                "cons": {
+                    "description": "What the reviewer disliked in this version\n@example \"Bruised easily\"",
+                    "type": "string"
+                },
+                "created_at": {
+                    "description": "Date of the change",
+                    "type": "string"
+                },
+                "first_name": {
+                    "description": "First name of the reviewer in this version\n@example \"Miguel\"",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "Unique identifier of the revision\n@example 12",
+                    "type": "integer"
+                },
+                "last_name": {
+                    "description": "Last name of the reviewer in this version\n@example \"Filip\"",
+                    "type": "string"
+                },
+                "pros": {
+                    "description": "What the reviewer liked in this version\n@example \"Ripe and sweet\"",
+                    "type": "string"
+                },
+                "rating": {
+                    "description": "Rating of the review in this version\n@example 4",
+                    "type": "integer"
+                },
+                "review_id": {
+                    "description": "ID of the changed review\n@example 7",
+                    "type": "integer"
+                },
+                "review_text": {
+                    "description": "Text of the review in this version\n@example \"This bananas are amazing!\"",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Moderation status of the review in this version\n@example \"approved\"",
+                    "type": "string"
+                },
+                "version": {
+                    "description": "Version of the review, starting at 1 for the review as it was created\n@example 1",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ScreeningErrorResponse": {
+            "description": "Review rejected by the content screening, with the rules it matched",
+            "type": "object",
+            "properties": {
+                "details": {
+                    "description": "Details contains additional information (optional)\n@example \"The product ID provided was not an integer.\"",
+                    "type": "string"
+                },
+                "findings": {
+                    "description": "Rules matched by the review text",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ScreeningFinding"
+                    }
+                },
+                "message": {
+                    "description": "Message contains a human-readable error message\n@example \"Invalid product ID\"",
+                    "type": "string"
+                },
+                "score": {
+                    "description": "Total score of the findings\n@example 6",
+                    "type": "number"
+                }
+            }
+        },
+        "models.ScreeningFinding": {
+            "description": "Rule of the content screening matched by a review text",
+            "type": "object",
+            "properties": {
+                "message": {
+                    "description": "Description of what was found\n@example \"Text contains a banned word\"",
+                    "type": "string"
+                },
+                "reject": {
+                    "description": "Reject is set when the finding rejects the review whatever the score",
+                    "type": "boolean"
+                },
+                "rule": {
+                    "description": "Rule that matched: length, banned_word, url, email, phone, caps or repeated_chars\n@example \"banned_word\"",
+                    "type": "string"
+                },
+                "score": {
+                    "description": "Score added by the finding, reviews are flagged or rejected past a threshold\n@example 3",
+                    "type": "number"
+                }
+            }
+        },
+        "models.VoteRequest": {
+            "description": "Vote of the caller on a review",
+            "type": "object",
+            "properties": {
+                "helpful": {
+                    "description": "Whether the review was helpful\n@example true",
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.VoteTally": {
+            "description": "Helpful and unhelpful votes of a review",
+            "type": "object",
+            "properties": {
+                "helpful": {
+                    "description": "Number of helpful votes\n@example 12",
+                    "type": "integer"
+                },
+                "review_id": {
+                    "description": "ID of the review\n@example 7",
+                    "type": "integer"
+                },
+                "unhelpful": {
+                    "description": "Number of unhelpful votes\n@example 3",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.WebhookDeliveryResponse": {
+            "description": "Delivery of an event to a webhook",
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "description": "Number of delivery attempts made so far\n@example 1",
+                    "type": "integer"
+                },
+                "created_at": {
+                    "description": "Date the delivery was created",
+                    "type": "string"
+                },
+                "delivered_at": {
+                    "description": "Date the target acknowledged the event",
+                    "type": "string"
+                },
+                "event_id": {
+                    "description": "ID of the delivered event\n@example \"4f1c2a9e0b7d4c3e8a6f5b2d1c0e9f8a\"",
+                    "type": "string"
+                },
+                "event_type": {
+                    "description": "Type of the delivered event\n@example \"review.created\"",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "Unique identifier of the delivery\n@example 12",
+                    "type": "integer"
+                },
+                "last_error": {
+                    "description": "Error of the last failed attempt",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Delivery status: pending, delivered or failed\n@example \"delivered\"",
+                    "type": "string"
+                },
+                "status_code": {
+                    "description": "HTTP status returned by the last attempt\n@example 200",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.WebhookRequest": {
+            "description": "Webhook subscription details",
+            "type": "object",
+            "properties": {
+                "event_types": {
+                    "description": "Event types to receive\n@example [\"review.created\",\"review.updated\"]",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "product_id": {
+                    "description": "Only receive the events of this product, omit for all products\n@example 1",
+                    "type": "integer"
+                },
+                "secret": {
+                    "description": "Secret used to sign the deliveries with HMAC-SHA256, at least 16 characters\n@example \"9f8e7d6c5b4a39281706f5e4d3c2b1a0\"",
+                    "type": "string"
+                },
+                "target_url": {
+                    "description": "URL the events are POSTed to\n@example \"https://partner.example.com/hooks/reviews\"",
+                    "type": "string"
+                }
+            }
+        },
+        "models.WebhookResponse": {
+            "description": "Webhook subscription",
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "Date the subscription was created",
+                    "type": "string"
+                },
+                "event_types": {
+                    "description": "Event types received\n@example [\"review.created\",\"review.updated\"]",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "description": "Unique identifier of the subscription\n@example 3",
+                    "type": "integer"
+                },
+                "product_id": {
+                    "description": "Product the subscription is restricted to, 0 for all products\n@example 1",
+                    "type": "integer"
+                },
+                "target_url": {
+                    "description": "URL the events are POSTed to\n@example \"https://partner.example.com/hooks/reviews\"",
+                    "type": "string"
                 }
             }
         }
@@ -1,12 +1,21 @@
 package main
 
 import (
+	"context"
 	"go_api_product_review/api"
 	"go_api_product_review/cache"
 	"go_api_product_review/db"
+	"go_api_product_review/events"
+	"go_api_product_review/media"
 	"go_api_product_review/middleware"
+	"go_api_product_review/screening"
+	"go_api_product_review/service"
+	"go_api_product_review/storage"
 	"log"
+	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	_ "go_api_product_review/cmd/docs" // Import the docs from the cmd/docs folder
 
@@ -28,15 +37,154 @@ func main() {
 	// Initialize Redis cache
 	cache.InitRedis(nil)
 
+	// Publish domain events on Redis Pub/Sub when configured, in-process otherwise,
+	// and schedule their delivery to the webhook subscriptions
+	var publisher events.Publisher = events.NewInProcessPublisher()
+	if os.Getenv("EVENT_PUBLISHER") == "redis" {
+		publisher = events.NewRedisPublisher(os.Getenv("EVENT_CHANNEL_PREFIX"))
+	}
+	events.Init(events.NewMultiPublisher(publisher, service.NewWebhookPublisher(db.GetDB())))
+
+	// Relay the events stored in the outbox to the publisher in the background
+	outboxInterval := time.Second
+	if value := os.Getenv("OUTBOX_POLL_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			log.Fatalf("Invalid OUTBOX_POLL_INTERVAL: %v", err)
+		}
+		outboxInterval = interval
+	}
+	go service.RunOutboxDispatcher(context.Background(), db.GetDB(), events.Default, outboxInterval)
+
+	// Send the scheduled webhook deliveries in the background
+	webhookClient := &http.Client{Timeout: 10 * time.Second}
+	go service.RunWebhookDispatcher(context.Background(), db.GetDB(), webhookClient, outboxInterval)
+
+	// Rank the products by their ratings weighted by the confidence in them
+	rankingConfig, err := service.LoadRankingConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid ranking configuration: %v", err)
+	}
+	service.InitRanking(rankingConfig)
+
+	// Average the recent reviews and weigh the reviews by their age alongside the all-time average
+	ratingTrendConfig, err := service.LoadRatingTrendConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid rating trend configuration: %v", err)
+	}
+	service.InitRatingTrends(ratingTrendConfig)
+
+	// Screen the text of the reviews before they are saved, rejecting or flagging them
+	screeningConfig, err := screening.LoadConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid screening configuration: %v", err)
+	}
+	service.InitScreening(screening.NewPipeline(screeningConfig))
+
+	// Reject or flag the reviews duplicating another review of their author or its text
+	duplicateConfig, err := service.LoadDuplicateConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid duplicate detection configuration: %v", err)
+	}
+	service.InitDuplicates(duplicateConfig)
+
+	// Keep the photos attached to reviews on the filesystem or in an S3-compatible bucket
+	storageConfig, err := storage.LoadConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid media storage configuration: %v", err)
+	}
+	mediaStore, err := storage.New(storageConfig)
+	if err != nil {
+		log.Fatalf("Failed to set up media storage: %v", err)
+	}
+	mediaConfig, err := media.LoadConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid media configuration: %v", err)
+	}
+	service.InitMedia(mediaStore, mediaConfig)
+
+	// Check the rating aggregates of the products against their reviews in the background,
+	// and refresh the ranking scores along
+	reconcileInterval := time.Hour
+	if value := os.Getenv("RATING_RECONCILE_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			log.Fatalf("Invalid RATING_RECONCILE_INTERVAL: %v", err)
+		}
+		reconcileInterval = interval
+	}
+	go service.RunRatingReconciler(context.Background(), db.GetDB(), reconcileInterval)
+
+	// Persist the vote tallies kept in Redis to the reviews in the background
+	votePersistInterval := time.Minute
+	if value := os.Getenv("VOTE_PERSIST_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			log.Fatalf("Invalid VOTE_PERSIST_INTERVAL: %v", err)
+		}
+		votePersistInterval = interval
+	}
+	go service.RunVoteTallyFlusher(context.Background(), db.GetDB(), votePersistInterval)
+
 	// Create Gin router
 	router := gin.Default()
 
+	// Only take the client address from X-Forwarded-For when the request comes through one of the
+	// proxies listed in TRUSTED_PROXIES, otherwise any client could pick the address it is throttled by
+	var trustedProxies []string
+	if value := os.Getenv("TRUSTED_PROXIES"); value != "" {
+		for _, proxy := range strings.Split(value, ",") {
+			trustedProxies = append(trustedProxies, strings.TrimSpace(proxy))
+		}
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
 	// Set up middleware (authentication)
-	router.Use(middleware.AuthMiddleware)
+	jwtConfig, err := middleware.LoadJWTConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to load JWT configuration: %v", err)
+	}
+	verifier, err := middleware.NewJWTVerifier(jwtConfig)
+	if err != nil {
+		log.Fatalf("Failed to create JWT verifier: %v", err)
+	}
+
+	// Throttle callers per route group, counting in Redis with an in-memory fallback,
+	// or only in memory when RATE_LIMIT_STORE=memory
+	var rateLimitStore middleware.RateLimitStore = middleware.NewFallbackRateLimitStore(
+		middleware.NewRedisRateLimitStore(),
+		middleware.NewMemoryRateLimitStore(),
+	)
+	if os.Getenv("RATE_LIMIT_STORE") == "memory" {
+		rateLimitStore = middleware.NewMemoryRateLimitStore()
+	}
+	limiter := middleware.NewRateLimiter(rateLimitStore)
+	rateLimit := func(group string, defaultLimit middleware.RateLimit) gin.HandlerFunc {
+		policy, err := middleware.LoadRateLimitPolicyFromEnv(group, defaultLimit)
+		if err != nil {
+			log.Fatalf("Failed to load rate limits: %v", err)
+		}
+		return limiter.Limit(group, policy)
+	}
+
+	// Throttle every client by IP address before authenticating it, so failed authentications count too
+	ipPolicy, err := middleware.LoadRateLimitPolicyFromEnv("ip", middleware.RateLimit{Requests: 1200, Window: time.Minute})
+	if err != nil {
+		log.Fatalf("Failed to load rate limits: %v", err)
+	}
+	router.Use(limiter.LimitByIP("ip", ipPolicy))
+	router.Use(middleware.AuthMiddleware(verifier, api.AuthenticateAPIKey))
 
 	// Set up routes
-	api.RegisterProductRoutes(router)
-	api.RegisterReviewRoutes(router)
+	api.RegisterProductRoutes(router, rateLimit("products", middleware.RateLimit{Requests: 300, Window: time.Minute}))
+	api.RegisterCategoryRoutes(router, rateLimit("categories", middleware.RateLimit{Requests: 300, Window: time.Minute}))
+	api.RegisterReviewRoutes(router, rateLimit("reviews", middleware.RateLimit{Requests: 60, Window: time.Minute}))
+	api.RegisterModerationRoutes(router, rateLimit("moderation", middleware.RateLimit{Requests: 120, Window: time.Minute}))
+	api.RegisterPurchaseRoutes(router, rateLimit("purchases", middleware.RateLimit{Requests: 30, Window: time.Minute}))
+	api.RegisterWebhookRoutes(router, rateLimit("webhooks", middleware.RateLimit{Requests: 60, Window: time.Minute}))
+	api.RegisterAPIKeyRoutes(router, rateLimit("api-keys", middleware.RateLimit{Requests: 30, Window: time.Minute}))
 
 	// Swagger router
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
@@ -1,7 +1,9 @@
 package db
 
 import (
+	"fmt"
 	"go_api_product_review/models"
+	"go_api_product_review/screening"
 	"log"
 	"os"
 
@@ -24,8 +26,126 @@ func InitPostgres() {
 		log.Fatalf("failed to connect to database: %v", err)
 	}
 
-	// Automatically migrate the Product and Review models.
-	DB.AutoMigrate(&models.Product{}, &models.Review{})
+	// Automatically migrate the models.
+	DB.AutoMigrate(
+		&models.Product{},
+		&models.Review{},
+		&models.Category{},
+		&models.ProductCategory{},
+		&models.CategoryAspect{},
+		&models.ReviewAspectRating{},
+		&models.ProductAspectRating{},
+		&models.ReviewVote{},
+		&models.ReviewReply{},
+		&models.ReviewRevision{},
+		&models.ReviewMedia{},
+		&models.ReviewTextBand{},
+		&models.Purchase{},
+		&models.OutboxEvent{},
+		&models.WebhookSubscription{},
+		&models.WebhookDelivery{},
+		&models.APIKey{},
+	)
+
+	// Votes, replies, revisions, media, aspect ratings and text bands belong to their review
+	DB.Model(&models.ReviewVote{}).AddForeignKey("review_id", "reviews(id)", "CASCADE", "CASCADE")
+	DB.Model(&models.ReviewReply{}).AddForeignKey("review_id", "reviews(id)", "CASCADE", "CASCADE")
+	DB.Model(&models.ReviewRevision{}).AddForeignKey("review_id", "reviews(id)", "CASCADE", "CASCADE")
+	DB.Model(&models.ReviewMedia{}).AddForeignKey("review_id", "reviews(id)", "CASCADE", "CASCADE")
+	DB.Model(&models.ReviewAspectRating{}).AddForeignKey("review_id", "reviews(id)", "CASCADE", "CASCADE")
+	DB.Model(&models.ReviewTextBand{}).AddForeignKey("review_id", "reviews(id)", "CASCADE", "CASCADE")
+
+	// Aspects belong to their category, aspect aggregates to their product
+	DB.Model(&models.CategoryAspect{}).AddForeignKey("category_id", "categories(id)", "CASCADE", "CASCADE")
+	DB.Model(&models.ProductAspectRating{}).AddForeignKey("product_id", "products(id)", "CASCADE", "CASCADE")
+	DB.Model(&models.Product{}).AddForeignKey("category_id", "categories(id)", "SET NULL", "CASCADE")
+
+	// Categories nest under their parent, products are assigned to categories
+	DB.Model(&models.Category{}).AddForeignKey("parent_id", "categories(id)", "RESTRICT", "CASCADE")
+	DB.Model(&models.ProductCategory{}).AddForeignKey("product_id", "products(id)", "CASCADE", "CASCADE")
+	DB.Model(&models.ProductCategory{}).AddForeignKey("category_id", "categories(id)", "CASCADE", "CASCADE")
+
+	backfillCategoryTree()
+	backfillReviewTextBands()
+}
+
+// backfillCategoryTree gives the categories created before the category tree a slug and a top-level path,
+// and assigns the products to the category set as their category_id.
+func backfillCategoryTree() {
+	var categories []models.Category
+	if err := DB.Unscoped().Where("path IS NULL OR path = ''").Find(&categories).Error; err != nil {
+		log.Printf("failed to load the categories without path: %v", err)
+		return
+	}
+	for _, category := range categories {
+		slug := models.Slugify(category.Name)
+		if slug == "" {
+			slug = "category"
+		}
+		// Suffix the ID when another category already uses the path
+		var count int
+		err := DB.Unscoped().Model(&models.Category{}).Where("path = ?", slug).Count(&count).Error
+		if err != nil {
+			log.Printf("failed to check the path of category %d: %v", category.ID, err)
+			continue
+		}
+		if count > 0 {
+			slug = fmt.Sprintf("%s-%d", slug, category.ID)
+		}
+		err = DB.Unscoped().Model(&category).UpdateColumns(map[string]interface{}{"slug": slug, "path": slug}).Error
+		if err != nil {
+			log.Printf("failed to backfill the path of category %d: %v", category.ID, err)
+		}
+	}
+
+	err := DB.Exec(`INSERT INTO product_categories (product_id, category_id)
+		SELECT p.id, p.category_id FROM products p
+		WHERE p.category_id IS NOT NULL AND NOT EXISTS (
+			SELECT 1 FROM product_categories pc WHERE pc.product_id = p.id AND pc.category_id = p.category_id
+		)`).Error
+	if err != nil {
+		log.Printf("failed to backfill the product categories: %v", err)
+	}
+}
+
+// backfillReviewTextBands signs the texts of the reviews saved without band keys, so that
+// the duplicate detection finds them. Deleted reviews have no band keys and are skipped.
+// Reviews are signed in batches of 500.
+func backfillReviewTextBands() {
+	var lastID uint
+	for {
+		var reviews []models.Review
+		err := DB.Select("id, review_text").
+			Where("id > ? AND NOT EXISTS (SELECT 1 FROM review_text_bands b WHERE b.review_id = reviews.id)", lastID).
+			Order("id").Limit(500).Find(&reviews).Error
+		if err != nil {
+			log.Printf("failed to load the reviews without text bands: %v", err)
+			return
+		}
+		if len(reviews) == 0 {
+			return
+		}
+		for _, review := range reviews {
+			lastID = review.ID
+			signature := screening.MinHash(review.ReviewText)
+			err := DB.Transaction(func(tx *gorm.DB) error {
+				err := tx.Unscoped().Model(&review).UpdateColumn("text_signature", signature.String()).Error
+				if err != nil {
+					return err
+				}
+				for _, key := range signature.BandKeys() {
+					err = tx.Create(&models.ReviewTextBand{ReviewID: review.ID, BandKey: key}).Error
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				log.Printf("failed to backfill the text bands of review %d: %v", review.ID, err)
+			}
+		}
+	}
 }
 
 // GetDB returns the current database instance.
@@ -0,0 +1,89 @@
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// Domain event types published by the service
+const (
+	// ReviewCreated is published when a review is added to a product
+	ReviewCreated = "review.created"
+	// ReviewUpdated is published when a review is modified
+	ReviewUpdated = "review.updated"
+	// ReviewDeleted is published when a review is deleted
+	ReviewDeleted = "review.deleted"
+	// ReviewModerated is published when a moderator changes the status of a review
+	ReviewModerated = "review.moderated"
+	// ProductRatingChanged is published when the average rating of a product changes
+	ProductRatingChanged = "product.rating_changed"
+)
+
+// Event is a domain event describing a change in the service.
+type Event struct {
+	// ID uniquely identifies the event, so consumers can drop duplicates
+	ID string `json:"id"`
+	// Type is one of the event type constants
+	Type string `json:"type"`
+	// ProductID is the product the event relates to
+	ProductID uint `json:"product_id"`
+	// OccurredAt is when the change happened
+	OccurredAt time.Time `json:"occurred_at"`
+	// Data holds the JSON payload of the event, which depends on its type
+	Data json.RawMessage `json:"data"`
+}
+
+// RatingChanged is the payload of ProductRatingChanged events.
+type RatingChanged struct {
+	ProductID             uint    `json:"product_id"`
+	PreviousAverageRating float64 `json:"previous_average_rating"`
+	AverageRating         float64 `json:"average_rating"`
+}
+
+// New creates an event of the given type with data marshalled as its payload.
+func New(eventType string, productID uint, data interface{}) (Event, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return Event{}, err
+	}
+
+	return Event{
+		ID:         hex.EncodeToString(id),
+		Type:       eventType,
+		ProductID:  productID,
+		OccurredAt: time.Now().UTC(),
+		Data:       payload,
+	}, nil
+}
+
+// Publisher delivers events to their subscribers.
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// Default is the publisher used by the service layer.
+// It is an in-process publisher without subscribers until Init is called.
+var Default Publisher = NewInProcessPublisher()
+
+// Init sets the publisher used by the service layer.
+func Init(publisher Publisher) {
+	Default = publisher
+}
+
+// Publish delivers the event through the default publisher.
+func Publish(ctx context.Context, event Event) error {
+	return Default.Publish(ctx, event)
+}
+
+// Types returns every event type published by the service.
+func Types() []string {
+	return []string{ReviewCreated, ReviewUpdated, ReviewDeleted, ReviewModerated, ProductRatingChanged}
+}
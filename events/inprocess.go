@@ -0,0 +1,50 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// AllEvents subscribes a handler to every event type
+const AllEvents = "*"
+
+// Handler processes an event delivered by a publisher.
+type Handler func(ctx context.Context, event Event) error
+
+// InProcessPublisher delivers events synchronously to handlers registered in the same process.
+// It is meant for single-node deployments and tests.
+type InProcessPublisher struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+// NewInProcessPublisher creates an in-process publisher without subscribers.
+func NewInProcessPublisher() *InProcessPublisher {
+	return &InProcessPublisher{
+		handlers: make(map[string][]Handler),
+	}
+}
+
+// Subscribe registers a handler for an event type, or for all of them with AllEvents.
+func (p *InProcessPublisher) Subscribe(eventType string, handler Handler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handlers[eventType] = append(p.handlers[eventType], handler)
+}
+
+// Publish calls every handler subscribed to the event type.
+// All handlers are called even if some of them fail, and their errors are joined.
+func (p *InProcessPublisher) Publish(ctx context.Context, event Event) error {
+	p.mu.RLock()
+	handlers := append(append([]Handler{}, p.handlers[event.Type]...), p.handlers[AllEvents]...)
+	p.mu.RUnlock()
+
+	var errs []error
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
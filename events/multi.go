@@ -0,0 +1,28 @@
+package events
+
+import (
+	"context"
+	"errors"
+)
+
+// MultiPublisher fans events out to several publishers.
+type MultiPublisher struct {
+	publishers []Publisher
+}
+
+// NewMultiPublisher creates a publisher delivering every event to all the given publishers.
+func NewMultiPublisher(publishers ...Publisher) *MultiPublisher {
+	return &MultiPublisher{publishers: publishers}
+}
+
+// Publish delivers the event to every publisher, even if some of them fail,
+// and joins their errors.
+func (p *MultiPublisher) Publish(ctx context.Context, event Event) error {
+	var errs []error
+	for _, publisher := range p.publishers {
+		if err := publisher.Publish(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
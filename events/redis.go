@@ -0,0 +1,35 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"go_api_product_review/cache"
+)
+
+// DefaultRedisChannelPrefix is prepended to the event type to build the Pub/Sub channel name
+const DefaultRedisChannelPrefix = "events:"
+
+// RedisPublisher publishes events as JSON on Redis Pub/Sub channels through cache.Rdb.
+// Each event type has its own channel, e.g. "events:review.created", so consumers can
+// SUBSCRIBE to the types they need or PSUBSCRIBE to "events:*".
+type RedisPublisher struct {
+	channelPrefix string
+}
+
+// NewRedisPublisher creates a Redis publisher using the given channel prefix,
+// or DefaultRedisChannelPrefix if it is empty.
+func NewRedisPublisher(channelPrefix string) *RedisPublisher {
+	if channelPrefix == "" {
+		channelPrefix = DefaultRedisChannelPrefix
+	}
+	return &RedisPublisher{channelPrefix: channelPrefix}
+}
+
+// Publish sends the event to the channel of its type.
+func (p *RedisPublisher) Publish(ctx context.Context, event Event) error {
+	message, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return cache.Rdb.Publish(ctx, p.channelPrefix+event.Type, message).Err()
+}
@@ -0,0 +1,205 @@
+// Package media checks the photos attached to reviews and generates their thumbnails.
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // Register the GIF decoder
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+var (
+	// ErrTooLarge is returned for files larger than the size limit
+	ErrTooLarge = errors.New("file is too large")
+	// ErrUnsupportedType is returned for files that are not JPEG, PNG or GIF images
+	ErrUnsupportedType = errors.New("unsupported file type, expected a JPEG, PNG or GIF image")
+	// ErrTooManyPixels is returned for images larger than the dimension limit
+	ErrTooManyPixels = errors.New("image dimensions are too large")
+)
+
+// extensions maps the supported content types to the extension of their files
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// Config limits the uploaded images and sizes their thumbnails.
+type Config struct {
+	// MaxBytes is the largest file accepted
+	MaxBytes int64
+	// MaxPixels is the largest number of pixels of an accepted image, checked before it is decoded
+	MaxPixels int
+	// ThumbnailSize is the largest width and height of the thumbnails
+	ThumbnailSize int
+	// MaxPerReview is the largest number of files attached to a review
+	MaxPerReview int
+}
+
+// DefaultConfig returns the limits of the uploads: files of up to 5 MiB and 40 megapixels,
+// at most 6 per review, with thumbnails fitting 320x320 pixels.
+func DefaultConfig() Config {
+	return Config{
+		MaxBytes:      5 << 20,
+		MaxPixels:     40_000_000,
+		ThumbnailSize: 320,
+		MaxPerReview:  6,
+	}
+}
+
+// LoadConfigFromEnv builds the media configuration from environment variables,
+// starting from DefaultConfig:
+//   - MEDIA_MAX_BYTES: largest file accepted, in bytes
+//   - MEDIA_MAX_PIXELS: largest number of pixels of an accepted image
+//   - MEDIA_THUMBNAIL_SIZE: largest width and height of the thumbnails
+//   - MEDIA_MAX_PER_REVIEW: largest number of files attached to a review
+func LoadConfigFromEnv() (Config, error) {
+	config := DefaultConfig()
+	if value := os.Getenv("MEDIA_MAX_BYTES"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed <= 0 {
+			return config, fmt.Errorf("invalid MEDIA_MAX_BYTES %q, expected a positive integer", value)
+		}
+		config.MaxBytes = parsed
+	}
+
+	ints := map[string]*int{
+		"MEDIA_MAX_PIXELS":     &config.MaxPixels,
+		"MEDIA_THUMBNAIL_SIZE": &config.ThumbnailSize,
+		"MEDIA_MAX_PER_REVIEW": &config.MaxPerReview,
+	}
+	for name, target := range ints {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed <= 0 {
+				return config, fmt.Errorf("invalid %s %q, expected a positive integer", name, value)
+			}
+			*target = parsed
+		}
+	}
+	return config, nil
+}
+
+// Image is a checked image along with its thumbnail.
+type Image struct {
+	// ContentType is sniffed from the content of the file, whatever the upload claimed
+	ContentType string
+	// Extension is the extension of the files of the content type, such as ".jpg"
+	Extension string
+	// Width and Height are the dimensions of the image in pixels
+	Width  int
+	Height int
+	// Thumbnail is the encoded thumbnail, a JPEG for JPEG images and a PNG otherwise
+	Thumbnail            []byte
+	ThumbnailContentType string
+	ThumbnailExtension   string
+	ThumbnailWidth       int
+	ThumbnailHeight      int
+}
+
+// Process checks an uploaded file against the limits, reads its dimensions and generates its thumbnail.
+// It returns ErrTooLarge, ErrUnsupportedType or ErrTooManyPixels if the file is not accepted.
+func Process(data []byte, config Config) (*Image, error) {
+	if int64(len(data)) > config.MaxBytes {
+		return nil, ErrTooLarge
+	}
+	contentType := http.DetectContentType(data)
+	extension, ok := extensions[contentType]
+	if !ok {
+		return nil, ErrUnsupportedType
+	}
+
+	// Check the dimensions from the header before decoding the pixels
+	imageConfig, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedType, err)
+	}
+	if "image/"+format != contentType {
+		return nil, ErrUnsupportedType
+	}
+	if imageConfig.Width <= 0 || imageConfig.Height <= 0 ||
+		imageConfig.Width > config.MaxPixels/imageConfig.Height {
+		return nil, ErrTooManyPixels
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedType, err)
+	}
+	thumbnail := Thumbnail(decoded, config.ThumbnailSize)
+
+	result := &Image{
+		ContentType:     contentType,
+		Extension:       extension,
+		Width:           imageConfig.Width,
+		Height:          imageConfig.Height,
+		ThumbnailWidth:  thumbnail.Bounds().Dx(),
+		ThumbnailHeight: thumbnail.Bounds().Dy(),
+	}
+	var encoded bytes.Buffer
+	if contentType == "image/jpeg" {
+		result.ThumbnailContentType = "image/jpeg"
+		err = jpeg.Encode(&encoded, thumbnail, &jpeg.Options{Quality: 85})
+	} else {
+		result.ThumbnailContentType = "image/png"
+		err = png.Encode(&encoded, thumbnail)
+	}
+	result.ThumbnailExtension = extensions[result.ThumbnailContentType]
+	if err != nil {
+		return nil, err
+	}
+	result.Thumbnail = encoded.Bytes()
+	return result, nil
+}
+
+// Thumbnail scales the image down to fit a size x size square, keeping its aspect ratio.
+// Every pixel of the thumbnail averages the pixels of the image it covers.
+// Images already fitting the square are copied as they are.
+func Thumbnail(src image.Image, size int) *image.NRGBA {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	thumbWidth, thumbHeight := width, height
+	if width > size || height > size {
+		if width >= height {
+			thumbWidth, thumbHeight = size, max(1, height*size/width)
+		} else {
+			thumbWidth, thumbHeight = max(1, width*size/height), size
+		}
+	}
+
+	thumbnail := image.NewNRGBA(image.Rect(0, 0, thumbWidth, thumbHeight))
+	for y := 0; y < thumbHeight; y++ {
+		y0 := bounds.Min.Y + y*height/thumbHeight
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*height/thumbHeight)
+		for x := 0; x < thumbWidth; x++ {
+			x0 := bounds.Min.X + x*width/thumbWidth
+			x1 := max(x0+1, bounds.Min.X+(x+1)*width/thumbWidth)
+
+			var r, g, b, a, count uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pixel := color.NRGBA64Model.Convert(src.At(sx, sy)).(color.NRGBA64)
+					r += uint64(pixel.R)
+					g += uint64(pixel.G)
+					b += uint64(pixel.B)
+					a += uint64(pixel.A)
+					count++
+				}
+			}
+			thumbnail.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(r / count >> 8),
+				G: uint8(g / count >> 8),
+				B: uint8(b / count >> 8),
+				A: uint8(a / count >> 8),
+			})
+		}
+	}
+	return thumbnail
+}
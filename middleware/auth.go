@@ -1,86 +1,128 @@
 package middleware
 
 import (
+	"errors"
 	"go_api_product_review/models"
-	"log"
 	"net/http"
-	"os"
 
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
 )
 
-// AuthMiddleware validates a Bearer token for incoming requests.
-func AuthMiddleware(c *gin.Context) {
-	// Load environment variables from .env file to access SECRET_KEY
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file") // Log error and terminate if loading fails
-	}
+// ClaimsKey is the gin.Context key holding the claims of the authenticated caller
+const ClaimsKey = "claims"
 
-	// Retrieve the token from the Authorization header
-	tokenString := c.GetHeader("Authorization")
-	// Skip authentication for the Swagger documentation endpoint
-	if c.FullPath() == "/swagger/*any" {
-		c.Next() // Proceed with the next handler
-		return
-	}
+// APIKeyHeader is the header carrying the API key of integrations
+const APIKeyHeader = "X-API-Key"
 
-	// If no token is provided, return an unauthorized error
-	if tokenString == "" {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
-			Message: "No token provided",
-			Details: "Authorization header is missing",
-		})
-		c.Abort()
-		return
-	}
+// ErrInvalidAPIKey is returned by an APIKeyAuthenticator for unknown, revoked or expired keys
+var ErrInvalidAPIKey = errors.New("invalid API key")
 
-	// Remove the "Bearer " prefix from the token if it's present
-	if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
-		tokenString = tokenString[7:] // Strip "Bearer " to get the actual token
-	} else {
-		// Return an error if the token format is invalid
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
-			Message: "Invalid token format",
-			Details: "Bearer is the accepted format",
-		})
-		c.Abort()
-		return
+// APIKeyAuthenticator returns the caller owning an API key.
+// It returns ErrInvalidAPIKey when the key is not accepted.
+type APIKeyAuthenticator func(key string) (*Principal, error)
+
+// AuthMiddleware authenticates incoming requests.
+// Requests carrying an API key in the X-API-Key header are authenticated with apiKeys,
+// unless it is nil, and the others must carry a Bearer JWT validated with the verifier.
+// The claims of a valid token are stored in the gin.Context under ClaimsKey,
+// and the authenticated caller under PrincipalKey.
+func AuthMiddleware(verifier *JWTVerifier, apiKeys APIKeyAuthenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Skip authentication for the Swagger documentation endpoint
+		if c.FullPath() == "/swagger/*any" {
+			c.Next() // Proceed with the next handler
+			return
+		}
+
+		if apiKey := c.GetHeader(APIKeyHeader); apiKey != "" && apiKeys != nil {
+			authenticateAPIKey(c, apiKeys, apiKey)
+			return
+		}
+
+		// Retrieve the token from the Authorization header
+		tokenString := c.GetHeader("Authorization")
+
+		// If no token is provided, return an unauthorized error
+		if tokenString == "" {
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+				Message: "No token provided",
+				Details: "Authorization header is missing",
+			})
+			c.Abort()
+			return
+		}
+
+		// Remove the "Bearer " prefix from the token if it's present
+		if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
+			tokenString = tokenString[7:] // Strip "Bearer " to get the actual token
+		} else {
+			// Return an error if the token format is invalid
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+				Message: "Invalid token format",
+				Details: "Bearer is the accepted format",
+			})
+			c.Abort()
+			return
+		}
+
+		// If the token is empty after stripping, return an error
+		if len(tokenString) == 0 {
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+				Message: "Token is empty",
+				Details: "Token is empty",
+			})
+			c.Abort()
+			return
+		}
+
+		// Verify the signature and the claims of the token
+		claims, err := verifier.Verify(tokenString)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+				Message: "Invalid token",
+				Details: err.Error(),
+			})
+			c.Abort()
+			return
+		}
+
+		// If the token is valid, expose its claims and caller and proceed to the next handler
+		c.Set(ClaimsKey, claims)
+		c.Set(PrincipalKey, claims.Principal())
+		c.Next()
 	}
+}
 
-	// If the token is empty after stripping, return an error
-	if len(tokenString) == 0 {
+// authenticateAPIKey authenticates the request with its API key.
+func authenticateAPIKey(c *gin.Context, apiKeys APIKeyAuthenticator, apiKey string) {
+	principal, err := apiKeys(apiKey)
+	if errors.Is(err, ErrInvalidAPIKey) {
 		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
-			Message: "Token is empty",
-			Details: "Token is empty",
+			Message: "Invalid API key",
+			Details: err.Error(),
 		})
 		c.Abort()
 		return
 	}
-
-	// Retrieve the secret token from the environment variables
-	secretToken := os.Getenv("SECRET_KEY")
-	if secretToken == "" {
-		// Return an error if the secret token is missing
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Message: "SECRET_KEY environment variable is missing",
-			Details: "Secret not defined",
-		})
-		c.Abort()
-		return
-	}
-
-	// If the provided token doesn't match the secret, return an invalid token error
-	if tokenString != secretToken {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
-			Message: "Invalid token",
-			Details: "Invalid token provided",
+			Message: "Failed to authenticate API key",
+			Details: err.Error(),
 		})
 		c.Abort()
 		return
 	}
 
-	// If the token is valid, proceed to the next handler
+	c.Set(PrincipalKey, principal)
 	c.Next()
 }
+
+// ClaimsFromContext returns the claims of the authenticated caller, if any.
+func ClaimsFromContext(c *gin.Context) (*Claims, bool) {
+	value, exists := c.Get(ClaimsKey)
+	if !exists {
+		return nil, false
+	}
+	claims, ok := value.(*Claims)
+	return claims, ok
+}
@@ -0,0 +1,220 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"go_api_product_review/models"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Roles granted to callers through token claims or API key metadata
+const (
+	// RoleAdmin can perform every operation
+	RoleAdmin = "admin"
+	// RoleCatalogEditor can create and update products
+	RoleCatalogEditor = "catalog-editor"
+	// RoleReviewer can write reviews and change the ones they authored
+	RoleReviewer = "reviewer"
+	// RoleModerator can approve, reject and flag reviews
+	RoleModerator = "moderator"
+	// RoleMerchant can reply to reviews and change the replies they wrote
+	RoleMerchant = "merchant"
+	// RoleReadOnly can only read
+	RoleReadOnly = "read-only"
+)
+
+// Scopes restricting what API keys may do, on top of their roles
+const (
+	// ScopeProductsRead allows reading products
+	ScopeProductsRead = "products:read"
+	// ScopeProductsWrite allows creating, updating and deleting products
+	ScopeProductsWrite = "products:write"
+	// ScopeReviewsRead allows reading reviews
+	ScopeReviewsRead = "reviews:read"
+	// ScopeReviewsWrite allows creating, updating, deleting and voting on reviews
+	ScopeReviewsWrite = "reviews:write"
+	// ScopeReviewsModerate allows moderating reviews
+	ScopeReviewsModerate = "reviews:moderate"
+	// ScopeReviewsReply allows replying to reviews
+	ScopeReviewsReply = "reviews:reply"
+	// ScopePurchasesWrite allows importing purchases
+	ScopePurchasesWrite = "purchases:write"
+	// ScopeWebhooksManage allows managing webhook subscriptions
+	ScopeWebhooksManage = "webhooks:manage"
+	// ScopeAPIKeysManage allows managing API keys
+	ScopeAPIKeysManage = "api_keys:manage"
+)
+
+// knownRoles and knownScopes list the roles and scopes that can be granted
+var (
+	knownRoles  = []string{RoleAdmin, RoleCatalogEditor, RoleReviewer, RoleModerator, RoleMerchant, RoleReadOnly}
+	knownScopes = []string{
+		ScopeProductsRead, ScopeProductsWrite, ScopeReviewsRead, ScopeReviewsWrite,
+		ScopeReviewsModerate, ScopeReviewsReply, ScopePurchasesWrite, ScopeWebhooksManage, ScopeAPIKeysManage,
+	}
+)
+
+// ValidateGrants checks that the roles and scopes granted to an API key exist.
+func ValidateGrants(roles []string, scopes []string) error {
+	for _, role := range roles {
+		if !contains(knownRoles, role) {
+			return fmt.Errorf("unknown role %q, roles are %s", role, strings.Join(knownRoles, ", "))
+		}
+	}
+	for _, scope := range scopes {
+		if !contains(knownScopes, scope) {
+			return fmt.Errorf("unknown scope %q, scopes are %s", scope, strings.Join(knownScopes, ", "))
+		}
+	}
+	return nil
+}
+
+// ErrGrantNotHeld is returned when a caller grants an API key a role or scope it does not hold itself
+var ErrGrantNotHeld = errors.New("cannot grant a role or scope the caller does not hold")
+
+// PrincipalKey is the gin.Context key holding the authenticated caller
+const PrincipalKey = "principal"
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	// Subject uniquely identifies the caller
+	Subject string
+	// Name is the display name of the caller
+	Name string
+	// Roles are the roles granted to the caller
+	Roles []string
+	// Scoped is set when the caller is restricted to Scopes, as API keys are
+	Scoped bool
+	// Scopes are the scopes granted to a scoped caller
+	Scopes []string
+}
+
+// HasRole reports whether the caller was granted the role.
+func (p *Principal) HasRole(role string) bool {
+	return contains(p.Roles, role)
+}
+
+// HasScope reports whether the caller may use the scope.
+// Callers that are not scoped may use every scope.
+func (p *Principal) HasScope(scope string) bool {
+	return !p.Scoped || contains(p.Scopes, scope)
+}
+
+// IsAdmin reports whether the caller is an administrator.
+func (p *Principal) IsAdmin() bool {
+	return p.HasRole(RoleAdmin)
+}
+
+// IsModerator reports whether the caller moderates reviews, administrators included.
+func (p *Principal) IsModerator() bool {
+	return p.IsAdmin() || p.HasRole(RoleModerator)
+}
+
+// CheckGrants checks that the caller holds the roles and scopes it grants to an API key,
+// so API keys cannot issue keys more powerful than themselves.
+// Administrators may grant every role, and callers that are not scoped every scope.
+func (p *Principal) CheckGrants(roles []string, scopes []string) error {
+	for _, role := range roles {
+		if !p.IsAdmin() && !p.HasRole(role) {
+			return fmt.Errorf("%w: role %q", ErrGrantNotHeld, role)
+		}
+	}
+	for _, scope := range scopes {
+		if !p.HasScope(scope) {
+			return fmt.Errorf("%w: scope %q", ErrGrantNotHeld, scope)
+		}
+	}
+	return nil
+}
+
+// PrincipalFromContext returns the authenticated caller, if any.
+func PrincipalFromContext(c *gin.Context) (*Principal, bool) {
+	value, exists := c.Get(PrincipalKey)
+	if !exists {
+		return nil, false
+	}
+	principal, ok := value.(*Principal)
+	return principal, ok
+}
+
+// Policy describes who may call a route
+type Policy struct {
+	// Roles lets through callers granted one of them, every caller when empty.
+	// Administrators are always let through.
+	Roles []string
+	// Scope is required from scoped callers, not checked when empty
+	Scope string
+}
+
+// Authorize only lets through callers allowed by the policy.
+// Unauthenticated requests are rejected with 401, and callers lacking
+// the required roles or scope with 403.
+func Authorize(policy Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := PrincipalFromContext(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+				Message: "Not authenticated",
+				Details: "No authenticated caller",
+			})
+			c.Abort()
+			return
+		}
+
+		if policy.Scope != "" && !principal.HasScope(policy.Scope) {
+			c.JSON(http.StatusForbidden, models.ErrorResponse{
+				Message: "Forbidden",
+				Details: "This operation requires the scope: " + policy.Scope,
+			})
+			c.Abort()
+			return
+		}
+
+		if len(policy.Roles) == 0 || principal.IsAdmin() {
+			c.Next()
+			return
+		}
+		for _, role := range policy.Roles {
+			if principal.HasRole(role) {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Message: "Forbidden",
+			Details: "This operation requires one of the roles: " + joinRoles(policy.Roles),
+		})
+		c.Abort()
+	}
+}
+
+// RequireRoles only lets through callers granted one of the roles.
+// Administrators are always let through.
+func RequireRoles(roles ...string) gin.HandlerFunc {
+	return Authorize(Policy{Roles: roles})
+}
+
+// joinRoles formats roles for error messages.
+func joinRoles(roles []string) string {
+	joined := RoleAdmin
+	for _, role := range roles {
+		if role != RoleAdmin {
+			joined += ", " + role
+		}
+	}
+	return joined
+}
+
+// contains reports whether the value is in the list.
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
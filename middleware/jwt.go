@@ -0,0 +1,298 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultClockSkew is the tolerance applied to the exp, nbf and iat checks
+const DefaultClockSkew = 30 * time.Second
+
+// Claims holds the claims of a verified token.
+type Claims struct {
+	jwt.RegisteredClaims
+	// Name is the display name of the caller
+	Name string `json:"name,omitempty"`
+	// Roles are the roles granted to the caller
+	Roles []string `json:"roles,omitempty"`
+}
+
+// Principal returns the caller described by the claims.
+func (c *Claims) Principal() *Principal {
+	return &Principal{Subject: c.Subject, Name: c.Name, Roles: c.Roles}
+}
+
+// JWTConfig holds the keys and checks used to verify tokens.
+type JWTConfig struct {
+	// HMACSecret verifies HS256 tokens, HS256 is disabled when empty
+	HMACSecret []byte
+	// PublicKeys verifies RS256 and ES256 tokens, indexed by key ID
+	PublicKeys map[string]crypto.PublicKey
+	// Issuer is the required iss claim, not checked when empty
+	Issuer string
+	// Audience is the required aud claim, not checked when empty
+	Audience string
+	// ClockSkew is the tolerance applied to the time based claims
+	ClockSkew time.Duration
+}
+
+// LoadJWTConfigFromEnv builds the JWT configuration from environment variables:
+//   - JWT_HMAC_SECRET (or SECRET_KEY): secret of HS256 tokens
+//   - JWT_PUBLIC_KEY_FILES: comma-separated PEM files with RSA or EC public keys,
+//     the key ID of each key is its file name without extension
+//   - JWT_JWKS_FILE: local JWKS file with RSA or EC public keys
+//   - JWT_ISSUER, JWT_AUDIENCE: required iss and aud claims
+//   - JWT_CLOCK_SKEW: tolerance of the time based claims, e.g. "30s"
+func LoadJWTConfigFromEnv() (JWTConfig, error) {
+	config := JWTConfig{
+		PublicKeys: make(map[string]crypto.PublicKey),
+		Issuer:     os.Getenv("JWT_ISSUER"),
+		Audience:   os.Getenv("JWT_AUDIENCE"),
+		ClockSkew:  DefaultClockSkew,
+	}
+
+	secret := os.Getenv("JWT_HMAC_SECRET")
+	if secret == "" {
+		secret = os.Getenv("SECRET_KEY")
+	}
+	config.HMACSecret = []byte(secret)
+
+	if files := os.Getenv("JWT_PUBLIC_KEY_FILES"); files != "" {
+		for _, file := range strings.Split(files, ",") {
+			file = strings.TrimSpace(file)
+			key, err := LoadPublicKeyPEM(file)
+			if err != nil {
+				return config, err
+			}
+			kid := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+			config.PublicKeys[kid] = key
+		}
+	}
+
+	if file := os.Getenv("JWT_JWKS_FILE"); file != "" {
+		keys, err := LoadJWKSFile(file)
+		if err != nil {
+			return config, err
+		}
+		for kid, key := range keys {
+			config.PublicKeys[kid] = key
+		}
+	}
+
+	if skew := os.Getenv("JWT_CLOCK_SKEW"); skew != "" {
+		duration, err := time.ParseDuration(skew)
+		if err != nil {
+			return config, fmt.Errorf("invalid JWT_CLOCK_SKEW: %w", err)
+		}
+		config.ClockSkew = duration
+	}
+
+	return config, nil
+}
+
+// LoadPublicKeyPEM reads an RSA or EC public key from a PEM file holding
+// either a PKIX public key or a certificate.
+func LoadPublicKeyPEM(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found in %s", path)
+	}
+
+	var key crypto.PublicKey
+	switch block.Type {
+	case "CERTIFICATE":
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key = certificate.PublicKey
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T in %s", key, path)
+	}
+}
+
+// jsonWebKey is a public key of a JWKS document.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// LoadJWKSFile reads the RSA and P-256 EC public keys of a local JWKS file, indexed by key ID.
+func LoadJWKSFile(path string) (map[string]crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var document struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("invalid JWKS file %s: %w", path, err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(document.Keys))
+	for _, jwk := range document.Keys {
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q in %s: %w", jwk.Kid, path, err)
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
+
+// publicKey decodes the key parameters.
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	decode := func(value string) (*big.Int, error) {
+		data, err := base64.RawURLEncoding.DecodeString(value)
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).SetBytes(data), nil
+	}
+
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+	}
+}
+
+// JWTVerifier verifies HS256, RS256 and ES256 tokens.
+type JWTVerifier struct {
+	config JWTConfig
+	parser *jwt.Parser
+}
+
+// NewJWTVerifier creates a verifier from the configuration.
+// At least one HMAC secret or public key is required.
+func NewJWTVerifier(config JWTConfig) (*JWTVerifier, error) {
+	if len(config.HMACSecret) == 0 && len(config.PublicKeys) == 0 {
+		return nil, errors.New("no JWT verification key configured")
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"HS256", "RS256", "ES256"}),
+		jwt.WithLeeway(config.ClockSkew),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+	if config.Issuer != "" {
+		options = append(options, jwt.WithIssuer(config.Issuer))
+	}
+	if config.Audience != "" {
+		options = append(options, jwt.WithAudience(config.Audience))
+	}
+
+	return &JWTVerifier{config: config, parser: jwt.NewParser(options...)}, nil
+}
+
+// Verify checks the signature and the claims of a token and returns its claims.
+func (v *JWTVerifier) Verify(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := v.parser.ParseWithClaims(tokenString, claims, v.key)
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// key returns the key verifying the token, matching its algorithm and key ID.
+func (v *JWTVerifier) key(token *jwt.Token) (interface{}, error) {
+	if token.Method.Alg() == "HS256" {
+		if len(v.config.HMACSecret) == 0 {
+			return nil, errors.New("HS256 tokens are not accepted")
+		}
+		return v.config.HMACSecret, nil
+	}
+
+	// Only keys of the type required by the algorithm are candidates
+	candidates := make(map[string]crypto.PublicKey)
+	for kid, key := range v.config.PublicKeys {
+		switch key.(type) {
+		case *rsa.PublicKey:
+			if token.Method.Alg() == "RS256" {
+				candidates[kid] = key
+			}
+		case *ecdsa.PublicKey:
+			if token.Method.Alg() == "ES256" {
+				candidates[kid] = key
+			}
+		}
+	}
+
+	if kid, ok := token.Header["kid"].(string); ok {
+		if key, found := candidates[kid]; found {
+			return key, nil
+		}
+		return nil, fmt.Errorf("unknown key ID %q", kid)
+	}
+
+	// Tokens without key ID are accepted when a single key can verify them
+	if len(candidates) == 1 {
+		for _, key := range candidates {
+			return key, nil
+		}
+	}
+	return nil, errors.New("token has no key ID")
+}
@@ -0,0 +1,231 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"go_api_product_review/models"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Headers describing the rate limit of the caller
+const (
+	// RateLimitLimitHeader holds the number of requests allowed per window
+	RateLimitLimitHeader = "X-RateLimit-Limit"
+	// RateLimitRemainingHeader holds the number of requests left in the current window
+	RateLimitRemainingHeader = "X-RateLimit-Remaining"
+	// RateLimitResetHeader holds the number of seconds until the current window ends
+	RateLimitResetHeader = "X-RateLimit-Reset"
+)
+
+// RateLimit is a number of requests allowed per window
+type RateLimit struct {
+	// Requests allowed per window, unlimited when 0
+	Requests int
+	// Window is the duration the requests are counted over
+	Window time.Duration
+}
+
+// ParseRateLimit parses a rate limit written as "<requests>/<window>", e.g. "300/1m".
+// "off" disables the limit.
+func ParseRateLimit(value string) (RateLimit, error) {
+	if value == "off" {
+		return RateLimit{}, nil
+	}
+
+	requests, window, found := strings.Cut(value, "/")
+	if !found {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q, expected <requests>/<window>", value)
+	}
+	count, err := strconv.Atoi(requests)
+	if err != nil || count <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid request count in rate limit %q", value)
+	}
+	duration, err := time.ParseDuration(window)
+	if err != nil || duration <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid window in rate limit %q", value)
+	}
+	return RateLimit{Requests: count, Window: duration}, nil
+}
+
+// RateLimitPolicy is the rate limit of a route group
+type RateLimitPolicy struct {
+	// Default applies to every caller without an override
+	Default RateLimit
+	// Subjects overrides the limit of callers by subject, e.g. "api-key:3"
+	Subjects map[string]RateLimit
+}
+
+// limitFor returns the limit applying to the subject.
+func (p RateLimitPolicy) limitFor(subject string) RateLimit {
+	if limit, ok := p.Subjects[subject]; ok {
+		return limit
+	}
+	return p.Default
+}
+
+// LoadRateLimitPolicyFromEnv builds the rate limit policy of a route group from environment variables,
+// falling back to the given default limit:
+//   - RATE_LIMIT_<GROUP>: limit of every caller, e.g. "300/1m", or "off"
+//   - RATE_LIMIT_<GROUP>_SUBJECTS: comma-separated subject overrides, e.g. "api-key:3=1000/1m,alice=off"
+func LoadRateLimitPolicyFromEnv(group string, defaultLimit RateLimit) (RateLimitPolicy, error) {
+	prefix := "RATE_LIMIT_" + strings.ToUpper(strings.ReplaceAll(group, "-", "_"))
+	policy := RateLimitPolicy{Default: defaultLimit, Subjects: make(map[string]RateLimit)}
+
+	if value := os.Getenv(prefix); value != "" {
+		limit, err := ParseRateLimit(value)
+		if err != nil {
+			return policy, fmt.Errorf("invalid %s: %w", prefix, err)
+		}
+		policy.Default = limit
+	}
+
+	if value := os.Getenv(prefix + "_SUBJECTS"); value != "" {
+		for _, override := range strings.Split(value, ",") {
+			subject, limitValue, found := strings.Cut(strings.TrimSpace(override), "=")
+			if !found {
+				return policy, fmt.Errorf("invalid %s_SUBJECTS entry %q, expected <subject>=<limit>", prefix, override)
+			}
+			limit, err := ParseRateLimit(limitValue)
+			if err != nil {
+				return policy, fmt.Errorf("invalid %s_SUBJECTS: %w", prefix, err)
+			}
+			policy.Subjects[subject] = limit
+		}
+	}
+
+	return policy, nil
+}
+
+// RateLimiter throttles callers with a sliding window counter: the count of the
+// previous window is weighted by how much of it still overlaps the sliding window,
+// and added to the count of the current window.
+type RateLimiter struct {
+	store RateLimitStore
+	// Now returns the current time, it defaults to time.Now
+	Now func() time.Time
+}
+
+// NewRateLimiter creates a rate limiter keeping its counters in the store.
+func NewRateLimiter(store RateLimitStore) *RateLimiter {
+	return &RateLimiter{store: store, Now: time.Now}
+}
+
+// Limit throttles the callers of a route group according to the policy.
+// Callers are identified by their principal subject, or by their IP address
+// when unauthenticated, and each group has its own counters.
+// Throttled requests are rejected with 429 and a Retry-After header.
+// Rejected requests are counted too, so callers that keep retrying stay throttled.
+func (l *RateLimiter) Limit(group string, policy RateLimitPolicy) gin.HandlerFunc {
+	return l.limit(group, policy, func(c *gin.Context) string {
+		if principal, ok := PrincipalFromContext(c); ok {
+			return principal.Subject
+		}
+		return "ip:" + c.ClientIP()
+	})
+}
+
+// LimitByIP throttles the clients according to the policy by their IP address, "ip:<address>",
+// whether they authenticate or not. Used before the authentication, it also throttles
+// the requests failing to authenticate, such as API key guesses.
+func (l *RateLimiter) LimitByIP(group string, policy RateLimitPolicy) gin.HandlerFunc {
+	return l.limit(group, policy, func(c *gin.Context) string {
+		return "ip:" + c.ClientIP()
+	})
+}
+
+// limit throttles the callers identified by the subject function according to the policy.
+func (l *RateLimiter) limit(group string, policy RateLimitPolicy, subjectOf func(c *gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		subject := subjectOf(c)
+
+		limit := policy.limitFor(subject)
+		if limit.Requests <= 0 {
+			c.Next()
+			return
+		}
+
+		now := l.Now()
+		window := now.UnixNano() / int64(limit.Window)
+		windowStart := time.Unix(0, window*int64(limit.Window))
+		key := "ratelimit:" + group + ":" + subject + ":"
+
+		// Windows are kept until the next one ends, when they stop being the previous window
+		current, err := l.store.Increment(c.Request.Context(), key+strconv.FormatInt(window, 10), 2*limit.Window)
+		var previous int64
+		if err == nil {
+			previous, err = l.store.Count(c.Request.Context(), key+strconv.FormatInt(window-1, 10))
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Message: "Failed to apply rate limit",
+				Details: err.Error(),
+			})
+			c.Abort()
+			return
+		}
+
+		elapsed := float64(now.Sub(windowStart)) / float64(limit.Window)
+		estimated := float64(previous)*(1-elapsed) + float64(current)
+		remaining := limit.Requests - int(math.Ceil(estimated))
+		if remaining < 0 {
+			remaining = 0
+		}
+
+		c.Header(RateLimitLimitHeader, strconv.Itoa(limit.Requests))
+		c.Header(RateLimitRemainingHeader, strconv.Itoa(remaining))
+		c.Header(RateLimitResetHeader, strconv.Itoa(ceilSeconds(windowStart.Add(limit.Window).Sub(now))))
+
+		if estimated > float64(limit.Requests) {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(retryAfter(limit, previous, current, now.Sub(windowStart)))))
+			c.JSON(http.StatusTooManyRequests, models.ErrorResponse{
+				Message: "Too many requests",
+				Details: fmt.Sprintf("Rate limit of %d requests per %s exceeded", limit.Requests, limit.Window),
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// retryAfter returns how long until the next request fits in the sliding window,
+// given the counts of the previous and current windows and the time elapsed in the current one.
+func retryAfter(limit RateLimit, previous int64, current int64, elapsed time.Duration) time.Duration {
+	requests := float64(limit.Requests)
+	window := float64(limit.Window)
+
+	// Within the current window, the weight of the previous one decreases
+	if float64(current) < requests && previous > 0 {
+		fraction := 1 - (requests-float64(current)-1)/float64(previous)
+		return time.Duration(fraction*window) - elapsed
+	}
+
+	// Otherwise wait for the current window to weigh little enough in the next one
+	fraction := 1 - (requests-1)/float64(current)
+	return limit.Window - elapsed + time.Duration(fraction*window)
+}
+
+// ceilSeconds rounds a duration up to whole seconds, at least 1.
+func ceilSeconds(duration time.Duration) int {
+	seconds := int(math.Ceil(duration.Seconds()))
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
+
+// RateLimitStore keeps the request counters of the rate limiter.
+type RateLimitStore interface {
+	// Increment adds one to the counter of the key, which expires after ttl, and returns the new count
+	Increment(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	// Count returns the counter of the key, 0 if it does not exist
+	Count(ctx context.Context, key string) (int64, error)
+}
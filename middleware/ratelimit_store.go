@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"context"
+	"go_api_product_review/cache"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisRateLimitStore keeps the rate limit counters in Redis, shared by every node.
+type RedisRateLimitStore struct{}
+
+// NewRedisRateLimitStore creates a store using the Redis client of the cache package.
+func NewRedisRateLimitStore() *RedisRateLimitStore {
+	return &RedisRateLimitStore{}
+}
+
+// Increment adds one to the counter of the key in Redis.
+// The first request of a window sets its expiration in the same atomic step,
+// so a failure can never leave a counter without expiration.
+func (s *RedisRateLimitStore) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return cache.IncrWithExpire(ctx, key, ttl)
+}
+
+// Count returns the counter of the key in Redis.
+func (s *RedisRateLimitStore) Count(ctx context.Context, key string) (int64, error) {
+	value, err := cache.Rdb.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// memoryCounter is a counter of the MemoryRateLimitStore.
+type memoryCounter struct {
+	count     int64
+	expiresAt time.Time
+}
+
+// memorySweepInterval is how often the MemoryRateLimitStore drops its expired counters
+const memorySweepInterval = time.Minute
+
+// MemoryRateLimitStore keeps the rate limit counters in memory, for single-node deployments and tests.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	counters  map[string]memoryCounter
+	lastSweep time.Time
+	// Now returns the current time, it defaults to time.Now
+	Now func() time.Time
+}
+
+// NewMemoryRateLimitStore creates an empty in-memory store.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{counters: make(map[string]memoryCounter), Now: time.Now}
+}
+
+// Increment adds one to the counter of the key, restarting it if it expired.
+// The expired counters of the other keys are dropped at most once per sweep interval.
+func (s *MemoryRateLimitStore) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.Now()
+	if now.Sub(s.lastSweep) >= memorySweepInterval {
+		for k, counter := range s.counters {
+			if !now.Before(counter.expiresAt) {
+				delete(s.counters, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	counter, exists := s.counters[key]
+	if !exists || !now.Before(counter.expiresAt) {
+		counter = memoryCounter{expiresAt: now.Add(ttl)}
+	}
+	counter.count++
+	s.counters[key] = counter
+	return counter.count, nil
+}
+
+// Count returns the counter of the key, 0 if it does not exist or expired.
+func (s *MemoryRateLimitStore) Count(ctx context.Context, key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counter, exists := s.counters[key]
+	if !exists || !s.Now().Before(counter.expiresAt) {
+		return 0, nil
+	}
+	return counter.count, nil
+}
+
+// FallbackRateLimitStore uses a primary store, typically Redis, and falls back to
+// a secondary store, typically in memory, while the primary one fails.
+// Callers are then limited per node instead of not at all.
+type FallbackRateLimitStore struct {
+	primary  RateLimitStore
+	fallback RateLimitStore
+}
+
+// NewFallbackRateLimitStore creates a store falling back to fallback when primary fails.
+func NewFallbackRateLimitStore(primary RateLimitStore, fallback RateLimitStore) *FallbackRateLimitStore {
+	return &FallbackRateLimitStore{primary: primary, fallback: fallback}
+}
+
+// Increment adds one to the counter of the key in the primary store, or in the fallback store if it fails.
+func (s *FallbackRateLimitStore) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	count, err := s.primary.Increment(ctx, key, ttl)
+	if err != nil {
+		log.Printf("rate limit store failed, falling back: %v", err)
+		return s.fallback.Increment(ctx, key, ttl)
+	}
+	return count, nil
+}
+
+// Count returns the counter of the key in the primary store, or in the fallback store if it fails.
+func (s *FallbackRateLimitStore) Count(ctx context.Context, key string) (int64, error) {
+	count, err := s.primary.Count(ctx, key)
+	if err != nil {
+		log.Printf("rate limit store failed, falling back: %v", err)
+		return s.fallback.Count(ctx, key)
+	}
+	return count, nil
+}
@@ -0,0 +1,147 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// APIKey is a credential issued to an integration.
+// Only the SHA-256 hash of the key is stored, the key itself is shown once when issued.
+type APIKey struct {
+	gorm.Model
+	// Name describes the integration using the key
+	Name string
+	// Prefix is the beginning of the key, used to identify it without revealing it
+	Prefix string
+	// KeyHash is the hex encoded SHA-256 hash of the key
+	KeyHash string `gorm:"unique_index"`
+	// Scopes is the comma-separated list of scopes granted to the key
+	Scopes string
+	// Roles is the comma-separated list of roles granted to the key
+	Roles string
+	// ExpiresAt is when the key stops being accepted, nil if it never expires
+	ExpiresAt *time.Time
+	// LastUsedAt is when the key was last used, updated at most once per cache period
+	LastUsedAt *time.Time
+	// RevokedAt is when the key was revoked, nil while it is active
+	RevokedAt *time.Time
+}
+
+// IsActive reports whether the key is neither revoked nor expired at the given time.
+func (k *APIKey) IsActive(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// ScopeList returns the scopes granted to the key.
+func (k *APIKey) ScopeList() []string {
+	return splitList(k.Scopes)
+}
+
+// RoleList returns the roles granted to the key.
+func (k *APIKey) RoleList() []string {
+	return splitList(k.Roles)
+}
+
+// splitList splits a comma-separated list, returning an empty slice for an empty list.
+func splitList(list string) []string {
+	if list == "" {
+		return []string{}
+	}
+	return strings.Split(list, ",")
+}
+
+// APIKeyRequest is the payload used to issue an API key
+// @Description API key details
+type APIKeyRequest struct {
+	// Name of the integration using the key
+	// @example "Partner storefront"
+	Name string `json:"name"`
+	// Scopes granted to the key
+	// @example ["products:read","reviews:write"]
+	Scopes []string `json:"scopes"`
+	// Roles granted to the key
+	// @example ["reviewer"]
+	Roles []string `json:"roles"`
+	// Expiration date of the key, omit for a key that never expires
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// Validate checks if the API key request fields are valid.
+// The scopes and roles themselves are checked against the authorization policies.
+func (r *APIKeyRequest) Validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return errors.New("name is required")
+	}
+	if len(r.Scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+	if r.ExpiresAt != nil && !r.ExpiresAt.After(time.Now()) {
+		return errors.New("expires_at must be in the future")
+	}
+	return nil
+}
+
+// ToAPIKey builds the API key described by the request, without its secret.
+func (r *APIKeyRequest) ToAPIKey() APIKey {
+	return APIKey{
+		Name:      strings.TrimSpace(r.Name),
+		Scopes:    strings.Join(r.Scopes, ","),
+		Roles:     strings.Join(r.Roles, ","),
+		ExpiresAt: r.ExpiresAt,
+	}
+}
+
+// APIKeyResponse is the public representation of an API key, without the key itself
+// @Description API key
+type APIKeyResponse struct {
+	// Unique identifier of the key
+	// @example 5
+	ID uint `json:"id"`
+	// Name of the integration using the key
+	// @example "Partner storefront"
+	Name string `json:"name"`
+	// Beginning of the key, to identify it
+	// @example "prk_4Fh2kQ9x"
+	Prefix string `json:"prefix"`
+	// Scopes granted to the key
+	// @example ["products:read","reviews:write"]
+	Scopes []string `json:"scopes"`
+	// Roles granted to the key
+	// @example ["reviewer"]
+	Roles []string `json:"roles"`
+	// Date the key was created
+	CreatedAt time.Time `json:"created_at"`
+	// Expiration date of the key
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// Date the key was last used
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	// Date the key was revoked
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// NewAPIKeyResponse builds the public representation of an API key.
+func NewAPIKeyResponse(key APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.ScopeList(),
+		Roles:      key.RoleList(),
+		CreatedAt:  key.CreatedAt,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+	}
+}
+
+// IssuedAPIKeyResponse is returned when a key is created or rotated, it is the only time the key is shown
+// @Description Newly issued API key
+type IssuedAPIKeyResponse struct {
+	APIKeyResponse
+	// The API key, to be sent in the X-API-Key header. It cannot be retrieved again.
+	// @example "prk_4Fh2kQ9xT0mJ8sLw1ZbYcVdR3nE6uA5pGiHkOq7fXy2"
+	Key string `json:"key"`
+}
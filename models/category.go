@@ -0,0 +1,218 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// MaxCategoryAspects is the maximum number of aspects defined by a category
+	MaxCategoryAspects = 20
+	// MaxCategoryDepth is the maximum number of levels of the category tree
+	MaxCategoryDepth = 6
+	// MaxProductCategories is the maximum number of categories a product is assigned to
+	MaxProductCategories = 20
+)
+
+// aspectKeyPattern matches the keys of the aspects, such as "battery_life"
+var aspectKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// categorySlugPattern matches the slugs of the categories, such as "noise-cancelling"
+var categorySlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// maxCategorySlugLength is the maximum length of a slug, which keeps the paths of the deepest categories within their column
+const maxCategorySlugLength = 50
+
+// Category groups products sharing the aspects their reviews rate,
+// such as the battery life of electronics or the fit of apparel.
+// Categories form a tree: the path joins the slugs of the ancestors of a category
+// and its own, such as "electronics/audio/headphones", so the descendants of a category
+// are the categories whose path starts with its path followed by a slash.
+// @Description Category of products in the category tree and the aspects their reviews rate
+type Category struct {
+	gorm.Model
+	// Name of the category
+	// @example "Headphones"
+	Name string `json:"name" gorm:"not null"`
+	// Slug identifying the category among its siblings, derived from the name when empty
+	// @example "headphones"
+	Slug string `json:"slug"`
+	// ID of the parent category, top-level categories have none
+	// @example 2
+	ParentID *uint `json:"parent_id,omitempty" gorm:"index"`
+	// Slugs of the ancestors of the category and its own, joined by slashes, maintained by the service
+	// @example "electronics/audio/headphones"
+	Path string `json:"path" gorm:"size:512;unique_index"`
+	// Aspects rated by the reviews of the products of the category, the products of its
+	// descendants rate them too
+	Aspects []CategoryAspect `json:"aspects" gorm:"foreignkey:CategoryID;association_autoupdate:false;association_autocreate:false"`
+	// Rating aggregates rolled up from the products of the category and its descendants, only set in responses
+	Ratings *CategoryRatings `json:"ratings,omitempty" gorm:"-"`
+}
+
+// CategoryAspect is an aspect the reviews of the products of a category rate from 1 to 5.
+// The unique index on the category and the key keeps the keys of a category distinct.
+type CategoryAspect struct {
+	// ID is the unique identifier of the aspect
+	ID uint `json:"-" gorm:"primary_key"`
+	// CategoryID is the category defining the aspect
+	CategoryID uint `json:"-" gorm:"not null;unique_index:idx_category_aspects_category_key"`
+	// Key identifying the aspect in the aspect ratings of the reviews
+	// @example "battery_life"
+	Key string `json:"key" gorm:"not null;unique_index:idx_category_aspects_category_key"`
+	// Name of the aspect shown to the reviewers
+	// @example "Battery life"
+	Name string `json:"name" gorm:"not null"`
+}
+
+// Validate checks the name and slug of the category and the keys and names of its aspects.
+// An empty slug is derived from the name.
+func (c *Category) Validate() error {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		return errors.New("name is required")
+	}
+	c.Slug = strings.TrimSpace(c.Slug)
+	if c.Slug == "" {
+		c.Slug = Slugify(c.Name)
+	}
+	if len(c.Slug) > maxCategorySlugLength || !categorySlugPattern.MatchString(c.Slug) {
+		return fmt.Errorf("invalid slug %q, expected at most %d lowercase letters and digits separated by hyphens",
+			c.Slug, maxCategorySlugLength)
+	}
+	if len(c.Aspects) > MaxCategoryAspects {
+		return fmt.Errorf("a category defines at most %d aspects", MaxCategoryAspects)
+	}
+	keys := map[string]bool{}
+	for i := range c.Aspects {
+		aspect := &c.Aspects[i]
+		aspect.Name = strings.TrimSpace(aspect.Name)
+		if !aspectKeyPattern.MatchString(aspect.Key) {
+			return fmt.Errorf("invalid aspect key %q, expected lowercase letters, digits and underscores", aspect.Key)
+		}
+		if keys[aspect.Key] {
+			return fmt.Errorf("aspect %q is defined twice", aspect.Key)
+		}
+		keys[aspect.Key] = true
+		if aspect.Name == "" {
+			return fmt.Errorf("aspect %q needs a name", aspect.Key)
+		}
+	}
+	return nil
+}
+
+// Slugify derives a slug from a name, keeping its ASCII letters and digits
+// and replacing every other run of characters with a hyphen.
+func Slugify(name string) string {
+	var slug strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if hyphen && slug.Len() > 0 {
+				slug.WriteByte('-')
+			}
+			slug.WriteRune(r)
+			hyphen = false
+			continue
+		}
+		hyphen = true
+	}
+	result := slug.String()
+	if len(result) > maxCategorySlugLength {
+		result = strings.TrimRight(result[:maxCategorySlugLength], "-")
+	}
+	return result
+}
+
+// ProductCategory assigns a product to a category. A product belongs to any number of categories,
+// and the category set as its category_id, whose aspects its reviews rate, is always one of them.
+type ProductCategory struct {
+	// ProductID and CategoryID identify the assignment
+	ProductID  uint `gorm:"primary_key;auto_increment:false"`
+	CategoryID uint `gorm:"primary_key;auto_increment:false;index"`
+}
+
+// CategoryRatings holds the rating aggregates of a category, rolled up from the products
+// assigned to the category or its descendants. Every product counts once, and the average rating
+// of the category is the mean of the average ratings of its reviewed products, so each product
+// weighs the same whatever its number of reviews.
+// @Description Rating aggregates of a category rolled up from the products of the category and its descendants
+type CategoryRatings struct {
+	// Number of products in the category and its descendants
+	// @example 14
+	ProductCount int `json:"product_count"`
+	// Number of those products with at least one approved review
+	// @example 9
+	RatedProductCount int `json:"rated_product_count"`
+	// Number of approved reviews of those products
+	// @example 120
+	ReviewCount int `json:"review_count"`
+	// Mean of the average ratings of the reviewed products
+	// @example 4.2
+	AverageRating float64 `json:"average_rating"`
+}
+
+// ReviewAspectRating is the rating of one aspect of the product given by a review,
+// from 1 to 5, alongside the overall rating of the review.
+// The unique index on the review and the aspect allows one rating per aspect.
+type ReviewAspectRating struct {
+	// ID is the unique identifier of the aspect rating
+	ID uint `json:"-" gorm:"primary_key"`
+	// ReviewID is the review giving the rating
+	ReviewID uint `json:"-" gorm:"not null;unique_index:idx_review_aspect_ratings_review_aspect"`
+	// Key of the rated aspect, defined by the category of the product
+	// @example "battery_life"
+	Aspect string `json:"aspect" gorm:"not null;unique_index:idx_review_aspect_ratings_review_aspect" validate:"required"`
+	// Rating of the aspect (1-5)
+	// @example 4
+	Rating int `json:"rating" gorm:"not null" validate:"min=1,max=5"`
+}
+
+// ProductAspectRating holds the rating aggregates of one aspect of a product, over the approved
+// reviews rating it. They are maintained along with every review change, like the overall ratings.
+type ProductAspectRating struct {
+	// ProductID and Aspect identify the aggregates
+	ProductID uint   `json:"-" gorm:"primary_key;auto_increment:false"`
+	Aspect    string `json:"aspect" gorm:"primary_key"`
+	// Number of approved reviews rating the aspect
+	ReviewCount int `json:"review_count" gorm:"not null;default:0"`
+	// Sum of the ratings of the aspect
+	RatingSum int64 `json:"-" gorm:"not null;default:0"`
+	// Average rating of the aspect
+	AverageRating float64 `json:"average_rating" gorm:"not null;default:0"`
+}
+
+// AspectRatingResponse is the public representation of the rating aggregates of an aspect of a product
+// @Description Average rating of an aspect of a product over the reviews rating it
+type AspectRatingResponse struct {
+	// Key of the aspect
+	// @example "battery_life"
+	Aspect string `json:"aspect"`
+	// Average rating of the aspect
+	// @example 4.2
+	AverageRating float64 `json:"average_rating"`
+	// Number of reviews rating the aspect
+	// @example 9
+	ReviewCount int `json:"review_count"`
+}
+
+// NewAspectRatingResponses builds the public representation of the aspect ratings of a product,
+// leaving out the aspects no approved review rates anymore.
+func NewAspectRatingResponses(ratings []ProductAspectRating) []AspectRatingResponse {
+	var responses []AspectRatingResponse
+	for _, rating := range ratings {
+		if rating.ReviewCount <= 0 {
+			continue
+		}
+		responses = append(responses, AspectRatingResponse{
+			Aspect:        rating.Aspect,
+			AverageRating: rating.AverageRating,
+			ReviewCount:   rating.ReviewCount,
+		})
+	}
+	return responses
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"errors"
+	"strings"
+)
+
+// Moderation statuses of a review
+const (
+	// ReviewPending reviews wait for a moderator and are not public yet
+	ReviewPending = "pending"
+	// ReviewApproved reviews are public and count toward the ratings of the product
+	ReviewApproved = "approved"
+	// ReviewRejected reviews were turned down by a moderator
+	ReviewRejected = "rejected"
+	// ReviewFlagged reviews were taken down until a moderator looks at them again
+	ReviewFlagged = "flagged"
+)
+
+// reviewTransitions lists the statuses a review can be moved to from each status
+var reviewTransitions = map[string][]string{
+	ReviewPending:  {ReviewApproved, ReviewRejected, ReviewFlagged},
+	ReviewFlagged:  {ReviewApproved, ReviewRejected},
+	ReviewApproved: {ReviewFlagged, ReviewRejected},
+	ReviewRejected: {ReviewApproved},
+}
+
+// CanTransition reports whether a review can be moved from one status to another.
+func CanTransition(from string, to string) bool {
+	for _, status := range reviewTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ModerationRequest is the payload of the moderation endpoints
+// @Description Reason of a moderation decision
+type ModerationRequest struct {
+	// Reason of the decision, required to reject or flag a review
+	// @example "Contains personal information"
+	Reason string `json:"reason"`
+}
+
+// Validate checks the request for a move to the status.
+func (r *ModerationRequest) Validate(status string) error {
+	r.Reason = strings.TrimSpace(r.Reason)
+	if r.Reason == "" && status != ReviewApproved {
+		return errors.New("reason is required to reject or flag a review")
+	}
+	if len(r.Reason) > 500 {
+		return errors.New("reason must be at most 500 characters")
+	}
+	return nil
+}
+
+// ModerationQuery holds the pagination and filter options of the moderation queue
+// @Description Query parameters accepted by the moderation queue endpoint
+type ModerationQuery struct {
+	// Status of the reviews to list: pending, flagged, rejected or approved
+	// @example "pending"
+	Status string `form:"status"`
+	// Maximum number of reviews to return (1-100)
+	// @example 20
+	Limit int `form:"limit"`
+	// Opaque cursor returned as next_cursor by the previous page
+	Cursor string `form:"cursor"`
+}
+
+// Validate checks the query values and fills in the defaults.
+func (q *ModerationQuery) Validate() error {
+	if q.Status == "" {
+		q.Status = ReviewPending
+	}
+	if _, ok := reviewTransitions[q.Status]; !ok {
+		return errors.New("status must be one of pending, flagged, rejected, approved")
+	}
+	if q.Limit == 0 {
+		q.Limit = DefaultPageLimit
+	}
+	if q.Limit < 0 || q.Limit > MaxPageLimit {
+		return errors.New("limit must be between 1 and 100")
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// OutboxEvent is a domain event waiting to be relayed to the event publisher.
+// It is written in the same transaction as the change it describes, so an event
+// is stored if and only if the change is committed.
+type OutboxEvent struct {
+	gorm.Model
+	// EventID is the unique ID of the event, kept across delivery attempts
+	EventID string `gorm:"unique_index"`
+	// EventType is the type of the event, e.g. "review.created"
+	EventType string
+	// ProductID is the product the event relates to
+	ProductID uint
+	// Payload is the JSON encoded event
+	Payload string `gorm:"type:text"`
+	// Attempts is the number of failed delivery attempts
+	Attempts int
+	// LastError is the error of the last failed delivery attempt
+	LastError string `gorm:"type:text"`
+	// NextAttemptAt is the earliest time the next delivery attempt may run
+	NextAttemptAt time.Time `gorm:"index"`
+	// DeliveredAt is when the event was relayed, nil while it is pending
+	DeliveredAt *time.Time `gorm:"index"`
+}
@@ -2,6 +2,8 @@ package models
 
 import (
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/jinzhu/gorm"
 )
@@ -20,13 +22,72 @@ type Product struct {
 	// Price of the product
 	// @example 20.00
 	Price float64 `json:"price" validate:"required"`
+	// ID of the category of the product, whose aspects the reviews of the product rate
+	// @example 3
+	CategoryID *uint `json:"category_id,omitempty" gorm:"index"`
+	// IDs of every category the product is assigned to, including category_id,
+	// loaded from the product_categories table
+	// @example [3,7]
+	CategoryIDs []uint `json:"category_ids,omitempty" gorm:"-"`
 	// Average rating of the product based on reviews
 	// @example 4.5
 	AverageRating float64 `json:"average_rating"`
-	// Reviews associated with this product
-	// @example []Review
-	// @readOnly
-	Reviews []Review `gorm:"foreignkey:ProductID"`
+	// Score used to rank the product, an average rating weighted by the confidence in it
+	// @example 4.31
+	RankingScore float64 `json:"ranking_score" gorm:"index"`
+	// Number of reviews of the product, maintained along with every review change
+	// @example 12
+	ReviewCount int `json:"review_count" gorm:"not null;default:0"`
+	// Sum of the ratings of the reviews, maintained along with every review change
+	RatingSum int64 `json:"-" gorm:"not null;default:0"`
+	// Number of reviews with each rating, maintained along with every review change
+	Rating1Count int `json:"-" gorm:"column:rating_1_count;not null;default:0"`
+	Rating2Count int `json:"-" gorm:"column:rating_2_count;not null;default:0"`
+	Rating3Count int `json:"-" gorm:"column:rating_3_count;not null;default:0"`
+	Rating4Count int `json:"-" gorm:"column:rating_4_count;not null;default:0"`
+	Rating5Count int `json:"-" gorm:"column:rating_5_count;not null;default:0"`
+	// Average rating of the reviews created within the recent window
+	// @example 4.8
+	RecentAverageRating float64 `json:"recent_average_rating"`
+	// Number of reviews created within the recent window
+	// @example 3
+	RecentReviewCount int `json:"recent_review_count" gorm:"not null;default:0"`
+	// Sum of the ratings of the reviews created within the recent window
+	RecentRatingSum int64 `json:"-" gorm:"not null;default:0"`
+	// Average rating with every review weighted by its age, halving every decay half-life
+	// @example 4.6
+	DecayedAverageRating float64 `json:"decayed_average_rating"`
+	// Sum of the decay weights of the reviews, and of their ratings weighted alike,
+	// relative to the decay reference time
+	DecayedWeight    float64 `json:"-" gorm:"not null;default:0"`
+	DecayedRatingSum float64 `json:"-" gorm:"not null;default:0"`
+	// Reference time of the decay weights, a review created then weighs 1.
+	// It is moved forward as it ages to keep the weights within floating-point range.
+	DecayedReference *time.Time `json:"-"`
+	// Average rating of the reviews of verified purchases
+	// @example 4.7
+	VerifiedAverageRating float64 `json:"verified_average_rating"`
+	// Number of reviews of verified purchases, maintained along with every review change
+	// @example 5
+	VerifiedReviewCount int `json:"verified_review_count" gorm:"not null;default:0"`
+	// Sum of the ratings of the reviews of verified purchases, maintained along with every review change
+	VerifiedRatingSum int64 `json:"-" gorm:"not null;default:0"`
+	// Rating aggregates of the aspects rated by the reviews, never serialized directly,
+	// responses embed them through ProductSummary
+	AspectRatings []ProductAspectRating `json:"-" gorm:"foreignkey:ProductID"`
+	// Reviews associated with this product, never serialized directly,
+	// responses embed them through ProductSummary when requested
+	Reviews []Review `json:"-" gorm:"foreignkey:ProductID"`
+}
+
+// RatingCounts returns the number of reviews with each rating, indexed by rating minus one.
+func (p *Product) RatingCounts() [5]int {
+	return [5]int{p.Rating1Count, p.Rating2Count, p.Rating3Count, p.Rating4Count, p.Rating5Count}
+}
+
+// RatingCountColumn returns the column holding the number of reviews with the rating (1-5).
+func RatingCountColumn(rating int) string {
+	return fmt.Sprintf("rating_%d_count", rating)
 }
 
 // Validate checks if the product fields are valid.
@@ -37,5 +98,8 @@ func (p *Product) Validate() error {
 	if p.Name == "" {
 		return errors.New("name is required")
 	}
+	if len(p.CategoryIDs) > MaxProductCategories {
+		return fmt.Errorf("a product is assigned to at most %d categories", MaxProductCategories)
+	}
 	return nil
 }
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	// DefaultPageLimit is the page size used when the caller does not provide one
+	DefaultPageLimit = 20
+	// MaxPageLimit is the largest page size a caller may request
+	MaxPageLimit = 100
+)
+
+// productSortKeys lists the fields products can be sorted by
+var productSortKeys = map[string]bool{
+	"price":          true,
+	"average_rating": true,
+	"ranking_score":  true,
+	"created_at":     true,
+}
+
+// ProductQuery holds the pagination, filter and sort options for listing products
+// @Description Query parameters accepted by the product listing endpoint
+type ProductQuery struct {
+	// Maximum number of products to return (1-100)
+	// @example 20
+	Limit int `form:"limit"`
+	// Opaque cursor returned as next_cursor by the previous page
+	Cursor string `form:"cursor"`
+	// Only return products with a price greater than or equal to this value
+	// @example 10
+	MinPrice *float64 `form:"min_price"`
+	// Only return products with a price lower than or equal to this value
+	// @example 50
+	MaxPrice *float64 `form:"max_price"`
+	// Only return products with an average rating greater than or equal to this value
+	// @example 4
+	MinRating *float64 `form:"min_rating"`
+	// Only return products assigned to this category or one of its descendants
+	// @example 3
+	CategoryID *uint `form:"category_id"`
+	// Case-insensitive substring the product name must contain
+	// @example "banana"
+	Name string `form:"name"`
+	// Sort key, prefixed with "-" for descending order
+	// @example "-average_rating"
+	Sort string `form:"sort"`
+	// Whether the total number of matching products should be returned
+	IncludeTotal bool `form:"include_total"`
+	// Set to "reviews" to embed the reviews of each product
+	// @example "reviews"
+	Include string `form:"include"`
+}
+
+// Validate checks the query values and fills in the defaults.
+func (q *ProductQuery) Validate() error {
+	if q.Limit == 0 {
+		q.Limit = DefaultPageLimit
+	}
+	if q.Limit < 0 || q.Limit > MaxPageLimit {
+		return errors.New("limit must be between 1 and 100")
+	}
+	if q.Sort == "" {
+		q.Sort = "-created_at"
+	}
+	if !productSortKeys[strings.TrimPrefix(q.Sort, "-")] {
+		return errors.New("sort must be one of price, average_rating, ranking_score, created_at")
+	}
+	if q.MinPrice != nil && q.MaxPrice != nil && *q.MinPrice > *q.MaxPrice {
+		return errors.New("min_price must not be greater than max_price")
+	}
+	if q.MinRating != nil && (*q.MinRating < 0 || *q.MinRating > 5) {
+		return errors.New("min_rating must be between 0 and 5")
+	}
+	if _, err := ParseInclude(q.Include); err != nil {
+		return err
+	}
+	return nil
+}
+
+// IncludeReviews reports whether reviews must be embedded in each product.
+func (q *ProductQuery) IncludeReviews() bool {
+	return q.Include == IncludeReviews
+}
+
+// SortKey returns the field to sort by and whether the order is descending.
+func (q *ProductQuery) SortKey() (string, bool) {
+	return strings.TrimPrefix(q.Sort, "-"), strings.HasPrefix(q.Sort, "-")
+}
+
+// ProductPage is a single page of products
+// @Description Paginated list of products
+type ProductPage struct {
+	// Products in this page
+	Items []ProductSummary `json:"items"`
+	// Cursor to request the next page, empty on the last page
+	// @example "eyJzIjoiY3JlYXRlZF9hdCJ9"
+	NextCursor string `json:"next_cursor,omitempty"`
+	// Total number of products matching the filters, only set when include_total=true
+	// @example 120
+	Total *int64 `json:"total,omitempty"`
+}
@@ -0,0 +1,205 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// IncludeReviews is the include value that embeds reviews in product responses
+const IncludeReviews = "reviews"
+
+// ParseInclude validates the include query parameter of the product endpoints
+// and reports whether reviews must be embedded in the response.
+func ParseInclude(include string) (bool, error) {
+	switch include {
+	case "":
+		return false, nil
+	case IncludeReviews:
+		return true, nil
+	default:
+		return false, errors.New("include must be empty or reviews")
+	}
+}
+
+// ProductSummary is the public representation of a product
+// @Description Product with its average rating, reviews are only embedded when requested with include=reviews
+type ProductSummary struct {
+	// Unique identifier of the product
+	// @example 1
+	ID uint `json:"id"`
+	// Name of the product
+	// @example "Bananas"
+	Name string `json:"name"`
+	// Description of the product
+	// @example "Bananas from Argentina"
+	Description string `json:"description"`
+	// Price of the product
+	// @example 20.00
+	Price float64 `json:"price"`
+	// ID of the category of the product
+	// @example 3
+	CategoryID *uint `json:"category_id,omitempty"`
+	// IDs of every category the product is assigned to
+	// @example [3,7]
+	CategoryIDs []uint `json:"category_ids,omitempty"`
+	// Average rating of the product based on reviews
+	// @example 4.5
+	AverageRating float64 `json:"average_rating"`
+	// Score used to rank the product, an average rating weighted by the confidence in it
+	// @example 4.31
+	RankingScore float64 `json:"ranking_score"`
+	// Number of reviews of the product
+	// @example 12
+	ReviewCount int `json:"review_count"`
+	// Average rating of the reviews created within the recent window
+	// @example 4.8
+	RecentAverageRating float64 `json:"recent_average_rating"`
+	// Number of reviews created within the recent window
+	// @example 3
+	RecentReviewCount int `json:"recent_review_count"`
+	// Average rating with every review weighted by its age, recent reviews weighing the most
+	// @example 4.6
+	DecayedAverageRating float64 `json:"decayed_average_rating"`
+	// Average rating of the reviews of verified purchases
+	// @example 4.7
+	VerifiedAverageRating float64 `json:"verified_average_rating"`
+	// Number of reviews of verified purchases
+	// @example 5
+	VerifiedReviewCount int `json:"verified_review_count"`
+	// Average ratings of the aspects rated by the reviews, such as the battery life or the fit
+	AspectRatings []AspectRatingResponse `json:"aspect_ratings,omitempty"`
+	// Reviews of the product, only set when include=reviews
+	Reviews []ReviewResponse `json:"reviews,omitempty"`
+}
+
+// NewProductSummary builds the public representation of a product.
+// Reviews are embedded only if they were loaded on the product.
+func NewProductSummary(product Product) ProductSummary {
+	summary := ProductSummary{
+		ID:            product.ID,
+		Name:          product.Name,
+		Description:   product.Description,
+		Price:         product.Price,
+		CategoryID:    product.CategoryID,
+		CategoryIDs:   product.CategoryIDs,
+		AverageRating: product.AverageRating,
+		RankingScore:  product.RankingScore,
+		ReviewCount:   product.ReviewCount,
+
+		RecentAverageRating:  product.RecentAverageRating,
+		RecentReviewCount:    product.RecentReviewCount,
+		DecayedAverageRating: product.DecayedAverageRating,
+
+		VerifiedAverageRating: product.VerifiedAverageRating,
+		VerifiedReviewCount:   product.VerifiedReviewCount,
+
+		AspectRatings: NewAspectRatingResponses(product.AspectRatings),
+	}
+	if product.Reviews != nil {
+		summary.Reviews = make([]ReviewResponse, 0, len(product.Reviews))
+		for _, review := range product.Reviews {
+			summary.Reviews = append(summary.Reviews, NewReviewResponse(review))
+		}
+	}
+	return summary
+}
+
+// ProductDetail is the full public representation of a single product
+// @Description Product with its average rating and record timestamps, reviews are only embedded when requested with include=reviews
+type ProductDetail struct {
+	ProductSummary
+	// Date the product was created
+	CreatedAt time.Time `json:"created_at"`
+	// Date the product was last updated
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NewProductDetail builds the full public representation of a product.
+func NewProductDetail(product Product) ProductDetail {
+	return ProductDetail{
+		ProductSummary: NewProductSummary(product),
+		CreatedAt:      product.CreatedAt,
+		UpdatedAt:      product.UpdatedAt,
+	}
+}
+
+// ReviewResponse is the public representation of a review
+// @Description Review of a product
+type ReviewResponse struct {
+	// Unique identifier of the review
+	// @example 7
+	ID uint `json:"id"`
+	// ID of the reviewed product
+	// @example 1
+	ProductID uint `json:"product_id"`
+	// First name of the reviewer
+	// @example "Miguel"
+	FirstName string `json:"first_name"`
+	// Last name of the reviewer
+	// @example "Filip"
+	LastName string `json:"last_name"`
+	// Text content of the review
+	// @example "This bananas are amazing!"
+	ReviewText string `json:"review_text"`
+	// Rating given by the reviewer (1-5)
+	// @example 4
+	Rating int `json:"rating"`
+	// Ratings of the aspects of the product defined by its category (1-5)
+	AspectRatings []ReviewAspectRating `json:"aspect_ratings,omitempty"`
+	// What the reviewer liked
+	// @example "Ripe and sweet"
+	Pros string `json:"pros,omitempty"`
+	// What the reviewer disliked
+	// @example "Bruised easily"
+	Cons string `json:"cons,omitempty"`
+	// Whether the reviewer bought the product
+	// @example true
+	Verified bool `json:"verified"`
+	// Moderation status of the review, only approved reviews are public
+	// @example "approved"
+	Status string `json:"status"`
+	// Reason given by the moderator for the last status change
+	// @example "Contains personal information"
+	ModerationReason string `json:"moderation_reason,omitempty"`
+	// Number of helpful votes
+	// @example 12
+	HelpfulCount int `json:"helpful_count"`
+	// Number of unhelpful votes
+	// @example 3
+	UnhelpfulCount int `json:"unhelpful_count"`
+	// Threads of the replies of merchants to the review, oldest first
+	Replies []ReviewReplyResponse `json:"replies,omitempty"`
+	// Photos attached to the review, oldest first
+	Media []ReviewMediaResponse `json:"media,omitempty"`
+	// Date the review was created
+	CreatedAt time.Time `json:"created_at"`
+	// Date the review was last updated
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NewReviewResponse builds the public representation of a review.
+func NewReviewResponse(review Review) ReviewResponse {
+	return ReviewResponse{
+		ID:         review.ID,
+		ProductID:  review.ProductID,
+		FirstName:  review.FirstName,
+		LastName:   review.LastName,
+		ReviewText: review.ReviewText,
+		Rating:     review.Rating,
+		Verified:   review.Verified,
+		CreatedAt:  review.CreatedAt,
+		UpdatedAt:  review.UpdatedAt,
+
+		AspectRatings: review.AspectRatings,
+		Pros:          review.Pros,
+		Cons:          review.Cons,
+
+		Status:           review.Status,
+		ModerationReason: review.ModerationReason,
+
+		HelpfulCount:   review.HelpfulCount,
+		UnhelpfulCount: review.UnhelpfulCount,
+		Replies:        NewReplyThreads(review.Replies),
+		Media:          NewReviewMediaResponses(review.Media),
+	}
+}
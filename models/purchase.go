@@ -0,0 +1,76 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MaxPurchaseImport is the maximum number of purchases of a single import
+const MaxPurchaseImport = 1000
+
+// Purchase records that a customer bought a product, ingested from the order system.
+// Reviews of the product by the customer are marked as verified purchases.
+// The unique index on the order, the customer and the product makes imports idempotent.
+type Purchase struct {
+	ID        uint      `json:"-" gorm:"primary_key"`
+	CreatedAt time.Time `json:"-"`
+	// Subject the customer authenticates with, the author subject of their reviews
+	// @example "alice"
+	CustomerID string `json:"customer_id" gorm:"not null;unique_index:idx_purchases_order_customer_product;index:idx_purchases_customer_product"`
+	// ID of the bought product
+	// @example 1
+	ProductID uint `json:"product_id" gorm:"not null;unique_index:idx_purchases_order_customer_product;index:idx_purchases_customer_product"`
+	// ID of the order in the order system
+	// @example "order-1042"
+	OrderID string `json:"order_id" gorm:"not null;unique_index:idx_purchases_order_customer_product"`
+	// Date of the purchase, the import date when omitted
+	PurchasedAt time.Time `json:"purchased_at"`
+}
+
+// PurchaseImport is the payload of the purchase ingestion endpoint
+// @Description Purchases to record, at most 1000 per import
+type PurchaseImport struct {
+	// Purchases to record, purchases already recorded are skipped
+	Purchases []Purchase `json:"purchases"`
+}
+
+// Validate checks the size of the import and the keys of every purchase.
+func (i *PurchaseImport) Validate() error {
+	if len(i.Purchases) == 0 {
+		return errors.New("purchases are required")
+	}
+	if len(i.Purchases) > MaxPurchaseImport {
+		return fmt.Errorf("at most %d purchases can be imported at once", MaxPurchaseImport)
+	}
+	for index := range i.Purchases {
+		purchase := &i.Purchases[index]
+		purchase.CustomerID = strings.TrimSpace(purchase.CustomerID)
+		purchase.OrderID = strings.TrimSpace(purchase.OrderID)
+		if purchase.CustomerID == "" {
+			return fmt.Errorf("purchase %d: customer_id is required", index)
+		}
+		if purchase.ProductID == 0 {
+			return fmt.Errorf("purchase %d: product_id is required", index)
+		}
+		if purchase.OrderID == "" {
+			return fmt.Errorf("purchase %d: order_id is required", index)
+		}
+	}
+	return nil
+}
+
+// PurchaseImportResult reports what an import changed
+// @Description Outcome of a purchase import
+type PurchaseImportResult struct {
+	// Number of purchases recorded
+	// @example 98
+	Recorded int `json:"recorded"`
+	// Number of purchases skipped because they were already recorded
+	// @example 2
+	Skipped int `json:"skipped"`
+	// Number of existing reviews marked as verified purchases
+	// @example 4
+	VerifiedReviews int `json:"verified_reviews"`
+}
@@ -0,0 +1,74 @@
+package models
+
+import "math"
+
+// RatingBucket is the number of reviews with a given rating
+// @Description Number and share of the reviews with a rating
+type RatingBucket struct {
+	// Rating of the reviews (1-5)
+	// @example 5
+	Rating int `json:"rating"`
+	// Number of reviews with this rating
+	// @example 8
+	Count int `json:"count"`
+	// Share of the reviews with this rating, in percent with one decimal
+	// @example 66.7
+	Percentage float64 `json:"percentage"`
+}
+
+// RatingSummary is the rating distribution of a product
+// @Description Distribution of the ratings of a product
+type RatingSummary struct {
+	// ID of the product
+	// @example 1
+	ProductID uint `json:"product_id"`
+	// Total number of reviews
+	// @example 12
+	Total int `json:"total"`
+	// Average rating of the reviews, 0 without reviews
+	// @example 4.25
+	AverageRating float64 `json:"average_rating"`
+	// Median rating of the reviews, 0 without reviews
+	// @example 4.5
+	Median float64 `json:"median"`
+	// Number of reviews with each rating, from 5 down to 1
+	Distribution []RatingBucket `json:"distribution"`
+}
+
+// NewRatingSummary builds the rating distribution of a product from its rating aggregates.
+func NewRatingSummary(product Product) RatingSummary {
+	counts := product.RatingCounts()
+	summary := RatingSummary{
+		ProductID:     product.ID,
+		Total:         product.ReviewCount,
+		AverageRating: product.AverageRating,
+		Distribution:  make([]RatingBucket, 0, len(counts)),
+	}
+
+	for rating := len(counts); rating >= 1; rating-- {
+		bucket := RatingBucket{Rating: rating, Count: counts[rating-1]}
+		if summary.Total > 0 {
+			bucket.Percentage = math.Round(float64(bucket.Count)*1000/float64(summary.Total)) / 10
+		}
+		summary.Distribution = append(summary.Distribution, bucket)
+	}
+
+	if summary.Total > 0 {
+		// The median is the middle rating, or the mean of the two middle ones
+		lower := ratingAtRank(counts, (summary.Total-1)/2)
+		upper := ratingAtRank(counts, summary.Total/2)
+		summary.Median = float64(lower+upper) / 2
+	}
+	return summary
+}
+
+// ratingAtRank returns the rating at the zero-based rank of the reviews sorted by rating.
+func ratingAtRank(counts [5]int, rank int) int {
+	for rating, count := range counts {
+		if rank < count {
+			return rating + 1
+		}
+		rank -= count
+	}
+	return len(counts)
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Rating trend intervals
+const (
+	// TrendWeekly buckets reviews by week, starting on Monday
+	TrendWeekly = "week"
+	// TrendMonthly buckets reviews by calendar month
+	TrendMonthly = "month"
+)
+
+// MaxRatingTrendPeriods is the maximum number of periods of a rating trend
+const MaxRatingTrendPeriods = 52
+
+// RatingTrendQuery holds the options of the rating trend of a product
+// @Description Query parameters accepted by the rating trend endpoint
+type RatingTrendQuery struct {
+	// Length of the periods: week or month
+	// @example "week"
+	Interval string `form:"interval"`
+	// Number of periods to return, ending with the current one (1-52)
+	// @example 12
+	Periods int `form:"periods"`
+}
+
+// Validate checks the query values and fills in the defaults.
+func (q *RatingTrendQuery) Validate() error {
+	if q.Interval == "" {
+		q.Interval = TrendWeekly
+	}
+	if q.Interval != TrendWeekly && q.Interval != TrendMonthly {
+		return errors.New("interval must be week or month")
+	}
+	if q.Periods == 0 {
+		q.Periods = 12
+	}
+	if q.Periods < 0 || q.Periods > MaxRatingTrendPeriods {
+		return errors.New("periods must be between 1 and 52")
+	}
+	return nil
+}
+
+// RatingTrendPoint holds the reviews created during one period
+// @Description Number and average rating of the reviews created during a period
+type RatingTrendPoint struct {
+	// Start of the period, in UTC
+	// @example "2024-05-06T00:00:00Z"
+	PeriodStart time.Time `json:"period_start"`
+	// Number of reviews created during the period
+	// @example 4
+	ReviewCount int `json:"review_count"`
+	// Average rating of the reviews created during the period, 0 without reviews
+	// @example 4.25
+	AverageRating float64 `json:"average_rating"`
+}
+
+// RatingTrend is the evolution of the ratings of a product over time
+// @Description Ratings of a product bucketed by week or month, oldest period first
+type RatingTrend struct {
+	// Unique identifier of the product
+	// @example 1
+	ProductID uint `json:"product_id"`
+	// Length of the periods: week or month
+	// @example "week"
+	Interval string `json:"interval"`
+	// All-time average rating of the product
+	// @example 4.5
+	AverageRating float64 `json:"average_rating"`
+	// Average rating of the reviews created within the recent window
+	// @example 4.8
+	RecentAverageRating float64 `json:"recent_average_rating"`
+	// Average rating with every review weighted by its age
+	// @example 4.6
+	DecayedAverageRating float64 `json:"decayed_average_rating"`
+	// Reviews of every period, oldest first, including periods without reviews
+	Points []RatingTrendPoint `json:"points"`
+}
+
+// PeriodStart returns the start of the period of the interval containing t, in UTC.
+func PeriodStart(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if interval == TrendMonthly {
+		return day.AddDate(0, 0, 1-day.Day())
+	}
+	// Weeks start on Monday
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
+
+// NextPeriod returns the start of the period following the one starting at start.
+func NextPeriod(start time.Time, interval string) time.Time {
+	if interval == TrendMonthly {
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 7)
+}
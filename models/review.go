@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"github.com/jinzhu/gorm"
 	"github.com/go-playground/validator/v10"
 )
@@ -22,9 +24,58 @@ type Review struct {
 	// Rating given by the reviewer (1-5)
 	// @example 4
 	Rating int `json:"rating" validate:"min=1,max=5"`
+	// Ratings of the aspects of the product defined by its category (1-5), such as the battery life or the fit
+	AspectRatings []ReviewAspectRating `json:"aspect_ratings,omitempty" gorm:"foreignkey:ReviewID;association_autoupdate:false;association_autocreate:false" validate:"max=20,dive"`
+	// What the reviewer liked
+	// @example "Ripe and sweet"
+	Pros string `json:"pros,omitempty" gorm:"type:text" validate:"max=1000"`
+	// What the reviewer disliked
+	// @example "Bruised easily"
+	Cons string `json:"cons,omitempty" gorm:"type:text" validate:"max=1000"`
 	// ProductID is the foreign key that links to the product being reviewed
 	// @example 999
 	ProductID uint `json:"product_id" validate:"required"`
+	// AuthorSubject is the subject of the authenticated caller who wrote the review,
+	// set by the server from the caller's credentials
+	// @readOnly
+	AuthorSubject string `json:"author_subject" gorm:"index"`
+	// Whether the author bought the product, set by the server from the ingested purchases
+	// @readOnly
+	// @example true
+	Verified bool `json:"verified" gorm:"not null;default:false"`
+	// Moderation status of the review: pending, approved, rejected or flagged.
+	// Only approved reviews are public and count toward the ratings of the product
+	// @readOnly
+	// @example "approved"
+	Status string `json:"status" gorm:"index;not null;default:'approved'"`
+	// Reason given by the moderator for the last status change
+	// @readOnly
+	ModerationReason string `json:"moderation_reason,omitempty"`
+	// Subject of the moderator who last changed the status
+	// @readOnly
+	ModeratedBy string `json:"moderated_by,omitempty"`
+	// Date of the last status change by a moderator
+	// @readOnly
+	ModeratedAt *time.Time `json:"moderated_at,omitempty"`
+	// Number of helpful votes, persisted periodically from the live tallies
+	// @readOnly
+	// @example 12
+	HelpfulCount int `json:"helpful_count"`
+	// Number of unhelpful votes, persisted periodically from the live tallies
+	// @readOnly
+	// @example 3
+	UnhelpfulCount int `json:"unhelpful_count"`
+	// HelpfulScore is the lower bound of the Wilson score interval of the share of helpful votes,
+	// the order of the reviews sorted by helpfulness
+	HelpfulScore float64 `json:"-" gorm:"index"`
+	// Replies of merchants to the review, oldest first, loaded with the review reads
+	// @readOnly
+	Replies []ReviewReply `json:"replies,omitempty" gorm:"foreignkey:ReviewID;association_autoupdate:false;association_autocreate:false"`
+	// Photos attached to the review by its author, oldest first, loaded with the review reads
+	// @readOnly
+	Media []ReviewMedia `json:"media,omitempty" gorm:"foreignkey:ReviewID;association_autoupdate:false;association_autocreate:false"`
+	// TextSignature is the MinHash signature of the review text, used to find near-duplicate reviews
+	TextSignature string `json:"-" gorm:"type:text"`
 }
 
 
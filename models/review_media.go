@@ -0,0 +1,96 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ReviewMedia is a photo attached to a review by its author, along with its thumbnail.
+// The files are kept in the object storage, the table only holds their metadata.
+type ReviewMedia struct {
+	gorm.Model
+	// ReviewID is the review the photo is attached to
+	ReviewID uint `json:"review_id" gorm:"index;not null"`
+	// UploaderSubject is the subject of the caller who uploaded the photo
+	UploaderSubject string `json:"uploader_subject"`
+	// ContentType is the type sniffed from the content of the photo
+	ContentType string `json:"content_type"`
+	// Size is the size of the photo in bytes
+	Size int64 `json:"size"`
+	// Width and Height are the dimensions of the photo in pixels
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// ThumbnailContentType, ThumbnailWidth and ThumbnailHeight describe the thumbnail of the photo
+	ThumbnailContentType string `json:"thumbnail_content_type"`
+	ThumbnailWidth       int    `json:"thumbnail_width"`
+	ThumbnailHeight      int    `json:"thumbnail_height"`
+	// StorageKey and ThumbnailKey are the keys of the photo and its thumbnail in the object storage,
+	// the files are served by the API
+	StorageKey   string `json:"-" gorm:"not null"`
+	ThumbnailKey string `json:"-" gorm:"not null"`
+}
+
+// ReviewMediaResponse is the public representation of a photo attached to a review
+// @Description Photo attached to a review, with the URLs of the photo and its thumbnail
+type ReviewMediaResponse struct {
+	// Unique identifier of the photo
+	// @example 4
+	ID uint `json:"id"`
+	// Type of the photo
+	// @example "image/jpeg"
+	ContentType string `json:"content_type"`
+	// Size of the photo in bytes
+	// @example 284133
+	Size int64 `json:"size"`
+	// Width of the photo in pixels
+	// @example 1600
+	Width int `json:"width"`
+	// Height of the photo in pixels
+	// @example 1200
+	Height int `json:"height"`
+	// URL of the photo
+	// @example "/reviews/7/media/4"
+	URL string `json:"url"`
+	// URL of the thumbnail of the photo
+	// @example "/reviews/7/media/4/thumbnail"
+	ThumbnailURL string `json:"thumbnail_url"`
+	// Width of the thumbnail in pixels
+	// @example 320
+	ThumbnailWidth int `json:"thumbnail_width"`
+	// Height of the thumbnail in pixels
+	// @example 240
+	ThumbnailHeight int `json:"thumbnail_height"`
+	// Date the photo was uploaded
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NewReviewMediaResponse builds the public representation of a photo attached to a review.
+func NewReviewMediaResponse(media ReviewMedia) ReviewMediaResponse {
+	url := fmt.Sprintf("/reviews/%d/media/%d", media.ReviewID, media.ID)
+	return ReviewMediaResponse{
+		ID:              media.ID,
+		ContentType:     media.ContentType,
+		Size:            media.Size,
+		Width:           media.Width,
+		Height:          media.Height,
+		URL:             url,
+		ThumbnailURL:    url + "/thumbnail",
+		ThumbnailWidth:  media.ThumbnailWidth,
+		ThumbnailHeight: media.ThumbnailHeight,
+		CreatedAt:       media.CreatedAt,
+	}
+}
+
+// NewReviewMediaResponses builds the public representation of the photos attached to a review.
+func NewReviewMediaResponses(media []ReviewMedia) []ReviewMediaResponse {
+	if len(media) == 0 {
+		return nil
+	}
+	responses := make([]ReviewMediaResponse, 0, len(media))
+	for _, item := range media {
+		responses = append(responses, NewReviewMediaResponse(item))
+	}
+	return responses
+}
@@ -0,0 +1,60 @@
+package models
+
+import "errors"
+
+// reviewSorts lists the orders reviews can be listed in
+var reviewSorts = map[string]bool{
+	"newest":  true,
+	"highest": true,
+	"lowest":  true,
+	"helpful": true,
+}
+
+// ReviewQuery holds the pagination, filter and sort options for listing the reviews of a product
+// @Description Query parameters accepted by the product reviews endpoint
+type ReviewQuery struct {
+	// Maximum number of reviews to return (1-100)
+	// @example 20
+	Limit int `form:"limit"`
+	// Opaque cursor returned as next_cursor by the previous page
+	Cursor string `form:"cursor"`
+	// Sort order: newest, highest, lowest or helpful
+	// @example "newest"
+	Sort string `form:"sort"`
+	// Only return reviews with this rating (1-5)
+	// @example 5
+	Rating *int `form:"rating"`
+	// Only return reviews of verified purchases
+	// @example true
+	Verified bool `form:"verified"`
+}
+
+// Validate checks the query values and fills in the defaults.
+func (q *ReviewQuery) Validate() error {
+	if q.Limit == 0 {
+		q.Limit = DefaultPageLimit
+	}
+	if q.Limit < 0 || q.Limit > MaxPageLimit {
+		return errors.New("limit must be between 1 and 100")
+	}
+	if q.Sort == "" {
+		q.Sort = "newest"
+	}
+	if !reviewSorts[q.Sort] {
+		return errors.New("sort must be one of newest, highest, lowest, helpful")
+	}
+	if q.Rating != nil && (*q.Rating < 1 || *q.Rating > 5) {
+		return errors.New("rating must be between 1 and 5")
+	}
+	return nil
+}
+
+// ReviewPage is a single page of reviews
+// @Description Paginated list of reviews
+type ReviewPage struct {
+	// Reviews in this page
+	Items []ReviewResponse `json:"items"`
+	// Cursor to request the next page, empty on the last page
+	// @example "eyJzIjoibmV3ZXN0In0"
+	NextCursor string `json:"next_cursor,omitempty"`
+}
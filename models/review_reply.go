@@ -0,0 +1,118 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// MaxReplyLength is the maximum number of characters of a reply
+const MaxReplyLength = 2000
+
+// ReviewReply is a public answer of a merchant to a review.
+// Replies are threaded: a reply answers the review itself or another reply of the same review.
+type ReviewReply struct {
+	gorm.Model
+	// ReviewID is the answered review
+	ReviewID uint `json:"review_id" gorm:"index;not null"`
+	// ParentID is the answered reply, nil for replies to the review itself
+	ParentID *uint `json:"parent_id,omitempty" gorm:"index"`
+	// AuthorSubject is the subject of the merchant who wrote the reply
+	AuthorSubject string `json:"author_subject"`
+	// Body is the text of the reply
+	Body string `json:"body" gorm:"type:text;not null"`
+}
+
+// ReplyRequest is the payload of the reply endpoints
+// @Description Text of a reply to a review, and the reply it answers
+type ReplyRequest struct {
+	// Text of the reply
+	// @example "Thank you for your feedback, a replacement is on its way."
+	Body string `json:"body"`
+	// ID of the answered reply, omitted to answer the review itself. Ignored on edits
+	// @example 3
+	ParentID *uint `json:"parent_id,omitempty"`
+}
+
+// Validate checks the reply text.
+func (r *ReplyRequest) Validate() error {
+	r.Body = strings.TrimSpace(r.Body)
+	if r.Body == "" {
+		return errors.New("body is required")
+	}
+	if len([]rune(r.Body)) > MaxReplyLength {
+		return errors.New("body must be at most 2000 characters")
+	}
+	return nil
+}
+
+// ReviewReplyResponse is the public representation of a reply
+// @Description Reply of a merchant to a review, with the replies answering it
+type ReviewReplyResponse struct {
+	// Unique identifier of the reply
+	// @example 3
+	ID uint `json:"id"`
+	// ID of the answered review
+	// @example 7
+	ReviewID uint `json:"review_id"`
+	// ID of the answered reply, omitted for replies to the review itself
+	// @example 2
+	ParentID *uint `json:"parent_id,omitempty"`
+	// Subject of the merchant who wrote the reply
+	// @example "bananas-shop"
+	AuthorSubject string `json:"author_subject"`
+	// Text of the reply
+	// @example "Thank you for your feedback, a replacement is on its way."
+	Body string `json:"body"`
+	// Date the reply was created
+	CreatedAt time.Time `json:"created_at"`
+	// Date the reply was last updated
+	UpdatedAt time.Time `json:"updated_at"`
+	// Replies answering this reply, oldest first
+	Replies []ReviewReplyResponse `json:"replies,omitempty"`
+}
+
+// NewReplyThreads arranges the replies of a review into threads, oldest first.
+// Replies whose parent is not in the list are left out.
+func NewReplyThreads(replies []ReviewReply) []ReviewReplyResponse {
+	children := make(map[uint][]ReviewReply)
+	for _, reply := range replies {
+		parent := uint(0)
+		if reply.ParentID != nil {
+			parent = *reply.ParentID
+		}
+		children[parent] = append(children[parent], reply)
+	}
+	return replyThreads(children, 0)
+}
+
+// replyThreads builds the threads answering the parent, 0 for the review itself.
+func replyThreads(children map[uint][]ReviewReply, parent uint) []ReviewReplyResponse {
+	replies := children[parent]
+	if len(replies) == 0 {
+		return nil
+	}
+
+	threads := make([]ReviewReplyResponse, 0, len(replies))
+	for _, reply := range replies {
+		thread := NewReviewReplyResponse(reply)
+		thread.Replies = replyThreads(children, reply.ID)
+		threads = append(threads, thread)
+	}
+	return threads
+}
+
+// NewReviewReplyResponse builds the public representation of a single reply, without its answers.
+func NewReviewReplyResponse(reply ReviewReply) ReviewReplyResponse {
+	return ReviewReplyResponse{
+		ID:            reply.ID,
+		ReviewID:      reply.ReviewID,
+		ParentID:      reply.ParentID,
+		AuthorSubject: reply.AuthorSubject,
+		Body:          reply.Body,
+		CreatedAt:     reply.CreatedAt,
+		UpdatedAt:     reply.UpdatedAt,
+	}
+}
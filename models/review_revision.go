@@ -0,0 +1,120 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Actions recorded by the revisions of a review
+const (
+	// RevisionUpdate revisions keep the review as it was before an edit
+	RevisionUpdate = "update"
+	// RevisionDelete revisions keep the review as it was when it was deleted
+	RevisionDelete = "delete"
+	// RevisionRestore revisions keep the review as it was before an administrator restored a prior revision
+	RevisionRestore = "restore"
+)
+
+// ReviewRevision is a version of the content of a review, recorded when the review is
+// changed so what the reviewer wrote is never lost. It holds the review as it was before the change,
+// along with who made the change and when.
+// The unique index on the review and the version numbers the revisions of each review from 1.
+type ReviewRevision struct {
+	// Unique identifier of the revision
+	// @example 12
+	ID uint `json:"id" gorm:"primary_key"`
+	// Date of the change
+	CreatedAt time.Time `json:"created_at"`
+	// ID of the changed review
+	// @example 7
+	ReviewID uint `json:"review_id" gorm:"not null;unique_index:idx_review_revisions_review_version"`
+	// Version of the review, starting at 1 for the review as it was created
+	// @example 1
+	Version int `json:"version" gorm:"not null;unique_index:idx_review_revisions_review_version"`
+	// Change that ended this version: update, delete or restore
+	// @example "update"
+	Action string `json:"action" gorm:"not null"`
+	// Subject of the caller who made the change
+	// @example "alice"
+	ChangedBy string `json:"changed_by"`
+	// First name of the reviewer in this version
+	// @example "Miguel"
+	FirstName string `json:"first_name"`
+	// Last name of the reviewer in this version
+	// @example "Filip"
+	LastName string `json:"last_name"`
+	// Text of the review in this version
+	// @example "This bananas are amazing!"
+	ReviewText string `json:"review_text" gorm:"type:text"`
+	// What the reviewer liked in this version
+	// @example "Ripe and sweet"
+	Pros string `json:"pros,omitempty" gorm:"type:text"`
+	// What the reviewer disliked in this version
+	// @example "Bruised easily"
+	Cons string `json:"cons,omitempty" gorm:"type:text"`
+	// Rating of the review in this version
+	// @example 4
+	Rating int `json:"rating"`
+	// Comma-separated aspect ratings of the review in this version, as aspect=rating pairs
+	// @example "fit=4,comfort=5"
+	AspectRatings string `json:"aspect_ratings,omitempty" gorm:"type:text"`
+	// Moderation status of the review in this version
+	// @example "approved"
+	Status string `json:"status"`
+}
+
+// NewReviewRevision records the current version of a review, before the change made by changedBy.
+func NewReviewRevision(review Review, version int, action string, changedBy string) ReviewRevision {
+	return ReviewRevision{
+		ReviewID:   review.ID,
+		Version:    version,
+		Action:     action,
+		ChangedBy:  changedBy,
+		FirstName:  review.FirstName,
+		LastName:   review.LastName,
+		ReviewText: review.ReviewText,
+		Pros:       review.Pros,
+		Cons:       review.Cons,
+		Rating:     review.Rating,
+		Status:     review.Status,
+
+		AspectRatings: joinAspectRatings(review.AspectRatings),
+	}
+}
+
+// AspectRatingList returns the aspect ratings of the review in this version.
+func (r *ReviewRevision) AspectRatingList() []ReviewAspectRating {
+	ratings := []ReviewAspectRating{}
+	for _, pair := range splitList(r.AspectRatings) {
+		aspect, value, _ := strings.Cut(pair, "=")
+		rating, _ := strconv.Atoi(value)
+		ratings = append(ratings, ReviewAspectRating{Aspect: aspect, Rating: rating})
+	}
+	return ratings
+}
+
+// joinAspectRatings formats aspect ratings as comma-separated aspect=rating pairs.
+func joinAspectRatings(ratings []ReviewAspectRating) string {
+	pairs := make([]string, 0, len(ratings))
+	for _, rating := range ratings {
+		pairs = append(pairs, rating.Aspect+"="+strconv.Itoa(rating.Rating))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// ReviewHistory is the revision history of a review
+// @Description Prior versions of a review, oldest first
+type ReviewHistory struct {
+	// ID of the review
+	// @example 7
+	ReviewID uint `json:"review_id"`
+	// Subject of the author of the review
+	// @example "alice"
+	AuthorSubject string `json:"author_subject"`
+	// Whether the review is deleted, deleted reviews can be restored by administrators
+	// @example false
+	Deleted bool `json:"deleted"`
+	// Prior versions of the review, oldest first. The current version is the review itself
+	Revisions []ReviewRevision `json:"revisions"`
+}
@@ -0,0 +1,11 @@
+package models
+
+// ReviewTextBand is a band key of the MinHash signature of a review text. Reviews sharing a band
+// key are the candidates compared to find near-duplicate texts, instead of every review.
+type ReviewTextBand struct {
+	ID uint `gorm:"primary_key"`
+	// ReviewID is the review whose text is signed
+	ReviewID uint `gorm:"not null;index"`
+	// BandKey is the hash of a band of the signature
+	BandKey int64 `gorm:"not null;index"`
+}
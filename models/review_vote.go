@@ -0,0 +1,50 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// ReviewVote is the helpful or unhelpful vote of a caller on a review.
+// The unique index on the review and the voter allows a single vote per caller and review.
+type ReviewVote struct {
+	ID        uint `gorm:"primary_key"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	// ReviewID is the voted review
+	ReviewID uint `gorm:"not null;unique_index:idx_review_votes_review_voter"`
+	// Voter is the subject of the caller who voted
+	Voter string `gorm:"not null;unique_index:idx_review_votes_review_voter"`
+	// Helpful is set for helpful votes and unset for unhelpful ones
+	Helpful bool `gorm:"not null"`
+}
+
+// VoteRequest is the payload of the review votes endpoint
+// @Description Vote of the caller on a review
+type VoteRequest struct {
+	// Whether the review was helpful
+	// @example true
+	Helpful *bool `json:"helpful"`
+}
+
+// Validate checks that the vote is given.
+func (r *VoteRequest) Validate() error {
+	if r.Helpful == nil {
+		return errors.New("helpful is required")
+	}
+	return nil
+}
+
+// VoteTally counts the votes of a review
+// @Description Helpful and unhelpful votes of a review
+type VoteTally struct {
+	// ID of the review
+	// @example 7
+	ReviewID uint `json:"review_id"`
+	// Number of helpful votes
+	// @example 12
+	Helpful int64 `json:"helpful"`
+	// Number of unhelpful votes
+	// @example 3
+	Unhelpful int64 `json:"unhelpful"`
+}
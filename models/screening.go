@@ -0,0 +1,28 @@
+package models
+
+// ScreeningFinding is a problem found in the text of a review by the content screening
+// @Description Rule of the content screening matched by a review text
+type ScreeningFinding struct {
+	// Rule that matched: length, banned_word, url, email, phone, caps or repeated_chars
+	// @example "banned_word"
+	Rule string `json:"rule"`
+	// Description of what was found
+	// @example "Text contains a banned word"
+	Message string `json:"message"`
+	// Score added by the finding, reviews are flagged or rejected past a threshold
+	// @example 3
+	Score float64 `json:"score"`
+	// Reject is set when the finding rejects the review whatever the score
+	Reject bool `json:"reject,omitempty"`
+}
+
+// ScreeningErrorResponse is returned when the content screening rejects a review
+// @Description Review rejected by the content screening, with the rules it matched
+type ScreeningErrorResponse struct {
+	ErrorResponse
+	// Total score of the findings
+	// @example 6
+	Score float64 `json:"score"`
+	// Rules matched by the review text
+	Findings []ScreeningFinding `json:"findings"`
+}
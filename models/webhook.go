@@ -0,0 +1,190 @@
+package models
+
+import (
+	"errors"
+	"go_api_product_review/events"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// WebhookSubscription is a partner endpoint receiving review and product events
+type WebhookSubscription struct {
+	gorm.Model
+	// TargetURL is the endpoint the events are POSTed to
+	TargetURL string
+	// EventTypes is the comma-separated list of event types the endpoint receives
+	EventTypes string
+	// ProductID restricts the subscription to the events of a single product, 0 means all products
+	ProductID uint `gorm:"index"`
+	// Secret is the key used to sign the deliveries
+	Secret string
+}
+
+// HasEventType reports whether the subscription receives the given event type.
+func (s *WebhookSubscription) HasEventType(eventType string) bool {
+	for _, subscribed := range strings.Split(s.EventTypes, ",") {
+		if subscribed == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookRequest is the payload used to create a webhook subscription
+// @Description Webhook subscription details
+type WebhookRequest struct {
+	// URL the events are POSTed to
+	// @example "https://partner.example.com/hooks/reviews"
+	TargetURL string `json:"target_url"`
+	// Event types to receive
+	// @example ["review.created","review.updated"]
+	EventTypes []string `json:"event_types"`
+	// Only receive the events of this product, omit for all products
+	// @example 1
+	ProductID uint `json:"product_id"`
+	// Secret used to sign the deliveries with HMAC-SHA256, at least 16 characters
+	// @example "9f8e7d6c5b4a39281706f5e4d3c2b1a0"
+	Secret string `json:"secret"`
+}
+
+// Validate checks if the webhook request fields are valid.
+func (r *WebhookRequest) Validate() error {
+	target, err := url.Parse(r.TargetURL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return errors.New("target_url must be an absolute http or https URL")
+	}
+	if len(r.EventTypes) == 0 {
+		return errors.New("at least one event type is required")
+	}
+	for _, eventType := range r.EventTypes {
+		known := false
+		for _, supported := range events.Types() {
+			known = known || eventType == supported
+		}
+		if !known {
+			return errors.New("unsupported event type: " + eventType)
+		}
+	}
+	if len(r.Secret) < 16 {
+		return errors.New("secret must be at least 16 characters long")
+	}
+	return nil
+}
+
+// ToSubscription builds the subscription described by the request.
+func (r *WebhookRequest) ToSubscription() WebhookSubscription {
+	return WebhookSubscription{
+		TargetURL:  r.TargetURL,
+		EventTypes: strings.Join(r.EventTypes, ","),
+		ProductID:  r.ProductID,
+		Secret:     r.Secret,
+	}
+}
+
+// WebhookResponse is the public representation of a webhook subscription, without its secret
+// @Description Webhook subscription
+type WebhookResponse struct {
+	// Unique identifier of the subscription
+	// @example 3
+	ID uint `json:"id"`
+	// URL the events are POSTed to
+	// @example "https://partner.example.com/hooks/reviews"
+	TargetURL string `json:"target_url"`
+	// Event types received
+	// @example ["review.created","review.updated"]
+	EventTypes []string `json:"event_types"`
+	// Product the subscription is restricted to, 0 for all products
+	// @example 1
+	ProductID uint `json:"product_id"`
+	// Date the subscription was created
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NewWebhookResponse builds the public representation of a webhook subscription.
+func NewWebhookResponse(subscription WebhookSubscription) WebhookResponse {
+	return WebhookResponse{
+		ID:         subscription.ID,
+		TargetURL:  subscription.TargetURL,
+		EventTypes: strings.Split(subscription.EventTypes, ","),
+		ProductID:  subscription.ProductID,
+		CreatedAt:  subscription.CreatedAt,
+	}
+}
+
+// WebhookDelivery is the delivery of an event to a webhook subscription
+type WebhookDelivery struct {
+	gorm.Model
+	// SubscriptionID is the subscription the event is delivered to
+	SubscriptionID uint `gorm:"index;unique_index:idx_webhook_deliveries_subscription_event"`
+	// EventID is the ID of the delivered event, delivered once per subscription
+	EventID string `gorm:"unique_index:idx_webhook_deliveries_subscription_event"`
+	// EventType is the type of the delivered event
+	EventType string
+	// Payload is the JSON encoded event sent as request body
+	Payload string `gorm:"type:text"`
+	// Attempts is the number of delivery attempts made so far
+	Attempts int
+	// StatusCode is the HTTP status returned by the last attempt, 0 if no response was received
+	StatusCode int
+	// LastError describes why the last attempt failed
+	LastError string `gorm:"type:text"`
+	// NextAttemptAt is the earliest time the next attempt may run
+	NextAttemptAt time.Time `gorm:"index"`
+	// DeliveredAt is when the target acknowledged the event
+	DeliveredAt *time.Time
+	// FailedAt is when the delivery was abandoned after too many attempts
+	FailedAt *time.Time
+}
+
+// WebhookDeliveryResponse is the public representation of a webhook delivery
+// @Description Delivery of an event to a webhook
+type WebhookDeliveryResponse struct {
+	// Unique identifier of the delivery
+	// @example 12
+	ID uint `json:"id"`
+	// ID of the delivered event
+	// @example "4f1c2a9e0b7d4c3e8a6f5b2d1c0e9f8a"
+	EventID string `json:"event_id"`
+	// Type of the delivered event
+	// @example "review.created"
+	EventType string `json:"event_type"`
+	// Delivery status: pending, delivered or failed
+	// @example "delivered"
+	Status string `json:"status"`
+	// Number of delivery attempts made so far
+	// @example 1
+	Attempts int `json:"attempts"`
+	// HTTP status returned by the last attempt
+	// @example 200
+	StatusCode int `json:"status_code,omitempty"`
+	// Error of the last failed attempt
+	LastError string `json:"last_error,omitempty"`
+	// Date the delivery was created
+	CreatedAt time.Time `json:"created_at"`
+	// Date the target acknowledged the event
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+}
+
+// NewWebhookDeliveryResponse builds the public representation of a webhook delivery.
+func NewWebhookDeliveryResponse(delivery WebhookDelivery) WebhookDeliveryResponse {
+	status := "pending"
+	if delivery.DeliveredAt != nil {
+		status = "delivered"
+	} else if delivery.FailedAt != nil {
+		status = "failed"
+	}
+	return WebhookDeliveryResponse{
+		ID:          delivery.ID,
+		EventID:     delivery.EventID,
+		EventType:   delivery.EventType,
+		Status:      status,
+		Attempts:    delivery.Attempts,
+		StatusCode:  delivery.StatusCode,
+		LastError:   delivery.LastError,
+		CreatedAt:   delivery.CreatedAt,
+		DeliveredAt: delivery.DeliveredAt,
+	}
+}
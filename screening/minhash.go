@@ -0,0 +1,168 @@
+package screening
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"unicode"
+)
+
+// Size of the MinHash signatures and number of words of their shingles
+const (
+	SignatureSize = 64
+	ShingleSize   = 3
+)
+
+// Number of bands of the signatures and number of values per band. Texts 80% similar share
+// a band with a probability above 99.9%, texts 30% similar with a probability below 13%.
+const (
+	BandCount = 16
+	BandRows  = SignatureSize / BandCount
+)
+
+// signatureSeeds are the seeds of the hash functions of the signatures. They are fixed
+// so that the signatures stored with the reviews stay comparable between restarts.
+var signatureSeeds = func() [SignatureSize]uint64 {
+	var seeds [SignatureSize]uint64
+	state := uint64(0x9e3779b97f4a7c15)
+	for i := range seeds {
+		state += 0x9e3779b97f4a7c15
+		seeds[i] = mix(state)
+	}
+	return seeds
+}()
+
+// Signature is the MinHash signature of a text: the minimum of each hash function over
+// the word shingles of the text. The share of equal values of two signatures estimates
+// the Jaccard similarity of the shingles of their texts.
+type Signature []uint32
+
+// Words returns the lowercase words of a text, punctuation aside.
+func Words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Shingles returns the distinct sequences of ShingleSize consecutive words of a text,
+// or the whole text for shorter texts.
+func Shingles(text string) []string {
+	words := Words(text)
+	if len(words) == 0 {
+		return nil
+	}
+	if len(words) < ShingleSize {
+		return []string{strings.Join(words, " ")}
+	}
+
+	seen := make(map[string]bool)
+	var shingles []string
+	for i := 0; i+ShingleSize <= len(words); i++ {
+		shingle := strings.Join(words[i:i+ShingleSize], " ")
+		if !seen[shingle] {
+			seen[shingle] = true
+			shingles = append(shingles, shingle)
+		}
+	}
+	return shingles
+}
+
+// MinHash computes the signature of a text, nil for texts without words.
+func MinHash(text string) Signature {
+	shingles := Shingles(text)
+	if len(shingles) == 0 {
+		return nil
+	}
+
+	signature := make(Signature, SignatureSize)
+	for i := range signature {
+		signature[i] = ^uint32(0)
+	}
+	for _, shingle := range shingles {
+		h := fnv.New64a()
+		h.Write([]byte(shingle))
+		base := h.Sum64()
+		for i, seed := range signatureSeeds {
+			if value := uint32(mix(base ^ seed)); value < signature[i] {
+				signature[i] = value
+			}
+		}
+	}
+	return signature
+}
+
+// Similarity estimates the Jaccard similarity of the texts of two signatures, between 0 and 1.
+func (s Signature) Similarity(other Signature) float64 {
+	if len(s) == 0 || len(s) != len(other) {
+		return 0
+	}
+	equal := 0
+	for i := range s {
+		if s[i] == other[i] {
+			equal++
+		}
+	}
+	return float64(equal) / float64(len(s))
+}
+
+// BandKeys hashes each band of BandRows values of the signature, along with its position.
+// Signatures sharing a band key are the candidates for near-duplicates (locality-sensitive hashing).
+// It returns nil for signatures of another size.
+func (s Signature) BandKeys() []int64 {
+	if len(s) != SignatureSize {
+		return nil
+	}
+	keys := make([]int64, BandCount)
+	buf := make([]byte, 4*(BandRows+1))
+	for band := range keys {
+		binary.BigEndian.PutUint32(buf, uint32(band))
+		for i, value := range s[band*BandRows : (band+1)*BandRows] {
+			binary.BigEndian.PutUint32(buf[4*(i+1):], value)
+		}
+		h := fnv.New64a()
+		h.Write(buf)
+		keys[band] = int64(h.Sum64())
+	}
+	return keys
+}
+
+// String encodes the signature in hexadecimal, the empty string for a nil signature.
+func (s Signature) String() string {
+	buf := make([]byte, 4*len(s))
+	for i, value := range s {
+		binary.BigEndian.PutUint32(buf[4*i:], value)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// ParseSignature decodes a signature encoded by String.
+func ParseSignature(encoded string) (Signature, error) {
+	buf, err := hex.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(buf)%4 != 0 {
+		return nil, fmt.Errorf("invalid signature length %d", len(buf))
+	}
+	if len(buf) == 0 {
+		return nil, nil
+	}
+
+	signature := make(Signature, len(buf)/4)
+	for i := range signature {
+		signature[i] = binary.BigEndian.Uint32(buf[4*i:])
+	}
+	return signature, nil
+}
+
+// mix is the finalizer of SplitMix64, spreading the bits of x.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,244 @@
+package screening
+
+import (
+	"fmt"
+	"go_api_product_review/models"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Rules reported by the standard screeners
+const (
+	RuleLength        = "length"
+	RuleBannedWord    = "banned_word"
+	RuleURL           = "url"
+	RuleEmail         = "email"
+	RulePhone         = "phone"
+	RuleCaps          = "caps"
+	RuleRepeatedChars = "repeated_chars"
+)
+
+// Scores of the findings of the standard screeners
+const (
+	bannedWordScore    = 3
+	contactScore       = 2
+	capsScore          = 1.5
+	repeatedCharsScore = 1
+)
+
+// minPhoneDigits is the number of digits from which a run of digits counts as a phone number
+const minPhoneDigits = 9
+
+// leetspeak maps the characters commonly substituted for letters back to the letters
+var leetspeak = map[rune]rune{
+	'0': 'o',
+	'1': 'i',
+	'3': 'e',
+	'4': 'a',
+	'5': 's',
+	'7': 't',
+	'8': 'b',
+	'@': 'a',
+	'$': 's',
+	'!': 'i',
+	'|': 'l',
+	'+': 't',
+}
+
+var (
+	emailPattern = regexp.MustCompile(`(?i)[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}`)
+	// Bare domains need a lowercase top-level domain, so that "love it.Me and my wife" is not a link
+	urlPattern   = regexp.MustCompile(`(?i:\b(?:https?://|www\.)\S+)|\b[a-zA-Z0-9-]+(?:\.[a-zA-Z0-9-]+)*\.(?:com|net|org|info|biz|io|co|me|ly|xyz|ru|shop)\b(?:/\S*)?`)
+	phonePattern = regexp.MustCompile(`\+?\(?\d[\d\s().-]{5,}\d`)
+	datePattern  = regexp.MustCompile(`\b\d{4}[-/.]\d{1,2}[-/.]\d{1,2}\b|\b\d{1,2}[-/.]\d{1,2}[-/.]\d{2,4}\b`)
+)
+
+// Normalize lowercases a word and undoes its leetspeak spelling, keeping only its letters,
+// so that "B@n4n4!" becomes "banana". Punctuation ending the word is dropped first.
+func Normalize(word string) string {
+	word = strings.TrimRight(strings.ToLower(word), ".,;:?!")
+	var normalized strings.Builder
+	for _, r := range word {
+		if letter, ok := leetspeak[r]; ok {
+			r = letter
+		}
+		if unicode.IsLetter(r) {
+			normalized.WriteRune(r)
+		}
+	}
+	return normalized.String()
+}
+
+// LengthScreener rejects texts shorter than min or longer than max characters, a max of 0 means no limit.
+func LengthScreener(min, max int) Screener {
+	return ScreenerFunc(func(text string) []models.ScreeningFinding {
+		length := len([]rune(strings.TrimSpace(text)))
+		switch {
+		case length < min:
+			return []models.ScreeningFinding{{
+				Rule:    RuleLength,
+				Message: fmt.Sprintf("Text must be at least %d characters long", min),
+				Reject:  true,
+			}}
+		case max > 0 && length > max:
+			return []models.ScreeningFinding{{
+				Rule:    RuleLength,
+				Message: fmt.Sprintf("Text must be at most %d characters long", max),
+				Reject:  true,
+			}}
+		}
+		return nil
+	})
+}
+
+// BannedWordScreener reports each banned word found in a text, whatever its case,
+// leetspeak spelling or repeated letters: "sp4aaam" matches the banned word "spam".
+// Words are split on spaces and on the punctuation not used as leetspeak, so "scam,fraud" holds two words.
+func BannedWordScreener(words []string) Screener {
+	var patterns []*regexp.Regexp
+	for _, word := range words {
+		normalized := Normalize(strings.TrimSpace(word))
+		if normalized == "" {
+			continue
+		}
+		var pattern strings.Builder
+		pattern.WriteString("^")
+		for _, r := range normalized {
+			pattern.WriteString(regexp.QuoteMeta(string(r)) + "+")
+		}
+		pattern.WriteString("$")
+		patterns = append(patterns, regexp.MustCompile(pattern.String()))
+	}
+
+	return ScreenerFunc(func(text string) []models.ScreeningFinding {
+		var findings []models.ScreeningFinding
+		found := make(map[int]bool)
+		for _, token := range bannedWordTokens(text) {
+			normalized := Normalize(token)
+			if normalized == "" {
+				continue
+			}
+			for i, pattern := range patterns {
+				if found[i] || !pattern.MatchString(normalized) {
+					continue
+				}
+				found[i] = true
+				findings = append(findings, models.ScreeningFinding{
+					Rule:    RuleBannedWord,
+					Message: "Text contains a banned word",
+					Score:   bannedWordScore,
+				})
+			}
+		}
+		return findings
+	})
+}
+
+// bannedWordTokens splits a text into the words checked against the banned words,
+// on every character that is neither a letter, a digit nor a leetspeak substitute.
+func bannedWordTokens(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		_, leet := leetspeak[r]
+		return !leet && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// ContactScreener reports the email addresses, links and phone numbers found in a text,
+// which usually point customers away from the shop.
+func ContactScreener() Screener {
+	return ScreenerFunc(func(text string) []models.ScreeningFinding {
+		var findings []models.ScreeningFinding
+		if emailPattern.MatchString(text) {
+			findings = append(findings, models.ScreeningFinding{
+				Rule:    RuleEmail,
+				Message: "Text contains an email address",
+				Score:   contactScore,
+			})
+			// The domain of an email address is not a link
+			text = emailPattern.ReplaceAllString(text, " ")
+		}
+		if urlPattern.MatchString(text) {
+			findings = append(findings, models.ScreeningFinding{
+				Rule:    RuleURL,
+				Message: "Text contains a link",
+				Score:   contactScore,
+			})
+		}
+		// Dates are not phone numbers, and shorter digit runs are order numbers or quantities
+		text = datePattern.ReplaceAllString(text, " ")
+		for _, match := range phonePattern.FindAllString(text, -1) {
+			if countDigits(match) >= minPhoneDigits {
+				findings = append(findings, models.ScreeningFinding{
+					Rule:    RulePhone,
+					Message: "Text contains a phone number",
+					Score:   contactScore,
+				})
+				break
+			}
+		}
+		return findings
+	})
+}
+
+// CapsScreener reports texts of at least 10 letters whose share of capital letters exceeds maxRatio.
+func CapsScreener(maxRatio float64) Screener {
+	return ScreenerFunc(func(text string) []models.ScreeningFinding {
+		letters, upper := 0, 0
+		for _, r := range text {
+			if !unicode.IsLetter(r) {
+				continue
+			}
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+		if letters < 10 || float64(upper)/float64(letters) <= maxRatio {
+			return nil
+		}
+		return []models.ScreeningFinding{{
+			Rule:    RuleCaps,
+			Message: "Text is mostly written in capital letters",
+			Score:   capsScore,
+		}}
+	})
+}
+
+// RepeatedCharsScreener reports texts repeating the same character more than max times in a row,
+// spaces aside, such as "!!!!!!" or "soooooo".
+func RepeatedCharsScreener(max int) Screener {
+	return ScreenerFunc(func(text string) []models.ScreeningFinding {
+		if max <= 0 {
+			return nil
+		}
+		var previous rune
+		run := 0
+		for _, r := range text {
+			if r == previous && !unicode.IsSpace(r) {
+				run++
+			} else {
+				previous, run = r, 1
+			}
+			if run > max {
+				return []models.ScreeningFinding{{
+					Rule:    RuleRepeatedChars,
+					Message: fmt.Sprintf("Text repeats a character more than %d times", max),
+					Score:   repeatedCharsScore,
+				}}
+			}
+		}
+		return nil
+	})
+}
+
+// countDigits returns the number of digits in s.
+func countDigits(s string) int {
+	count := 0
+	for _, r := range s {
+		if unicode.IsDigit(r) {
+			count++
+		}
+	}
+	return count
+}
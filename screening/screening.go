@@ -0,0 +1,237 @@
+package screening
+
+import (
+	"bufio"
+	"fmt"
+	"go_api_product_review/models"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Verdicts of the screening pipeline
+const (
+	// Accept lets the review through
+	Accept = "accept"
+	// Flag routes the review to the moderators
+	Flag = "flag"
+	// Reject refuses the review
+	Reject = "reject"
+)
+
+// Screener inspects the text of a review and reports what it found.
+type Screener interface {
+	Screen(text string) []models.ScreeningFinding
+}
+
+// ScreenerFunc adapts a function to the Screener interface.
+type ScreenerFunc func(text string) []models.ScreeningFinding
+
+// Screen calls the function.
+func (f ScreenerFunc) Screen(text string) []models.ScreeningFinding {
+	return f(text)
+}
+
+// Result is the outcome of screening a review text.
+type Result struct {
+	// Verdict is Accept, Flag or Reject
+	Verdict string
+	// Score is the sum of the scores of the findings
+	Score float64
+	// Findings lists what the screeners found
+	Findings []models.ScreeningFinding
+}
+
+// Rules returns the distinct rules of the findings, in order.
+func (r Result) Rules() []string {
+	var rules []string
+	for _, finding := range r.Findings {
+		if !contains(rules, finding.Rule) {
+			rules = append(rules, finding.Rule)
+		}
+	}
+	return rules
+}
+
+// Pipeline runs every screener on a review text and sums the scores of their findings:
+// reviews scoring FlagScore or more are flagged, RejectScore or more rejected.
+type Pipeline struct {
+	Screeners   []Screener
+	FlagScore   float64
+	RejectScore float64
+}
+
+// Screen runs the screeners on the text and returns the verdict.
+func (p *Pipeline) Screen(text string) Result {
+	result := Result{Verdict: Accept, Findings: []models.ScreeningFinding{}}
+	reject := false
+	for _, screener := range p.Screeners {
+		for _, finding := range screener.Screen(text) {
+			result.Findings = append(result.Findings, finding)
+			result.Score += finding.Score
+			reject = reject || finding.Reject
+		}
+	}
+
+	switch {
+	case reject || result.Score >= p.RejectScore:
+		result.Verdict = Reject
+	case result.Score >= p.FlagScore:
+		result.Verdict = Flag
+	}
+	return result
+}
+
+// Config configures the standard screening pipeline.
+type Config struct {
+	// Enabled turns the screening on
+	Enabled bool
+	// BannedWords are found whatever their case, leetspeak spelling or repeated letters; each one
+	// scores 3, so one banned word flags a review and two reject it with the default thresholds
+	BannedWords []string
+	// MinLength and MaxLength bound the number of characters of the text, MinLength 0 allows empty texts
+	MinLength int
+	MaxLength int
+	// MaxCapsRatio is the share of capital letters above which a text is shouting
+	MaxCapsRatio float64
+	// MaxRepeatedChars is the longest run of the same character allowed
+	MaxRepeatedChars int
+	// FlagScore and RejectScore are the score thresholds of the verdicts
+	FlagScore   float64
+	RejectScore float64
+}
+
+// DefaultConfig returns an enabled pipeline without banned words, accepting texts of up to
+// 5000 characters, flagging at a score of 2 and rejecting at 5.
+func DefaultConfig() Config {
+	return Config{
+		Enabled:          true,
+		MaxLength:        5000,
+		MaxCapsRatio:     0.7,
+		MaxRepeatedChars: 5,
+		FlagScore:        2,
+		RejectScore:      5,
+	}
+}
+
+// LoadConfigFromEnv builds the screening configuration from environment variables,
+// starting from DefaultConfig:
+//   - SCREENING_ENABLED: false turns the screening off
+//   - SCREENING_BANNED_WORDS: comma-separated banned words
+//   - SCREENING_BANNED_WORDS_FILE: file with one banned word per line, added to the list
+//   - SCREENING_MIN_LENGTH, SCREENING_MAX_LENGTH: bounds of the text length
+//   - SCREENING_MAX_CAPS_RATIO, SCREENING_MAX_REPEATED_CHARS: thresholds of the heuristics
+//   - SCREENING_FLAG_SCORE, SCREENING_REJECT_SCORE: score thresholds of the verdicts
+func LoadConfigFromEnv() (Config, error) {
+	config := DefaultConfig()
+	if value := os.Getenv("SCREENING_ENABLED"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid SCREENING_ENABLED: %w", err)
+		}
+		config.Enabled = enabled
+	}
+
+	if value := os.Getenv("SCREENING_BANNED_WORDS"); value != "" {
+		config.BannedWords = append(config.BannedWords, strings.Split(value, ",")...)
+	}
+	if path := os.Getenv("SCREENING_BANNED_WORDS_FILE"); path != "" {
+		words, err := readWordList(path)
+		if err != nil {
+			return config, fmt.Errorf("failed to read SCREENING_BANNED_WORDS_FILE: %w", err)
+		}
+		config.BannedWords = append(config.BannedWords, words...)
+	}
+
+	ints := map[string]*int{
+		"SCREENING_MIN_LENGTH":         &config.MinLength,
+		"SCREENING_MAX_LENGTH":         &config.MaxLength,
+		"SCREENING_MAX_REPEATED_CHARS": &config.MaxRepeatedChars,
+	}
+	for name, target := range ints {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 0 {
+				return config, fmt.Errorf("invalid %s %q, expected a non-negative integer", name, value)
+			}
+			*target = parsed
+		}
+	}
+	floats := map[string]*float64{
+		"SCREENING_MAX_CAPS_RATIO": &config.MaxCapsRatio,
+		"SCREENING_FLAG_SCORE":     &config.FlagScore,
+		"SCREENING_REJECT_SCORE":   &config.RejectScore,
+	}
+	for name, target := range floats {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil || parsed <= 0 {
+				return config, fmt.Errorf("invalid %s %q, expected a positive number", name, value)
+			}
+			*target = parsed
+		}
+	}
+
+	if config.MaxLength > 0 && config.MinLength > config.MaxLength {
+		return config, fmt.Errorf("SCREENING_MIN_LENGTH must not exceed SCREENING_MAX_LENGTH")
+	}
+	if config.FlagScore > config.RejectScore {
+		return config, fmt.Errorf("SCREENING_FLAG_SCORE must not exceed SCREENING_REJECT_SCORE")
+	}
+	return config, nil
+}
+
+// NewPipeline builds the standard pipeline from the configuration:
+// length bounds, banned words, contact details and the caps and repeated characters heuristics.
+// It returns nil when the screening is disabled.
+func NewPipeline(config Config) *Pipeline {
+	if !config.Enabled {
+		return nil
+	}
+
+	screeners := []Screener{LengthScreener(config.MinLength, config.MaxLength)}
+	if len(config.BannedWords) > 0 {
+		screeners = append(screeners, BannedWordScreener(config.BannedWords))
+	}
+	screeners = append(screeners,
+		ContactScreener(),
+		CapsScreener(config.MaxCapsRatio),
+		RepeatedCharsScreener(config.MaxRepeatedChars),
+	)
+
+	return &Pipeline{
+		Screeners:   screeners,
+		FlagScore:   config.FlagScore,
+		RejectScore: config.RejectScore,
+	}
+}
+
+// readWordList reads a file with one word per line, skipping blank lines and # comments.
+func readWordList(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	return words, scanner.Err()
+}
+
+// contains reports whether the list holds the value.
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
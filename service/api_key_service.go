@@ -0,0 +1,252 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"go_api_product_review/cache"
+	"go_api_product_review/models"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// APIKeyPrefix starts every API key, so they can be told apart from JWTs
+	APIKeyPrefix = "prk_"
+	// apiKeyCacheTTL is how long a validated key is cached, revocations and
+	// rotations drop the cached entry right away
+	apiKeyCacheTTL = time.Minute
+)
+
+var (
+	// ErrInvalidAPIKey is returned when an API key is unknown, revoked or expired
+	ErrInvalidAPIKey = errors.New("invalid API key")
+	// ErrAPIKeyRevoked is returned when changing a revoked API key
+	ErrAPIKeyRevoked = errors.New("API key is revoked")
+)
+
+// CreateAPIKey issues a new API key with the name, scopes, roles and expiry of the given key.
+// It returns the stored key and the plaintext key, which is not stored and cannot be retrieved again.
+func CreateAPIKey(db *gorm.DB, key *models.APIKey) (*models.APIKey, string, error) {
+	plaintext, err := generateAPIKey()
+	if err != nil {
+		return nil, "", err
+	}
+	key.Prefix = apiKeyPrefixOf(plaintext)
+	key.KeyHash = hashAPIKey(plaintext)
+
+	result := db.Create(key)
+	if result.Error != nil {
+		return nil, "", result.Error
+	}
+	return key, plaintext, nil
+}
+
+// ListAPIKeys retrieves all API keys, including the revoked ones.
+func ListAPIKeys(db *gorm.DB) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	result := db.Order("id ASC").Find(&keys)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return keys, nil
+}
+
+// GetAPIKey retrieves an API key by its ID. It returns ErrNotFound if the key does not exist.
+func GetAPIKey(db *gorm.DB, id uint) (*models.APIKey, error) {
+	return findAPIKey(db, id)
+}
+
+// RotateAPIKey replaces the secret of an API key, keeping its name, scopes, roles and expiry.
+// The previous key stops being accepted immediately.
+// It returns ErrNotFound if the key does not exist and ErrAPIKeyRevoked if it is revoked.
+func RotateAPIKey(db *gorm.DB, id uint) (*models.APIKey, string, error) {
+	plaintext, err := generateAPIKey()
+	if err != nil {
+		return nil, "", err
+	}
+	prefix, hash := apiKeyPrefixOf(plaintext), hashAPIKey(plaintext)
+
+	var key *models.APIKey
+	var previousHash string
+	for {
+		key, err = findAPIKey(db, id)
+		if err != nil {
+			return nil, "", err
+		}
+		if key.RevokedAt != nil {
+			return nil, "", ErrAPIKeyRevoked
+		}
+		previousHash = key.KeyHash
+
+		// Only replace the secret that was read, on a key that is still active: a concurrent
+		// revocation or rotation matches no row and must not be undone, read the key again
+		result := db.Model(&models.APIKey{}).
+			Where("id = ? AND revoked_at IS NULL AND key_hash = ?", key.ID, previousHash).
+			Updates(map[string]interface{}{"prefix": prefix, "key_hash": hash})
+		if result.Error != nil {
+			return nil, "", result.Error
+		}
+		if result.RowsAffected > 0 {
+			break
+		}
+	}
+	key.Prefix = prefix
+	key.KeyHash = hash
+
+	// Drop the previous key from the cache so it is rejected right away
+	err = cache.Rdb.Del(cache.Ctx, apiKeyCacheKey(previousHash)).Err()
+	if err != nil {
+		return nil, "", err
+	}
+	return key, plaintext, nil
+}
+
+// RevokeAPIKey revokes an API key, which stops being accepted immediately.
+// Revoking a revoked key has no effect. It returns ErrNotFound if the key does not exist.
+func RevokeAPIKey(db *gorm.DB, id uint) (*models.APIKey, error) {
+	key, err := findAPIKey(db, id)
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return key, nil
+	}
+
+	// Only the revocation is written, so a concurrent rotation keeps its new secret and is revoked along
+	now := time.Now().UTC()
+	result := db.Model(&models.APIKey{}).Where("id = ? AND revoked_at IS NULL", key.ID).
+		Update("revoked_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		// Revoked meanwhile, which has no further effect
+		return findAPIKey(db, id)
+	}
+
+	// Read the key again, a concurrent rotation may have replaced the cached secret
+	key, err = findAPIKey(db, id)
+	if err != nil {
+		return nil, err
+	}
+	err = cache.Rdb.Del(cache.Ctx, apiKeyCacheKey(key.KeyHash)).Err()
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// AuthenticateAPIKey returns the API key matching the plaintext key.
+// Validated keys are cached in Redis for a short time, and their last used date
+// is updated every time they are loaded from the database.
+// It returns ErrInvalidAPIKey if the key is unknown, revoked or expired.
+func AuthenticateAPIKey(db *gorm.DB, plaintext string) (*models.APIKey, error) {
+	hash := hashAPIKey(plaintext)
+	cacheKey := apiKeyCacheKey(hash)
+
+	// Check the Redis cache first
+	keyJSON, err := cache.Rdb.Get(cache.Ctx, cacheKey).Result()
+	if err == nil {
+		var key models.APIKey
+		if err := json.Unmarshal([]byte(keyJSON), &key); err != nil {
+			return nil, err
+		}
+		// The cached entry may outlive the expiry of the key
+		if !key.IsActive(time.Now()) {
+			return nil, ErrInvalidAPIKey
+		}
+		return &key, nil
+	}
+	if err != redis.Nil {
+		return nil, err // Return error if there is a Redis issue
+	}
+
+	var key models.APIKey
+	result := db.Where("key_hash = ?", hash).First(&key)
+	if gorm.IsRecordNotFoundError(result.Error) {
+		return nil, ErrInvalidAPIKey
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	now := time.Now().UTC()
+	if !key.IsActive(now) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	result = db.Model(&key).UpdateColumn("last_used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	key.LastUsedAt = &now
+
+	data, err := json.Marshal(key)
+	if err != nil {
+		return nil, err
+	}
+	err = cache.Rdb.Set(cache.Ctx, cacheKey, data, apiKeyCacheTTL).Err()
+	if err != nil {
+		return nil, err
+	}
+
+	// A rotation or revocation committed since the key was read may have dropped the cached
+	// entry before it was written: check the key again now that it is cached. A later one
+	// drops the entry written here
+	var current models.APIKey
+	result = db.Select("id, key_hash, revoked_at").First(&current, key.ID)
+	if result.Error != nil && !gorm.IsRecordNotFoundError(result.Error) {
+		return nil, result.Error
+	}
+	if gorm.IsRecordNotFoundError(result.Error) || current.KeyHash != hash || current.RevokedAt != nil {
+		err = cache.Rdb.Del(cache.Ctx, cacheKey).Err()
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidAPIKey
+	}
+	return &key, nil
+}
+
+// findAPIKey loads an API key by its ID, returning ErrNotFound if it does not exist.
+func findAPIKey(db *gorm.DB, id uint) (*models.APIKey, error) {
+	var key models.APIKey
+	result := db.First(&key, id)
+	if gorm.IsRecordNotFoundError(result.Error) {
+		return nil, ErrNotFound
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &key, nil
+}
+
+// generateAPIKey returns a new random plaintext API key.
+func generateAPIKey() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return APIKeyPrefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// apiKeyPrefixOf returns the part of a key that is stored in clear to identify it.
+func apiKeyPrefixOf(plaintext string) string {
+	return plaintext[:len(APIKeyPrefix)+8]
+}
+
+// hashAPIKey returns the hex encoded SHA-256 hash of a plaintext key.
+func hashAPIKey(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
+
+// apiKeyCacheKey returns the Redis key of a cached API key.
+func apiKeyCacheKey(hash string) string {
+	return "apikey:" + hash
+}
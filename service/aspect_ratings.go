@@ -0,0 +1,244 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go_api_product_review/models"
+	"sort"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ErrInvalidAspectRating is returned when the aspect ratings of a review do not match
+// the aspects defined by the category of the product.
+var ErrInvalidAspectRating = errors.New("invalid aspect rating")
+
+// aspectDelta is a change of the rating aggregates of an aspect of a product.
+type aspectDelta struct {
+	count int
+	sum   int
+}
+
+// checkAspectRatings checks the aspect ratings of a review of a product against the aspects
+// defined by the category of the product and its ancestors: every aspect must be defined, and rated once from 1 to 5.
+// It returns an error wrapping ErrInvalidAspectRating if they do not match.
+func checkAspectRatings(db *gorm.DB, productID uint, ratings []models.ReviewAspectRating) error {
+	if len(ratings) == 0 {
+		return nil
+	}
+
+	var product models.Product
+	result := db.Select("id, category_id").First(&product, productID)
+	if gorm.IsRecordNotFoundError(result.Error) {
+		return fmt.Errorf("%w: product %d not found", ErrInvalidAspectRating, productID)
+	}
+	if result.Error != nil {
+		return result.Error
+	}
+	if product.CategoryID == nil {
+		return fmt.Errorf("%w: the product has no category defining aspects", ErrInvalidAspectRating)
+	}
+
+	var aspects []models.CategoryAspect
+	result = db.Where(categoryAncestorsFilter, *product.CategoryID).Find(&aspects)
+	if result.Error != nil {
+		return result.Error
+	}
+	defined := map[string]bool{}
+	for _, aspect := range aspects {
+		defined[aspect.Key] = true
+	}
+
+	rated := map[string]bool{}
+	for _, rating := range ratings {
+		if !defined[rating.Aspect] {
+			return fmt.Errorf("%w: aspect %q is not defined by the category of the product or its ancestors", ErrInvalidAspectRating, rating.Aspect)
+		}
+		if rated[rating.Aspect] {
+			return fmt.Errorf("%w: aspect %q is rated twice", ErrInvalidAspectRating, rating.Aspect)
+		}
+		rated[rating.Aspect] = true
+		if rating.Rating < 1 || rating.Rating > 5 {
+			return fmt.Errorf("%w: the rating of aspect %q must be between 1 and 5", ErrInvalidAspectRating, rating.Aspect)
+		}
+	}
+	return nil
+}
+
+// categoryAncestorsFilter matches the aspects defined by a category or one of its ancestors.
+const categoryAncestorsFilter = `category_id IN (SELECT a.id FROM categories a
+	JOIN categories c ON ` + categorySubtreeCondition + `
+	WHERE c.id = ?)`
+
+// saveAspectRatings replaces the aspect ratings of a review with the ones set on it.
+// It must run inside the transaction saving the review.
+func saveAspectRatings(tx *gorm.DB, review *models.Review) error {
+	result := tx.Where("review_id = ?", review.ID).Delete(&models.ReviewAspectRating{})
+	if result.Error != nil {
+		return result.Error
+	}
+	for i := range review.AspectRatings {
+		rating := &review.AspectRatings[i]
+		rating.ID = 0
+		rating.ReviewID = review.ID
+		if err := tx.Create(rating).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findAspectRatings loads the aspect ratings of a review, ordered by aspect.
+func findAspectRatings(db *gorm.DB, reviewID uint) ([]models.ReviewAspectRating, error) {
+	var ratings []models.ReviewAspectRating
+	result := db.Where("review_id = ?", reviewID).Order("aspect ASC").Find(&ratings)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return ratings, nil
+}
+
+// preloadAspectRatings makes the query load the aspect ratings of the reviews it finds, ordered by aspect.
+// association is the path of the ratings from the queried model, such as "AspectRatings" or "Reviews.AspectRatings".
+func preloadAspectRatings(db *gorm.DB, association string) *gorm.DB {
+	return db.Preload(association, func(db *gorm.DB) *gorm.DB {
+		return db.Order("aspect ASC")
+	})
+}
+
+// preloadProductAspectRatings makes the query load the aggregates of the aspects
+// still rated by the reviews of the products it finds, ordered by aspect.
+func preloadProductAspectRatings(db *gorm.DB) *gorm.DB {
+	return db.Preload("AspectRatings", func(db *gorm.DB) *gorm.DB {
+		return db.Where("review_count > 0").Order("aspect ASC")
+	})
+}
+
+// applyAspectDeltas applies changes to the aspect rating aggregates of a product with SQL arithmetic,
+// creating the aggregates of the aspects rated for the first time.
+// It must run inside the transaction of the review change, after the product row was updated:
+// the lock on the product row keeps concurrent changes from creating the same aggregates.
+func applyAspectDeltas(tx *gorm.DB, productID uint, deltas map[string]aspectDelta) error {
+	aspects := make([]string, 0, len(deltas))
+	for aspect := range deltas {
+		aspects = append(aspects, aspect)
+	}
+	sort.Strings(aspects)
+
+	for _, aspect := range aspects {
+		delta := deltas[aspect]
+		if delta.count == 0 && delta.sum == 0 {
+			continue
+		}
+		result := tx.Model(&models.ProductAspectRating{}).Where("product_id = ? AND aspect = ?", productID, aspect).
+			UpdateColumns(map[string]interface{}{
+				"review_count":   gorm.Expr("review_count + ?", delta.count),
+				"rating_sum":     gorm.Expr("rating_sum + ?", delta.sum),
+				"average_rating": gorm.Expr(averageRatingExpr, delta.count, delta.sum, delta.count),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 || delta.count <= 0 {
+			continue
+		}
+
+		err := tx.Create(&models.ProductAspectRating{
+			ProductID:     productID,
+			Aspect:        aspect,
+			ReviewCount:   delta.count,
+			RatingSum:     int64(delta.sum),
+			AverageRating: averageRating(int64(delta.sum), delta.count),
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// aspectAggregates computes the aspect rating aggregates of a product from its approved reviews.
+func aspectAggregates(tx *gorm.DB, productID uint) (map[string]models.ProductAspectRating, error) {
+	rows, err := tx.Table("review_aspect_ratings a").
+		Select("a.aspect, COUNT(*), COALESCE(SUM(a.rating), 0)").
+		Joins("JOIN reviews r ON r.id = a.review_id").
+		Where("r.product_id = ? AND r.status = ? AND r.deleted_at IS NULL", productID, models.ReviewApproved).
+		Group("a.aspect").Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	aggregates := map[string]models.ProductAspectRating{}
+	for rows.Next() {
+		aggregate := models.ProductAspectRating{ProductID: productID}
+		if err := rows.Scan(&aggregate.Aspect, &aggregate.ReviewCount, &aggregate.RatingSum); err != nil {
+			return nil, err
+		}
+		aggregate.AverageRating = averageRating(aggregate.RatingSum, aggregate.ReviewCount)
+		aggregates[aggregate.Aspect] = aggregate
+	}
+	return aggregates, rows.Err()
+}
+
+// reconcileAspectRatings recomputes the aspect rating aggregates of a product from its approved reviews
+// and stores them if they drifted. It returns the stored and recomputed review counts per aspect
+// when they drifted, nil otherwise. It must run inside the transaction reconciling the product.
+func reconcileAspectRatings(tx *gorm.DB, productID uint) (map[string]int, map[string]int, error) {
+	actual, err := aspectAggregates(tx, productID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var stored []models.ProductAspectRating
+	result := tx.Where("product_id = ? AND review_count <> 0", productID).Find(&stored)
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+
+	drifted := len(stored) != len(actual)
+	for _, aggregate := range stored {
+		expected, ok := actual[aggregate.Aspect]
+		if !ok || aggregate.ReviewCount != expected.ReviewCount || aggregate.RatingSum != expected.RatingSum ||
+			aggregate.AverageRating != expected.AverageRating {
+			drifted = true
+		}
+	}
+	if !drifted {
+		return nil, nil, nil
+	}
+
+	storedCounts, actualCounts := map[string]int{}, map[string]int{}
+	for _, aggregate := range stored {
+		storedCounts[aggregate.Aspect] = aggregate.ReviewCount
+	}
+	result = tx.Where("product_id = ?", productID).Delete(&models.ProductAspectRating{})
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+	for _, aggregate := range actual {
+		actualCounts[aggregate.Aspect] = aggregate.ReviewCount
+		if err := tx.Create(&aggregate).Error; err != nil {
+			return nil, nil, err
+		}
+	}
+	return storedCounts, actualCounts, nil
+}
+
+// aspectDriftQuery selects the products whose stored aspect rating aggregates
+// do not match their approved reviews, taking the approved status as parameter.
+const aspectDriftQuery = `SELECT DISTINCT p.id FROM products p
+	JOIN (
+		SELECT r.product_id, a.aspect, COUNT(*) AS review_count, SUM(a.rating) AS rating_sum
+		FROM review_aspect_ratings a JOIN reviews r ON r.id = a.review_id
+		WHERE r.deleted_at IS NULL AND r.status = ? GROUP BY r.product_id, a.aspect
+	) c ON c.product_id = p.id
+	LEFT JOIN product_aspect_ratings s ON s.product_id = c.product_id AND s.aspect = c.aspect
+	WHERE p.deleted_at IS NULL
+		AND (s.product_id IS NULL OR s.review_count <> c.review_count OR s.rating_sum <> c.rating_sum)
+	UNION
+	SELECT DISTINCT p.id FROM products p
+	JOIN product_aspect_ratings s ON s.product_id = p.id
+	WHERE p.deleted_at IS NULL AND s.review_count <> 0 AND NOT EXISTS (
+		SELECT 1 FROM review_aspect_ratings a JOIN reviews r ON r.id = a.review_id
+		WHERE r.product_id = s.product_id AND a.aspect = s.aspect AND r.deleted_at IS NULL AND r.status = ?
+	)`
@@ -0,0 +1,451 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go_api_product_review/cache"
+	"go_api_product_review/models"
+	"sort"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	// ErrUnknownCategory is returned when a product is assigned a category that does not exist.
+	ErrUnknownCategory = errors.New("category does not exist")
+	// ErrInvalidCategoryParent is returned when the parent of a category does not exist,
+	// is the category itself or one of its descendants, or nests the category too deep.
+	ErrInvalidCategoryParent = errors.New("invalid parent category")
+	// ErrDuplicateCategory is returned when a sibling category already uses the slug.
+	ErrDuplicateCategory = errors.New("a category with this slug already exists under the parent")
+	// ErrCategoryHasChildren is returned when deleting a category that still has subcategories.
+	ErrCategoryHasChildren = errors.New("category has subcategories")
+)
+
+// categorySubtreeCondition joins an ancestor category a to its descendants c, a included.
+// Slugs only hold lowercase letters, digits and hyphens, so paths never contain LIKE wildcards.
+const categorySubtreeCondition = `c.path = a.path OR c.path LIKE a.path || '/%'`
+
+// categoryTreeLockKey is the PostgreSQL advisory lock serializing the changes of the category paths
+const categoryTreeLockKey = 7301451
+
+// CreateCategory creates a category under its parent, along with the aspects its products are rated on.
+// The slug must have been validated.
+// It returns an error wrapping ErrInvalidCategoryParent if the parent does not exist or is too deep,
+// and ErrDuplicateCategory if a sibling already uses the slug.
+func CreateCategory(db *gorm.DB, category *models.Category) (*models.Category, error) {
+	category.ID = 0
+	category.Ratings = nil
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := lockCategoryTree(tx); err != nil {
+			return err
+		}
+		path, err := categoryPath(tx, category.ParentID, category.Slug, nil)
+		if err != nil {
+			return err
+		}
+		if err := checkCategoryPathFree(tx, path, 0); err != nil {
+			return err
+		}
+		category.Path = path
+
+		if err := tx.Create(category).Error; err != nil {
+			return err
+		}
+		return saveCategoryAspects(tx, category)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// GetCategory retrieves a category with its aspects and the rating aggregates
+// rolled up from the products of the category and its descendants.
+// It returns ErrNotFound if the category does not exist.
+func GetCategory(db *gorm.DB, id uint) (*models.Category, error) {
+	var category models.Category
+	result := preloadCategoryAspects(db).First(&category, id)
+	if gorm.IsRecordNotFoundError(result.Error) {
+		return nil, ErrNotFound
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	ratings, err := categoryRatings(db, []uint{category.ID})
+	if err != nil {
+		return nil, err
+	}
+	categoryRatings := ratings[category.ID]
+	category.Ratings = &categoryRatings
+	return &category, nil
+}
+
+// ListCategories retrieves every category with its aspects and rolled up rating aggregates,
+// ordered by path so each category directly follows its ancestors.
+func ListCategories(db *gorm.DB) ([]models.Category, error) {
+	categories := []models.Category{}
+	result := preloadCategoryAspects(db).Order("path ASC").Find(&categories)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if len(categories) == 0 {
+		return categories, nil
+	}
+
+	ids := make([]uint, 0, len(categories))
+	for _, category := range categories {
+		ids = append(ids, category.ID)
+	}
+	ratings, err := categoryRatings(db, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range categories {
+		categoryRatings := ratings[categories[i].ID]
+		categories[i].Ratings = &categoryRatings
+	}
+	return categories, nil
+}
+
+// UpdateCategory renames a category, moves it under another parent and replaces its aspects.
+// When its slug or parent changes, the paths of its descendants follow.
+// The ratings already given to removed aspects are kept along with their aggregates,
+// but new reviews can no longer rate them.
+// It returns ErrNotFound if the category does not exist, an error wrapping ErrInvalidCategoryParent
+// if the parent does not exist, is the category itself or one of its descendants or nests the
+// category too deep, and ErrDuplicateCategory if a sibling already uses the slug.
+func UpdateCategory(db *gorm.DB, id uint, updated *models.Category) (*models.Category, error) {
+	var category models.Category
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// The paths read to check the move must stay the same until it is saved,
+		// or two categories moved under each other concurrently would form a cycle
+		if err := lockCategoryTree(tx); err != nil {
+			return err
+		}
+		result := tx.First(&category, id)
+		if gorm.IsRecordNotFoundError(result.Error) {
+			return ErrNotFound
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+
+		path, err := categoryPath(tx, updated.ParentID, updated.Slug, &category)
+		if err != nil {
+			return err
+		}
+		if path != category.Path {
+			if err := moveCategory(tx, &category, path); err != nil {
+				return err
+			}
+		}
+
+		category.Name = updated.Name
+		category.Slug = updated.Slug
+		category.ParentID = updated.ParentID
+		category.Path = path
+		category.Aspects = updated.Aspects
+		if err := tx.Save(&category).Error; err != nil {
+			return err
+		}
+		return saveCategoryAspects(tx, &category)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// DeleteCategory deletes a category without subcategories along with its aspects.
+// Its products stay in the catalog: they leave the category, and the products it was
+// the category_id of no longer have one.
+// It returns ErrNotFound if the category does not exist and ErrCategoryHasChildren if it has subcategories.
+func DeleteCategory(db *gorm.DB, id uint) error {
+	var productIDs []uint
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var category models.Category
+		result := tx.First(&category, id)
+		if gorm.IsRecordNotFoundError(result.Error) {
+			return ErrNotFound
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+
+		var children int
+		if err := tx.Model(&models.Category{}).Where("parent_id = ?", id).Count(&children).Error; err != nil {
+			return err
+		}
+		if children > 0 {
+			return ErrCategoryHasChildren
+		}
+
+		result = tx.Model(&models.ProductCategory{}).Where("category_id = ?", id).Pluck("product_id", &productIDs)
+		if result.Error != nil {
+			return result.Error
+		}
+		if err := tx.Where("category_id = ?", id).Delete(&models.ProductCategory{}).Error; err != nil {
+			return err
+		}
+		result = tx.Unscoped().Model(&models.Product{}).Where("category_id = ?", id).UpdateColumn("category_id", nil)
+		if result.Error != nil {
+			return result.Error
+		}
+		if err := tx.Where("category_id = ?", id).Delete(&models.CategoryAspect{}).Error; err != nil {
+			return err
+		}
+		// Delete the row for good so its path can be used again
+		return tx.Unscoped().Delete(&category).Error
+	})
+	if err != nil {
+		return err
+	}
+	if len(productIDs) == 0 {
+		return nil
+	}
+
+	// Drop the cached representations listing the category
+	keys := make([]string, 0, len(productIDs))
+	for _, productID := range productIDs {
+		keys = append(keys, productCacheKey(productID))
+	}
+	return cache.Rdb.Del(cache.Ctx, keys...).Err()
+}
+
+// ListCategoryProducts retrieves a page of the products assigned to a category or one of its descendants,
+// with the filters, sort order and keyset pagination of ListProducts.
+// It returns ErrNotFound if the category does not exist.
+func ListCategoryProducts(db *gorm.DB, id uint, query models.ProductQuery) (*models.ProductPage, error) {
+	var count int
+	if err := db.Model(&models.Category{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrNotFound
+	}
+	query.CategoryID = &id
+	return ListProducts(db, query)
+}
+
+// categoryPath computes the path of a category with the slug under the parent.
+// When moving an existing category, the parent must be neither the category nor one of its descendants,
+// and the deepest descendant must stay within MaxCategoryDepth.
+func categoryPath(tx *gorm.DB, parentID *uint, slug string, moving *models.Category) (string, error) {
+	path := slug
+	if parentID != nil {
+		var parent models.Category
+		result := tx.First(&parent, *parentID)
+		if gorm.IsRecordNotFoundError(result.Error) {
+			return "", fmt.Errorf("%w: category %d not found", ErrInvalidCategoryParent, *parentID)
+		}
+		if result.Error != nil {
+			return "", result.Error
+		}
+		if moving != nil && (parent.Path == moving.Path || strings.HasPrefix(parent.Path, moving.Path+"/")) {
+			return "", fmt.Errorf("%w: a category cannot be moved under itself or one of its descendants", ErrInvalidCategoryParent)
+		}
+		path = parent.Path + "/" + slug
+	}
+
+	depth := categoryDepth(path)
+	if moving != nil {
+		var paths []string
+		result := tx.Model(&models.Category{}).Where("path LIKE ?", moving.Path+"/%").Pluck("path", &paths)
+		if result.Error != nil {
+			return "", result.Error
+		}
+		for _, descendant := range paths {
+			if d := categoryDepth(path) + categoryDepth(descendant) - categoryDepth(moving.Path); d > depth {
+				depth = d
+			}
+		}
+	}
+	if depth > models.MaxCategoryDepth {
+		return "", fmt.Errorf("%w: the category tree is at most %d levels deep", ErrInvalidCategoryParent, models.MaxCategoryDepth)
+	}
+	return path, nil
+}
+
+// lockCategoryTree serializes the transactions creating and moving categories on PostgreSQL,
+// so the paths they compute from the parent categories are not changed by another one meanwhile.
+// The lock is released at the end of the transaction. It must run inside a transaction.
+func lockCategoryTree(tx *gorm.DB) error {
+	if tx.Dialect().GetName() != "postgres" {
+		return nil
+	}
+	return tx.Exec("SELECT pg_advisory_xact_lock(?)", categoryTreeLockKey).Error
+}
+
+// categoryDepth returns the level of a category in the tree from its path, top-level categories being at level 1.
+func categoryDepth(path string) int {
+	return strings.Count(path, "/") + 1
+}
+
+// checkCategoryPathFree checks that no category but the one with the ID uses the path.
+func checkCategoryPathFree(tx *gorm.DB, path string, id uint) error {
+	var count int
+	result := tx.Unscoped().Model(&models.Category{}).Where("path = ? AND id <> ?", path, id).Count(&count)
+	if result.Error != nil {
+		return result.Error
+	}
+	if count > 0 {
+		return ErrDuplicateCategory
+	}
+	return nil
+}
+
+// moveCategory rewrites the paths of the descendants of a category moving to a new path.
+// It must run inside the transaction saving the category, which sets the path of the category itself.
+func moveCategory(tx *gorm.DB, category *models.Category, path string) error {
+	if err := checkCategoryPathFree(tx, path, category.ID); err != nil {
+		return err
+	}
+	return tx.Model(&models.Category{}).Where("path LIKE ?", category.Path+"/%").
+		UpdateColumn("path", gorm.Expr("? || SUBSTR(path, ?)", path, len(category.Path)+1)).Error
+}
+
+// saveCategoryAspects replaces the aspects of a category with the ones set on it.
+// It must run inside the transaction saving the category.
+func saveCategoryAspects(tx *gorm.DB, category *models.Category) error {
+	result := tx.Where("category_id = ?", category.ID).Delete(&models.CategoryAspect{})
+	if result.Error != nil {
+		return result.Error
+	}
+	for i := range category.Aspects {
+		aspect := &category.Aspects[i]
+		aspect.ID = 0
+		aspect.CategoryID = category.ID
+		if err := tx.Create(aspect).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// preloadCategoryAspects makes the query load the aspects of the categories it finds, in their defined order.
+func preloadCategoryAspects(db *gorm.DB) *gorm.DB {
+	return db.Preload("Aspects", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	})
+}
+
+// categoryRatingsQuery rolls up the rating aggregates of the categories from their products
+// and the products of their descendants, counting every product once per category.
+const categoryRatingsQuery = `SELECT x.category_id, COUNT(*),
+		COALESCE(SUM(CASE WHEN p.review_count > 0 THEN 1 ELSE 0 END), 0),
+		COALESCE(SUM(p.review_count), 0),
+		COALESCE(AVG(CASE WHEN p.review_count > 0 THEN p.average_rating END), 0)
+	FROM (
+		SELECT DISTINCT a.id AS category_id, pc.product_id
+		FROM categories a
+		JOIN categories c ON ` + categorySubtreeCondition + `
+		JOIN product_categories pc ON pc.category_id = c.id
+		WHERE a.id IN (?)
+	) x
+	JOIN products p ON p.id = x.product_id
+	WHERE p.deleted_at IS NULL
+	GROUP BY x.category_id`
+
+// categoryRatings computes the rating aggregates of the categories with the IDs, rolled up from the products
+// of each category and its descendants. Categories without products have zero aggregates.
+func categoryRatings(db *gorm.DB, ids []uint) (map[uint]models.CategoryRatings, error) {
+	rows, err := db.Raw(categoryRatingsQuery, ids).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ratings := map[uint]models.CategoryRatings{}
+	for rows.Next() {
+		var id uint
+		var aggregate models.CategoryRatings
+		err := rows.Scan(&id, &aggregate.ProductCount, &aggregate.RatedProductCount,
+			&aggregate.ReviewCount, &aggregate.AverageRating)
+		if err != nil {
+			return nil, err
+		}
+		ratings[id] = aggregate
+	}
+	return ratings, rows.Err()
+}
+
+// productCategoryIDs returns the distinct categories a product is assigned to, its category_id included, in ascending order.
+func productCategoryIDs(product *models.Product) []uint {
+	seen := map[uint]bool{}
+	var ids []uint
+	if product.CategoryID != nil {
+		seen[*product.CategoryID] = true
+		ids = append(ids, *product.CategoryID)
+	}
+	for _, id := range product.CategoryIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// checkCategories checks that the categories assigned to a product exist.
+// Products without categories are valid.
+func checkCategories(db *gorm.DB, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	var count int
+	result := db.Model(&models.Category{}).Where("id IN (?)", ids).Count(&count)
+	if result.Error != nil {
+		return result.Error
+	}
+	if count != len(ids) {
+		return ErrUnknownCategory
+	}
+	return nil
+}
+
+// saveProductCategories replaces the categories a product is assigned to with the ones set on it,
+// its category_id included, and sets them back on the product.
+// It must run inside the transaction saving the product.
+func saveProductCategories(tx *gorm.DB, product *models.Product) error {
+	ids := productCategoryIDs(product)
+	result := tx.Where("product_id = ?", product.ID).Delete(&models.ProductCategory{})
+	if result.Error != nil {
+		return result.Error
+	}
+	for _, id := range ids {
+		if err := tx.Create(&models.ProductCategory{ProductID: product.ID, CategoryID: id}).Error; err != nil {
+			return err
+		}
+	}
+	product.CategoryIDs = ids
+	return nil
+}
+
+// loadProductCategoryIDs sets the categories each product is assigned to, in ascending order.
+func loadProductCategoryIDs(db *gorm.DB, products []models.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+	productIDs := make([]uint, 0, len(products))
+	for _, product := range products {
+		productIDs = append(productIDs, product.ID)
+	}
+
+	var assignments []models.ProductCategory
+	result := db.Where("product_id IN (?)", productIDs).Order("category_id ASC").Find(&assignments)
+	if result.Error != nil {
+		return result.Error
+	}
+	categoryIDs := map[uint][]uint{}
+	for _, assignment := range assignments {
+		categoryIDs[assignment.ProductID] = append(categoryIDs[assignment.ProductID], assignment.CategoryID)
+	}
+	for i := range products {
+		products[i].CategoryIDs = categoryIDs[products[i].ID]
+	}
+	return nil
+}
@@ -0,0 +1,255 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go_api_product_review/models"
+	"go_api_product_review/screening"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Actions taken on duplicate reviews
+const (
+	// DuplicateReject refuses duplicate reviews with ErrDuplicateReview
+	DuplicateReject = "reject"
+	// DuplicateFlag saves duplicate reviews flagged for moderation
+	DuplicateFlag = "flag"
+)
+
+// DuplicateModerator is recorded as the moderator of the reviews flagged as duplicates
+const DuplicateModerator = "duplicate-detection"
+
+// ErrDuplicateReview is returned when a review duplicates another one and duplicates are rejected.
+var ErrDuplicateReview = errors.New("duplicate review")
+
+// DuplicateConfig configures the detection of duplicate reviews.
+type DuplicateConfig struct {
+	// Action is DuplicateReject or DuplicateFlag
+	Action string
+	// Similarity is the estimated Jaccard similarity of the word shingles
+	// from which two review texts are near-duplicates
+	Similarity float64
+	// Window is how far back the texts of the reviews of other products are compared,
+	// the reviews of the same product are always compared
+	Window time.Duration
+	// MinWords is the number of words below which texts are too short to be compared
+	MinWords int
+}
+
+// DefaultDuplicateConfig returns a detection flagging texts 80% similar to the texts of the
+// product or to the texts posted in the last hour, from 5 words.
+func DefaultDuplicateConfig() DuplicateConfig {
+	return DuplicateConfig{
+		Action:     DuplicateFlag,
+		Similarity: 0.8,
+		Window:     time.Hour,
+		MinWords:   5,
+	}
+}
+
+// LoadDuplicateConfigFromEnv builds the duplicate detection configuration from environment
+// variables, starting from DefaultDuplicateConfig:
+//   - DUPLICATE_ACTION: reject or flag
+//   - DUPLICATE_SIMILARITY: similarity threshold, between 0 and 1
+//   - DUPLICATE_WINDOW: duration the texts of other products are compared over, such as 30m
+//   - DUPLICATE_MIN_WORDS: minimum number of words of the compared texts
+func LoadDuplicateConfigFromEnv() (DuplicateConfig, error) {
+	config := DefaultDuplicateConfig()
+	if action := os.Getenv("DUPLICATE_ACTION"); action != "" {
+		config.Action = action
+	}
+	if value := os.Getenv("DUPLICATE_SIMILARITY"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return config, fmt.Errorf("invalid DUPLICATE_SIMILARITY: %w", err)
+		}
+		config.Similarity = parsed
+	}
+	if value := os.Getenv("DUPLICATE_WINDOW"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid DUPLICATE_WINDOW: %w", err)
+		}
+		config.Window = parsed
+	}
+	if value := os.Getenv("DUPLICATE_MIN_WORDS"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid DUPLICATE_MIN_WORDS: %w", err)
+		}
+		config.MinWords = parsed
+	}
+	return config, config.Validate()
+}
+
+// Validate checks the duplicate detection configuration.
+func (c DuplicateConfig) Validate() error {
+	if c.Action != DuplicateReject && c.Action != DuplicateFlag {
+		return fmt.Errorf("duplicate action must be %s or %s", DuplicateReject, DuplicateFlag)
+	}
+	if c.Similarity <= 0 || c.Similarity > 1 {
+		return fmt.Errorf("duplicate similarity must be greater than 0 and at most 1")
+	}
+	if c.Window < 0 {
+		return fmt.Errorf("duplicate window must not be negative")
+	}
+	if c.MinWords < 1 {
+		return fmt.Errorf("duplicate minimum number of words must be at least 1")
+	}
+	return nil
+}
+
+// maxDuplicateCandidates bounds the number of reviews the text of a review is compared with
+const maxDuplicateCandidates = 200
+
+// duplicates holds the duplicate detection configuration shared by the review writes.
+var duplicates = struct {
+	sync.RWMutex
+	config DuplicateConfig
+}{config: DefaultDuplicateConfig()}
+
+// InitDuplicates sets the duplicate detection configuration.
+func InitDuplicates(config DuplicateConfig) {
+	duplicates.Lock()
+	defer duplicates.Unlock()
+	duplicates.config = config
+}
+
+// duplicateConfig returns the current duplicate detection configuration.
+func duplicateConfig() DuplicateConfig {
+	duplicates.RLock()
+	defer duplicates.RUnlock()
+	return duplicates.config
+}
+
+// checkDuplicates looks for a review duplicating the review about to be saved: for new reviews,
+// a review of the product by the same author, and for every review, a near-duplicate text.
+// Depending on the configuration it returns ErrDuplicateReview or flags the review for moderation.
+func checkDuplicates(tx *gorm.DB, review *models.Review) error {
+	// Lock the product first, so that concurrent reviews of the same author or text
+	// are checked one after the other instead of both finding no duplicate
+	err := lockProduct(tx, review.ProductID)
+	if err != nil {
+		return err
+	}
+
+	config := duplicateConfig()
+	reason, err := findDuplicate(tx, review, config)
+	if err != nil || reason == "" {
+		return err
+	}
+
+	if config.Action == DuplicateReject {
+		return fmt.Errorf("%w: %s", ErrDuplicateReview, reason)
+	}
+	flagReview(review, DuplicateModerator, "Flagged as possible duplicate: "+reason)
+	return nil
+}
+
+// findDuplicate describes the first duplicate found for the review, the empty string if there is none.
+// Authors are identified by their subject or their full name, case aside; their rejected reviews
+// do not count. Texts are compared through their MinHash signatures with the texts of the reviews
+// of the product and the texts posted on other products within the window, among the latest
+// maxDuplicateCandidates reviews sharing a band key of the signature.
+func findDuplicate(tx *gorm.DB, review *models.Review, config DuplicateConfig) (string, error) {
+	if review.ID == 0 {
+		var conditions []string
+		var args []interface{}
+		if review.AuthorSubject != "" {
+			conditions = append(conditions, "author_subject = ?")
+			args = append(args, review.AuthorSubject)
+		}
+		if review.FirstName != "" && review.LastName != "" {
+			conditions = append(conditions, "(LOWER(first_name) = ? AND LOWER(last_name) = ?)")
+			args = append(args, strings.ToLower(review.FirstName), strings.ToLower(review.LastName))
+		}
+
+		if len(conditions) > 0 {
+			var existing models.Review
+			result := tx.Select("id").
+				Where("product_id = ? AND status <> ?", review.ProductID, models.ReviewRejected).
+				Where(strings.Join(conditions, " OR "), args...).
+				First(&existing)
+			if result.Error == nil {
+				return fmt.Sprintf("the author already reviewed the product in review %d", existing.ID), nil
+			}
+			if !gorm.IsRecordNotFoundError(result.Error) {
+				return "", result.Error
+			}
+		}
+	}
+
+	if len(screening.Words(review.ReviewText)) < config.MinWords {
+		return "", nil
+	}
+	signature := screening.MinHash(review.ReviewText)
+
+	// Only the latest reviews sharing a band of the signature are compared
+	var candidates []models.Review
+	query := tx.Select("id, product_id, text_signature").
+		Where("id IN (SELECT review_id FROM review_text_bands WHERE band_key IN (?))", signature.BandKeys()).
+		Where("product_id = ? OR created_at >= ?", review.ProductID, time.Now().Add(-config.Window))
+	if review.ID != 0 {
+		query = query.Where("id <> ?", review.ID)
+	}
+	result := query.Order("id DESC").Limit(maxDuplicateCandidates).Find(&candidates)
+	if result.Error != nil {
+		return "", result.Error
+	}
+
+	for _, candidate := range candidates {
+		other, err := screening.ParseSignature(candidate.TextSignature)
+		if err != nil {
+			continue
+		}
+		if signature.Similarity(other) < config.Similarity {
+			continue
+		}
+		if candidate.ProductID == review.ProductID {
+			return fmt.Sprintf("the text is similar to review %d", candidate.ID), nil
+		}
+		return fmt.Sprintf("the text is similar to review %d of product %d", candidate.ID, candidate.ProductID), nil
+	}
+	return "", nil
+}
+
+// lockProduct locks the row of a product until the end of the transaction.
+// Missing products are left to the writes that follow.
+func lockProduct(tx *gorm.DB, productID uint) error {
+	query := tx
+	if tx.Dialect().GetName() == "postgres" {
+		query = tx.Set("gorm:query_option", "FOR UPDATE")
+	}
+	var product models.Product
+	result := query.Select("id").First(&product, productID)
+	if result.Error != nil && !gorm.IsRecordNotFoundError(result.Error) {
+		return result.Error
+	}
+	return nil
+}
+
+// saveTextBands replaces the band keys of a review with the ones of its text signature.
+// It must run inside the transaction saving the review.
+func saveTextBands(tx *gorm.DB, review *models.Review) error {
+	result := tx.Where("review_id = ?", review.ID).Delete(&models.ReviewTextBand{})
+	if result.Error != nil {
+		return result.Error
+	}
+	signature, err := screening.ParseSignature(review.TextSignature)
+	if err != nil {
+		return err
+	}
+	for _, key := range signature.BandKeys() {
+		result = tx.Create(&models.ReviewTextBand{ReviewID: review.ID, BandKey: key})
+		if result.Error != nil {
+			return result.Error
+		}
+	}
+	return nil
+}
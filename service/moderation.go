@@ -0,0 +1,145 @@
+package service
+
+import (
+	"errors"
+	"go_api_product_review/events"
+	"go_api_product_review/models"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ErrInvalidTransition is returned when a review cannot be moved to the requested status.
+var ErrInvalidTransition = errors.New("invalid review status transition")
+
+// moderationQueueSort is the sort name embedded in the cursors of the moderation queue
+const moderationQueueSort = "queue"
+
+// ModerateReview moves a review to a new moderation status, recording the reason and the moderator.
+// Reviews entering or leaving the approved status are added to or removed from the rating
+// aggregates of their product in the same transaction, along with a review.moderated event;
+// the caches are updated once it is committed.
+// It returns ErrNotFound if the review does not exist and ErrInvalidTransition if the review
+// cannot be moved from its current status to the new one.
+func ModerateReview(db *gorm.DB, id uint, status string, reason string, moderator string) (*models.Review, error) {
+	var review models.Review
+	var averageRating float64
+	var ratingsChanged bool
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// The review enters or leaves the aggregates with its ratings as read, keep concurrent edits out until then
+		result := lockReview(tx, &review, id)
+		if gorm.IsRecordNotFoundError(result.Error) {
+			return ErrNotFound
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		if !models.CanTransition(review.Status, status) {
+			return ErrInvalidTransition
+		}
+		var err error
+		review.AspectRatings, err = findAspectRatings(tx, review.ID)
+		if err != nil {
+			return err
+		}
+
+		// Only move the review from the status it was read with: a concurrent moderation
+		// of the review already applied its transition to the aggregates
+		wasApproved := review.Status == models.ReviewApproved
+		now := time.Now()
+		result = tx.Model(&review).Where("status = ?", review.Status).Updates(map[string]interface{}{
+			"status":            status,
+			"moderation_reason": reason,
+			"moderated_by":      moderator,
+			"moderated_at":      &now,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidTransition
+		}
+
+		err = enqueueEvent(tx, events.ReviewModerated, review.ProductID, models.NewReviewResponse(review))
+		if err != nil {
+			return err
+		}
+
+		// Only approved reviews count toward the ratings of the product
+		isApproved := status == models.ReviewApproved
+		if wasApproved == isApproved {
+			return nil
+		}
+		delta := reviewAdded(review)
+		if wasApproved {
+			delta = reviewRemoved(review)
+		}
+		ratingsChanged = true
+		averageRating, err = applyRatingDelta(tx, review.ProductID, delta)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The cached review embeds its replies and media
+	err = loadReviewDetails(db, &review)
+	if err != nil {
+		return nil, err
+	}
+	err = CacheReview(&review)
+	if err != nil {
+		return nil, err
+	}
+
+	// The public reads of the product only change when the review is published or taken down
+	if ratingsChanged {
+		err = refreshProductCaches(review.ProductID, averageRating)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &review, nil
+}
+
+// ListModerationQueue retrieves a page of the reviews with the requested status, oldest first,
+// so moderators work through the queue in order. The queue is never cached.
+func ListModerationQueue(db *gorm.DB, query models.ModerationQuery) (*models.ReviewPage, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	paged := db.Where("status = ?", query.Status)
+	if query.Cursor != "" {
+		var value time.Time
+		id, err := decodeCursor(query.Cursor, moderationQueueSort+":"+query.Status, &value)
+		if err != nil {
+			return nil, err
+		}
+		paged = applyKeyset(paged, "created_at", false, value, id)
+	}
+
+	// Fetch one extra row to know whether there is a next page
+	var reviews []models.Review
+	result := paged.Order(keysetOrder("created_at", false)).Limit(query.Limit + 1).Find(&reviews)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	page := &models.ReviewPage{Items: []models.ReviewResponse{}}
+	if len(reviews) > query.Limit {
+		reviews = reviews[:query.Limit]
+		last := reviews[len(reviews)-1]
+		cursor, err := encodeCursor(moderationQueueSort+":"+query.Status, last.CreatedAt, last.ID)
+		if err != nil {
+			return nil, err
+		}
+		page.NextCursor = cursor
+	}
+
+	for _, review := range reviews {
+		page.Items = append(page.Items, models.NewReviewResponse(review))
+	}
+	return page, nil
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"go_api_product_review/events"
+	"go_api_product_review/models"
+	"log"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// outboxBaseBackoff is the delay before retrying an event that failed once
+	outboxBaseBackoff = time.Second
+	// outboxMaxBackoff caps the delay between two delivery attempts
+	outboxMaxBackoff = 5 * time.Minute
+	// outboxClaimLease is how long a claimed event is kept from the other dispatchers,
+	// longer than relaying an event takes
+	outboxClaimLease = time.Minute
+)
+
+// enqueueEvent stores a domain event in the outbox.
+// It must be called with the transaction that performs the change the event describes.
+func enqueueEvent(tx *gorm.DB, eventType string, productID uint, data interface{}) error {
+	event, err := events.New(eventType, productID, data)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return tx.Create(&models.OutboxEvent{
+		EventID:       event.ID,
+		EventType:     event.Type,
+		ProductID:     productID,
+		Payload:       string(payload),
+		NextAttemptAt: event.OccurredAt,
+	}).Error
+}
+
+// DispatchOutbox relays up to batchSize pending events to the publisher, oldest first.
+// Delivered events are marked as such; failed ones are retried later with an
+// exponential backoff, so consumers get every event at least once.
+// Every event is claimed before it is relayed, so concurrent dispatchers never relay the same one;
+// an event claimed by a dispatcher that stopped is relayed again once its claim expires.
+// It returns the number of events delivered.
+func DispatchOutbox(db *gorm.DB, publisher events.Publisher, batchSize int) (int, error) {
+	now := time.Now().UTC()
+	var pending []models.OutboxEvent
+	result := db.Where("delivered_at IS NULL AND next_attempt_at <= ?", now).
+		Order("id ASC").
+		Limit(batchSize).
+		Find(&pending)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	delivered := 0
+	for _, outboxEvent := range pending {
+		// Claim the event by moving its next attempt past the time needed to relay it,
+		// an event claimed meanwhile by another dispatcher is no longer due
+		lease := now.Add(outboxClaimLease)
+		result := db.Model(&models.OutboxEvent{}).
+			Where("id = ? AND delivered_at IS NULL AND next_attempt_at <= ?", outboxEvent.ID, now).
+			UpdateColumn("next_attempt_at", lease)
+		if result.Error != nil {
+			return delivered, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		outboxEvent.NextAttemptAt = lease
+
+		var event events.Event
+		err := json.Unmarshal([]byte(outboxEvent.Payload), &event)
+		if err == nil {
+			err = publisher.Publish(context.Background(), event)
+		}
+
+		if err != nil {
+			outboxEvent.Attempts++
+			outboxEvent.LastError = err.Error()
+			outboxEvent.NextAttemptAt = time.Now().UTC().Add(outboxBackoff(outboxEvent.Attempts))
+		} else {
+			deliveredAt := time.Now().UTC()
+			outboxEvent.DeliveredAt = &deliveredAt
+			delivered++
+		}
+
+		if err := db.Save(&outboxEvent).Error; err != nil {
+			return delivered, err
+		}
+	}
+
+	return delivered, nil
+}
+
+// RunOutboxDispatcher relays pending outbox events to the publisher every interval
+// until the context is cancelled.
+func RunOutboxDispatcher(ctx context.Context, db *gorm.DB, publisher events.Publisher, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Keep going while full batches are delivered to drain backlogs quickly
+			for {
+				delivered, err := DispatchOutbox(db, publisher, 100)
+				if err != nil {
+					log.Printf("failed to dispatch outbox events: %v", err)
+				}
+				if err != nil || delivered < 100 {
+					break
+				}
+			}
+		}
+	}
+}
+
+// outboxBackoff returns the delay before the next delivery attempt of an event
+// that already failed the given number of times.
+func outboxBackoff(attempts int) time.Duration {
+	backoff := outboxBaseBackoff
+	for i := 1; i < attempts && backoff < outboxMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > outboxMaxBackoff {
+		return outboxMaxBackoff
+	}
+	return backoff
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded
+// or was issued for a different sort order.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// pageCursor is the decoded form of the opaque cursor handed to clients.
+// It holds the sort key and value of the last item of a page plus its ID,
+// which is used as a tie-breaker between items sharing the same value.
+type pageCursor struct {
+	Sort  string          `json:"s"`
+	Value json.RawMessage `json:"v"`
+	ID    uint            `json:"id"`
+}
+
+// encodeCursor builds an opaque cursor pointing right after the given item.
+func encodeCursor(sort string, value interface{}, id uint) (string, error) {
+	rawValue, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(pageCursor{Sort: sort, Value: rawValue, ID: id})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor decodes an opaque cursor and stores its sort value into value.
+// It fails if the cursor was issued for a different sort order.
+func decodeCursor(token string, sort string, value interface{}) (uint, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+
+	var cursor pageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return 0, ErrInvalidCursor
+	}
+	if cursor.Sort != sort || cursor.ID == 0 {
+		return 0, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(cursor.Value, value); err != nil {
+		return 0, ErrInvalidCursor
+	}
+
+	return cursor.ID, nil
+}
+
+// applyKeyset restricts the query to the rows that come after the cursor
+// position for an ORDER BY <column>, id clause in the given direction.
+// The column must never come from user input.
+func applyKeyset(query *gorm.DB, column string, desc bool, value interface{}, id uint) *gorm.DB {
+	op := ">"
+	if desc {
+		op = "<"
+	}
+	return query.Where(column+" "+op+" ? OR ("+column+" = ? AND id "+op+" ?)", value, value, id)
+}
+
+// keysetOrder returns the ORDER BY clause matching applyKeyset.
+func keysetOrder(column string, desc bool) string {
+	if desc {
+		return column + " DESC, id DESC"
+	}
+	return column + " ASC, id ASC"
+}
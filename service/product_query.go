@@ -0,0 +1,68 @@
+package service
+
+import (
+	"go_api_product_review/models"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// likeEscaper escapes the LIKE wildcards so user input is matched literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// categoryProductsFilter matches the products assigned to a category or one of its descendants.
+const categoryProductsFilter = `id IN (SELECT pc.product_id FROM product_categories pc
+	JOIN categories c ON c.id = pc.category_id
+	JOIN categories a ON ` + categorySubtreeCondition + `
+	WHERE a.id = ?)`
+
+// applyProductFilters adds the WHERE clauses for the filters set in the query.
+func applyProductFilters(db *gorm.DB, query models.ProductQuery) *gorm.DB {
+	if query.MinPrice != nil {
+		db = db.Where("price >= ?", *query.MinPrice)
+	}
+	if query.MaxPrice != nil {
+		db = db.Where("price <= ?", *query.MaxPrice)
+	}
+	if query.MinRating != nil {
+		db = db.Where("average_rating >= ?", *query.MinRating)
+	}
+	if query.CategoryID != nil {
+		db = db.Where(categoryProductsFilter, *query.CategoryID)
+	}
+	if name := strings.TrimSpace(query.Name); name != "" {
+		pattern := "%" + likeEscaper.Replace(strings.ToLower(name)) + "%"
+		db = db.Where(`LOWER(name) LIKE ? ESCAPE '\'`, pattern)
+	}
+	return db
+}
+
+// productSortValue returns the value of the sort key for a product,
+// which is stored in the cursor of the page ending with that product.
+func productSortValue(product models.Product, sortKey string) interface{} {
+	switch sortKey {
+	case "price":
+		return product.Price
+	case "average_rating":
+		return product.AverageRating
+	case "ranking_score":
+		return product.RankingScore
+	default:
+		return product.CreatedAt
+	}
+}
+
+// decodeProductCursor decodes a product listing cursor into the typed sort value
+// and the ID of the last product of the previous page.
+func decodeProductCursor(token string, sortKey string) (interface{}, uint, error) {
+	if sortKey == "created_at" {
+		var value time.Time
+		id, err := decodeCursor(token, sortKey, &value)
+		return value, id, err
+	}
+
+	var value float64
+	id, err := decodeCursor(token, sortKey, &value)
+	return value, id, err
+}
@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"errors"
 	"go_api_product_review/cache"
+	"go_api_product_review/events"
 	"go_api_product_review/models"
+	"go_api_product_review/screening"
 	"math"
 	"strconv"
 	"time"
@@ -14,18 +16,47 @@ import (
 	_ "github.com/jinzhu/gorm/dialects/postgres" // Import the PostgreSQL dialect for GORM
 )
 
+// ErrNotFound is returned when the requested record does not exist.
+var ErrNotFound = errors.New("record not found")
+
+// ErrReviewModerated is returned when a review is moderated while it is being changed.
+var ErrReviewModerated = errors.New("review was moderated while it was being changed")
+
 // CreateProduct creates a new product in the database.
 // Pass a mock or real DB instance as a parameter for testing.
 func CreateProduct(db *gorm.DB, product *models.Product) (*models.Product, error) {
-	result := db.Create(product)
-	if result.Error != nil {
-		return nil, result.Error
+	// New products have no reviews, whatever the payload says
+	product.AverageRating = 0
+	product.ReviewCount = 0
+	product.RatingSum = 0
+	product.RecentAverageRating = 0
+	product.RecentReviewCount = 0
+	product.DecayedAverageRating = 0
+	product.VerifiedAverageRating = 0
+	product.VerifiedReviewCount = 0
+	product.VerifiedRatingSum = 0
+	product.RankingScore = rankingScore(*product)
+	product.AspectRatings = nil
+
+	if err := checkCategories(db, productCategoryIDs(product)); err != nil {
+		return nil, err
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(product).Error; err != nil {
+			return err
+		}
+		return saveProductCategories(tx, product)
+	})
+	if err != nil {
+		return nil, err
 	}
 	return product, nil
 }
 
 // UpdateProduct updates an existing product in the database by ID.
 // It accepts an ID and an updated product object.
+// The categories of the product are replaced with the ones of the updated product.
+// It returns ErrUnknownCategory if the product is assigned a category that does not exist.
 // Returns the updated product or an error if the operation fails.
 func UpdateProduct(db *gorm.DB, id uint, updatedProduct *models.Product) (*models.Product, error) {
 	var product models.Product
@@ -36,11 +67,34 @@ func UpdateProduct(db *gorm.DB, id uint, updatedProduct *models.Product) (*model
 		return nil, result.Error
 	}
 
-	// Update product fields
-	product.Name = updatedProduct.Name
-	product.Description = updatedProduct.Description
-	product.Price = updatedProduct.Price
-	db.Save(&product)
+	if err := checkCategories(db, productCategoryIDs(updatedProduct)); err != nil {
+		return nil, err
+	}
+
+	// Update the product fields only, review changes maintain the rating aggregates concurrently
+	product.CategoryIDs = updatedProduct.CategoryIDs
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&product).Updates(map[string]interface{}{
+			"name":        updatedProduct.Name,
+			"description": updatedProduct.Description,
+			"price":       updatedProduct.Price,
+			"category_id": updatedProduct.CategoryID,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return saveProductCategories(tx, &product)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Drop the cached representation so the next read reloads it
+	err = cache.Rdb.Del(cache.Ctx, productCacheKey(id)).Err()
+	if err != nil {
+		return nil, err
+	}
+
 	return &product, nil
 }
 
@@ -50,7 +104,8 @@ func UpdateProduct(db *gorm.DB, id uint, updatedProduct *models.Product) (*model
 func DeleteProduct(db *gorm.DB, id uint) error {
 	result := db.Delete(&models.Product{}, id)
 	key := "product:" + strconv.Itoa(int(id)) + ":average_rating"
-	err := cache.Rdb.Del(cache.Ctx, key).Err()
+	keys := append([]string{key, productCacheKey(id), ratingSummaryCacheKey(id)}, ratingTrendCacheKeys(id)...)
+	err := cache.Rdb.Del(cache.Ctx, keys...).Err()
 	if err != nil {
 		return err
 	}
@@ -58,175 +113,539 @@ func DeleteProduct(db *gorm.DB, id uint) error {
 	return result.Error
 }
 
-// GetProductByID retrieves a product's average rating by its ID.
-// First, it checks the cache for the average rating.
-// If not found, it calculates the rating, updates the cache, and returns the value.
-func GetProductByID(db *gorm.DB, id uint) (float64, error) {
+// GetProductByID retrieves a product by its ID along with its average rating.
+// The product record is read through the Redis cache, so only cache misses hit the database.
+// The average rating comes from its own cache entry; if not found, it is read from the
+// product row, which review changes keep up to date, and cached again.
+// Reviews are embedded in the returned detail only when includeReviews is set.
+// It returns ErrNotFound if the product does not exist.
+func GetProductByID(db *gorm.DB, id uint, includeReviews bool) (*models.ProductDetail, error) {
 	// Attempt to get the cached average rating
-	avgRating, err := GetCachedProductAverageRating(id)
+	avgRating, found, err := lookupCachedProductAverageRating(id)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	// If no cached value, calculate the average rating from reviews
-	if avgRating == 0 {
-		var product models.Product
-		result := db.First(&product, id)
+	// If no cached value, read it from the product and cache it
+	if !found {
+		var stored models.Product
+		result := db.Select("id, average_rating").First(&stored, id)
+		if gorm.IsRecordNotFoundError(result.Error) {
+			return nil, ErrNotFound
+		}
 		if result.Error != nil {
-			return 0, result.Error
+			return nil, result.Error
 		}
+		avgRating = stored.AverageRating
 
-		// Recalculate and update the cached average rating
-		err := UpdateProductAverageRating(db, id)
+		err := CacheProductAverageRating(id, avgRating)
 		if err != nil {
-			return 0, err
+			return nil, err
 		}
+	}
 
-		// Fetch the newly cached average rating
-		avgRating, err = GetCachedProductAverageRating(id)
-		if err != nil {
-			return avgRating, err
-		}
+	product, err := getCachedProduct(db, id)
+	if err != nil {
+		return nil, err
 	}
 
 	// Handle case where the rating is not available
 	if math.IsNaN(avgRating) {
-		return 0, errors.New("Error: Product average rating is not available")
+		return nil, errors.New("Error: Product average rating is not available")
+	}
+	product.AverageRating = avgRating
+
+	if includeReviews {
+		var reviews []models.Review
+		result := preloadReviewDetails(db, "").
+			Where("product_id = ? AND status = ?", id, models.ReviewApproved).
+			Order("created_at DESC").
+			Find(&reviews)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		product.Reviews = make([]models.ReviewResponse, 0, len(reviews))
+		for _, review := range reviews {
+			product.Reviews = append(product.Reviews, models.NewReviewResponse(review))
+		}
+	}
+
+	return product, nil
+}
+
+// getCachedProduct retrieves the product from the cache or the database if not found in cache.
+// The cached representation never embeds reviews.
+func getCachedProduct(db *gorm.DB, id uint) (*models.ProductDetail, error) {
+	// Check the Redis cache first
+	key := productCacheKey(id)
+	productJSON, err := cache.Rdb.Get(cache.Ctx, key).Result()
+	if err == nil {
+		var detail models.ProductDetail
+		if err := json.Unmarshal([]byte(productJSON), &detail); err != nil {
+			return nil, err
+		}
+		return &detail, nil
+	}
+	if err != redis.Nil {
+		return nil, err // Return error if there is a Redis issue
+	}
+
+	// Product not found in cache, query the database
+	var product models.Product
+	result := preloadProductAspectRatings(db).First(&product, id)
+	if gorm.IsRecordNotFoundError(result.Error) {
+		return nil, ErrNotFound
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	products := []models.Product{product}
+	if err := loadProductCategoryIDs(db, products); err != nil {
+		return nil, err
 	}
 
-	return avgRating, nil
+	detail := models.NewProductDetail(products[0])
+
+	// Store the JSON representation in Redis
+	detailJSON, err := json.Marshal(detail)
+	if err != nil {
+		return nil, err
+	}
+	err = cache.Rdb.Set(cache.Ctx, key, detailJSON, 10*time.Minute).Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return &detail, nil
 }
 
-// ListProducts retrieves all products along with their reviews.
-// It returns a list of products or an error if the operation fails.
-func ListProducts(db *gorm.DB) ([]models.Product, error) {
+// productCacheKey returns the Redis key of the cached product representation.
+func productCacheKey(id uint) string {
+	return "product:" + strconv.Itoa(int(id))
+}
+
+// ListProducts retrieves a page of product summaries.
+// Reviews are embedded only when the query asks for them. It applies the filters and sort order of the query and uses keyset pagination,
+// so the cursor of the returned page can be passed back to fetch the next one.
+// It returns the page or an error if the operation fails.
+func ListProducts(db *gorm.DB, query models.ProductQuery) (*models.ProductPage, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+	sortKey, desc := query.SortKey()
+
+	filtered := applyProductFilters(db.Model(&models.Product{}), query)
+	page := &models.ProductPage{Items: []models.ProductSummary{}}
+
+	// Count the matching products before the cursor restricts the result set
+	if query.IncludeTotal {
+		var total int64
+		if err := filtered.Count(&total).Error; err != nil {
+			return nil, err
+		}
+		page.Total = &total
+	}
+
+	paged := filtered
+	if query.Cursor != "" {
+		value, id, err := decodeProductCursor(query.Cursor, sortKey)
+		if err != nil {
+			return nil, err
+		}
+		paged = applyKeyset(paged, sortKey, desc, value, id)
+	}
+
+	if query.IncludeReviews() {
+		paged = preloadReviewDetails(paged.Preload("Reviews", "status = ?", models.ReviewApproved), "Reviews.")
+	}
+
+	// Fetch one extra row to know whether there is a next page
 	var products []models.Product
-	result := db.Preload("Reviews").Find(&products)
+	result := preloadProductAspectRatings(paged).Order(keysetOrder(sortKey, desc)).Limit(query.Limit + 1).Find(&products)
 	if result.Error != nil {
 		return nil, result.Error
 	}
-	// Ensure AverageRating is not NaN
-	for i := range products {
-		if math.IsNaN(products[i].AverageRating) {
-			products[i].AverageRating = 0 // Set to 0 if NaN
+
+	if len(products) > query.Limit {
+		products = products[:query.Limit]
+		last := products[len(products)-1]
+		cursor, err := encodeCursor(sortKey, productSortValue(last, sortKey), last.ID)
+		if err != nil {
+			return nil, err
 		}
+		page.NextCursor = cursor
 	}
-	return products, nil
+	if err := loadProductCategoryIDs(db, products); err != nil {
+		return nil, err
+	}
+
+	for _, product := range products {
+		// Ensure AverageRating is not NaN
+		if math.IsNaN(product.AverageRating) {
+			product.AverageRating = 0 // Set to 0 if NaN
+		}
+		page.Items = append(page.Items, models.NewProductSummary(product))
+	}
+	return page, nil
 }
 
-// UpdateProductAverageRating recalculates the average rating of a product
-// based on its reviews and updates both the product record and the cache.
+// UpdateProductAverageRating recalculates the rating aggregates of a product
+// from its reviews, stores them if they drifted and caches the average rating.
+// Review changes keep the aggregates up to date, so this is only needed to repair them;
+// the decayed sums are left to the background reconciliation unless their reference is stale.
+// It returns ErrNotFound if the product does not exist.
 func UpdateProductAverageRating(db *gorm.DB, productID uint) error {
-	var product models.Product
-	var reviews []models.Review
-
-	db.First(&product, productID)
-	db.Where("product_id = ?", productID).Find(&reviews)
-
-	// Calculate the total rating from reviews
-	var totalRating int
-	for _, review := range reviews {
-		totalRating += review.Rating
+	var drift *RatingDrift
+	var averageRating float64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		drift, averageRating, err = reconcileProductRating(tx, productID, false)
+		return err
+	})
+	if err != nil {
+		return err
 	}
 
-	averageRating := 0.0
-	// Calculate the average rating
-	if totalRating != 0 && len(reviews) != 0 {
-		averageRating = float64(totalRating) / float64(len(reviews))
+	// The cached product holds the aggregates too
+	if drift != nil {
+		return refreshProductCaches(productID, averageRating)
 	}
 
 	// Cache the updated average rating
+	return CacheProductAverageRating(productID, averageRating)
+}
+
+// refreshProductCaches updates the caches of a product after one of its reviews changed:
+// it stores the new average rating and drops the cached product, which holds the
+// review count, and rating summary and trends, along with every cached page of its reviews.
+func refreshProductCaches(productID uint, averageRating float64) error {
 	err := CacheProductAverageRating(productID, averageRating)
 	if err != nil {
 		return err
 	}
 
-	// Update the product record with the new average rating
-	product.AverageRating = averageRating
-	db.Save(&product)
+	keys := append([]string{productCacheKey(productID), ratingSummaryCacheKey(productID)}, ratingTrendCacheKeys(productID)...)
+	err = cache.Rdb.Del(cache.Ctx, keys...).Err()
+	if err != nil {
+		return err
+	}
 
-	return nil
+	return invalidateProductReviewPages(productID)
 }
 
-// CreateReview creates a new review for a product and updates the product's average rating.
-// It accepts a review object, saves it to the database, and recalculates the product's average rating.
+// CreateReview creates a new review for a product and adds it to the product's rating aggregates if it is approved.
+// Reviews without a status are approved, the API creates pending reviews.
+// The review is checked by screenReview, checkDuplicates and checkAspectRatings first, whose errors it returns.
 func CreateReview(db *gorm.DB, review *models.Review) (*models.Review, error) {
-	result := db.Create(review)
-	if result.Error != nil {
-		return nil, result.Error
+	if review.Status == "" {
+		review.Status = models.ReviewApproved
 	}
+	err := screenReview(review)
+	if err != nil {
+		return nil, err
+	}
+	review.TextSignature = screening.MinHash(review.ReviewText).String()
 
-	// Cache the new review
-	err := CacheReview(review)
+	// The review, the new rating and their events are written in a single transaction,
+	// the caches are updated once it is committed
+	var averageRating float64
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := checkDuplicates(tx, review)
+		if err != nil {
+			return err
+		}
+
+		err = checkAspectRatings(tx, review.ProductID, review.AspectRatings)
+		if err != nil {
+			return err
+		}
+
+		// Reviews of authors who bought the product are verified purchases
+		review.Verified, err = hasPurchased(tx, review.AuthorSubject, review.ProductID)
+		if err != nil {
+			return err
+		}
+
+		result := tx.Create(review)
+		if result.Error != nil {
+			return result.Error
+		}
+		err = saveAspectRatings(tx, review)
+		if err != nil {
+			return err
+		}
+		err = saveTextBands(tx, review)
+		if err != nil {
+			return err
+		}
+
+		err = enqueueEvent(tx, events.ReviewCreated, review.ProductID, models.NewReviewResponse(*review))
+		if err != nil {
+			return err
+		}
+
+		// Add the review to the rating aggregates of the product, once approved
+		if review.Status != models.ReviewApproved {
+			return nil
+		}
+		averageRating, err = applyRatingDelta(tx, review.ProductID, reviewAdded(*review))
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Update the product's average rating after the review is added
-	err = UpdateProductAverageRating(db, review.ProductID)
+	// Cache the new review
+	err = CacheReview(review)
 	if err != nil {
 		return nil, err
 	}
 
+	if review.Status == models.ReviewApproved {
+		err = refreshProductCaches(review.ProductID, averageRating)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return review, nil
 }
 
-// UpdateReview updates an existing review for a product by its ID.
-// It accepts a review ID and the updated review data, saves the updated review,
-// and recalculates the product's average rating.
-func UpdateReview(db *gorm.DB, id uint, updatedReview *models.Review) (*models.Review, error) {
-	var review models.Review
-	result := db.First(&review, id)
-	if result.Error != nil {
-		return nil, result.Error
+// UpdateReview updates an existing review for a product by its ID and applies the rating change
+// to the product's rating aggregates in the same transaction if the review is approved.
+// The update is checked as in CreateReview, a suspicious text takes the review down for moderation.
+// The review as it was before the update is recorded as a revision made by editor.
+// It returns ErrReviewModerated if the review is moderated while it is being updated.
+func UpdateReview(db *gorm.DB, id uint, updatedReview *models.Review, editor string) (*models.Review, error) {
+	screened := models.Review{ReviewText: updatedReview.ReviewText, Pros: updatedReview.Pros, Cons: updatedReview.Cons}
+	err := screenReview(&screened)
+	if err != nil {
+		return nil, err
 	}
 
-	// Update review fields
-	review.FirstName = updatedReview.FirstName
-	review.LastName = updatedReview.LastName
-	review.ReviewText = updatedReview.ReviewText
-	review.Rating = updatedReview.Rating
+	var review models.Review
+	var averageRating float64
+	var ratingsChanged bool
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// The rating change is computed from the review as read, keep concurrent edits out until it is applied
+		result := lockReview(tx, &review, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		var err error
+		review.AspectRatings, err = findAspectRatings(tx, review.ID)
+		if err != nil {
+			return err
+		}
+		err = checkAspectRatings(tx, review.ProductID, updatedReview.AspectRatings)
+		if err != nil {
+			return err
+		}
 
-	db.Save(&review)
+		previous := review
+		if updatedReview.ReviewText != review.ReviewText {
+			screened.ID = review.ID
+			screened.ProductID = review.ProductID
+			err := checkDuplicates(tx, &screened)
+			if err != nil {
+				return err
+			}
+		}
+		err = recordRevision(tx, previous, models.RevisionUpdate, editor)
+		if err != nil {
+			return err
+		}
 
-	err := CacheReview(&review)
+		// Update the edited fields only: the status, the verified badge and the vote tallies
+		// are changed concurrently by moderators, purchase imports and vote persistence
+		review.FirstName = updatedReview.FirstName
+		review.LastName = updatedReview.LastName
+		review.ReviewText = updatedReview.ReviewText
+		review.TextSignature = screening.MinHash(review.ReviewText).String()
+		review.Rating = updatedReview.Rating
+		review.Pros = updatedReview.Pros
+		review.Cons = updatedReview.Cons
+		review.AspectRatings = updatedReview.AspectRatings
+		columns := map[string]interface{}{
+			"first_name":     review.FirstName,
+			"last_name":      review.LastName,
+			"review_text":    review.ReviewText,
+			"text_signature": review.TextSignature,
+			"rating":         review.Rating,
+			"pros":           review.Pros,
+			"cons":           review.Cons,
+		}
+		if screened.Status == models.ReviewFlagged && models.CanTransition(review.Status, models.ReviewFlagged) {
+			review.Status = screened.Status
+			review.ModerationReason = screened.ModerationReason
+			review.ModeratedBy = screened.ModeratedBy
+			review.ModeratedAt = screened.ModeratedAt
+			columns["status"] = review.Status
+			columns["moderation_reason"] = review.ModerationReason
+			columns["moderated_by"] = review.ModeratedBy
+			columns["moderated_at"] = review.ModeratedAt
+		}
+
+		// Only update the review in the status it was read with: the rating change below
+		// depends on it, and a moderation committed meanwhile must not be undone
+		result = tx.Model(&models.Review{}).Where("id = ? AND status = ?", review.ID, previous.Status).Updates(columns)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return reviewChangedError(tx, review.ID)
+		}
+		err = saveAspectRatings(tx, &review)
+		if err != nil {
+			return err
+		}
+		err = saveTextBands(tx, &review)
+		if err != nil {
+			return err
+		}
+
+		err = enqueueEvent(tx, events.ReviewUpdated, review.ProductID, models.NewReviewResponse(review))
+		if err != nil {
+			return err
+		}
+
+		// Apply the rating change to the rating aggregates of the product,
+		// or remove the review from them if the screening took it down
+		if previous.Status != models.ReviewApproved {
+			return nil
+		}
+		delta := reviewRated(previous, review)
+		if review.Status != models.ReviewApproved {
+			delta = reviewRemoved(previous)
+		}
+		ratingsChanged = true
+		averageRating, err = applyRatingDelta(tx, review.ProductID, delta)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Recalculate the product's average rating after the review is updated
-	err = UpdateProductAverageRating(db, review.ProductID)
+	// The cached review embeds its replies and media
+	err = loadReviewDetails(db, &review)
 	if err != nil {
 		return nil, err
 	}
+	err = CacheReview(&review)
+	if err != nil {
+		return nil, err
+	}
+
+	if ratingsChanged {
+		err = refreshProductCaches(review.ProductID, averageRating)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	return &review, nil
 }
 
-// DeleteReview deletes an existing review by its ID and recalculates the product's average rating.
-// It accepts a review ID, deletes the review, and updates the product's rating accordingly.
-func DeleteReview(db *gorm.DB, id uint) error {
+// DeleteReview deletes an existing review by its ID and updates the product's average rating.
+// It accepts a review ID, deletes the review, and removes it from the product's rating aggregates
+// in the same transaction if it was approved.
+// The deleted review is recorded as a revision made by editor, so administrators can restore it.
+// It returns ErrNotFound if the review does not exist or was deleted meanwhile,
+// and ErrReviewModerated if it is moderated while it is being deleted.
+func DeleteReview(db *gorm.DB, id uint, editor string) error {
 	var review models.Review
-	result := db.First(&review, id)
-	if result.Error != nil {
-		return result.Error
-	}
+	var averageRating float64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// The review is removed from the aggregates according to its status as read, keep moderations out until then
+		result := lockReview(tx, &review, id)
+		if gorm.IsRecordNotFoundError(result.Error) {
+			return ErrNotFound
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		var err error
+		review.AspectRatings, err = findAspectRatings(tx, review.ID)
+		if err != nil {
+			return err
+		}
 
-	// Delete the review from the database
-	db.Delete(&review)
+		err = recordRevision(tx, review, models.RevisionDelete, editor)
+		if err != nil {
+			return err
+		}
 
-	// Remove the review from the Redis cache
-	err := cache.Rdb.Del(cache.Ctx, "review:"+strconv.Itoa(int(id))).Err()
+		// Delete the review from the database, along with its replies and text bands. A concurrent delete
+		// or moderation of the review matches no row, its change to the aggregates already applies
+		result = tx.Where("status = ?", review.Status).Delete(&review)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return reviewChangedError(tx, review.ID)
+		}
+		result = tx.Where("review_id = ?", review.ID).Delete(&models.ReviewReply{})
+		if result.Error != nil {
+			return result.Error
+		}
+		// Deleted reviews are no longer duplicate candidates, restoring the review signs its text again
+		result = tx.Where("review_id = ?", review.ID).Delete(&models.ReviewTextBand{})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		err = enqueueEvent(tx, events.ReviewDeleted, review.ProductID, models.NewReviewResponse(review))
+		if err != nil {
+			return err
+		}
+
+		// Remove the review from the rating aggregates of the product
+		if review.Status != models.ReviewApproved {
+			return nil
+		}
+		averageRating, err = applyRatingDelta(tx, review.ProductID, reviewRemoved(review))
+		return err
+	})
 	if err != nil {
 		return err
 	}
 
-	err = UpdateProductAverageRating(db, review.ProductID)
+	// Remove the review and its live vote tally from the Redis cache
+	helpfulKey, unhelpfulKey := voteTallyCacheKeys(id)
+	err = cache.Rdb.Del(cache.Ctx, "review:"+strconv.Itoa(int(id)), helpfulKey, unhelpfulKey).Err()
 	if err != nil {
 		return err
 	}
 
-	return nil
+	if review.Status != models.ReviewApproved {
+		return nil
+	}
+	return refreshProductCaches(review.ProductID, averageRating)
+}
+
+// reviewChangedError returns the error of a review change that matched no row because the review
+// was changed meanwhile: ErrNotFound if the review was deleted, ErrReviewModerated otherwise.
+func reviewChangedError(tx *gorm.DB, id uint) error {
+	var review models.Review
+	result := tx.Select("id").First(&review, id)
+	if gorm.IsRecordNotFoundError(result.Error) {
+		return ErrNotFound
+	}
+	if result.Error != nil {
+		return result.Error
+	}
+	return ErrReviewModerated
+}
+
+// lockReview reads a review and, on PostgreSQL, locks its row until the end of the transaction,
+// so the review cannot change between the read and the rating deltas computed from it.
+// It must run inside a transaction.
+func lockReview(tx *gorm.DB, review *models.Review, id uint) *gorm.DB {
+	query := tx
+	if tx.Dialect().GetName() == "postgres" {
+		query = tx.Set("gorm:query_option", "FOR UPDATE")
+	}
+	return query.First(review, id)
 }
 
 // CacheProductAverageRating caches the average rating of a product in Redis.
@@ -240,20 +659,32 @@ func CacheProductAverageRating(productID uint, rating float64) error {
 // GetCachedProductAverageRating retrieves the cached average rating of a product from Redis.
 // It returns the cached rating if available or zero if it's a cache miss.
 func GetCachedProductAverageRating(productID uint) (float64, error) {
+	rating, _, err := lookupCachedProductAverageRating(productID)
+	return rating, err
+}
+
+// lookupCachedProductAverageRating retrieves the cached average rating of a product from Redis
+// and reports whether it was found, so a cached zero rating can be told apart from a cache miss.
+func lookupCachedProductAverageRating(productID uint) (float64, bool, error) {
 	key := "product:" + strconv.Itoa(int(productID)) + ":average_rating"
 	cachedRating, err := cache.Rdb.Get(cache.Ctx, key).Result()
 	if err == redis.Nil {
-		return 0, nil // Cache miss, rating not found
+		return 0, false, nil // Cache miss, rating not found
 	}
 	if err != nil {
-		return 0, err
+		return 0, false, err
 	}
 
 	// Convert cached rating to float64
-	return strconv.ParseFloat(cachedRating, 64)
+	rating, err := strconv.ParseFloat(cachedRating, 64)
+	if err != nil {
+		return 0, false, err
+	}
+	return rating, true, nil
 }
 
 // GetReview retrieves a review from the cache or database if not found in cache.
+// It returns ErrNotFound if the review does not exist.
 func GetReview(db *gorm.DB, id uint) (*models.Review, error) {
 	// Check the Redis cache first
 	reviewKey := "review:" + strconv.Itoa(int(id))
@@ -262,22 +693,21 @@ func GetReview(db *gorm.DB, id uint) (*models.Review, error) {
 	if err == redis.Nil {
 		// Review not found in cache, query the database
 		var review models.Review
-		result := db.First(&review, id)
+		result := preloadReviewDetails(db, "").First(&review, id)
+		if gorm.IsRecordNotFoundError(result.Error) {
+			return nil, ErrNotFound
+		}
 		if result.Error != nil {
-			return nil, result.Error // Return error if not found
+			return nil, result.Error // Return error if the query fails
 		}
 
 		// Cache the new review
-		reviewJSON, err := json.Marshal(review)
-		if err != nil {
-			return nil, err
-		}
-
-		// Store the JSON string in Redis
-		err = cache.Rdb.Set(cache.Ctx, reviewKey, reviewJSON, 10*time.Minute).Err()
+		err := CacheReview(&review)
 		if err != nil {
 			return nil, err // Return error if Redis store fails
 		}
+
+		return &review, nil
 	} else if err != nil {
 		return nil, err // Return error if there is a Redis issue
 	}
@@ -308,3 +738,48 @@ func CacheReview(review *models.Review) error {
 
 	return nil
 }
+
+// preloadReviewDetails makes the query load the aspect ratings, replies and media of the reviews it finds,
+// which the reads of the reviews embed. prefix is the path of the reviews from the queried model,
+// empty when querying reviews and "Reviews." when querying products.
+func preloadReviewDetails(db *gorm.DB, prefix string) *gorm.DB {
+	db = preloadAspectRatings(db, prefix+"AspectRatings")
+	return preloadMedia(preloadReplies(db, prefix+"Replies"), prefix+"Media")
+}
+
+// loadReviewDetails loads the aspect ratings, replies and media of a review, which the reads of the review embed.
+func loadReviewDetails(db *gorm.DB, review *models.Review) error {
+	aspectRatings, err := findAspectRatings(db, review.ID)
+	if err != nil {
+		return err
+	}
+	replies, err := findReplies(db, review.ID)
+	if err != nil {
+		return err
+	}
+	media, err := findMedia(db, review.ID)
+	if err != nil {
+		return err
+	}
+	review.AspectRatings, review.Replies, review.Media = aspectRatings, replies, media
+	return nil
+}
+
+// refreshCachedReview caches a review again with its current aspect ratings, replies and media under its review key,
+// and drops the cached pages of the reviews of its product, which embed them.
+func refreshCachedReview(db *gorm.DB, reviewID uint) error {
+	var review models.Review
+	result := preloadReviewDetails(db, "").First(&review, reviewID)
+	if gorm.IsRecordNotFoundError(result.Error) {
+		return nil // The review was deleted meanwhile
+	}
+	if result.Error != nil {
+		return result.Error
+	}
+
+	err := CacheReview(&review)
+	if err != nil {
+		return err
+	}
+	return invalidateProductReviewPages(review.ProductID)
+}
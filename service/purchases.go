@@ -0,0 +1,119 @@
+package service
+
+import (
+	"go_api_product_review/models"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// hasPurchased reports whether a customer bought a product.
+// Anonymous authors never did.
+func hasPurchased(db *gorm.DB, customerID string, productID uint) (bool, error) {
+	if customerID == "" {
+		return false, nil
+	}
+	var count int
+	result := db.Model(&models.Purchase{}).Where("customer_id = ? AND product_id = ?", customerID, productID).Count(&count)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return count > 0, nil
+}
+
+// ImportPurchases records purchases ingested from the order system, skipping the ones already recorded.
+// The existing reviews of the customers on the bought products are marked as verified purchases,
+// and the approved ones are added to the verified purchase aggregates of their product in the same
+// transaction; the caches are updated once it is committed.
+func ImportPurchases(db *gorm.DB, purchases []models.Purchase) (*models.PurchaseImportResult, error) {
+	type customerProduct struct {
+		customerID string
+		productID  uint
+	}
+
+	result := &models.PurchaseImportResult{}
+	var verified []models.Review
+	averageRatings := map[uint]float64{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		bought := map[customerProduct]bool{}
+		now := time.Now()
+		for _, purchase := range purchases {
+			if purchase.PurchasedAt.IsZero() {
+				purchase.PurchasedAt = now
+			}
+			purchase.ID = 0
+			// Concurrent imports of the same order record each purchase once
+			inserted, err := createIfAbsent(tx, &purchase)
+			if err != nil {
+				return err
+			}
+			if !inserted {
+				result.Skipped++
+				continue
+			}
+			result.Recorded++
+			bought[customerProduct{purchase.CustomerID, purchase.ProductID}] = true
+		}
+
+		// Lock the reviews, so their status stays the one the verified aggregates are changed for
+		locked := tx
+		if tx.Dialect().GetName() == "postgres" {
+			locked = tx.Set("gorm:query_option", "FOR UPDATE")
+		}
+		for key := range bought {
+			var reviews []models.Review
+			query := locked.Where("author_subject = ? AND product_id = ? AND verified = ?", key.customerID, key.productID, false).
+				Find(&reviews)
+			if query.Error != nil {
+				return query.Error
+			}
+
+			for _, review := range reviews {
+				// A concurrent import that verified the review meanwhile matches no row
+				query = tx.Model(&review).Where("verified = ?", false).UpdateColumn("verified", true)
+				if query.Error != nil {
+					return query.Error
+				}
+				if query.RowsAffected == 0 {
+					continue
+				}
+				verified = append(verified, review)
+
+				// Only approved reviews count toward the ratings of the product
+				if review.Status != models.ReviewApproved {
+					continue
+				}
+				averageRating, err := applyRatingDelta(tx, review.ProductID, reviewVerified(review))
+				if err != nil {
+					return err
+				}
+				averageRatings[review.ProductID] = averageRating
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.VerifiedReviews = len(verified)
+
+	// The cached reviews embed their replies and media
+	for _, review := range verified {
+		err = loadReviewDetails(db, &review)
+		if err != nil {
+			return nil, err
+		}
+		err = CacheReview(&review)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for productID, averageRating := range averageRatings {
+		err = refreshProductCaches(productID, averageRating)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
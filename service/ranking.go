@@ -0,0 +1,225 @@
+package service
+
+import (
+	"fmt"
+	"go_api_product_review/cache"
+	"go_api_product_review/models"
+	"math"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Ranking methods
+const (
+	// RankingBayesian ranks products by their average rating pulled toward a prior mean,
+	// as if every product had PriorWeight extra reviews rated at the prior mean
+	RankingBayesian = "bayesian"
+	// RankingWilson ranks products by the lower bound of the Wilson score interval
+	// of the share of positive reviews
+	RankingWilson = "wilson"
+)
+
+// RankingConfig configures the ranking score of the products.
+type RankingConfig struct {
+	// Method is RankingBayesian or RankingWilson
+	Method string
+	// PriorWeight is the number of virtual reviews of the Bayesian average
+	PriorWeight float64
+	// PriorMean is the rating of the virtual reviews, the catalog-wide mean rating when 0
+	PriorMean float64
+	// Confidence is the z-score of the Wilson interval, 1.96 for 95%
+	Confidence float64
+	// PositiveRating is the lowest rating counted as positive by the Wilson bound
+	PositiveRating int
+}
+
+// DefaultRankingConfig returns a Bayesian average with 10 virtual reviews at the catalog-wide mean.
+func DefaultRankingConfig() RankingConfig {
+	return RankingConfig{
+		Method:         RankingBayesian,
+		PriorWeight:    10,
+		Confidence:     1.96,
+		PositiveRating: 4,
+	}
+}
+
+// LoadRankingConfigFromEnv builds the ranking configuration from environment variables,
+// starting from DefaultRankingConfig:
+//   - RANKING_METHOD: bayesian or wilson
+//   - RANKING_PRIOR_WEIGHT, RANKING_PRIOR_MEAN: virtual reviews of the Bayesian average
+//   - RANKING_CONFIDENCE, RANKING_POSITIVE_RATING: z-score and positive threshold of the Wilson bound
+func LoadRankingConfigFromEnv() (RankingConfig, error) {
+	config := DefaultRankingConfig()
+	if method := os.Getenv("RANKING_METHOD"); method != "" {
+		config.Method = method
+	}
+
+	floats := map[string]*float64{
+		"RANKING_PRIOR_WEIGHT": &config.PriorWeight,
+		"RANKING_PRIOR_MEAN":   &config.PriorMean,
+		"RANKING_CONFIDENCE":   &config.Confidence,
+	}
+	for name, target := range floats {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return config, fmt.Errorf("invalid %s: %w", name, err)
+			}
+			*target = parsed
+		}
+	}
+	if value := os.Getenv("RANKING_POSITIVE_RATING"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid RANKING_POSITIVE_RATING: %w", err)
+		}
+		config.PositiveRating = parsed
+	}
+
+	return config, config.Validate()
+}
+
+// Validate checks the ranking configuration.
+func (c RankingConfig) Validate() error {
+	switch c.Method {
+	case RankingBayesian:
+		if c.PriorWeight < 0 {
+			return fmt.Errorf("ranking prior weight must not be negative")
+		}
+		if c.PriorMean != 0 && (c.PriorMean < 1 || c.PriorMean > 5) {
+			return fmt.Errorf("ranking prior mean must be between 1 and 5")
+		}
+	case RankingWilson:
+		if c.Confidence <= 0 {
+			return fmt.Errorf("ranking confidence must be greater than 0")
+		}
+		if c.PositiveRating < 1 || c.PositiveRating > 5 {
+			return fmt.Errorf("ranking positive rating must be between 1 and 5")
+		}
+	default:
+		return fmt.Errorf("ranking method must be %s or %s", RankingBayesian, RankingWilson)
+	}
+	return nil
+}
+
+// Score returns the ranking score of a product from its rating aggregates.
+// catalogMean is the prior of the Bayesian average when PriorMean is not set.
+func (c RankingConfig) Score(product models.Product, catalogMean float64) float64 {
+	if c.Method == RankingWilson {
+		n := float64(product.ReviewCount)
+		if n <= 0 {
+			return 0
+		}
+		positive := 0
+		for rating, count := range product.RatingCounts() {
+			if rating+1 >= c.PositiveRating {
+				positive += count
+			}
+		}
+		return wilsonLowerBound(float64(positive), n, c.Confidence)
+	}
+
+	prior := c.PriorMean
+	if prior == 0 {
+		prior = catalogMean
+	}
+	weight := c.PriorWeight + float64(product.ReviewCount)
+	if weight == 0 {
+		return 0
+	}
+	return (c.PriorWeight*prior + float64(product.RatingSum)) / weight
+}
+
+// wilsonLowerBound returns the lower bound of the Wilson score interval at the z-score
+// of the share of positive outcomes among n, 0 when n is 0.
+func wilsonLowerBound(positive float64, n float64, z float64) float64 {
+	if n <= 0 {
+		return 0
+	}
+	p := positive / n
+	return (p + z*z/(2*n) - z*math.Sqrt((p*(1-p)+z*z/(4*n))/n)) / (1 + z*z/n)
+}
+
+// ranking holds the ranking configuration and the catalog-wide mean rating
+// it was last computed with, shared by every score computation.
+var ranking = struct {
+	sync.RWMutex
+	config      RankingConfig
+	catalogMean float64
+}{config: DefaultRankingConfig(), catalogMean: 3}
+
+// InitRanking sets the ranking configuration. Scores computed before keep their value
+// until the next RefreshRankingScores.
+func InitRanking(config RankingConfig) {
+	ranking.Lock()
+	defer ranking.Unlock()
+	ranking.config = config
+}
+
+// rankingScore returns the ranking score of a product with the current configuration.
+func rankingScore(product models.Product) float64 {
+	ranking.RLock()
+	defer ranking.RUnlock()
+	return ranking.config.Score(product, ranking.catalogMean)
+}
+
+// RefreshRankingScores recomputes the catalog-wide mean rating and the ranking score of every
+// product, storing the scores that changed. It returns the number of products updated.
+// Review changes keep the score of their product up to date, this is needed when the catalog
+// mean moves or the configuration changes.
+func RefreshRankingScores(db *gorm.DB) (int, error) {
+	var sum, count int64
+	row := db.Model(&models.Product{}).Select("COALESCE(SUM(rating_sum), 0), COALESCE(SUM(review_count), 0)").Row()
+	if err := row.Scan(&sum, &count); err != nil {
+		return 0, err
+	}
+	if count > 0 {
+		ranking.Lock()
+		ranking.catalogMean = float64(sum) / float64(count)
+		ranking.Unlock()
+	}
+
+	updated := 0
+	var lastID uint
+	for {
+		var products []models.Product
+		result := db.Where("id > ?", lastID).Order("id ASC").Limit(500).Find(&products)
+		if result.Error != nil {
+			return updated, result.Error
+		}
+		if len(products) == 0 {
+			return updated, nil
+		}
+
+		for _, product := range products {
+			lastID = product.ID
+			score := rankingScore(product)
+			if score == product.RankingScore {
+				continue
+			}
+
+			// Skip products whose aggregates changed meanwhile, their review change stored a fresh score
+			query := db.Model(&models.Product{}).
+				Where("id = ? AND review_count = ? AND rating_sum = ?", product.ID, product.ReviewCount, product.RatingSum)
+			for rating, ratingCount := range product.RatingCounts() {
+				query = query.Where(models.RatingCountColumn(rating+1)+" = ?", ratingCount)
+			}
+			result := query.UpdateColumn("ranking_score", score)
+			if result.Error != nil {
+				return updated, result.Error
+			}
+			if result.RowsAffected == 0 {
+				continue
+			}
+
+			// The cached product holds the score too
+			if err := cache.Rdb.Del(cache.Ctx, productCacheKey(product.ID)).Err(); err != nil {
+				return updated, err
+			}
+			updated++
+		}
+	}
+}
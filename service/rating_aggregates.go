@@ -0,0 +1,602 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_api_product_review/events"
+	"go_api_product_review/models"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// averageRatingExpr computes the average rating from the review_count and rating_sum
+// columns after adding the deltas passed as count, sum and count parameters.
+// UPDATE evaluates every assignment against the previous row, so it can be used
+// alongside the assignments of review_count and rating_sum.
+const averageRatingExpr = "CASE WHEN review_count + ? > 0 THEN CAST(rating_sum + ? AS FLOAT) / (review_count + ?) ELSE 0 END"
+
+// verifiedAverageRatingExpr computes the average rating of the reviews of verified purchases alike,
+// from the verified_review_count and verified_rating_sum columns.
+const verifiedAverageRatingExpr = "CASE WHEN verified_review_count + ? > 0 " +
+	"THEN CAST(verified_rating_sum + ? AS FLOAT) / (verified_review_count + ?) ELSE 0 END"
+
+// RatingDrift describes a product whose stored rating aggregates did not match its reviews.
+type RatingDrift struct {
+	ProductID uint
+	// StoredCount and ActualCount are the stored and recomputed review counts
+	StoredCount int
+	ActualCount int
+	// StoredSum and ActualSum are the stored and recomputed rating sums
+	StoredSum int64
+	ActualSum int64
+	// StoredRatings and ActualRatings are the stored and recomputed review counts per rating
+	StoredRatings [5]int
+	ActualRatings [5]int
+	// StoredRecentCount and ActualRecentCount are the stored and recomputed review counts of the recent window
+	StoredRecentCount int
+	ActualRecentCount int
+	// StoredVerifiedCount and ActualVerifiedCount are the stored and recomputed review counts of verified purchases
+	StoredVerifiedCount int
+	ActualVerifiedCount int
+	// StoredAspectCounts and ActualAspectCounts are the stored and recomputed review counts per aspect,
+	// only set when the aspect rating aggregates drifted
+	StoredAspectCounts map[string]int
+	ActualAspectCounts map[string]int
+	// PreviousAverage and AverageRating are the average ratings before and after the fix
+	PreviousAverage float64
+	AverageRating   float64
+}
+
+// averageRating returns the average of ratings summing to sum over count reviews.
+func averageRating(sum int64, count int) float64 {
+	if count <= 0 {
+		return 0
+	}
+	return float64(sum) / float64(count)
+}
+
+// ratingDelta is a change of the rating aggregates of a product.
+type ratingDelta struct {
+	// count and sum are added to the review count and the rating sum
+	count int
+	sum   int
+	// ratings maps ratings (1-5) to the change of their review count
+	ratings map[int]int
+	// recentCount and recentSum are added to the review count and rating sum of the recent window
+	recentCount int
+	recentSum   int
+	// decayed lists the changes of the decay weight and decayed rating sums, weighted once the
+	// decay reference of the product is known
+	decayed []decayedChange
+	// verifiedCount and verifiedSum are added to the review count and rating sum of verified purchases
+	verifiedCount int
+	verifiedSum   int
+	// aspects maps the rated aspects to the change of their review count and rating sum
+	aspects map[string]aspectDelta
+}
+
+// decayedChange is a change of count reviews summing to sum, created at createdAt, of the decayed aggregates.
+type decayedChange struct {
+	createdAt time.Time
+	count     int
+	sum       int
+}
+
+// addTrends adds count reviews summing to sum, created along with the review,
+// to the recent and decayed aggregates.
+func (d *ratingDelta) addTrends(review models.Review, count int, sum int) {
+	config := ratingTrendConfig()
+	d.decayed = append(d.decayed, decayedChange{createdAt: review.CreatedAt, count: count, sum: sum})
+	if !review.CreatedAt.Before(recentCutoff(config)) {
+		d.recentCount += count
+		d.recentSum += sum
+	}
+}
+
+// addVerified adds count reviews summing to sum to the aggregates of verified purchases
+// if the review is a verified purchase.
+func (d *ratingDelta) addVerified(review models.Review, count int, sum int) {
+	if review.Verified {
+		d.verifiedCount += count
+		d.verifiedSum += sum
+	}
+}
+
+// addAspects adds the aspect ratings to the aspect aggregates, count times:
+// 1 when they are added and -1 when they are removed.
+func (d *ratingDelta) addAspects(ratings []models.ReviewAspectRating, count int) {
+	if d.aspects == nil {
+		d.aspects = map[string]aspectDelta{}
+	}
+	for _, rating := range ratings {
+		aspect := d.aspects[rating.Aspect]
+		aspect.count += count
+		aspect.sum += count * rating.Rating
+		d.aspects[rating.Aspect] = aspect
+	}
+}
+
+// reviewAdded returns the change of the aggregates when the review is added.
+func reviewAdded(review models.Review) ratingDelta {
+	delta := ratingDelta{count: 1, sum: review.Rating, ratings: map[int]int{review.Rating: 1}}
+	delta.addTrends(review, 1, review.Rating)
+	delta.addVerified(review, 1, review.Rating)
+	delta.addAspects(review.AspectRatings, 1)
+	return delta
+}
+
+// reviewRemoved returns the change of the aggregates when the review is removed.
+func reviewRemoved(review models.Review) ratingDelta {
+	delta := ratingDelta{count: -1, sum: -review.Rating, ratings: map[int]int{review.Rating: -1}}
+	delta.addTrends(review, -1, -review.Rating)
+	delta.addVerified(review, -1, -review.Rating)
+	delta.addAspects(review.AspectRatings, -1)
+	return delta
+}
+
+// reviewVerified returns the change of the aggregates when the review is found to be a verified purchase.
+func reviewVerified(review models.Review) ratingDelta {
+	return ratingDelta{verifiedCount: 1, verifiedSum: review.Rating}
+}
+
+// reviewRated returns the change of the aggregates when the ratings of the review change,
+// its overall rating and its aspect ratings.
+func reviewRated(previous models.Review, review models.Review) ratingDelta {
+	previousRating := previous.Rating
+	delta := ratingDelta{sum: review.Rating - previousRating, ratings: map[int]int{}}
+	if previousRating != review.Rating {
+		delta.ratings[previousRating] = -1
+		delta.ratings[review.Rating] = 1
+	}
+	delta.addTrends(review, 0, review.Rating-previousRating)
+	delta.addVerified(review, 0, review.Rating-previousRating)
+	delta.addAspects(previous.AspectRatings, -1)
+	delta.addAspects(review.AspectRatings, 1)
+	return delta
+}
+
+// applyDerivedRatings stores the recent and decayed average ratings and the ranking score
+// derived from the rating aggregates of a product when they changed.
+// It must run in the transaction that changed the aggregates.
+func applyDerivedRatings(tx *gorm.DB, product *models.Product) error {
+	columns := map[string]interface{}{}
+	if product.ReviewCount == 0 && (product.DecayedWeight != 0 || product.DecayedRatingSum != 0) {
+		// Drop the rounding errors left by the removed reviews
+		product.DecayedWeight, product.DecayedRatingSum = 0, 0
+		columns["decayed_weight"], columns["decayed_rating_sum"] = 0.0, 0.0
+	}
+
+	if recent := averageRating(product.RecentRatingSum, product.RecentReviewCount); recent != product.RecentAverageRating {
+		columns["recent_average_rating"] = recent
+	}
+	decayed := 0.0
+	if product.DecayedWeight > 0 {
+		decayed = product.DecayedRatingSum / product.DecayedWeight
+	}
+	if decayed != product.DecayedAverageRating {
+		columns["decayed_average_rating"] = decayed
+	}
+	if score := rankingScore(*product); score != product.RankingScore {
+		columns["ranking_score"] = score
+	}
+
+	if len(columns) == 0 {
+		return nil
+	}
+	return tx.Model(product).UpdateColumns(columns).Error
+}
+
+// applyDecayedChanges weighs the changes of the decayed aggregates of a product relative to its decay
+// reference and stores the new sums. A stale reference is first moved to the current time, the sums
+// being recomputed from the reviews, which already include the changes.
+// It must run in the transaction that changed the aggregates, after the product row was updated.
+func applyDecayedChanges(tx *gorm.DB, product *models.Product, changes []decayedChange) error {
+	halfLife := ratingTrendConfig().DecayHalfLife
+	now := time.Now()
+	if decayReferenceStale(product.DecayedReference, now) {
+		weight, sum, err := decayedAggregates(tx, product.ID, halfLife, now)
+		if err != nil {
+			return err
+		}
+		return tx.Model(product).UpdateColumns(map[string]interface{}{
+			"decayed_reference":  &now,
+			"decayed_weight":     weight,
+			"decayed_rating_sum": sum,
+		}).Error
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	weight, sum := product.DecayedWeight, product.DecayedRatingSum
+	for _, change := range changes {
+		reviewWeight := decayWeight(change.createdAt, *product.DecayedReference, halfLife)
+		weight += float64(change.count) * reviewWeight
+		sum += float64(change.sum) * reviewWeight
+	}
+	return tx.Model(product).UpdateColumns(map[string]interface{}{
+		"decayed_weight":     weight,
+		"decayed_rating_sum": sum,
+	}).Error
+}
+
+// applyRatingDelta applies a change to the rating aggregates of a product with SQL arithmetic
+// and returns the new average rating. When the average changes, a product.rating_changed
+// event is queued in the outbox. It must run inside the transaction of the review change.
+func applyRatingDelta(tx *gorm.DB, productID uint, delta ratingDelta) (float64, error) {
+	columns := map[string]interface{}{
+		"review_count":   gorm.Expr("review_count + ?", delta.count),
+		"rating_sum":     gorm.Expr("rating_sum + ?", delta.sum),
+		"average_rating": gorm.Expr(averageRatingExpr, delta.count, delta.sum, delta.count),
+
+		"recent_review_count": gorm.Expr("recent_review_count + ?", delta.recentCount),
+		"recent_rating_sum":   gorm.Expr("recent_rating_sum + ?", delta.recentSum),
+
+		"verified_review_count":   gorm.Expr("verified_review_count + ?", delta.verifiedCount),
+		"verified_rating_sum":     gorm.Expr("verified_rating_sum + ?", delta.verifiedSum),
+		"verified_average_rating": gorm.Expr(verifiedAverageRatingExpr, delta.verifiedCount, delta.verifiedSum, delta.verifiedCount),
+	}
+	for rating, change := range delta.ratings {
+		if rating < 1 || rating > 5 || change == 0 {
+			continue
+		}
+		column := models.RatingCountColumn(rating)
+		columns[column] = gorm.Expr(column+" + ?", change)
+	}
+
+	result := tx.Model(&models.Product{}).Where("id = ?", productID).UpdateColumns(columns)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return 0, nil // Nothing to update for reviews of a deleted product
+	}
+	if err := applyAspectDeltas(tx, productID, delta.aspects); err != nil {
+		return 0, err
+	}
+
+	// The updated row is locked until the transaction ends, so the values read back
+	// are exactly the ones this change produced
+	var product models.Product
+	result = tx.First(&product, productID)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	if err := applyDecayedChanges(tx, &product, delta.decayed); err != nil {
+		return 0, err
+	}
+	if err := applyDerivedRatings(tx, &product); err != nil {
+		return 0, err
+	}
+
+	previousRating := averageRating(product.RatingSum-int64(delta.sum), product.ReviewCount-delta.count)
+	if previousRating != product.AverageRating {
+		err := enqueueEvent(tx, events.ProductRatingChanged, productID, events.RatingChanged{
+			ProductID:             productID,
+			PreviousAverageRating: previousRating,
+			AverageRating:         product.AverageRating,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return product.AverageRating, nil
+}
+
+// reconcileProductRating recomputes the rating aggregates of a product from its approved
+// reviews with SQL aggregates and stores them if they drifted. It returns the drift, nil when the
+// aggregates were correct, and the average rating of the product. When the average changes,
+// a product.rating_changed event is queued in the outbox. It must run inside a transaction.
+// The decayed sums, which take a scan of every review, are only recomputed when the decay reference
+// is stale or checkDecayed is set. It returns ErrNotFound if the product does not exist.
+func reconcileProductRating(tx *gorm.DB, productID uint, checkDecayed bool) (*RatingDrift, float64, error) {
+	// Lock the product first, so review changes committed meanwhile apply their deltas
+	// on top of the recomputed aggregates instead of being overwritten
+	query := tx
+	if tx.Dialect().GetName() == "postgres" {
+		query = tx.Set("gorm:query_option", "FOR UPDATE")
+	}
+	var product models.Product
+	result := query.First(&product, productID)
+	if gorm.IsRecordNotFoundError(result.Error) {
+		return nil, 0, ErrNotFound
+	}
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+
+	config := ratingTrendConfig()
+	cutoff := recentCutoff(config)
+	var count, recentCount int
+	var sum, recentSum int64
+	var ratingCounts [5]int
+	selection := "COUNT(*), COALESCE(SUM(rating), 0)"
+	for rating := 1; rating <= len(ratingCounts); rating++ {
+		selection += fmt.Sprintf(", COALESCE(SUM(CASE WHEN rating = %d THEN 1 ELSE 0 END), 0)", rating)
+	}
+	selection += ", COALESCE(SUM(CASE WHEN created_at >= ? THEN 1 ELSE 0 END), 0)" +
+		", COALESCE(SUM(CASE WHEN created_at >= ? THEN rating ELSE 0 END), 0)" +
+		", COALESCE(SUM(CASE WHEN verified = ? THEN 1 ELSE 0 END), 0)" +
+		", COALESCE(SUM(CASE WHEN verified = ? THEN rating ELSE 0 END), 0)"
+	var verifiedCount int
+	var verifiedSum int64
+	row := tx.Model(&models.Review{}).Select(selection, cutoff, cutoff, true, true).
+		Where("product_id = ? AND status = ?", productID, models.ReviewApproved).Row()
+	err := row.Scan(&count, &sum, &ratingCounts[0], &ratingCounts[1], &ratingCounts[2], &ratingCounts[3], &ratingCounts[4],
+		&recentCount, &recentSum, &verifiedCount, &verifiedSum)
+	if err != nil {
+		return nil, 0, err
+	}
+	// Move a stale decay reference first, which is not a drift
+	decayedWeight, decayedSum := product.DecayedWeight, product.DecayedRatingSum
+	if now := time.Now(); decayReferenceStale(product.DecayedReference, now) {
+		decayedWeight, decayedSum, err = decayedAggregates(tx, productID, config.DecayHalfLife, now)
+		if err != nil {
+			return nil, 0, err
+		}
+		result = tx.Model(&product).UpdateColumns(map[string]interface{}{
+			"decayed_reference":  &now,
+			"decayed_weight":     decayedWeight,
+			"decayed_rating_sum": decayedSum,
+		})
+		if result.Error != nil {
+			return nil, 0, result.Error
+		}
+	} else if checkDecayed {
+		decayedWeight, decayedSum, err = decayedAggregates(tx, productID, config.DecayHalfLife, *product.DecayedReference)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	storedAspects, actualAspects, err := reconcileAspectRatings(tx, productID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	average := averageRating(sum, count)
+	verifiedAverage := averageRating(verifiedSum, verifiedCount)
+	if actualAspects == nil && product.ReviewCount == count && product.RatingSum == sum && product.AverageRating == average &&
+		product.RatingCounts() == ratingCounts && product.RecentReviewCount == recentCount &&
+		product.RecentRatingSum == recentSum && closeTo(product.DecayedWeight, decayedWeight) &&
+		closeTo(product.DecayedRatingSum, decayedSum) && product.VerifiedReviewCount == verifiedCount &&
+		product.VerifiedRatingSum == verifiedSum && product.VerifiedAverageRating == verifiedAverage {
+		return nil, average, nil
+	}
+
+	drift := &RatingDrift{
+		ProductID:       productID,
+		StoredCount:     product.ReviewCount,
+		ActualCount:     count,
+		StoredSum:       product.RatingSum,
+		ActualSum:       sum,
+		StoredRatings:   product.RatingCounts(),
+		ActualRatings:   ratingCounts,
+		PreviousAverage: product.AverageRating,
+		AverageRating:   average,
+
+		StoredRecentCount: product.RecentReviewCount,
+		ActualRecentCount: recentCount,
+
+		StoredVerifiedCount: product.VerifiedReviewCount,
+		ActualVerifiedCount: verifiedCount,
+
+		StoredAspectCounts: storedAspects,
+		ActualAspectCounts: actualAspects,
+	}
+
+	// Updating the columns also sets them on the product, the drift keeps the stored values
+	columns := map[string]interface{}{
+		"review_count":   count,
+		"rating_sum":     sum,
+		"average_rating": average,
+
+		"recent_review_count": recentCount,
+		"recent_rating_sum":   recentSum,
+		"decayed_weight":      decayedWeight,
+		"decayed_rating_sum":  decayedSum,
+
+		"verified_review_count":   verifiedCount,
+		"verified_rating_sum":     verifiedSum,
+		"verified_average_rating": verifiedAverage,
+	}
+	for rating, ratingCount := range ratingCounts {
+		columns[models.RatingCountColumn(rating+1)] = ratingCount
+	}
+	result = tx.Model(&product).UpdateColumns(columns)
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+	if err := applyDerivedRatings(tx, &product); err != nil {
+		return nil, 0, err
+	}
+
+	if drift.PreviousAverage != average {
+		err := enqueueEvent(tx, events.ProductRatingChanged, productID, events.RatingChanged{
+			ProductID:             productID,
+			PreviousAverageRating: drift.PreviousAverage,
+			AverageRating:         average,
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return drift, average, nil
+}
+
+// ReconcileRatingAggregates recomputes the rating aggregates of every product whose
+// stored review count, rating sum, review counts per rating, verified purchase aggregates
+// or aspect rating aggregates do not match its approved reviews, fixes them, refreshes their caches and returns the drifts found.
+// It also moves the stale decay references of the products forward.
+func ReconcileRatingAggregates(db *gorm.DB) ([]RatingDrift, error) {
+	aggregates := "COUNT(*) AS review_count, SUM(rating) AS rating_sum" +
+		", SUM(CASE WHEN verified = ? THEN 1 ELSE 0 END) AS verified_review_count" +
+		", SUM(CASE WHEN verified = ? THEN rating ELSE 0 END) AS verified_rating_sum"
+	conditions := "p.review_count <> COALESCE(r.review_count, 0) OR p.rating_sum <> COALESCE(r.rating_sum, 0)" +
+		" OR p.verified_review_count <> COALESCE(r.verified_review_count, 0)" +
+		" OR p.verified_rating_sum <> COALESCE(r.verified_rating_sum, 0)"
+	for rating := 1; rating <= 5; rating++ {
+		column := models.RatingCountColumn(rating)
+		aggregates += fmt.Sprintf(", SUM(CASE WHEN rating = %d THEN 1 ELSE 0 END) AS %s", rating, column)
+		conditions += fmt.Sprintf(" OR p.%s <> COALESCE(r.%s, 0)", column, column)
+	}
+
+	candidates, err := queryProductIDs(db, `SELECT p.id FROM products p
+		LEFT JOIN (
+			SELECT product_id, `+aggregates+`
+			FROM reviews WHERE deleted_at IS NULL AND status = ? GROUP BY product_id
+		) r ON r.product_id = p.id
+		WHERE p.deleted_at IS NULL AND (`+conditions+`)
+		ORDER BY p.id`, true, true, models.ReviewApproved)
+	if err != nil {
+		return nil, err
+	}
+	aspectCandidates, err := queryProductIDs(db, aspectDriftQuery, models.ReviewApproved, models.ReviewApproved)
+	if err != nil {
+		return nil, err
+	}
+	candidates = mergeProductIDs(candidates, aspectCandidates)
+
+	// Move the stale decay references of the reviewed products, which is not reported as a drift
+	staleCandidates, err := queryProductIDs(db, `SELECT id FROM products
+		WHERE deleted_at IS NULL AND review_count > 0 AND (decayed_reference IS NULL OR decayed_reference < ?)
+		ORDER BY id`, time.Now().Add(-decayRebaseAge))
+	if err != nil {
+		return nil, err
+	}
+	return reconcileProducts(db, mergeProductIDs(candidates, staleCandidates))
+}
+
+// queryProductIDs returns the product IDs selected by a raw query.
+func queryProductIDs(db *gorm.DB, query string, args ...interface{}) ([]uint, error) {
+	var ids []uint
+	rows, err := db.Raw(query, args...).Rows()
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// mergeProductIDs returns the product IDs of both lists once, in ascending order.
+func mergeProductIDs(a []uint, b []uint) []uint {
+	seen := map[uint]bool{}
+	merged := make([]uint, 0, len(a)+len(b))
+	for _, id := range append(append([]uint{}, a...), b...) {
+		if !seen[id] {
+			seen[id] = true
+			merged = append(merged, id)
+		}
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i] < merged[j] })
+	return merged
+}
+
+// reconcileProducts reconciles the rating aggregates of the products, refreshes the caches
+// of the ones that drifted and returns their drifts.
+func reconcileProducts(db *gorm.DB, productIDs []uint) ([]RatingDrift, error) {
+	drifts := []RatingDrift{}
+	for _, productID := range productIDs {
+		// Each product is fixed in its own transaction to keep the locks short
+		var drift *RatingDrift
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			drift, _, err = reconcileProductRating(tx, productID, true)
+			return err
+		})
+		// The product may have been deleted or fixed by a review change in the meantime
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return drifts, err
+		}
+		if drift == nil {
+			continue
+		}
+
+		if err := refreshProductCaches(productID, drift.AverageRating); err != nil {
+			return drifts, err
+		}
+		drifts = append(drifts, *drift)
+	}
+	return drifts, nil
+}
+
+// decayedAggregates computes the decay weight and decayed rating sums of the reviews of a product
+// relative to the decay reference.
+func decayedAggregates(tx *gorm.DB, productID uint, halfLife time.Duration, reference time.Time) (float64, float64, error) {
+	rows, err := tx.Model(&models.Review{}).Select("rating, created_at").
+		Where("product_id = ? AND status = ?", productID, models.ReviewApproved).Rows()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+
+	var weight, sum float64
+	for rows.Next() {
+		var rating int
+		var createdAt time.Time
+		if err := rows.Scan(&rating, &createdAt); err != nil {
+			return 0, 0, err
+		}
+		reviewWeight := decayWeight(createdAt, reference, halfLife)
+		weight += reviewWeight
+		sum += float64(rating) * reviewWeight
+	}
+	return weight, sum, rows.Err()
+}
+
+// closeTo reports whether the decayed sums a and b only differ by rounding errors.
+func closeTo(a float64, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Max(math.Abs(a), math.Abs(b)))
+}
+
+// RunRatingReconciler reconciles the rating aggregates, refreshes the recent ratings and the
+// ranking scores right away and then every interval until the context is cancelled, logging every drift found.
+func RunRatingReconciler(ctx context.Context, db *gorm.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		drifts, err := ReconcileRatingAggregates(db)
+		if err != nil {
+			log.Printf("failed to reconcile rating aggregates: %v", err)
+		}
+		for _, drift := range drifts {
+			log.Printf("rating aggregates of product %d drifted: review count %d, expected %d; rating sum %d, expected %d",
+				drift.ProductID, drift.StoredCount, drift.ActualCount, drift.StoredSum, drift.ActualSum)
+		}
+		if _, err := RefreshRecentRatings(db); err != nil {
+			log.Printf("failed to refresh recent ratings: %v", err)
+		}
+		if _, err := RefreshRankingScores(db); err != nil {
+			log.Printf("failed to refresh ranking scores: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
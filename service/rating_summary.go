@@ -0,0 +1,58 @@
+package service
+
+import (
+	"encoding/json"
+	"go_api_product_review/cache"
+	"go_api_product_review/models"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/jinzhu/gorm"
+)
+
+// GetRatingSummary returns the rating distribution of a product, built from its rating
+// aggregates and read through the Redis cache. Review changes drop the cached summary.
+// It returns ErrNotFound if the product does not exist.
+func GetRatingSummary(db *gorm.DB, productID uint) (*models.RatingSummary, error) {
+	// Check the Redis cache first
+	key := ratingSummaryCacheKey(productID)
+	summaryJSON, err := cache.Rdb.Get(cache.Ctx, key).Result()
+	if err == nil {
+		var summary models.RatingSummary
+		if err := json.Unmarshal([]byte(summaryJSON), &summary); err != nil {
+			return nil, err
+		}
+		return &summary, nil
+	}
+	if err != redis.Nil {
+		return nil, err // Return error if there is a Redis issue
+	}
+
+	var product models.Product
+	result := db.First(&product, productID)
+	if gorm.IsRecordNotFoundError(result.Error) {
+		return nil, ErrNotFound
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	summary := models.NewRatingSummary(product)
+
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return nil, err
+	}
+	err = cache.Rdb.Set(cache.Ctx, key, data, 10*time.Minute).Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return &summary, nil
+}
+
+// ratingSummaryCacheKey returns the Redis key of the cached rating summary of a product,
+// next to the key of its average rating.
+func ratingSummaryCacheKey(productID uint) string {
+	return "product:" + strconv.Itoa(int(productID)) + ":rating_summary"
+}
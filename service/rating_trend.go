@@ -0,0 +1,258 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"go_api_product_review/cache"
+	"go_api_product_review/models"
+	"math"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/jinzhu/gorm"
+)
+
+// RatingTrendConfig configures the recent and time-decayed average ratings of the products.
+type RatingTrendConfig struct {
+	// RecentWindow is how far back the reviews of the recent average rating go
+	RecentWindow time.Duration
+	// DecayHalfLife is the age at which a review weighs half as much as a new one in the decayed average
+	DecayHalfLife time.Duration
+}
+
+// DefaultRatingTrendConfig returns a recent window of 30 days and a decay half-life of 90 days.
+func DefaultRatingTrendConfig() RatingTrendConfig {
+	return RatingTrendConfig{
+		RecentWindow:  30 * 24 * time.Hour,
+		DecayHalfLife: 90 * 24 * time.Hour,
+	}
+}
+
+// LoadRatingTrendConfigFromEnv builds the rating trend configuration from environment variables,
+// starting from DefaultRatingTrendConfig:
+//   - RATING_RECENT_WINDOW_DAYS: number of days of reviews in the recent average rating
+//   - RATING_DECAY_HALF_LIFE_DAYS: number of days after which a review weighs half in the decayed average
+func LoadRatingTrendConfigFromEnv() (RatingTrendConfig, error) {
+	config := DefaultRatingTrendConfig()
+	durations := map[string]*time.Duration{
+		"RATING_RECENT_WINDOW_DAYS":   &config.RecentWindow,
+		"RATING_DECAY_HALF_LIFE_DAYS": &config.DecayHalfLife,
+	}
+	for name, target := range durations {
+		if value := os.Getenv(name); value != "" {
+			days, err := strconv.Atoi(value)
+			if err != nil || days <= 0 {
+				return config, fmt.Errorf("invalid %s %q, expected a positive number of days", name, value)
+			}
+			*target = time.Duration(days) * 24 * time.Hour
+		}
+	}
+	return config, config.Validate()
+}
+
+// Validate checks that the recent window is positive and that the decay half-life is long
+// enough for the weights to stay within floating-point range until their reference is moved.
+func (c RatingTrendConfig) Validate() error {
+	if c.RecentWindow <= 0 {
+		return fmt.Errorf("invalid recent window %s, expected a positive duration", c.RecentWindow)
+	}
+	if c.DecayHalfLife <= 0 || float64(decayRebaseAge)/float64(c.DecayHalfLife) > maxDecayRebaseExponent {
+		return fmt.Errorf("invalid decay half-life %s, expected at least %s",
+			c.DecayHalfLife, decayRebaseAge/maxDecayRebaseExponent)
+	}
+	return nil
+}
+
+// ratingTrends holds the rating trend configuration shared by every aggregate computation.
+var ratingTrends = struct {
+	sync.RWMutex
+	config RatingTrendConfig
+}{config: DefaultRatingTrendConfig()}
+
+// InitRatingTrends sets the rating trend configuration. A new recent window applies to
+// every product on the next RefreshRecentRatings, a new half-life only to the reviews
+// changed afterwards and to the products reconciled.
+func InitRatingTrends(config RatingTrendConfig) {
+	ratingTrends.Lock()
+	defer ratingTrends.Unlock()
+	ratingTrends.config = config
+}
+
+// ratingTrendConfig returns the current rating trend configuration.
+func ratingTrendConfig() RatingTrendConfig {
+	ratingTrends.RLock()
+	defer ratingTrends.RUnlock()
+	return ratingTrends.config
+}
+
+const (
+	// decayRebaseAge is the age from which the decay reference of a product is moved to the current time
+	decayRebaseAge = 7 * 24 * time.Hour
+	// maxDecayRebaseExponent bounds the number of half-lives within decayRebaseAge, so the weight of
+	// a new review relative to the reference never exceeds 2^64
+	maxDecayRebaseExponent = 64
+	// maxDecayExponent bounds the exponent of the weights whatever the creation time of the reviews:
+	// much older reviews all weigh 2^-1000 instead of underflowing to 0
+	maxDecayExponent = 1000
+)
+
+// decayWeight returns the weight of a review created at createdAt in the decayed average,
+// relative to the decay reference of the product. Weights grow with the creation time of the
+// reviews instead of shrinking with their age, which scales every weight of a product by the
+// same factor as time passes: the decayed average stays the same until the reviews change, so
+// it can be maintained like the other aggregates. Moving the reference keeps them bounded.
+func decayWeight(createdAt time.Time, reference time.Time, halfLife time.Duration) float64 {
+	exponent := float64(createdAt.Sub(reference)) / float64(halfLife)
+	return math.Exp2(math.Max(-maxDecayExponent, math.Min(maxDecayExponent, exponent)))
+}
+
+// decayReferenceStale reports whether the decay reference of a product must be moved to now.
+// Products without reference, created before it existed, always need one.
+func decayReferenceStale(reference *time.Time, now time.Time) bool {
+	return reference == nil || now.Sub(*reference) > decayRebaseAge
+}
+
+// recentCutoff returns the creation time from which reviews are in the recent window.
+func recentCutoff(config RatingTrendConfig) time.Time {
+	return time.Now().Add(-config.RecentWindow)
+}
+
+// RefreshRecentRatings reconciles the products whose recent review count or rating sum no
+// longer match the reviews of the recent window, as reviews age out of it, and returns the
+// number of products updated. Review changes keep the recent aggregates up to date otherwise.
+func RefreshRecentRatings(db *gorm.DB) (int, error) {
+	candidates, err := queryProductIDs(db, `SELECT p.id FROM products p
+		LEFT JOIN (
+			SELECT product_id, COUNT(*) AS review_count, SUM(rating) AS rating_sum
+			FROM reviews WHERE deleted_at IS NULL AND status = ? AND created_at >= ? GROUP BY product_id
+		) r ON r.product_id = p.id
+		WHERE p.deleted_at IS NULL
+			AND (p.recent_review_count <> COALESCE(r.review_count, 0) OR p.recent_rating_sum <> COALESCE(r.rating_sum, 0))
+		ORDER BY p.id`, models.ReviewApproved, recentCutoff(ratingTrendConfig()))
+	if err != nil {
+		return 0, err
+	}
+
+	drifts, err := reconcileProducts(db, candidates)
+	return len(drifts), err
+}
+
+// GetRatingTrend returns the number and average rating of the reviews of a product per week
+// or month, computed from their creation time, along with its recent and decayed average ratings.
+// Trends are read through the Redis cache, which holds the longest trend of each interval.
+// Review changes drop the cached trends.
+// It returns ErrNotFound if the product does not exist.
+func GetRatingTrend(db *gorm.DB, productID uint, query models.RatingTrendQuery) (*models.RatingTrend, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	trend, err := getCachedRatingTrend(db, productID, query.Interval)
+	if err != nil {
+		return nil, err
+	}
+	trend.Points = trend.Points[len(trend.Points)-query.Periods:]
+	return trend, nil
+}
+
+// getCachedRatingTrend returns the trend of a product over the maximum number of periods
+// of the interval, from the Redis cache or built from its reviews on a miss.
+func getCachedRatingTrend(db *gorm.DB, productID uint, interval string) (*models.RatingTrend, error) {
+	// Check the Redis cache first
+	key := ratingTrendCacheKey(productID, interval)
+	trendJSON, err := cache.Rdb.Get(cache.Ctx, key).Result()
+	if err == nil {
+		var trend models.RatingTrend
+		if err := json.Unmarshal([]byte(trendJSON), &trend); err != nil {
+			return nil, err
+		}
+		return &trend, nil
+	}
+	if err != redis.Nil {
+		return nil, err // Return error if there is a Redis issue
+	}
+
+	var product models.Product
+	result := db.First(&product, productID)
+	if gorm.IsRecordNotFoundError(result.Error) {
+		return nil, ErrNotFound
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	trend := models.RatingTrend{
+		ProductID:            productID,
+		Interval:             interval,
+		AverageRating:        product.AverageRating,
+		RecentAverageRating:  product.RecentAverageRating,
+		DecayedAverageRating: product.DecayedAverageRating,
+		Points:               make([]models.RatingTrendPoint, 0, models.MaxRatingTrendPeriods),
+	}
+
+	start := models.PeriodStart(time.Now(), interval)
+	if interval == models.TrendMonthly {
+		start = start.AddDate(0, 1-models.MaxRatingTrendPeriods, 0)
+	} else {
+		start = start.AddDate(0, 0, 7*(1-models.MaxRatingTrendPeriods))
+	}
+	periods := make(map[int64]int, models.MaxRatingTrendPeriods)
+	for period := start; len(trend.Points) < models.MaxRatingTrendPeriods; period = models.NextPeriod(period, interval) {
+		periods[period.Unix()] = len(trend.Points)
+		trend.Points = append(trend.Points, models.RatingTrendPoint{PeriodStart: period})
+	}
+
+	rows, err := db.Model(&models.Review{}).Select("rating, created_at").
+		Where("product_id = ? AND status = ? AND created_at >= ?", productID, models.ReviewApproved, start).Rows()
+	if err != nil {
+		return nil, err
+	}
+	sums := make([]int, len(trend.Points))
+	for rows.Next() {
+		var rating int
+		var createdAt time.Time
+		if err := rows.Scan(&rating, &createdAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if i, ok := periods[models.PeriodStart(createdAt, interval).Unix()]; ok {
+			trend.Points[i].ReviewCount++
+			sums[i] += rating
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range trend.Points {
+		trend.Points[i].AverageRating = averageRating(int64(sums[i]), trend.Points[i].ReviewCount)
+	}
+
+	data, err := json.Marshal(trend)
+	if err != nil {
+		return nil, err
+	}
+	err = cache.Rdb.Set(cache.Ctx, key, data, 10*time.Minute).Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return &trend, nil
+}
+
+// ratingTrendCacheKey returns the Redis key of the cached rating trend of a product
+// for the interval, next to the key of its average rating.
+func ratingTrendCacheKey(productID uint, interval string) string {
+	return "product:" + strconv.Itoa(int(productID)) + ":rating_trend:" + interval
+}
+
+// ratingTrendCacheKeys returns the Redis keys of the cached rating trends of a product.
+func ratingTrendCacheKeys(productID uint) []string {
+	return []string{
+		ratingTrendCacheKey(productID, models.TrendWeekly),
+		ratingTrendCacheKey(productID, models.TrendMonthly),
+	}
+}
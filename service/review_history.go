@@ -0,0 +1,169 @@
+package service
+
+import (
+	"go_api_product_review/events"
+	"go_api_product_review/models"
+	"go_api_product_review/screening"
+
+	"github.com/jinzhu/gorm"
+)
+
+// recordRevision records the current version of a review before it is changed by changedBy,
+// numbering it after the last revision of the review. It must run inside the transaction changing the review.
+func recordRevision(tx *gorm.DB, review models.Review, action string, changedBy string) error {
+	var last struct{ Version int }
+	result := tx.Model(&models.ReviewRevision{}).Select("COALESCE(MAX(version), 0) AS version").
+		Where("review_id = ?", review.ID).Scan(&last)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	revision := models.NewReviewRevision(review, last.Version+1, action, changedBy)
+	return tx.Create(&revision).Error
+}
+
+// GetReviewHistory retrieves the prior versions of a review, oldest first.
+// The history of deleted reviews is kept, so it can be read after the review is deleted.
+// It returns ErrNotFound if the review never existed.
+func GetReviewHistory(db *gorm.DB, id uint) (*models.ReviewHistory, error) {
+	var review models.Review
+	result := db.Unscoped().Select("id, author_subject, deleted_at").First(&review, id)
+	if gorm.IsRecordNotFoundError(result.Error) {
+		return nil, ErrNotFound
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	revisions := []models.ReviewRevision{}
+	result = db.Where("review_id = ?", id).Order("version ASC").Find(&revisions)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &models.ReviewHistory{
+		ReviewID:      review.ID,
+		AuthorSubject: review.AuthorSubject,
+		Deleted:       review.DeletedAt != nil,
+		Revisions:     revisions,
+	}, nil
+}
+
+// RestoreReviewRevision brings a review back to the content of one of its prior versions:
+// the names of the reviewer, the text, the pros and cons, the rating and the aspect ratings.
+// A deleted review is undeleted along with the replies deleted with it. The moderation status
+// of the review is kept.
+// The current version is recorded as a restore revision first, so the restore can be undone too.
+// The rating aggregates of the product, aspect ones included, are recomputed from its reviews in the
+// same transaction, along with a review.updated event; the caches are updated once it is committed.
+// It returns ErrNotFound if the review or the version does not exist, and an error wrapping
+// ErrInvalidAspectRating if the version rates aspects the category of the product no longer defines.
+func RestoreReviewRevision(db *gorm.DB, id uint, version int, admin string) (*models.Review, error) {
+	var review models.Review
+	var averageRating float64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Keep moderations out until the restored review is counted in the aggregates with its status
+		result := lockReview(tx.Unscoped(), &review, id)
+		if gorm.IsRecordNotFoundError(result.Error) {
+			return ErrNotFound
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+
+		var revision models.ReviewRevision
+		result = tx.Where("review_id = ? AND version = ?", id, version).First(&revision)
+		if gorm.IsRecordNotFoundError(result.Error) {
+			return ErrNotFound
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+
+		var err error
+		review.AspectRatings, err = findAspectRatings(tx, review.ID)
+		if err != nil {
+			return err
+		}
+		aspectRatings := revision.AspectRatingList()
+		err = checkAspectRatings(tx, review.ProductID, aspectRatings)
+		if err != nil {
+			return err
+		}
+
+		err = recordRevision(tx, review, models.RevisionRestore, admin)
+		if err != nil {
+			return err
+		}
+
+		// Replies are deleted right after their review, undelete the ones deleted with it
+		if review.DeletedAt != nil {
+			result = tx.Unscoped().Model(&models.ReviewReply{}).
+				Where("review_id = ? AND deleted_at >= ?", id, *review.DeletedAt).
+				UpdateColumn("deleted_at", nil)
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+
+		review.FirstName = revision.FirstName
+		review.LastName = revision.LastName
+		review.ReviewText = revision.ReviewText
+		review.Pros = revision.Pros
+		review.Cons = revision.Cons
+		review.TextSignature = screening.MinHash(review.ReviewText).String()
+		review.Rating = revision.Rating
+		review.AspectRatings = aspectRatings
+		review.DeletedAt = nil
+		// The status, the verified badge and the vote tallies are not restored, they are changed concurrently
+		result = tx.Unscoped().Model(&models.Review{}).Where("id = ?", review.ID).Updates(map[string]interface{}{
+			"first_name":     review.FirstName,
+			"last_name":      review.LastName,
+			"review_text":    review.ReviewText,
+			"pros":           review.Pros,
+			"cons":           review.Cons,
+			"text_signature": review.TextSignature,
+			"rating":         review.Rating,
+			"deleted_at":     nil,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		err = saveAspectRatings(tx, &review)
+		if err != nil {
+			return err
+		}
+		err = saveTextBands(tx, &review)
+		if err != nil {
+			return err
+		}
+
+		err = enqueueEvent(tx, events.ReviewUpdated, review.ProductID, models.NewReviewResponse(review))
+		if err != nil {
+			return err
+		}
+
+		// The restored ratings or the undeleted review may change the ratings of the product, decayed and aspect ones included
+		_, averageRating, err = reconcileProductRating(tx, review.ProductID, true)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The cached review embeds its replies and media
+	err = loadReviewDetails(db, &review)
+	if err != nil {
+		return nil, err
+	}
+	err = CacheReview(&review)
+	if err != nil {
+		return nil, err
+	}
+
+	err = refreshProductCaches(review.ProductID, averageRating)
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go_api_product_review/media"
+	"go_api_product_review/models"
+	"go_api_product_review/storage"
+	"io"
+	"log"
+	"sync"
+
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	// ErrTooManyMedia is returned when a review already has as many photos as allowed.
+	ErrTooManyMedia = errors.New("review has too many photos attached")
+	// ErrMediaStorageDisabled is returned when no object storage is configured.
+	ErrMediaStorageDisabled = errors.New("media storage is not configured")
+)
+
+// mediaStorage holds the object storage of the photos attached to reviews and the upload limits.
+var mediaStorage = struct {
+	sync.RWMutex
+	store  storage.Store
+	config media.Config
+}{config: media.DefaultConfig()}
+
+// InitMedia sets the object storage of the photos attached to reviews and the upload limits.
+// A nil store turns the uploads off.
+func InitMedia(store storage.Store, config media.Config) {
+	mediaStorage.Lock()
+	defer mediaStorage.Unlock()
+	mediaStorage.store = store
+	mediaStorage.config = config
+}
+
+// mediaSettings returns the current object storage and upload limits.
+func mediaSettings() (storage.Store, media.Config) {
+	mediaStorage.RLock()
+	defer mediaStorage.RUnlock()
+	return mediaStorage.store, mediaStorage.config
+}
+
+// mediaUploadOverhead is the room left for the boundaries and headers of the multipart
+// form around the photo of an upload
+const mediaUploadOverhead = 64 << 10
+
+// MaxMediaUploadBytes returns the largest request body of a photo upload:
+// the largest file accepted plus the multipart overhead.
+func MaxMediaUploadBytes() int64 {
+	_, config := mediaSettings()
+	return config.MaxBytes + mediaUploadOverhead
+}
+
+// AddReviewMedia attaches the photo read from file to a review. The photo is checked against
+// the upload limits, its dimensions are read and its thumbnail generated, then both files are
+// stored before the metadata is saved. The review is cached again with its media.
+// It returns ErrNotFound if the review does not exist, ErrTooManyMedia if the review already has
+// as many photos as allowed, and the errors of media.Process if the file is not accepted.
+func AddReviewMedia(db *gorm.DB, reviewID uint, uploader string, file io.Reader) (*models.ReviewMedia, error) {
+	store, config := mediaSettings()
+	if store == nil {
+		return nil, ErrMediaStorageDisabled
+	}
+
+	// Check the review before spending time on the image
+	if err := checkMediaCount(db, reviewID, config.MaxPerReview); err != nil {
+		return nil, err
+	}
+	// Read one byte past the limit to tell files at the limit from larger ones
+	data, err := io.ReadAll(io.LimitReader(file, config.MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	image, err := media.Process(data, config)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := randomObjectName()
+	if err != nil {
+		return nil, err
+	}
+	item := models.ReviewMedia{
+		ReviewID:             reviewID,
+		UploaderSubject:      uploader,
+		ContentType:          image.ContentType,
+		Size:                 int64(len(data)),
+		Width:                image.Width,
+		Height:               image.Height,
+		ThumbnailContentType: image.ThumbnailContentType,
+		ThumbnailWidth:       image.ThumbnailWidth,
+		ThumbnailHeight:      image.ThumbnailHeight,
+		StorageKey:           fmt.Sprintf("reviews/%d/%s%s", reviewID, name, image.Extension),
+		ThumbnailKey:         fmt.Sprintf("reviews/%d/%s_thumb%s", reviewID, name, image.ThumbnailExtension),
+	}
+
+	ctx := context.Background()
+	err = store.Put(ctx, item.StorageKey, data, item.ContentType)
+	if err == nil {
+		err = store.Put(ctx, item.ThumbnailKey, image.Thumbnail, item.ThumbnailContentType)
+	}
+	if err == nil {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			// Check again under the lock of the review, other photos may have been attached meanwhile
+			if err := lockReviewMedia(tx, reviewID); err != nil {
+				return err
+			}
+			if err := checkMediaCount(tx, reviewID, config.MaxPerReview); err != nil {
+				return err
+			}
+			return tx.Create(&item).Error
+		})
+	}
+	if err != nil {
+		deleteMediaFiles(store, item)
+		return nil, err
+	}
+
+	err = refreshCachedReview(db, reviewID)
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// GetReviewMedia retrieves a photo attached to a review.
+// It returns ErrNotFound if the review has no such photo.
+func GetReviewMedia(db *gorm.DB, reviewID uint, mediaID uint) (*models.ReviewMedia, error) {
+	var item models.ReviewMedia
+	result := db.Where("review_id = ?", reviewID).First(&item, mediaID)
+	if gorm.IsRecordNotFoundError(result.Error) {
+		return nil, ErrNotFound
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &item, nil
+}
+
+// OpenReviewMedia opens the file of a photo attached to a review, or of its thumbnail.
+// It returns ErrNotFound if the file is missing from the object storage.
+func OpenReviewMedia(item *models.ReviewMedia, thumbnail bool) (io.ReadCloser, error) {
+	store, _ := mediaSettings()
+	if store == nil {
+		return nil, ErrMediaStorageDisabled
+	}
+
+	key := item.StorageKey
+	if thumbnail {
+		key = item.ThumbnailKey
+	}
+	file, err := store.Get(context.Background(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+// DeleteReviewMedia removes a photo from a review along with its files,
+// and caches the review again with its remaining media.
+// It returns ErrNotFound if the review has no such photo.
+func DeleteReviewMedia(db *gorm.DB, reviewID uint, mediaID uint) error {
+	item, err := GetReviewMedia(db, reviewID, mediaID)
+	if err != nil {
+		return err
+	}
+
+	// The metadata goes first, a file left behind is only wasted space
+	result := db.Unscoped().Delete(item)
+	if result.Error != nil {
+		return result.Error
+	}
+	if store, _ := mediaSettings(); store != nil {
+		deleteMediaFiles(store, *item)
+	}
+
+	return refreshCachedReview(db, reviewID)
+}
+
+// checkMediaCount checks that the review exists and can take another photo.
+func checkMediaCount(db *gorm.DB, reviewID uint, maxPerReview int) error {
+	var review models.Review
+	result := db.Select("id").First(&review, reviewID)
+	if gorm.IsRecordNotFoundError(result.Error) {
+		return ErrNotFound
+	}
+	if result.Error != nil {
+		return result.Error
+	}
+
+	var count int
+	result = db.Model(&models.ReviewMedia{}).Where("review_id = ?", reviewID).Count(&count)
+	if result.Error != nil {
+		return result.Error
+	}
+	if count >= maxPerReview {
+		return ErrTooManyMedia
+	}
+	return nil
+}
+
+// lockReviewMedia locks the row of a review until the end of the transaction, so concurrent uploads
+// count and attach their photos one after the other. It must run inside a transaction.
+func lockReviewMedia(tx *gorm.DB, reviewID uint) error {
+	var review models.Review
+	result := lockReview(tx.Select("id"), &review, reviewID)
+	if gorm.IsRecordNotFoundError(result.Error) {
+		return ErrNotFound
+	}
+	return result.Error
+}
+
+// deleteMediaFiles removes the files of a photo from the object storage, logging the failures.
+func deleteMediaFiles(store storage.Store, item models.ReviewMedia) {
+	for _, key := range []string{item.StorageKey, item.ThumbnailKey} {
+		if err := store.Delete(context.Background(), key); err != nil {
+			log.Printf("failed to delete media file %s: %v", key, err)
+		}
+	}
+}
+
+// findMedia loads the photos attached to a review, oldest first.
+func findMedia(db *gorm.DB, reviewID uint) ([]models.ReviewMedia, error) {
+	var items []models.ReviewMedia
+	result := db.Where("review_id = ?", reviewID).Order("created_at ASC, id ASC").Find(&items)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return items, nil
+}
+
+// preloadMedia makes the query load the photos attached to the reviews it finds, oldest first.
+// association is the path of the photos from the queried model, such as "Media" or "Reviews.Media".
+func preloadMedia(db *gorm.DB, association string) *gorm.DB {
+	return db.Preload(association, func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at ASC, id ASC")
+	})
+}
+
+// randomObjectName returns a random name for the files of a photo.
+func randomObjectName() (string, error) {
+	name := make([]byte, 16)
+	if _, err := rand.Read(name); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(name), nil
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"go_api_product_review/cache"
+	"go_api_product_review/models"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/jinzhu/gorm"
+)
+
+// reviewSortColumns maps each review sort order to its column and direction
+var reviewSortColumns = map[string]struct {
+	column string
+	desc   bool
+}{
+	"newest":  {"created_at", true},
+	"highest": {"rating", true},
+	"lowest":  {"rating", false},
+	"helpful": {"helpful_score", true},
+}
+
+// ListProductReviews retrieves a page of reviews of a product.
+// Pages are cached in Redis under a per-product version, which is bumped every time
+// one of the product's reviews changes so that all its cached pages are dropped at once.
+// It returns ErrNotFound if the product does not exist.
+func ListProductReviews(db *gorm.DB, productID uint, query models.ReviewQuery) (*models.ReviewPage, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Make sure the product exists, this is served from the cache most of the time
+	if _, err := getCachedProduct(db, productID); err != nil {
+		return nil, err
+	}
+
+	pageKey, err := reviewPageCacheKey(productID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the Redis cache first
+	pageJSON, err := cache.Rdb.Get(cache.Ctx, pageKey).Result()
+	if err == nil {
+		var page models.ReviewPage
+		if err := json.Unmarshal([]byte(pageJSON), &page); err != nil {
+			return nil, err
+		}
+		return &page, nil
+	}
+	if err != redis.Nil {
+		return nil, err // Return error if there is a Redis issue
+	}
+
+	page, err := queryProductReviews(db, productID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Store the JSON representation in Redis
+	data, err := json.Marshal(page)
+	if err != nil {
+		return nil, err
+	}
+	err = cache.Rdb.Set(cache.Ctx, pageKey, data, 10*time.Minute).Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// queryProductReviews loads a page of reviews of a product from the database.
+func queryProductReviews(db *gorm.DB, productID uint, query models.ReviewQuery) (*models.ReviewPage, error) {
+	sort := reviewSortColumns[query.Sort]
+
+	paged := db.Where("product_id = ? AND status = ?", productID, models.ReviewApproved)
+	if query.Rating != nil {
+		paged = paged.Where("rating = ?", *query.Rating)
+	}
+	if query.Verified {
+		paged = paged.Where("verified = ?", true)
+	}
+	if query.Cursor != "" {
+		value, id, err := decodeReviewCursor(query.Cursor, query.Sort)
+		if err != nil {
+			return nil, err
+		}
+		paged = applyKeyset(paged, sort.column, sort.desc, value, id)
+	}
+
+	// Fetch one extra row to know whether there is a next page
+	var reviews []models.Review
+	result := preloadReviewDetails(paged, "").Order(keysetOrder(sort.column, sort.desc)).Limit(query.Limit + 1).Find(&reviews)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	page := &models.ReviewPage{Items: []models.ReviewResponse{}}
+	if len(reviews) > query.Limit {
+		reviews = reviews[:query.Limit]
+		last := reviews[len(reviews)-1]
+		var value interface{} = last.Rating
+		switch sort.column {
+		case "created_at":
+			value = last.CreatedAt
+		case "helpful_score":
+			value = last.HelpfulScore
+		}
+		cursor, err := encodeCursor(query.Sort, value, last.ID)
+		if err != nil {
+			return nil, err
+		}
+		page.NextCursor = cursor
+	}
+
+	for _, review := range reviews {
+		page.Items = append(page.Items, models.NewReviewResponse(review))
+	}
+	return page, nil
+}
+
+// decodeReviewCursor decodes a review listing cursor into the typed sort value
+// and the ID of the last review of the previous page.
+func decodeReviewCursor(token string, sort string) (interface{}, uint, error) {
+	switch reviewSortColumns[sort].column {
+	case "created_at":
+		var value time.Time
+		id, err := decodeCursor(token, sort, &value)
+		return value, id, err
+	case "helpful_score":
+		var value float64
+		id, err := decodeCursor(token, sort, &value)
+		return value, id, err
+	}
+
+	var value int
+	id, err := decodeCursor(token, sort, &value)
+	return value, id, err
+}
+
+// reviewPageCacheKey returns the Redis key of a cached page of product reviews.
+// The key embeds the current reviews version of the product.
+func reviewPageCacheKey(productID uint, query models.ReviewQuery) (string, error) {
+	version, err := cache.Rdb.Get(cache.Ctx, reviewVersionCacheKey(productID)).Result()
+	if err == redis.Nil {
+		version = "0"
+	} else if err != nil {
+		return "", err
+	}
+
+	rating := 0
+	if query.Rating != nil {
+		rating = *query.Rating
+	}
+	return fmt.Sprintf("product:%d:reviews:%s:%s:%d:%t:%d:%s",
+		productID, version, query.Sort, rating, query.Verified, query.Limit, query.Cursor), nil
+}
+
+// invalidateProductReviewPages drops every cached page of reviews of a product
+// by moving the product to a new reviews version.
+func invalidateProductReviewPages(productID uint) error {
+	version := strconv.FormatInt(time.Now().UnixNano(), 10)
+	return cache.Rdb.Set(cache.Ctx, reviewVersionCacheKey(productID), version, 0).Err()
+}
+
+// reviewVersionCacheKey returns the Redis key holding the reviews version of a product.
+func reviewVersionCacheKey(productID uint) string {
+	return "product:" + strconv.Itoa(int(productID)) + ":reviews:version"
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"errors"
+	"go_api_product_review/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ErrInvalidReplyParent is returned when a reply answers a reply that is not part of the same review.
+var ErrInvalidReplyParent = errors.New("parent reply does not belong to the review")
+
+// CreateReply adds the reply of a merchant to a review, answering the review itself or,
+// with a parentID, another reply of the review. The review is cached again with its replies.
+// It returns ErrNotFound if the review does not exist or is not public, and ErrInvalidReplyParent
+// if the parent is not a reply of the review.
+func CreateReply(db *gorm.DB, reviewID uint, parentID *uint, author string, body string) (*models.ReviewReply, error) {
+	reply := models.ReviewReply{ReviewID: reviewID, ParentID: parentID, AuthorSubject: author, Body: body}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var review models.Review
+		result := tx.Select("id").Where("status = ?", models.ReviewApproved).First(&review, reviewID)
+		if gorm.IsRecordNotFoundError(result.Error) {
+			return ErrNotFound
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if parentID != nil {
+			var parent models.ReviewReply
+			result = tx.Where("review_id = ?", reviewID).First(&parent, *parentID)
+			if gorm.IsRecordNotFoundError(result.Error) {
+				return ErrInvalidReplyParent
+			}
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+
+		return tx.Create(&reply).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = refreshCachedReview(db, reviewID)
+	if err != nil {
+		return nil, err
+	}
+	return &reply, nil
+}
+
+// GetReply retrieves a reply of a review.
+// It returns ErrNotFound if the review has no such reply.
+func GetReply(db *gorm.DB, reviewID uint, replyID uint) (*models.ReviewReply, error) {
+	var reply models.ReviewReply
+	result := db.Where("review_id = ?", reviewID).First(&reply, replyID)
+	if gorm.IsRecordNotFoundError(result.Error) {
+		return nil, ErrNotFound
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &reply, nil
+}
+
+// UpdateReply changes the text of a reply of a review and caches the review again with its replies.
+// It returns ErrNotFound if the review has no such reply.
+func UpdateReply(db *gorm.DB, reviewID uint, replyID uint, body string) (*models.ReviewReply, error) {
+	reply, err := GetReply(db, reviewID, replyID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := db.Model(reply).Update("body", body)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	err = refreshCachedReview(db, reviewID)
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// DeleteReply deletes a reply of a review along with the replies answering it,
+// and caches the review again with its remaining replies.
+// It returns ErrNotFound if the review has no such reply.
+func DeleteReply(db *gorm.DB, reviewID uint, replyID uint) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		replies, err := findReplies(tx, reviewID)
+		if err != nil {
+			return err
+		}
+
+		// Collect the reply and every reply below it in its thread
+		deleted := map[uint]bool{}
+		for _, reply := range replies {
+			if reply.ID == replyID {
+				deleted[replyID] = true
+			}
+		}
+		if !deleted[replyID] {
+			return ErrNotFound
+		}
+		for found := true; found; {
+			found = false
+			for _, reply := range replies {
+				if reply.ParentID != nil && deleted[*reply.ParentID] && !deleted[reply.ID] {
+					deleted[reply.ID] = true
+					found = true
+				}
+			}
+		}
+
+		ids := make([]uint, 0, len(deleted))
+		for id := range deleted {
+			ids = append(ids, id)
+		}
+		return tx.Where("id IN (?)", ids).Delete(&models.ReviewReply{}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return refreshCachedReview(db, reviewID)
+}
+
+// findReplies loads the replies of a review, oldest first.
+func findReplies(db *gorm.DB, reviewID uint) ([]models.ReviewReply, error) {
+	var replies []models.ReviewReply
+	result := db.Where("review_id = ?", reviewID).Order("created_at ASC, id ASC").Find(&replies)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return replies, nil
+}
+
+// preloadReplies makes the query load the replies of the reviews it finds, oldest first.
+// association is the path of the replies from the queried model, such as "Replies" or "Reviews.Replies".
+func preloadReplies(db *gorm.DB, association string) *gorm.DB {
+	return db.Preload(association, func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at ASC, id ASC")
+	})
+}
@@ -0,0 +1,349 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go_api_product_review/cache"
+	"go_api_product_review/models"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/jinzhu/gorm"
+)
+
+// ErrSelfVote is returned when the author of a review votes on it.
+var ErrSelfVote = errors.New("authors cannot vote on their own reviews")
+
+// helpfulConfidence is the z-score of the Wilson interval ordering the reviews by helpfulness, 95%
+const helpfulConfidence = 1.96
+
+// voteDirtyKey is the Redis set of the reviews whose tallies changed since they were last persisted
+const voteDirtyKey = "reviews:votes:dirty"
+
+// voteFlushBatch is the number of reviews persisted per round
+const voteFlushBatch = 100
+
+// CastVote records the helpful or unhelpful vote of a caller on an approved review,
+// replacing the previous vote of the caller. The vote is stored in the database, which allows
+// a single vote per caller and review, and the live tally of the review is updated in Redis.
+// It returns ErrNotFound if the review does not exist or is not public, and ErrSelfVote
+// if the caller wrote the review.
+func CastVote(db *gorm.DB, reviewID uint, voter string, helpful bool) (*models.VoteTally, error) {
+	var helpfulDelta, unhelpfulDelta int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var review models.Review
+		result := tx.Where("status = ?", models.ReviewApproved).First(&review, reviewID)
+		if gorm.IsRecordNotFoundError(result.Error) {
+			return ErrNotFound
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		if review.AuthorSubject != "" && review.AuthorSubject == voter {
+			return ErrSelfVote
+		}
+
+		var vote models.ReviewVote
+		result = tx.Where("review_id = ? AND voter = ?", reviewID, voter).First(&vote)
+		if gorm.IsRecordNotFoundError(result.Error) {
+			vote = models.ReviewVote{ReviewID: reviewID, Voter: voter, Helpful: helpful}
+			inserted, err := createIfAbsent(tx, &vote)
+			if err != nil {
+				return err
+			}
+			if inserted {
+				helpfulDelta, unhelpfulDelta = voteDelta(helpful, 1)
+				return nil
+			}
+			// A concurrent first vote of the caller was stored meanwhile, replace it
+			vote = models.ReviewVote{}
+			result = tx.Where("review_id = ? AND voter = ?", reviewID, voter).First(&vote)
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		if vote.Helpful == helpful {
+			// Voting twice the same way changes nothing
+			return nil
+		}
+
+		// A concurrent vote of the caller that already changed the vote matches no row
+		previous := vote.Helpful
+		result = tx.Model(&vote).Where("helpful = ?", previous).Update("helpful", helpful)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		helpfulDelta, unhelpfulDelta = voteDelta(previous, -1)
+		h, u := voteDelta(helpful, 1)
+		helpfulDelta += h
+		unhelpfulDelta += u
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return applyVoteDelta(db, reviewID, helpfulDelta, unhelpfulDelta)
+}
+
+// DeleteVote withdraws the vote of a caller on a review.
+// It returns ErrNotFound if the caller has not voted on the review.
+func DeleteVote(db *gorm.DB, reviewID uint, voter string) (*models.VoteTally, error) {
+	var vote models.ReviewVote
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("review_id = ? AND voter = ?", reviewID, voter).First(&vote)
+		if gorm.IsRecordNotFoundError(result.Error) {
+			return ErrNotFound
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		return tx.Delete(&vote).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	helpfulDelta, unhelpfulDelta := voteDelta(vote.Helpful, -1)
+	return applyVoteDelta(db, reviewID, helpfulDelta, unhelpfulDelta)
+}
+
+// GetVoteTally returns the live vote tally of a review.
+// Tallies are kept in Redis without expiry so that votes can increment them atomically;
+// a missing tally is counted from the votes in the database and cached.
+func GetVoteTally(db *gorm.DB, reviewID uint) (*models.VoteTally, error) {
+	tally, err := readVoteTally(reviewID)
+	if err != redis.Nil {
+		return tally, err
+	}
+
+	counted, _, _, err := seedVoteTally(db, reviewID)
+	if err != nil {
+		return nil, err
+	}
+	tally, err = readVoteTally(reviewID)
+	if err == redis.Nil {
+		// The tally was dropped by a persistence meanwhile
+		return counted, nil
+	}
+	return tally, err
+}
+
+// readVoteTally reads the live tally of a review from Redis.
+// It returns redis.Nil if one of its keys is missing.
+func readVoteTally(reviewID uint) (*models.VoteTally, error) {
+	helpfulKey, unhelpfulKey := voteTallyCacheKeys(reviewID)
+	helpful, err := cache.Rdb.Get(cache.Ctx, helpfulKey).Int64()
+	if err != nil {
+		return nil, err
+	}
+	unhelpful, err := cache.Rdb.Get(cache.Ctx, unhelpfulKey).Int64()
+	if err != nil {
+		return nil, err
+	}
+	return &models.VoteTally{ReviewID: reviewID, Helpful: helpful, Unhelpful: unhelpful}, nil
+}
+
+// seedVoteTally counts the votes of a review in the database and sets the missing keys of its
+// live tally with SETNX, so that a tally seeded or incremented meanwhile is never overwritten.
+// It returns the counted tally and whether the helpful and unhelpful keys were set from it.
+func seedVoteTally(db *gorm.DB, reviewID uint) (*models.VoteTally, bool, bool, error) {
+	tally, err := countVotes(db, reviewID)
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	helpfulKey, unhelpfulKey := voteTallyCacheKeys(reviewID)
+	helpfulSet, err := cache.Rdb.SetNX(cache.Ctx, helpfulKey, tally.Helpful, 0).Result()
+	if err != nil {
+		return nil, false, false, err
+	}
+	unhelpfulSet, err := cache.Rdb.SetNX(cache.Ctx, unhelpfulKey, tally.Unhelpful, 0).Result()
+	if err != nil {
+		return nil, false, false, err
+	}
+	return tally, helpfulSet, unhelpfulSet, nil
+}
+
+// countVotes counts the helpful and unhelpful votes of a review in the database.
+func countVotes(db *gorm.DB, reviewID uint) (*models.VoteTally, error) {
+	rows, err := db.Model(&models.ReviewVote{}).
+		Select("helpful, COUNT(*)").
+		Where("review_id = ?", reviewID).
+		Group("helpful").
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tally := &models.VoteTally{ReviewID: reviewID}
+	for rows.Next() {
+		var isHelpful bool
+		var count int64
+		if err := rows.Scan(&isHelpful, &count); err != nil {
+			return nil, err
+		}
+		if isHelpful {
+			tally.Helpful = count
+		} else {
+			tally.Unhelpful = count
+		}
+	}
+	return tally, rows.Err()
+}
+
+// applyVoteDelta increments the live tally of a review once a vote is committed,
+// and marks the review for the next persistence of the tallies. A missing tally is seeded
+// from the votes, which already hold this one, and only the keys seeded by another
+// request are incremented.
+func applyVoteDelta(db *gorm.DB, reviewID uint, helpfulDelta int64, unhelpfulDelta int64) (*models.VoteTally, error) {
+	if helpfulDelta == 0 && unhelpfulDelta == 0 {
+		return GetVoteTally(db, reviewID)
+	}
+
+	var counted *models.VoteTally
+	var helpfulSet, unhelpfulSet bool
+	_, err := readVoteTally(reviewID)
+	if err == redis.Nil {
+		counted, helpfulSet, unhelpfulSet, err = seedVoteTally(db, reviewID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	helpfulKey, unhelpfulKey := voteTallyCacheKeys(reviewID)
+	tally := &models.VoteTally{ReviewID: reviewID}
+	if helpfulSet {
+		tally.Helpful = counted.Helpful
+	} else {
+		tally.Helpful, err = cache.Rdb.IncrBy(cache.Ctx, helpfulKey, helpfulDelta).Result()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if unhelpfulSet {
+		tally.Unhelpful = counted.Unhelpful
+	} else {
+		tally.Unhelpful, err = cache.Rdb.IncrBy(cache.Ctx, unhelpfulKey, unhelpfulDelta).Result()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = cache.Rdb.SAdd(cache.Ctx, voteDirtyKey, reviewID).Err()
+	if err != nil {
+		return nil, err
+	}
+	return tally, nil
+}
+
+// PersistVoteTallies recounts the votes of the reviews voted on since the last run from the
+// database into their vote counts and helpfulness scores, and drops the cached reads and the
+// live tallies of those reviews, so that a tally that drifted is seeded again from the votes.
+// It returns the number of reviews persisted.
+func PersistVoteTallies(db *gorm.DB) (int, error) {
+	persisted := 0
+	for {
+		members, err := cache.Rdb.SPopN(cache.Ctx, voteDirtyKey, voteFlushBatch).Result()
+		if err != nil {
+			return persisted, err
+		}
+		if len(members) == 0 {
+			return persisted, nil
+		}
+
+		for i, member := range members {
+			id, err := strconv.Atoi(member)
+			if err != nil {
+				continue
+			}
+			err = persistVoteTally(db, uint(id))
+			if err != nil {
+				// Keep the reviews not persisted yet for the next run
+				pending := make([]interface{}, 0, len(members)-i)
+				for _, rest := range members[i:] {
+					pending = append(pending, rest)
+				}
+				cache.Rdb.SAdd(cache.Ctx, voteDirtyKey, pending...)
+				return persisted, err
+			}
+			persisted++
+		}
+	}
+}
+
+// persistVoteTally writes the votes of a review counted in the database to the review.
+func persistVoteTally(db *gorm.DB, reviewID uint) error {
+	var review models.Review
+	result := db.Select("id, product_id").First(&review, reviewID)
+	if gorm.IsRecordNotFoundError(result.Error) {
+		return nil // The review was deleted meanwhile
+	}
+	if result.Error != nil {
+		return result.Error
+	}
+
+	tally, err := countVotes(db, reviewID)
+	if err != nil {
+		return err
+	}
+	result = db.Model(&models.Review{}).Where("id = ?", reviewID).UpdateColumns(map[string]interface{}{
+		"helpful_count":   tally.Helpful,
+		"unhelpful_count": tally.Unhelpful,
+		"helpful_score":   helpfulnessScore(tally.Helpful, tally.Unhelpful),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	helpfulKey, unhelpfulKey := voteTallyCacheKeys(reviewID)
+	keys := []string{"review:" + strconv.Itoa(int(reviewID)), productCacheKey(review.ProductID), helpfulKey, unhelpfulKey}
+	err = cache.Rdb.Del(cache.Ctx, keys...).Err()
+	if err != nil {
+		return err
+	}
+	return invalidateProductReviewPages(review.ProductID)
+}
+
+// RunVoteTallyFlusher persists the live vote tallies every interval until the context is cancelled.
+func RunVoteTallyFlusher(ctx context.Context, db *gorm.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := PersistVoteTallies(db); err != nil {
+				log.Printf("failed to persist vote tallies: %v", err)
+			}
+		}
+	}
+}
+
+// helpfulnessScore orders the reviews by helpfulness: the lower bound of the Wilson score interval
+// of the share of helpful votes, so that a few votes weigh less than many.
+func helpfulnessScore(helpful int64, unhelpful int64) float64 {
+	return wilsonLowerBound(float64(helpful), float64(helpful+unhelpful), helpfulConfidence)
+}
+
+// voteDelta returns the change of the helpful and unhelpful tallies for a vote counted sign times.
+func voteDelta(helpful bool, sign int64) (int64, int64) {
+	if helpful {
+		return sign, 0
+	}
+	return 0, sign
+}
+
+// voteTallyCacheKeys returns the Redis keys of the helpful and unhelpful tallies of a review.
+func voteTallyCacheKeys(reviewID uint) (string, string) {
+	prefix := "review:" + strconv.Itoa(int(reviewID)) + ":votes:"
+	return prefix + "helpful", prefix + "unhelpful"
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"fmt"
+	"go_api_product_review/models"
+	"go_api_product_review/screening"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ScreeningModerator is recorded as the moderator of the reviews flagged by the content screening
+const ScreeningModerator = "screening"
+
+// ScreeningError is returned when the content screening rejects the text of a review.
+type ScreeningError struct {
+	Result screening.Result
+}
+
+// Error lists the rules matched by the review text.
+func (e *ScreeningError) Error() string {
+	return fmt.Sprintf("review rejected by content screening: %s", strings.Join(e.Result.Rules(), ", "))
+}
+
+// screener holds the content screening pipeline run on the reviews before they are saved.
+var screener = struct {
+	sync.RWMutex
+	pipeline *screening.Pipeline
+}{pipeline: screening.NewPipeline(screening.DefaultConfig())}
+
+// InitScreening sets the content screening pipeline, nil turns the screening off.
+func InitScreening(pipeline *screening.Pipeline) {
+	screener.Lock()
+	defer screener.Unlock()
+	screener.pipeline = pipeline
+}
+
+// screenReview runs the content screening on the text of a review.
+// It returns a ScreeningError if the text is rejected, and flags the review
+// with the matched rules as its moderation reason if the text is suspicious.
+func screenReview(review *models.Review) error {
+	screener.RLock()
+	pipeline := screener.pipeline
+	screener.RUnlock()
+	if pipeline == nil {
+		return nil
+	}
+
+	result := pipeline.Screen(reviewContent(review))
+	switch result.Verdict {
+	case screening.Reject:
+		return &ScreeningError{Result: result}
+	case screening.Flag:
+		flagReview(review, ScreeningModerator, "Flagged by content screening: "+strings.Join(result.Rules(), ", "))
+	}
+	return nil
+}
+
+// flagReview takes a review down for moderation on behalf of an automatic check.
+// The reasons of reviews flagged by several checks are joined, the first check is the moderator.
+func flagReview(review *models.Review, moderator string, reason string) {
+	if review.Status == models.ReviewFlagged && review.ModerationReason != "" {
+		review.ModerationReason += "; " + reason
+		return
+	}
+
+	now := time.Now()
+	review.Status = models.ReviewFlagged
+	review.ModerationReason = reason
+	review.ModeratedBy = moderator
+	review.ModeratedAt = &now
+}
+
+// reviewContent returns the text written by the reviewer: the review text, the pros and the cons.
+func reviewContent(review *models.Review) string {
+	content := review.ReviewText
+	for _, text := range []string{review.Pros, review.Cons} {
+		if text != "" {
+			content += "\n" + text
+		}
+	}
+	return content
+}
@@ -0,0 +1,350 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go_api_product_review/events"
+	"go_api_product_review/models"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// WebhookSignatureHeader holds the HMAC-SHA256 signature of a webhook delivery
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	// WebhookTimestampHeader holds the Unix time the delivery was signed at
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	// WebhookEventHeader holds the type of the delivered event
+	WebhookEventHeader = "X-Webhook-Event"
+	// WebhookIDHeader holds the ID of the delivered event
+	WebhookIDHeader = "X-Webhook-ID"
+
+	// webhookMaxAttempts is the number of attempts after which a delivery is abandoned
+	webhookMaxAttempts = 8
+	// webhookBaseBackoff is the delay before retrying a delivery that failed once
+	webhookBaseBackoff = 5 * time.Second
+	// webhookMaxBackoff caps the delay between two delivery attempts
+	webhookMaxBackoff = time.Hour
+	// webhookSubscriptionBatch is the number of deliveries sent to the same subscription per batch
+	webhookSubscriptionBatch = 10
+	// webhookClaimLease is how long a claimed delivery is kept from the other dispatchers,
+	// longer than sending a batch of deliveries to a subscription takes
+	webhookClaimLease = 5 * time.Minute
+)
+
+// CreateWebhook stores a new webhook subscription.
+func CreateWebhook(db *gorm.DB, subscription *models.WebhookSubscription) (*models.WebhookSubscription, error) {
+	result := db.Create(subscription)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return subscription, nil
+}
+
+// ListWebhooks retrieves all webhook subscriptions.
+func ListWebhooks(db *gorm.DB) ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	result := db.Order("id ASC").Find(&subscriptions)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return subscriptions, nil
+}
+
+// DeleteWebhook deletes a webhook subscription by its ID.
+// Its pending deliveries are no longer attempted.
+// It returns ErrNotFound if the subscription does not exist.
+func DeleteWebhook(db *gorm.DB, id uint) error {
+	result := db.Delete(&models.WebhookSubscription{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListWebhookDeliveries retrieves the latest deliveries of a webhook subscription, newest first.
+// It returns ErrNotFound if the subscription does not exist.
+func ListWebhookDeliveries(db *gorm.DB, subscriptionID uint, limit int) ([]models.WebhookDelivery, error) {
+	var subscription models.WebhookSubscription
+	result := db.First(&subscription, subscriptionID)
+	if gorm.IsRecordNotFoundError(result.Error) {
+		return nil, ErrNotFound
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	var deliveries []models.WebhookDelivery
+	result = db.Where("subscription_id = ?", subscriptionID).Order("id DESC").Limit(limit).Find(&deliveries)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return deliveries, nil
+}
+
+// WebhookPublisher is an event publisher scheduling a delivery for every
+// webhook subscription interested in the event. The deliveries themselves
+// are sent by DeliverWebhooks, so a slow partner never blocks the publisher.
+type WebhookPublisher struct {
+	db *gorm.DB
+}
+
+// NewWebhookPublisher creates a publisher scheduling webhook deliveries in the given database.
+func NewWebhookPublisher(db *gorm.DB) *WebhookPublisher {
+	return &WebhookPublisher{db: db}
+}
+
+// Publish schedules the delivery of the event to the matching subscriptions.
+func (p *WebhookPublisher) Publish(ctx context.Context, event events.Event) error {
+	var subscriptions []models.WebhookSubscription
+	result := p.db.Where("product_id = 0 OR product_id = ?", event.ProductID).Find(&subscriptions)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return p.db.Transaction(func(tx *gorm.DB) error {
+		for _, subscription := range subscriptions {
+			if !subscription.HasEventType(event.Type) {
+				continue
+			}
+
+			// The outbox delivers events at least once and every replica relays it,
+			// the unique index of the deliveries skips the ones already scheduled
+			_, err := createIfAbsent(tx, &models.WebhookDelivery{
+				SubscriptionID: subscription.ID,
+				EventID:        event.ID,
+				EventType:      event.Type,
+				Payload:        string(payload),
+				NextAttemptAt:  time.Now().UTC(),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeliverWebhooks sends up to batchSize due webhook deliveries, oldest first, and at most
+// webhookSubscriptionBatch of them to the same subscription.
+// Every delivery is claimed before it is sent, so concurrent dispatchers never send the same one.
+// The subscriptions are sent to concurrently and the deliveries of each one in order; once an
+// attempt fails, the following deliveries of the subscription wait for its retry, so a dead partner
+// holds up no other subscription. Failed deliveries are retried with an exponential backoff and
+// abandoned after webhookMaxAttempts attempts. It returns the number of successful deliveries.
+func DeliverWebhooks(db *gorm.DB, client *http.Client, batchSize int) (int, error) {
+	now := time.Now().UTC()
+	var due []models.WebhookDelivery
+	result := db.Where("delivered_at IS NULL AND failed_at IS NULL AND next_attempt_at <= ?", now).
+		Order("id ASC").
+		Limit(batchSize).
+		Find(&due)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	// Claim the deliveries by moving their next attempt past the time needed to send them,
+	// the deliveries claimed meanwhile by another dispatcher are no longer due
+	var subscriptionIDs []uint
+	claimed := map[uint][]models.WebhookDelivery{}
+	for _, delivery := range due {
+		if len(claimed[delivery.SubscriptionID]) >= webhookSubscriptionBatch {
+			continue
+		}
+		lease := now.Add(webhookClaimLease)
+		result := db.Model(&models.WebhookDelivery{}).
+			Where("id = ? AND delivered_at IS NULL AND failed_at IS NULL AND next_attempt_at <= ?", delivery.ID, now).
+			UpdateColumn("next_attempt_at", lease)
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		delivery.NextAttemptAt = lease
+		if _, ok := claimed[delivery.SubscriptionID]; !ok {
+			subscriptionIDs = append(subscriptionIDs, delivery.SubscriptionID)
+		}
+		claimed[delivery.SubscriptionID] = append(claimed[delivery.SubscriptionID], delivery)
+	}
+	if len(subscriptionIDs) == 0 {
+		return 0, nil
+	}
+
+	var subscriptions []models.WebhookSubscription
+	result = db.Where("id IN (?)", subscriptionIDs).Find(&subscriptions)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	subscriptionsByID := make(map[uint]models.WebhookSubscription, len(subscriptions))
+	for _, subscription := range subscriptions {
+		subscriptionsByID[subscription.ID] = subscription
+	}
+
+	var wg sync.WaitGroup
+	for _, subscriptionID := range subscriptionIDs {
+		subscription, ok := subscriptionsByID[subscriptionID]
+		if !ok {
+			// The subscription was deleted, give up on its deliveries
+			failedAt := time.Now().UTC()
+			for i := range claimed[subscriptionID] {
+				claimed[subscriptionID][i].LastError = "subscription deleted"
+				claimed[subscriptionID][i].FailedAt = &failedAt
+			}
+			continue
+		}
+		wg.Add(1)
+		go func(deliveries []models.WebhookDelivery) {
+			defer wg.Done()
+			sendWebhooks(client, subscription, deliveries)
+		}(claimed[subscriptionID])
+	}
+	wg.Wait()
+
+	delivered := 0
+	for _, subscriptionID := range subscriptionIDs {
+		for _, delivery := range claimed[subscriptionID] {
+			if delivery.DeliveredAt != nil {
+				delivered++
+			}
+			if err := db.Save(&delivery).Error; err != nil {
+				return delivered, err
+			}
+		}
+	}
+
+	return delivered, nil
+}
+
+// sendWebhooks attempts the claimed deliveries of a subscription in order and records their outcome.
+// Once an attempt fails, the following deliveries are postponed to its retry without being attempted.
+func sendWebhooks(client *http.Client, subscription models.WebhookSubscription, deliveries []models.WebhookDelivery) {
+	var retryAt *time.Time
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		if retryAt != nil {
+			delivery.NextAttemptAt = *retryAt
+			continue
+		}
+
+		delivery.Attempts++
+		delivery.StatusCode, delivery.LastError = sendWebhook(client, subscription, *delivery)
+		now := time.Now().UTC()
+		if delivery.LastError == "" {
+			delivery.DeliveredAt = &now
+			continue
+		}
+		next := now.Add(webhookBackoff(delivery.Attempts))
+		retryAt = &next
+		if delivery.Attempts >= webhookMaxAttempts {
+			delivery.FailedAt = &now
+		} else {
+			delivery.NextAttemptAt = next
+		}
+	}
+}
+
+// RunWebhookDispatcher sends the due webhook deliveries every interval until the context is cancelled.
+func RunWebhookDispatcher(ctx context.Context, db *gorm.DB, client *http.Client, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := DeliverWebhooks(db, client, 100); err != nil {
+				log.Printf("failed to deliver webhooks: %v", err)
+			}
+		}
+	}
+}
+
+// SignWebhookPayload returns the signature sent in the WebhookSignatureHeader:
+// the hex encoded HMAC-SHA256 of "<timestamp>.<body>" keyed with the subscription secret,
+// prefixed with "sha256=". Receivers recompute it to authenticate the delivery.
+func SignWebhookPayload(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// sendWebhook POSTs a delivery to its subscription and returns the response status
+// and an error message, which is empty when the target acknowledged it with a 2xx status.
+func sendWebhook(client *http.Client, subscription models.WebhookSubscription, delivery models.WebhookDelivery) (int, string) {
+	body := []byte(delivery.Payload)
+	request, err := http.NewRequest(http.MethodPost, subscription.TargetURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err.Error()
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(WebhookEventHeader, delivery.EventType)
+	request.Header.Set(WebhookIDHeader, delivery.EventID)
+	request.Header.Set(WebhookTimestampHeader, timestamp)
+	request.Header.Set(WebhookSignatureHeader, SignWebhookPayload(subscription.Secret, timestamp, body))
+
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Sprintf("unexpected status %d", response.StatusCode)
+	}
+	return response.StatusCode, ""
+}
+
+// webhookBackoff returns the delay before the next attempt of a delivery
+// that already failed the given number of times.
+func webhookBackoff(attempts int) time.Duration {
+	backoff := webhookBaseBackoff
+	for i := 1; i < attempts && backoff < webhookMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > webhookMaxBackoff {
+		return webhookMaxBackoff
+	}
+	return backoff
+}
+
+// createIfAbsent inserts a record unless it conflicts with a unique index of its table,
+// and reports whether it was inserted. It does not abort the transaction it runs in on conflict.
+func createIfAbsent(tx *gorm.DB, value interface{}) (bool, error) {
+	result := tx.Set("gorm:insert_option", "ON CONFLICT DO NOTHING").Create(value)
+	// PostgreSQL returns no ID for the skipped row
+	if errors.Is(result.Error, sql.ErrNoRows) {
+		return false, nil
+	}
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
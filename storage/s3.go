@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Config configures the S3 backend.
+type S3Config struct {
+	// Endpoint is the URL of the S3-compatible service, such as https://s3.us-east-1.amazonaws.com
+	// or the URL of a local MinIO server
+	Endpoint string
+	// Bucket holds the objects, addressed path-style as <endpoint>/<bucket>/<key>
+	Bucket string
+	// Region is the region the requests are signed for
+	Region string
+	// AccessKeyID and SecretAccessKey sign the requests with AWS Signature Version 4
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// S3Store stores the objects in a bucket of an S3-compatible object storage,
+// speaking the S3 REST API with requests signed with AWS Signature Version 4.
+type S3Store struct {
+	config S3Config
+	client *http.Client
+}
+
+// NewS3Store returns a store of the objects of the configured bucket.
+// A nil client uses a client timing out after 30 seconds.
+func NewS3Store(config S3Config, client *http.Client) *S3Store {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &S3Store{config: config, client: client}
+}
+
+// Put uploads the data as the object of the key.
+func (s *S3Store) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	response, err := s.do(ctx, http.MethodPut, key, data, contentType)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return s.statusError(http.MethodPut, key, response)
+	}
+	return nil
+}
+
+// Get downloads the object of the key.
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	response, err := s.do(ctx, http.MethodGet, key, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode == http.StatusNotFound {
+		response.Body.Close()
+		return nil, ErrNotFound
+	}
+	if response.StatusCode != http.StatusOK {
+		defer response.Body.Close()
+		return nil, s.statusError(http.MethodGet, key, response)
+	}
+	return response.Body, nil
+}
+
+// Delete removes the object of the key.
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	response, err := s.do(ctx, http.MethodDelete, key, nil, "")
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusNoContent && response.StatusCode != http.StatusOK &&
+		response.StatusCode != http.StatusNotFound {
+		return s.statusError(http.MethodDelete, key, response)
+	}
+	return nil
+}
+
+// do sends a signed request on the object of the key.
+func (s *S3Store) do(ctx context.Context, method string, key string, body []byte, contentType string) (*http.Response, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, err
+	}
+	url := strings.TrimRight(s.config.Endpoint, "/") + "/" + s.config.Bucket + "/" + key
+	request, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	s.sign(request, body)
+	return s.client.Do(request)
+}
+
+// sign adds the AWS Signature Version 4 headers to the request.
+// Keys never need escaping, so the path of the request is already canonical.
+func (s *S3Store) sign(request *http.Request, body []byte) {
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{
+		"host":                 request.URL.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           amzDate,
+	}
+	if contentType := request.Header.Get("Content-Type"); contentType != "" {
+		headers["content-type"] = contentType
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		request.URL.EscapedPath(),
+		request.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + s.config.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.config.SecretAccessKey), date)
+	signingKey = hmacSHA256(signingKey, s.config.Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.config.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// statusError describes an unexpected response, with the start of the error document of the service.
+func (s *S3Store) statusError(method string, key string, response *http.Response) error {
+	message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+	return fmt.Errorf("s3 %s %s: %s: %s", method, key, response.Status, strings.TrimSpace(string(message)))
+}
+
+// sha256Hex returns the hex-encoded SHA-256 hash of the data.
+func sha256Hex(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of the data with the key.
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
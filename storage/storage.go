@@ -0,0 +1,116 @@
+// Package storage keeps the files uploaded with the reviews, such as photos and their thumbnails,
+// on the local filesystem or in an S3-compatible object storage.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrNotFound is returned when the requested object does not exist.
+var ErrNotFound = errors.New("object not found")
+
+// Backends of the object storage
+const (
+	// Filesystem stores the objects as files under a directory
+	Filesystem = "filesystem"
+	// S3 stores the objects in a bucket of an S3-compatible object storage
+	S3 = "s3"
+)
+
+// Store keeps objects under keys made of letters, digits, '-', '_', '.' and '/'.
+type Store interface {
+	// Put stores the data under the key, replacing any previous object
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get opens the object stored under the key, it returns ErrNotFound if there is none
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object stored under the key, deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// Config selects and configures the backend of the object storage.
+type Config struct {
+	// Backend is Filesystem or S3
+	Backend string
+	// Dir is the root directory of the Filesystem backend
+	Dir string
+	// S3 configures the S3 backend
+	S3 S3Config
+}
+
+// LoadConfigFromEnv builds the storage configuration from environment variables:
+//   - MEDIA_STORAGE: filesystem (default) or s3
+//   - MEDIA_DIR: root directory of the filesystem backend, ./uploads by default
+//   - S3_ENDPOINT, S3_BUCKET: URL of the S3-compatible service and bucket of the objects
+//   - S3_REGION: region the requests are signed for, us-east-1 by default
+//   - S3_ACCESS_KEY_ID, S3_SECRET_ACCESS_KEY: credentials of the S3 backend
+func LoadConfigFromEnv() (Config, error) {
+	config := Config{
+		Backend: os.Getenv("MEDIA_STORAGE"),
+		Dir:     os.Getenv("MEDIA_DIR"),
+		S3: S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			Region:          os.Getenv("S3_REGION"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+		},
+	}
+	if config.Backend == "" {
+		config.Backend = Filesystem
+	}
+	if config.Dir == "" {
+		config.Dir = "./uploads"
+	}
+	if config.S3.Region == "" {
+		config.S3.Region = "us-east-1"
+	}
+
+	switch config.Backend {
+	case Filesystem:
+	case S3:
+		if config.S3.Endpoint == "" || config.S3.Bucket == "" {
+			return config, errors.New("S3_ENDPOINT and S3_BUCKET are required by the s3 storage")
+		}
+	default:
+		return config, fmt.Errorf("invalid MEDIA_STORAGE %q, expected filesystem or s3", config.Backend)
+	}
+	return config, nil
+}
+
+// New builds the store of the configured backend.
+func New(config Config) (Store, error) {
+	switch config.Backend {
+	case Filesystem:
+		return NewFileStore(config.Dir)
+	case S3:
+		return NewS3Store(config.S3, nil), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", config.Backend)
+	}
+}
+
+// ValidateKey checks that the key is a relative path made of letters, digits, '-', '_' and '.',
+// so it maps to a file under the root directory and needs no escaping in URLs.
+func ValidateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.HasSuffix(key, "/") {
+		return fmt.Errorf("invalid object key %q", key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("invalid object key %q", key)
+		}
+	}
+	for _, char := range key {
+		valid := (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') || (char >= '0' && char <= '9') ||
+			char == '-' || char == '_' || char == '.' || char == '/'
+		if !valid {
+			return fmt.Errorf("invalid object key %q", key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+package servicetester
+
+import (
+	"encoding/json"
+	"go_api_product_review/cache"
+	"go_api_product_review/db"
+	"go_api_product_review/middleware"
+	"go_api_product_review/models"
+	"go_api_product_review/service"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+// callWithAPIKey performs a JSON request authenticated with an API key against the router
+func callWithAPIKey(router *gin.Engine, key string, method string, path string, body interface{}) *httptest.ResponseRecorder {
+	var payload string
+	if body != nil {
+		data, _ := json.Marshal(body)
+		payload = string(data)
+	}
+	request := httptest.NewRequest(method, path, strings.NewReader(payload))
+	request.Header.Set(middleware.APIKeyHeader, key)
+	request.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// issueAPIKey creates an API key through the admin endpoint and returns the response
+func issueAPIKey(t *testing.T, router *gin.Engine, request models.APIKeyRequest) models.IssuedAPIKeyResponse {
+	admin := tokenFor(t, "admin", middleware.RoleAdmin)
+	recorder := callAPI(router, admin, http.MethodPost, "/api-keys/", request)
+	assert.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+
+	var issued models.IssuedAPIKeyResponse
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &issued))
+	return issued
+}
+
+// TestAPIKeyScopes tests that API keys are limited by both their roles and their scopes
+func TestAPIKeyScopes(t *testing.T) {
+	router := newAPIRouter(t)
+	product := models.Product{Name: "Bananas", Price: 2.5}
+	db.DB.Create(&product)
+
+	issued := issueAPIKey(t, router, models.APIKeyRequest{
+		Name:   "Partner storefront",
+		Scopes: []string{middleware.ScopeProductsRead, middleware.ScopeReviewsWrite},
+		Roles:  []string{middleware.RoleReviewer},
+	})
+	assert.True(t, strings.HasPrefix(issued.Key, service.APIKeyPrefix))
+	assert.True(t, strings.HasPrefix(issued.Key, issued.Prefix))
+
+	// Only the hash of the key is stored
+	var stored models.APIKey
+	db.DB.First(&stored, issued.ID)
+	assert.NotEmpty(t, stored.KeyHash)
+	assert.NotContains(t, stored.KeyHash, issued.Key)
+
+	assert.Equal(t, http.StatusOK, callWithAPIKey(router, issued.Key, http.MethodGet, "/products/", nil).Code)
+	// The key lacks the reviews:read scope and the catalog-editor role
+	path := "/products/" + strconv.Itoa(int(product.ID))
+	assert.Equal(t, http.StatusForbidden, callWithAPIKey(router, issued.Key, http.MethodGet, path+"/reviews", nil).Code)
+	assert.Equal(t, http.StatusForbidden, callWithAPIKey(router, issued.Key, http.MethodPut, path, product).Code)
+
+	review := map[string]interface{}{"product_id": product.ID, "rating": 5, "review_text": "Great"}
+	recorder := callWithAPIKey(router, issued.Key, http.MethodPost, "/reviews/", review)
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	var created models.Review
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &created))
+	assert.Equal(t, "api-key:"+strconv.Itoa(int(issued.ID)), created.AuthorSubject)
+
+	// Admin API keys still need the scope of the route
+	adminKey := issueAPIKey(t, router, models.APIKeyRequest{
+		Name:   "Catalog sync",
+		Scopes: []string{middleware.ScopeProductsWrite},
+		Roles:  []string{middleware.RoleAdmin},
+	})
+	assert.Equal(t, http.StatusForbidden, callWithAPIKey(router, adminKey.Key, http.MethodGet, "/api-keys/", nil).Code)
+	assert.Equal(t, http.StatusOK, callWithAPIKey(router, adminKey.Key, http.MethodPut, path, product).Code)
+
+	assert.Equal(t, http.StatusUnauthorized, callWithAPIKey(router, "prk_unknown", http.MethodGet, "/products/", nil).Code)
+}
+
+// TestAPIKeyLifecycle tests the listing, rotation, revocation and expiry of API keys
+func TestAPIKeyLifecycle(t *testing.T) {
+	router := newAPIRouter(t)
+	admin := tokenFor(t, "admin", middleware.RoleAdmin)
+
+	invalid := models.APIKeyRequest{Name: "Unknown", Scopes: []string{"products:destroy"}}
+	assert.Equal(t, http.StatusBadRequest, callAPI(router, admin, http.MethodPost, "/api-keys/", invalid).Code)
+	assert.Equal(t, http.StatusForbidden,
+		callAPI(router, tokenFor(t, "editor", middleware.RoleCatalogEditor), http.MethodGet, "/api-keys/", nil).Code)
+
+	issued := issueAPIKey(t, router, models.APIKeyRequest{Name: "Reporting", Scopes: []string{middleware.ScopeProductsRead}})
+	assert.Equal(t, http.StatusOK, callWithAPIKey(router, issued.Key, http.MethodGet, "/products/", nil).Code)
+
+	recorder := callAPI(router, admin, http.MethodGet, "/api-keys/", nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var listed []models.APIKeyResponse
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &listed))
+	assert.Len(t, listed, 1)
+	assert.NotNil(t, listed[0].LastUsedAt)
+	assert.NotContains(t, recorder.Body.String(), issued.Key)
+
+	// The previous key stops working right away even though it was cached
+	path := "/api-keys/" + strconv.Itoa(int(issued.ID))
+	recorder = callAPI(router, admin, http.MethodPost, path+"/rotate", nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var rotated models.IssuedAPIKeyResponse
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rotated))
+	assert.NotEqual(t, issued.Key, rotated.Key)
+	assert.Equal(t, http.StatusUnauthorized, callWithAPIKey(router, issued.Key, http.MethodGet, "/products/", nil).Code)
+	assert.Equal(t, http.StatusOK, callWithAPIKey(router, rotated.Key, http.MethodGet, "/products/", nil).Code)
+
+	assert.Equal(t, http.StatusOK, callAPI(router, admin, http.MethodDelete, path, nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, callWithAPIKey(router, rotated.Key, http.MethodGet, "/products/", nil).Code)
+	assert.Equal(t, http.StatusConflict, callAPI(router, admin, http.MethodPost, path+"/rotate", nil).Code)
+	assert.Equal(t, http.StatusNotFound, callAPI(router, admin, http.MethodDelete, "/api-keys/404", nil).Code)
+
+	// Expired keys are rejected
+	expiring := issueAPIKey(t, router, models.APIKeyRequest{Name: "Temporary", Scopes: []string{middleware.ScopeProductsRead}})
+	db.DB.Model(&models.APIKey{}).Where("id = ?", expiring.ID).UpdateColumn("expires_at", time.Now().Add(-time.Minute))
+	assert.Equal(t, http.StatusUnauthorized, callWithAPIKey(router, expiring.Key, http.MethodGet, "/products/", nil).Code)
+}
+
+// TestAPIKeyGrantEscalation tests that API keys cannot issue or rotate keys granting more than they hold
+func TestAPIKeyGrantEscalation(t *testing.T) {
+	router := newAPIRouter(t)
+
+	manager := issueAPIKey(t, router, models.APIKeyRequest{
+		Name:   "Key manager",
+		Scopes: []string{middleware.ScopeAPIKeysManage},
+		Roles:  []string{middleware.RoleAdmin},
+	})
+	escalation := models.APIKeyRequest{
+		Name:   "Everything",
+		Scopes: []string{middleware.ScopeAPIKeysManage, middleware.ScopeProductsWrite},
+		Roles:  []string{middleware.RoleAdmin},
+	}
+	recorder := callWithAPIKey(router, manager.Key, http.MethodPost, "/api-keys/", escalation)
+	assert.Equal(t, http.StatusForbidden, recorder.Code, recorder.Body.String())
+
+	// Keys within the scopes of the caller can still be issued
+	limited := models.APIKeyRequest{Name: "Sub manager", Scopes: []string{middleware.ScopeAPIKeysManage}}
+	assert.Equal(t, http.StatusCreated, callWithAPIKey(router, manager.Key, http.MethodPost, "/api-keys/", limited).Code)
+
+	// Rotating a more powerful key would hand it over to the caller
+	writer := issueAPIKey(t, router, models.APIKeyRequest{
+		Name:   "Catalog sync",
+		Scopes: []string{middleware.ScopeProductsRead, middleware.ScopeProductsWrite},
+	})
+	path := "/api-keys/" + strconv.Itoa(int(writer.ID)) + "/rotate"
+	assert.Equal(t, http.StatusForbidden, callWithAPIKey(router, manager.Key, http.MethodPost, path, nil).Code)
+	assert.Equal(t, http.StatusOK, callWithAPIKey(router, writer.Key, http.MethodGet, "/products/", nil).Code)
+}
+
+// TestAPIKeyRevokedDuringAuthentication tests that a key revoked while it is being authenticated is not cached
+func TestAPIKeyRevokedDuringAuthentication(t *testing.T) {
+	testDB, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer testDB.Close()
+	mock := NewMockRedisClient()
+	cache.InitRedis(mock)
+
+	key, plaintext, err := service.CreateAPIKey(testDB, &models.APIKey{Name: "Reporting", Scopes: middleware.ScopeProductsRead})
+	assert.NoError(t, err)
+
+	// An administrator revokes the key right after it was read as active
+	revokedMeanwhile := false
+	testDB.Callback().Query().After("gorm:query").Register("test:concurrent_revocation", func(scope *gorm.Scope) {
+		if stored, ok := scope.Value.(*models.APIKey); ok && stored.ID == key.ID && !revokedMeanwhile {
+			revokedMeanwhile = true
+			_, err := service.RevokeAPIKey(scope.NewDB(), key.ID)
+			assert.NoError(t, err)
+		}
+	})
+
+	_, err = service.AuthenticateAPIKey(testDB, plaintext)
+	assert.ErrorIs(t, err, service.ErrInvalidAPIKey)
+	assert.True(t, revokedMeanwhile)
+	assert.Empty(t, mock.data)
+	_, err = service.AuthenticateAPIKey(testDB, plaintext)
+	assert.ErrorIs(t, err, service.ErrInvalidAPIKey)
+}
+
+// TestAPIKeyRotationAndRevocationRace tests that a rotation and a revocation racing each other leave the key revoked
+func TestAPIKeyRotationAndRevocationRace(t *testing.T) {
+	testDB, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer testDB.Close()
+	cache.InitRedis(NewMockRedisClient())
+
+	// The key is revoked right after the rotation read it
+	rotated, rotatedPlaintext, err := service.CreateAPIKey(testDB, &models.APIKey{Name: "Rotated", Scopes: middleware.ScopeProductsRead})
+	assert.NoError(t, err)
+	revokedMeanwhile := false
+	testDB.Callback().Query().After("gorm:query").Register("test:concurrent_revocation", func(scope *gorm.Scope) {
+		if stored, ok := scope.Value.(*models.APIKey); ok && stored.ID == rotated.ID && !revokedMeanwhile {
+			revokedMeanwhile = true
+			_, err := service.RevokeAPIKey(scope.NewDB(), rotated.ID)
+			assert.NoError(t, err)
+		}
+	})
+	_, _, err = service.RotateAPIKey(testDB, rotated.ID)
+	assert.ErrorIs(t, err, service.ErrAPIKeyRevoked)
+	assert.True(t, revokedMeanwhile)
+	stored, err := service.GetAPIKey(testDB, rotated.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, stored.RevokedAt)
+	_, err = service.AuthenticateAPIKey(testDB, rotatedPlaintext)
+	assert.ErrorIs(t, err, service.ErrInvalidAPIKey)
+
+	// The key is rotated right after the revocation read it
+	revoked, _, err := service.CreateAPIKey(testDB, &models.APIKey{Name: "Revoked", Scopes: middleware.ScopeProductsRead})
+	assert.NoError(t, err)
+	var newPlaintext string
+	testDB.Callback().Query().After("gorm:query").Register("test:concurrent_rotation", func(scope *gorm.Scope) {
+		if stored, ok := scope.Value.(*models.APIKey); ok && stored.ID == revoked.ID && newPlaintext == "" {
+			newPlaintext = "pending"
+			_, newPlaintext, err = service.RotateAPIKey(scope.NewDB(), revoked.ID)
+			assert.NoError(t, err)
+		}
+	})
+	_, err = service.RevokeAPIKey(testDB, revoked.ID)
+	assert.NoError(t, err)
+	stored, err = service.GetAPIKey(testDB, revoked.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, stored.RevokedAt)
+	_, err = service.AuthenticateAPIKey(testDB, newPlaintext)
+	assert.ErrorIs(t, err, service.ErrInvalidAPIKey)
+}
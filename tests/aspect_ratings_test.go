@@ -0,0 +1,211 @@
+package servicetester
+
+import (
+	"encoding/json"
+	"go_api_product_review/cache"
+	"go_api_product_review/middleware"
+	"go_api_product_review/models"
+	"go_api_product_review/service"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAspectRatings tests that aspect ratings are checked against the category of the product
+// and that their aggregates follow the review changes
+func TestAspectRatings(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+
+	category, err := service.CreateCategory(db, &models.Category{Name: "Headphones", Aspects: []models.CategoryAspect{
+		{Key: "battery_life", Name: "Battery life"},
+		{Key: "value", Name: "Value"},
+	}})
+	assert.NoError(t, err)
+	product, err := service.CreateProduct(db, &models.Product{Name: "Headphones", Price: 99, CategoryID: &category.ID})
+	assert.NoError(t, err)
+	plain, err := service.CreateProduct(db, &models.Product{Name: "Bananas", Price: 2.5})
+	assert.NoError(t, err)
+
+	first, err := service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 4, AspectRatings: []models.ReviewAspectRating{
+		{Aspect: "battery_life", Rating: 5},
+		{Aspect: "value", Rating: 3},
+	}})
+	assert.NoError(t, err)
+	_, err = service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 2, Pros: "Light", Cons: "Flimsy",
+		AspectRatings: []models.ReviewAspectRating{{Aspect: "battery_life", Rating: 2}}})
+	assert.NoError(t, err)
+	pending, err := service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 1, Status: models.ReviewPending,
+		AspectRatings: []models.ReviewAspectRating{{Aspect: "value", Rating: 1}}})
+	assert.NoError(t, err)
+
+	detail, err := service.GetProductByID(db, product.ID, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.AspectRatingResponse{
+		{Aspect: "battery_life", AverageRating: 3.5, ReviewCount: 2},
+		{Aspect: "value", AverageRating: 3, ReviewCount: 1},
+	}, detail.AspectRatings)
+	assert.Len(t, detail.Reviews, 2)
+	cached, err := service.GetReview(db, first.ID)
+	assert.NoError(t, err)
+	assert.Len(t, cached.AspectRatings, 2)
+
+	// Aspects must be defined by the category of the product and rated once from 1 to 5
+	invalid := [][]models.ReviewAspectRating{
+		{{Aspect: "fit", Rating: 3}},
+		{{Aspect: "value", Rating: 3}, {Aspect: "value", Rating: 4}},
+		{{Aspect: "value", Rating: 6}},
+	}
+	for _, ratings := range invalid {
+		_, err = service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 3, AspectRatings: ratings})
+		assert.ErrorIs(t, err, service.ErrInvalidAspectRating)
+	}
+	_, err = service.CreateReview(db, &models.Review{ProductID: plain.ID, Rating: 3,
+		AspectRatings: []models.ReviewAspectRating{{Aspect: "value", Rating: 3}}})
+	assert.ErrorIs(t, err, service.ErrInvalidAspectRating)
+
+	// Updates replace the aspect ratings, approvals and deletes add and remove them
+	_, err = service.UpdateReview(db, first.ID, &models.Review{Rating: 4,
+		AspectRatings: []models.ReviewAspectRating{{Aspect: "value", Rating: 5}}}, "")
+	assert.NoError(t, err)
+	_, err = service.ModerateReview(db, pending.ID, models.ReviewApproved, "", "moderator")
+	assert.NoError(t, err)
+	var aggregates []models.ProductAspectRating
+	db.Where("product_id = ?", product.ID).Order("aspect ASC").Find(&aggregates)
+	assert.Len(t, aggregates, 2)
+	assert.Equal(t, 1, aggregates[0].ReviewCount)
+	assert.Equal(t, 2.0, aggregates[0].AverageRating)
+	assert.Equal(t, 2, aggregates[1].ReviewCount)
+	assert.Equal(t, 3.0, aggregates[1].AverageRating)
+
+	assert.NoError(t, service.DeleteReview(db, pending.ID, ""))
+	db.Where("product_id = ? AND aspect = ?", product.ID, "value").First(&aggregates[1])
+	assert.Equal(t, 1, aggregates[1].ReviewCount)
+	assert.Equal(t, 5.0, aggregates[1].AverageRating)
+	drifts, err := service.ReconcileRatingAggregates(db)
+	assert.NoError(t, err)
+	assert.Empty(t, drifts)
+
+	// Drifted aspect aggregates are found and fixed by the reconciliation
+	db.Model(&models.ProductAspectRating{}).Where("product_id = ? AND aspect = ?", product.ID, "value").
+		UpdateColumn("review_count", 7)
+	db.Create(&models.ProductAspectRating{ProductID: product.ID, Aspect: "removed", ReviewCount: 1, RatingSum: 4, AverageRating: 4})
+	drifts, err = service.ReconcileRatingAggregates(db)
+	assert.NoError(t, err)
+	if assert.Len(t, drifts, 1) {
+		assert.Equal(t, map[string]int{"battery_life": 1, "value": 7, "removed": 1}, drifts[0].StoredAspectCounts)
+		assert.Equal(t, map[string]int{"battery_life": 1, "value": 1}, drifts[0].ActualAspectCounts)
+	}
+	detail, err = service.GetProductByID(db, product.ID, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.AspectRatingResponse{
+		{Aspect: "battery_life", AverageRating: 2, ReviewCount: 1},
+		{Aspect: "value", AverageRating: 5, ReviewCount: 1},
+	}, detail.AspectRatings)
+}
+
+// TestCategoryEndpoints tests that catalog editors define the categories and that reviews are checked against them
+func TestCategoryEndpoints(t *testing.T) {
+	router := newAPIRouter(t)
+	editor := tokenFor(t, "editor", middleware.RoleCatalogEditor)
+	reviewer := tokenFor(t, "alice", middleware.RoleReviewer)
+	category := map[string]interface{}{"name": "Apparel", "aspects": []map[string]string{
+		{"key": "fit", "name": "Fit"},
+		{"key": "value", "name": "Value"},
+	}}
+
+	assert.Equal(t, http.StatusForbidden, callAPI(router, reviewer, http.MethodPost, "/categories/", category).Code)
+	assert.Equal(t, http.StatusBadRequest, callAPI(router, editor, http.MethodPost, "/categories/",
+		map[string]interface{}{"name": "Apparel", "aspects": []map[string]string{{"key": "Fit!", "name": "Fit"}}}).Code)
+
+	recorder := callAPI(router, editor, http.MethodPost, "/categories/", category)
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	var created models.Category
+	json.Unmarshal(recorder.Body.Bytes(), &created)
+	assert.Len(t, created.Aspects, 2)
+	path := "/categories/" + strconv.Itoa(int(created.ID))
+	recorder = callAPI(router, reviewer, http.MethodGet, path, nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, http.StatusNotFound, callAPI(router, reviewer, http.MethodGet, "/categories/999", nil).Code)
+
+	assert.Equal(t, http.StatusBadRequest, callAPI(router, editor, http.MethodPost, "/products/",
+		map[string]interface{}{"name": "Jacket", "price": 80, "category_id": 999}).Code)
+	recorder = callAPI(router, editor, http.MethodPost, "/products/",
+		map[string]interface{}{"name": "Jacket", "price": 80, "category_id": created.ID})
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	var product models.Product
+	json.Unmarshal(recorder.Body.Bytes(), &product)
+
+	review := map[string]interface{}{"product_id": product.ID, "rating": 4, "pros": "Warm",
+		"aspect_ratings": []map[string]interface{}{{"aspect": "fit", "rating": 6}}}
+	assert.Equal(t, http.StatusBadRequest, callAPI(router, reviewer, http.MethodPost, "/reviews/", review).Code)
+	review["aspect_ratings"] = []map[string]interface{}{{"aspect": "battery_life", "rating": 4}}
+	assert.Equal(t, http.StatusBadRequest, callAPI(router, reviewer, http.MethodPost, "/reviews/", review).Code)
+	review["aspect_ratings"] = []map[string]interface{}{{"aspect": "fit", "rating": 4}}
+	recorder = callAPI(router, reviewer, http.MethodPost, "/reviews/", review)
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	var createdReview models.ReviewResponse
+	json.Unmarshal(recorder.Body.Bytes(), &createdReview)
+	assert.Equal(t, []models.ReviewAspectRating{{Aspect: "fit", Rating: 4}}, createdReview.AspectRatings)
+	assert.Equal(t, "Warm", createdReview.Pros)
+
+	// Removed aspects can no longer be rated
+	recorder = callAPI(router, editor, http.MethodPut, path,
+		map[string]interface{}{"name": "Apparel", "aspects": []map[string]string{{"key": "value", "name": "Value"}}})
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	review["aspect_ratings"] = []map[string]interface{}{{"aspect": "fit", "rating": 4}}
+	assert.Equal(t, http.StatusBadRequest, callAPI(router, reviewer, http.MethodPost, "/reviews/", review).Code)
+}
+
+// TestRestoreAspectRatings tests that revisions keep the aspect ratings and that restores bring them back
+func TestRestoreAspectRatings(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+
+	category, err := service.CreateCategory(db, &models.Category{Name: "Headphones", Aspects: []models.CategoryAspect{
+		{Key: "battery_life", Name: "Battery life"},
+		{Key: "value", Name: "Value"},
+	}})
+	assert.NoError(t, err)
+	product, err := service.CreateProduct(db, &models.Product{Name: "Headphones", Price: 99, CategoryID: &category.ID})
+	assert.NoError(t, err)
+	review, err := service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 4, AspectRatings: []models.ReviewAspectRating{
+		{Aspect: "battery_life", Rating: 5},
+		{Aspect: "value", Rating: 3},
+	}})
+	assert.NoError(t, err)
+	_, err = service.UpdateReview(db, review.ID, &models.Review{Rating: 2,
+		AspectRatings: []models.ReviewAspectRating{{Aspect: "value", Rating: 1}}}, "")
+	assert.NoError(t, err)
+
+	history, err := service.GetReviewHistory(db, review.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "battery_life=5,value=3", history.Revisions[0].AspectRatings)
+
+	restored, err := service.RestoreReviewRevision(db, review.ID, 1, "admin")
+	assert.NoError(t, err)
+	if assert.Len(t, restored.AspectRatings, 2) {
+		assert.Equal(t, "battery_life", restored.AspectRatings[0].Aspect)
+		assert.Equal(t, 5, restored.AspectRatings[0].Rating)
+	}
+	history, err = service.GetReviewHistory(db, review.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "value=1", history.Revisions[1].AspectRatings)
+
+	detail, err := service.GetProductByID(db, product.ID, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.AspectRatingResponse{
+		{Aspect: "battery_life", AverageRating: 5, ReviewCount: 1},
+		{Aspect: "value", AverageRating: 3, ReviewCount: 1},
+	}, detail.AspectRatings)
+}
@@ -0,0 +1,207 @@
+package servicetester
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"go_api_product_review/middleware"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// testHMACSecret is the HS256 secret used by the middleware tests
+const testHMACSecret = "test-secret-key-for-hs256-tokens"
+
+// newAuthRouter creates a router protected by the auth middleware, echoing the caller's subject
+func newAuthRouter(t *testing.T, config middleware.JWTConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	verifier, err := middleware.NewJWTVerifier(config)
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+
+	router := gin.New()
+	router.Use(middleware.AuthMiddleware(verifier, nil))
+	router.GET("/whoami", func(c *gin.Context) {
+		claims, _ := middleware.ClaimsFromContext(c)
+		c.String(http.StatusOK, claims.Subject)
+	})
+	return router
+}
+
+// callWithToken performs an authenticated request against the router
+func callWithToken(router *gin.Engine, token string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// validClaims returns claims valid for an hour
+func validClaims(subject string) jwt.RegisteredClaims {
+	now := time.Now()
+	return jwt.RegisteredClaims{
+		Subject:   subject,
+		Issuer:    "https://auth.example.com",
+		Audience:  jwt.ClaimStrings{"product-review-api"},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+	}
+}
+
+// signHS256 signs claims with the test HMAC secret
+func signHS256(t *testing.T, claims jwt.Claims) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testHMACSecret))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return token
+}
+
+// TestAuthMiddlewareHS256 tests the verification of HS256 tokens and their registered claims
+func TestAuthMiddlewareHS256(t *testing.T) {
+	router := newAuthRouter(t, middleware.JWTConfig{
+		HMACSecret: []byte(testHMACSecret),
+		Issuer:     "https://auth.example.com",
+		Audience:   "product-review-api",
+		ClockSkew:  30 * time.Second,
+	})
+
+	// A valid token exposes its claims to the handlers
+	recorder := callWithToken(router, signHS256(t, validClaims("user-42")))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "user-42", recorder.Body.String())
+
+	// A token expired within the clock skew is still accepted
+	claims := validClaims("user-42")
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-10 * time.Second))
+	assert.Equal(t, http.StatusOK, callWithToken(router, signHS256(t, claims)).Code)
+
+	// A token expired beyond the clock skew is rejected
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	assert.Equal(t, http.StatusUnauthorized, callWithToken(router, signHS256(t, claims)).Code)
+
+	// A token not valid yet is rejected
+	claims = validClaims("user-42")
+	claims.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Minute))
+	assert.Equal(t, http.StatusUnauthorized, callWithToken(router, signHS256(t, claims)).Code)
+
+	// A token without expiration is rejected
+	claims = validClaims("user-42")
+	claims.ExpiresAt = nil
+	assert.Equal(t, http.StatusUnauthorized, callWithToken(router, signHS256(t, claims)).Code)
+
+	// Issuer and audience must match
+	claims = validClaims("user-42")
+	claims.Issuer = "https://evil.example.com"
+	assert.Equal(t, http.StatusUnauthorized, callWithToken(router, signHS256(t, claims)).Code)
+	claims = validClaims("user-42")
+	claims.Audience = jwt.ClaimStrings{"another-api"}
+	assert.Equal(t, http.StatusUnauthorized, callWithToken(router, signHS256(t, claims)).Code)
+
+	// A token signed with another secret is rejected
+	forged, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims("user-42")).SignedString([]byte("another-secret"))
+	assert.Equal(t, http.StatusUnauthorized, callWithToken(router, forged).Code)
+
+	// Unsigned tokens are rejected
+	unsigned, _ := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims("user-42")).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	assert.Equal(t, http.StatusUnauthorized, callWithToken(router, unsigned).Code)
+
+	// Missing tokens are rejected
+	assert.Equal(t, http.StatusUnauthorized, callWithToken(router, "").Code)
+}
+
+// TestAuthMiddlewareRS256PEM tests the verification of RS256 tokens with a key loaded from a PEM file
+func TestAuthMiddlewareRS256PEM(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	publicDER, _ := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	keyFile := filepath.Join(t.TempDir(), "main.pem")
+	err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+
+	t.Setenv("SECRET_KEY", "")
+	t.Setenv("JWT_HMAC_SECRET", "")
+	t.Setenv("JWT_PUBLIC_KEY_FILES", keyFile)
+	t.Setenv("JWT_JWKS_FILE", "")
+	config, err := middleware.LoadJWTConfigFromEnv()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	router := newAuthRouter(t, config)
+
+	// The key ID is the file name without extension
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, validClaims("rsa-user"))
+	token.Header["kid"] = "main"
+	signed, _ := token.SignedString(privateKey)
+	recorder := callWithToken(router, signed)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "rsa-user", recorder.Body.String())
+
+	// Unknown key IDs are rejected
+	token.Header["kid"] = "other"
+	signed, _ = token.SignedString(privateKey)
+	assert.Equal(t, http.StatusUnauthorized, callWithToken(router, signed).Code)
+
+	// HS256 is disabled without secret, even when signed with the public key
+	hs256, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims("rsa-user")).SignedString(publicDER)
+	assert.Equal(t, http.StatusUnauthorized, callWithToken(router, hs256).Code)
+}
+
+// TestAuthMiddlewareES256JWKS tests the verification of ES256 tokens with a key loaded from a JWKS file
+func TestAuthMiddlewareES256JWKS(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	jwks, _ := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "EC",
+			"kid": "ec-1",
+			"crv": "P-256",
+			"x":   base64.RawURLEncoding.EncodeToString(privateKey.X.FillBytes(make([]byte, 32))),
+			"y":   base64.RawURLEncoding.EncodeToString(privateKey.Y.FillBytes(make([]byte, 32))),
+		}},
+	})
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(jwksFile, jwks, 0o600); err != nil {
+		t.Fatalf("failed to write jwks: %v", err)
+	}
+
+	keys, err := middleware.LoadJWKSFile(jwksFile)
+	if err != nil {
+		t.Fatalf("failed to load jwks: %v", err)
+	}
+	router := newAuthRouter(t, middleware.JWTConfig{PublicKeys: keys, ClockSkew: middleware.DefaultClockSkew})
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, validClaims("ec-user"))
+	token.Header["kid"] = "ec-1"
+	signed, _ := token.SignedString(privateKey)
+	recorder := callWithToken(router, signed)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "ec-user", recorder.Body.String())
+
+	// A single candidate key is used for tokens without key ID
+	signed, _ = jwt.NewWithClaims(jwt.SigningMethodES256, validClaims("ec-user")).SignedString(privateKey)
+	assert.Equal(t, http.StatusOK, callWithToken(router, signed).Code)
+}
@@ -0,0 +1,119 @@
+package servicetester
+
+import (
+	"bytes"
+	"encoding/json"
+	"go_api_product_review/api"
+	"go_api_product_review/cache"
+	"go_api_product_review/db"
+	"go_api_product_review/middleware"
+	"go_api_product_review/models"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// newAPIRouter creates the API router backed by a test database and authenticated with HS256 tokens and API keys
+func newAPIRouter(t *testing.T) *gin.Engine {
+	testDB, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	t.Cleanup(func() { testDB.Close() })
+	db.DB = testDB
+	cache.InitRedis(NewMockRedisClient())
+
+	gin.SetMode(gin.TestMode)
+	verifier, err := middleware.NewJWTVerifier(middleware.JWTConfig{HMACSecret: []byte(testHMACSecret)})
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+	router := gin.New()
+	router.Use(middleware.AuthMiddleware(verifier, api.AuthenticateAPIKey))
+	api.RegisterProductRoutes(router)
+	api.RegisterCategoryRoutes(router)
+	api.RegisterReviewRoutes(router)
+	api.RegisterModerationRoutes(router)
+	api.RegisterPurchaseRoutes(router)
+	api.RegisterWebhookRoutes(router)
+	api.RegisterAPIKeyRoutes(router)
+	return router
+}
+
+// tokenFor returns a token for the subject granted the roles
+func tokenFor(t *testing.T, subject string, roles ...string) string {
+	return signHS256(t, middleware.Claims{RegisteredClaims: validClaims(subject), Roles: roles})
+}
+
+// callAPI performs an authenticated JSON request against the router
+func callAPI(router *gin.Engine, token string, method string, path string, body interface{}) *httptest.ResponseRecorder {
+	var payload []byte
+	if body != nil {
+		payload, _ = json.Marshal(body)
+	}
+	request := httptest.NewRequest(method, path, bytes.NewReader(payload))
+	request.Header.Set("Authorization", "Bearer "+token)
+	request.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// TestProductRoutePolicies tests which roles may read, write and delete products
+func TestProductRoutePolicies(t *testing.T) {
+	router := newAPIRouter(t)
+	readOnly := tokenFor(t, "reader", middleware.RoleReadOnly)
+	editor := tokenFor(t, "editor", middleware.RoleCatalogEditor)
+	admin := tokenFor(t, "admin", middleware.RoleAdmin)
+	product := map[string]interface{}{"name": "Bananas", "price": 2.5}
+
+	assert.Equal(t, http.StatusOK, callAPI(router, readOnly, http.MethodGet, "/products/", nil).Code)
+	assert.Equal(t, http.StatusForbidden, callAPI(router, readOnly, http.MethodPost, "/products/", product).Code)
+
+	recorder := callAPI(router, editor, http.MethodPost, "/products/", product)
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	var created models.Product
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &created))
+	path := "/products/" + strconv.Itoa(int(created.ID))
+
+	assert.Equal(t, http.StatusOK, callAPI(router, editor, http.MethodPut, path, product).Code)
+	assert.Equal(t, http.StatusForbidden, callAPI(router, editor, http.MethodDelete, path, nil).Code)
+	assert.Equal(t, http.StatusNoContent, callAPI(router, admin, http.MethodDelete, path, nil).Code)
+
+	assert.Equal(t, http.StatusForbidden, callAPI(router, editor, http.MethodGet, "/webhooks/", nil).Code)
+	assert.Equal(t, http.StatusOK, callAPI(router, admin, http.MethodGet, "/webhooks/", nil).Code)
+}
+
+// TestReviewOwnership tests that reviewers may only change the reviews they authored
+func TestReviewOwnership(t *testing.T) {
+	router := newAPIRouter(t)
+	product := models.Product{Name: "Bananas", Price: 2.5}
+	db.DB.Create(&product)
+
+	author := tokenFor(t, "alice", middleware.RoleReviewer)
+	other := tokenFor(t, "bob", middleware.RoleReviewer)
+	readOnly := tokenFor(t, "reader", middleware.RoleReadOnly)
+	admin := tokenFor(t, "admin", middleware.RoleAdmin)
+	review := map[string]interface{}{"product_id": product.ID, "rating": 4, "review_text": "Good", "author_subject": "mallory"}
+
+	assert.Equal(t, http.StatusForbidden, callAPI(router, readOnly, http.MethodPost, "/reviews/", review).Code)
+
+	// The author is taken from the token, not from the body
+	recorder := callAPI(router, author, http.MethodPost, "/reviews/", review)
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	var created models.Review
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &created))
+	assert.Equal(t, "alice", created.AuthorSubject)
+	path := "/reviews/" + strconv.Itoa(int(created.ID))
+
+	update := map[string]interface{}{"product_id": product.ID, "rating": 2}
+	assert.Equal(t, http.StatusForbidden, callAPI(router, other, http.MethodPut, path, update).Code)
+	assert.Equal(t, http.StatusForbidden, callAPI(router, other, http.MethodDelete, path, nil).Code)
+	assert.Equal(t, http.StatusOK, callAPI(router, author, http.MethodPut, path, update).Code)
+	assert.Equal(t, http.StatusNotFound, callAPI(router, author, http.MethodDelete, "/reviews/404", nil).Code)
+	assert.Equal(t, http.StatusNoContent, callAPI(router, admin, http.MethodDelete, path, nil).Code)
+}
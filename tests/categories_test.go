@@ -0,0 +1,233 @@
+package servicetester
+
+import (
+	"encoding/json"
+	"go_api_product_review/cache"
+	"go_api_product_review/middleware"
+	"go_api_product_review/models"
+	"go_api_product_review/service"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCategoryTree tests that categories nest under their parent and that moves keep the paths of the subtree in sync
+func TestCategoryTree(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+
+	newCategory := func(name string, parent *models.Category) (*models.Category, error) {
+		category := &models.Category{Name: name}
+		if parent != nil {
+			category.ParentID = &parent.ID
+		}
+		if err := category.Validate(); err != nil {
+			return nil, err
+		}
+		return service.CreateCategory(db, category)
+	}
+	electronics, err := newCategory("Electronics", nil)
+	assert.NoError(t, err)
+	audio, err := newCategory("Audio & Hi-Fi", electronics)
+	assert.NoError(t, err)
+	headphones, err := newCategory("Headphones", audio)
+	assert.NoError(t, err)
+	assert.Equal(t, "audio-hi-fi", audio.Slug)
+	assert.Equal(t, "electronics/audio-hi-fi/headphones", headphones.Path)
+
+	// Slugs are unique among siblings only
+	_, err = newCategory("Headphones", audio)
+	assert.ErrorIs(t, err, service.ErrDuplicateCategory)
+	_, err = newCategory("Headphones", electronics)
+	assert.NoError(t, err)
+	_, err = newCategory("Orphan", &models.Category{Model: gorm.Model{ID: 999}})
+	assert.ErrorIs(t, err, service.ErrInvalidCategoryParent)
+	assert.Error(t, (&models.Category{Name: "Audio", Slug: "Audio_Gear"}).Validate())
+
+	// Moving a category rewrites the paths of its descendants
+	home, err := newCategory("Home", nil)
+	assert.NoError(t, err)
+	moved, err := service.UpdateCategory(db, audio.ID, &models.Category{Name: "Audio", Slug: "audio", ParentID: &home.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, "home/audio", moved.Path)
+	reloaded, err := service.GetCategory(db, headphones.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "home/audio/headphones", reloaded.Path)
+
+	// A category cannot move under its own subtree
+	_, err = service.UpdateCategory(db, home.ID, &models.Category{Name: "Home", Slug: "home", ParentID: &headphones.ID})
+	assert.ErrorIs(t, err, service.ErrInvalidCategoryParent)
+	_, err = service.UpdateCategory(db, home.ID, &models.Category{Name: "Home", Slug: "home", ParentID: &home.ID})
+	assert.ErrorIs(t, err, service.ErrInvalidCategoryParent)
+
+	// The tree is at most MaxCategoryDepth levels deep, subtrees included
+	parent := reloaded
+	for depth := 4; depth <= models.MaxCategoryDepth; depth++ {
+		parent, err = newCategory("Level "+strconv.Itoa(depth), parent)
+		assert.NoError(t, err)
+	}
+	_, err = newCategory("Too deep", parent)
+	assert.ErrorIs(t, err, service.ErrInvalidCategoryParent)
+	_, err = service.UpdateCategory(db, home.ID, &models.Category{Name: "Home", Slug: "home", ParentID: &electronics.ID})
+	assert.ErrorIs(t, err, service.ErrInvalidCategoryParent)
+
+	categories, err := service.ListCategories(db)
+	assert.NoError(t, err)
+	assert.Equal(t, "electronics", categories[0].Path)
+	assert.Equal(t, "electronics/headphones", categories[1].Path)
+	assert.Equal(t, "home", categories[2].Path)
+	assert.Equal(t, "home/audio", categories[3].Path)
+
+	// Only leaves can be deleted, and their paths can be used again
+	assert.ErrorIs(t, service.DeleteCategory(db, audio.ID), service.ErrCategoryHasChildren)
+	assert.NoError(t, service.DeleteCategory(db, parent.ID))
+	assert.ErrorIs(t, service.DeleteCategory(db, parent.ID), service.ErrNotFound)
+	_, err = service.CreateCategory(db, &models.Category{Name: parent.Name, Slug: parent.Slug, ParentID: parent.ParentID})
+	assert.NoError(t, err)
+}
+
+// TestCategoryProducts tests that products are listed and rated in their categories and the ancestors of them
+func TestCategoryProducts(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+
+	electronics, err := service.CreateCategory(db, &models.Category{Name: "Electronics", Slug: "electronics",
+		Aspects: []models.CategoryAspect{{Key: "value", Name: "Value"}}})
+	assert.NoError(t, err)
+	headphones, err := service.CreateCategory(db, &models.Category{Name: "Headphones", Slug: "headphones", ParentID: &electronics.ID,
+		Aspects: []models.CategoryAspect{{Key: "battery_life", Name: "Battery life"}}})
+	assert.NoError(t, err)
+	gifts, err := service.CreateCategory(db, &models.Category{Name: "Gifts", Slug: "gifts"})
+	assert.NoError(t, err)
+
+	_, err = service.CreateProduct(db, &models.Product{Name: "Headset", Price: 80, CategoryIDs: []uint{999}})
+	assert.ErrorIs(t, err, service.ErrUnknownCategory)
+	headset, err := service.CreateProduct(db, &models.Product{Name: "Headset", Price: 80, CategoryID: &headphones.ID,
+		CategoryIDs: []uint{gifts.ID, headphones.ID}})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{headphones.ID, gifts.ID}, headset.CategoryIDs)
+	television, err := service.CreateProduct(db, &models.Product{Name: "Television", Price: 500, CategoryID: &electronics.ID})
+	assert.NoError(t, err)
+	_, err = service.CreateProduct(db, &models.Product{Name: "Bananas", Price: 2})
+	assert.NoError(t, err)
+
+	// Reviews rate the aspects of the category of the product and of its ancestors
+	for _, rating := range []int{5, 3} {
+		_, err = service.CreateReview(db, &models.Review{ProductID: headset.ID, Rating: rating, AspectRatings: []models.ReviewAspectRating{
+			{Aspect: "battery_life", Rating: rating},
+			{Aspect: "value", Rating: 4},
+		}})
+		assert.NoError(t, err)
+	}
+	_, err = service.CreateReview(db, &models.Review{ProductID: television.ID, Rating: 2,
+		AspectRatings: []models.ReviewAspectRating{{Aspect: "battery_life", Rating: 2}}})
+	assert.ErrorIs(t, err, service.ErrInvalidAspectRating)
+	_, err = service.CreateReview(db, &models.Review{ProductID: television.ID, Rating: 1})
+	assert.NoError(t, err)
+
+	// Category listings include the products of the descendants
+	page, err := service.ListCategoryProducts(db, electronics.ID, models.ProductQuery{Sort: "price", IncludeTotal: true})
+	assert.NoError(t, err)
+	if assert.Len(t, page.Items, 2) {
+		assert.Equal(t, headset.ID, page.Items[0].ID)
+		assert.Equal(t, []uint{headphones.ID, gifts.ID}, page.Items[0].CategoryIDs)
+		assert.Equal(t, television.ID, page.Items[1].ID)
+	}
+	assert.Equal(t, int64(2), *page.Total)
+	page, err = service.ListProducts(db, models.ProductQuery{CategoryID: &gifts.ID})
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+	_, err = service.ListCategoryProducts(db, 999, models.ProductQuery{})
+	assert.ErrorIs(t, err, service.ErrNotFound)
+
+	// Category ratings roll up the average ratings of the products, each product weighing the same
+	category, err := service.GetCategory(db, electronics.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.CategoryRatings{ProductCount: 2, RatedProductCount: 2, ReviewCount: 3, AverageRating: 2.5}, category.Ratings)
+	category, err = service.GetCategory(db, headphones.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.CategoryRatings{ProductCount: 1, RatedProductCount: 1, ReviewCount: 2, AverageRating: 4}, category.Ratings)
+
+	// Updates replace the categories of a product, deletes remove the category from its products
+	updated, err := service.UpdateProduct(db, television.ID, &models.Product{Name: "Television", Price: 450, CategoryIDs: []uint{gifts.ID}})
+	assert.NoError(t, err)
+	assert.Nil(t, updated.CategoryID)
+	assert.Equal(t, []uint{gifts.ID}, updated.CategoryIDs)
+	category, err = service.GetCategory(db, gifts.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.CategoryRatings{ProductCount: 2, RatedProductCount: 2, ReviewCount: 3, AverageRating: 2.5}, category.Ratings)
+
+	assert.NoError(t, service.DeleteCategory(db, headphones.ID))
+	detail, err := service.GetProductByID(db, headset.ID, false)
+	assert.NoError(t, err)
+	assert.Nil(t, detail.CategoryID)
+	assert.Equal(t, []uint{gifts.ID}, detail.CategoryIDs)
+	category, err = service.GetCategory(db, electronics.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.CategoryRatings{}, category.Ratings)
+}
+
+// TestCategoryTreeEndpoints tests that catalog editors manage the category tree and that its products are browsed by category
+func TestCategoryTreeEndpoints(t *testing.T) {
+	router := newAPIRouter(t)
+	editor := tokenFor(t, "editor", middleware.RoleCatalogEditor)
+	reviewer := tokenFor(t, "alice", middleware.RoleReviewer)
+
+	recorder := callAPI(router, editor, http.MethodPost, "/categories/", map[string]interface{}{"name": "Outdoor Gear"})
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	var outdoor models.Category
+	json.Unmarshal(recorder.Body.Bytes(), &outdoor)
+	assert.Equal(t, "outdoor-gear", outdoor.Path)
+	assert.Equal(t, http.StatusConflict, callAPI(router, editor, http.MethodPost, "/categories/",
+		map[string]interface{}{"name": "Outdoor gear"}).Code)
+	assert.Equal(t, http.StatusBadRequest, callAPI(router, editor, http.MethodPost, "/categories/",
+		map[string]interface{}{"name": "Tents", "parent_id": 999}).Code)
+
+	recorder = callAPI(router, editor, http.MethodPost, "/categories/", map[string]interface{}{"name": "Tents", "parent_id": outdoor.ID})
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	var tents models.Category
+	json.Unmarshal(recorder.Body.Bytes(), &tents)
+	assert.Equal(t, "outdoor-gear/tents", tents.Path)
+
+	recorder = callAPI(router, editor, http.MethodPost, "/products/",
+		map[string]interface{}{"name": "Tent", "price": 200, "category_ids": []uint{tents.ID}})
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	var product models.Product
+	json.Unmarshal(recorder.Body.Bytes(), &product)
+	assert.Equal(t, []uint{tents.ID}, product.CategoryIDs)
+
+	path := "/categories/" + strconv.Itoa(int(outdoor.ID))
+	recorder = callAPI(router, reviewer, http.MethodGet, path+"/products", nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var page models.ProductPage
+	json.Unmarshal(recorder.Body.Bytes(), &page)
+	if assert.Len(t, page.Items, 1) {
+		assert.Equal(t, product.ID, page.Items[0].ID)
+	}
+	assert.Equal(t, http.StatusNotFound, callAPI(router, reviewer, http.MethodGet, "/categories/999/products", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, callAPI(router, reviewer, http.MethodGet, path+"/products?limit=500", nil).Code)
+
+	recorder = callAPI(router, reviewer, http.MethodGet, path, nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var category models.Category
+	json.Unmarshal(recorder.Body.Bytes(), &category)
+	assert.Equal(t, &models.CategoryRatings{ProductCount: 1}, category.Ratings)
+
+	// Only leaves are deleted, by catalog editors
+	assert.Equal(t, http.StatusForbidden, callAPI(router, reviewer, http.MethodDelete, path, nil).Code)
+	assert.Equal(t, http.StatusConflict, callAPI(router, editor, http.MethodDelete, path, nil).Code)
+	assert.Equal(t, http.StatusNoContent, callAPI(router, editor, http.MethodDelete, "/categories/"+strconv.Itoa(int(tents.ID)), nil).Code)
+	assert.Equal(t, http.StatusNoContent, callAPI(router, editor, http.MethodDelete, path, nil).Code)
+	assert.Equal(t, http.StatusNotFound, callAPI(router, editor, http.MethodDelete, path, nil).Code)
+}
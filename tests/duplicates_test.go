@@ -0,0 +1,180 @@
+package servicetester
+
+import (
+	"go_api_product_review/cache"
+	"go_api_product_review/db"
+	"go_api_product_review/middleware"
+	"go_api_product_review/models"
+	"go_api_product_review/screening"
+	"go_api_product_review/service"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const duplicateText = "These bananas arrived green and ripened within two days, sweet and firm, " +
+	"perfect for my morning smoothies and lunch boxes"
+
+// useDuplicates sets the duplicate detection action for the test, restoring the default one afterwards
+func useDuplicates(t *testing.T, action string) {
+	config := service.DefaultDuplicateConfig()
+	config.Action = action
+	service.InitDuplicates(config)
+	t.Cleanup(func() {
+		service.InitDuplicates(service.DefaultDuplicateConfig())
+	})
+}
+
+// TestMinHash tests the similarity estimated from the MinHash signatures
+func TestMinHash(t *testing.T) {
+	signature := screening.MinHash(duplicateText)
+	assert.Len(t, signature, screening.SignatureSize)
+	assert.Equal(t, 1.0, signature.Similarity(screening.MinHash("THESE bananas arrived green, and ripened within two days! sweet and firm; perfect for my morning smoothies and lunch boxes")))
+	assert.Greater(t, signature.Similarity(screening.MinHash(duplicateText+" too")), 0.8)
+	assert.Less(t, signature.Similarity(screening.MinHash("The apples were bruised and the delivery took three weeks to arrive at my door")), 0.2)
+
+	// Near-duplicates share band keys, unrelated texts do not
+	keys := signature.BandKeys()
+	assert.Len(t, keys, screening.BandCount)
+	assert.NotEmpty(t, sharedKeys(keys, screening.MinHash(duplicateText+" too").BandKeys()))
+	assert.Empty(t, sharedKeys(keys, screening.MinHash("The apples were bruised and the delivery took three weeks to arrive at my door").BandKeys()))
+	assert.Nil(t, screening.Signature(nil).BandKeys())
+
+	parsed, err := screening.ParseSignature(signature.String())
+	assert.NoError(t, err)
+	assert.Equal(t, signature, parsed)
+	assert.Nil(t, screening.MinHash("!!!"))
+	_, err = screening.ParseSignature("abc")
+	assert.Error(t, err)
+}
+
+// sharedKeys returns the band keys found in both lists
+func sharedKeys(keys, others []int64) []int64 {
+	var shared []int64
+	for _, key := range keys {
+		for _, other := range others {
+			if key == other {
+				shared = append(shared, key)
+			}
+		}
+	}
+	return shared
+}
+
+// TestDuplicateReviews tests that reviews of the same author or with near-duplicate texts are flagged
+func TestDuplicateReviews(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+	useDuplicates(t, service.DuplicateFlag)
+
+	product := models.Product{Name: "Bananas", Price: 2.5}
+	other := models.Product{Name: "Apples", Price: 3}
+	db.Create(&product)
+	db.Create(&other)
+
+	first, err := service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 5, FirstName: "Miguel", LastName: "Filip", AuthorSubject: "miguel", ReviewText: duplicateText})
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReviewApproved, first.Status)
+	var bands []models.ReviewTextBand
+	db.Where("review_id = ?", first.ID).Find(&bands)
+	assert.Len(t, bands, screening.BandCount)
+
+	// Same author, by subject or by name
+	bySubject, err := service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 1, AuthorSubject: "miguel"})
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReviewFlagged, bySubject.Status)
+	assert.Equal(t, service.DuplicateModerator, bySubject.ModeratedBy)
+	assert.Contains(t, bySubject.ModerationReason, "review "+strconv.Itoa(int(first.ID)))
+	byName, err := service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 1, FirstName: "MIGUEL", LastName: "filip"})
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReviewFlagged, byName.Status)
+
+	// Near-duplicate texts on the product, and on other products within the window
+	copied, err := service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 1, AuthorSubject: "alice", ReviewText: duplicateText + " too"})
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReviewFlagged, copied.Status)
+	elsewhere, err := service.CreateReview(db, &models.Review{ProductID: other.ID, Rating: 1, AuthorSubject: "bob", ReviewText: duplicateText})
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReviewFlagged, elsewhere.Status)
+	assert.Contains(t, elsewhere.ModerationReason, "product "+strconv.Itoa(int(product.ID)))
+
+	// Older texts of other products are not compared
+	db.Unscoped().Delete(&models.Review{})
+	old := models.Review{Model: gormModelAt(time.Now().Add(-2 * time.Hour)), ProductID: product.ID, Rating: 4, Status: models.ReviewRejected, AuthorSubject: "carol", ReviewText: duplicateText}
+	db.Create(&old)
+	fresh, err := service.CreateReview(db, &models.Review{ProductID: other.ID, Rating: 4, AuthorSubject: "carol", ReviewText: duplicateText})
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReviewApproved, fresh.Status)
+
+	// Rejected reviews do not count as a previous review of their author
+	again, err := service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 4, AuthorSubject: "carol", ReviewText: "Much better bananas this time"})
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReviewApproved, again.Status)
+
+	// Editing a review into a copy takes it down
+	var before, after models.Product
+	db.First(&before, product.ID)
+	updated, err := service.UpdateReview(db, again.ID, &models.Review{Rating: 4, ReviewText: duplicateText}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReviewFlagged, updated.Status)
+	db.First(&after, product.ID)
+	assert.Equal(t, before.ReviewCount-1, after.ReviewCount)
+	var keys []int64
+	db.Model(&models.ReviewTextBand{}).Where("review_id = ?", again.ID).Pluck("band_key", &keys)
+	assert.ElementsMatch(t, screening.MinHash(duplicateText).BandKeys(), keys)
+
+	useDuplicates(t, service.DuplicateReject)
+	_, err = service.CreateReview(db, &models.Review{ProductID: other.ID, Rating: 1, AuthorSubject: "carol"})
+	assert.ErrorIs(t, err, service.ErrDuplicateReview)
+	_, err = service.UpdateReview(db, fresh.ID, &models.Review{Rating: 4, ReviewText: duplicateText + " too"}, "")
+	assert.ErrorIs(t, err, service.ErrDuplicateReview)
+
+	// Deleted reviews drop their band keys, restored ones get them back
+	assert.NoError(t, service.DeleteReview(db, fresh.ID, "carol"))
+	keys = nil
+	db.Model(&models.ReviewTextBand{}).Where("review_id = ?", fresh.ID).Pluck("band_key", &keys)
+	assert.Empty(t, keys)
+	_, err = service.RestoreReviewRevision(db, fresh.ID, 1, "admin")
+	assert.NoError(t, err)
+	db.Model(&models.ReviewTextBand{}).Where("review_id = ?", fresh.ID).Pluck("band_key", &keys)
+	assert.ElementsMatch(t, screening.MinHash(duplicateText).BandKeys(), keys)
+}
+
+// TestDuplicateReviewEndpoint tests that duplicate reviews are answered with a conflict when rejected
+func TestDuplicateReviewEndpoint(t *testing.T) {
+	router := newAPIRouter(t)
+	useDuplicates(t, service.DuplicateReject)
+	product := models.Product{Name: "Bananas", Price: 2.5}
+	db.DB.Create(&product)
+	author := tokenFor(t, "alice", middleware.RoleReviewer)
+
+	review := map[string]interface{}{"product_id": product.ID, "rating": 4, "review_text": "Good bananas"}
+	assert.Equal(t, http.StatusCreated, callAPI(router, author, http.MethodPost, "/reviews/", review).Code)
+	assert.Equal(t, http.StatusConflict, callAPI(router, author, http.MethodPost, "/reviews/", review).Code)
+}
+
+// TestLoadDuplicateConfigFromEnv tests the duplicate detection configuration read from the environment
+func TestLoadDuplicateConfigFromEnv(t *testing.T) {
+	t.Setenv("DUPLICATE_ACTION", "reject")
+	t.Setenv("DUPLICATE_WINDOW", "30m")
+	config, err := service.LoadDuplicateConfigFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, service.DuplicateReject, config.Action)
+	assert.Equal(t, 30*time.Minute, config.Window)
+
+	t.Setenv("DUPLICATE_ACTION", "ignore")
+	_, err = service.LoadDuplicateConfigFromEnv()
+	assert.Error(t, err)
+
+	t.Setenv("DUPLICATE_ACTION", "flag")
+	t.Setenv("DUPLICATE_SIMILARITY", "1.5")
+	_, err = service.LoadDuplicateConfigFromEnv()
+	assert.Error(t, err)
+}
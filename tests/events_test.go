@@ -0,0 +1,209 @@
+package servicetester
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go_api_product_review/cache"
+	"go_api_product_review/events"
+	"go_api_product_review/models"
+	"go_api_product_review/service"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestReviewEventsPublished tests that review changes publish domain events through the outbox
+func TestReviewEventsPublished(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+
+	// Record every published event in order
+	publisher := events.NewInProcessPublisher()
+	var received []events.Event
+	publisher.Subscribe(events.AllEvents, func(ctx context.Context, event events.Event) error {
+		received = append(received, event)
+		return nil
+	})
+	product := models.Product{Name: "Test Product", Price: 10}
+	db.Create(&product)
+
+	review, err := service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 4})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	_, err = service.UpdateReview(db, review.ID, &models.Review{Rating: 2}, "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	err = service.DeleteReview(db, review.ID, "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// Events are only published once relayed from the outbox
+	assert.Empty(t, received)
+	delivered, err := service.DispatchOutbox(db, publisher, 100)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	assert.Equal(t, 6, delivered)
+
+	var types []string
+	for _, event := range received {
+		assert.Equal(t, product.ID, event.ProductID)
+		assert.NotEmpty(t, event.ID)
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []string{
+		events.ReviewCreated, events.ProductRatingChanged,
+		events.ReviewUpdated, events.ProductRatingChanged,
+		events.ReviewDeleted, events.ProductRatingChanged,
+	}, types)
+
+	// The last rating change brings the average back to zero
+	var change events.RatingChanged
+	err = json.Unmarshal(received[len(received)-1].Data, &change)
+	assert.NoError(t, err)
+	assert.Equal(t, 2.0, change.PreviousAverageRating)
+	assert.Equal(t, 0.0, change.AverageRating)
+}
+
+// TestRedisPublisher tests that events are published as JSON on their type's channel
+func TestRedisPublisher(t *testing.T) {
+	mockClient := NewMockRedisClient()
+	cache.InitRedis(mockClient)
+
+	event, err := events.New(events.ReviewCreated, 7, models.ReviewResponse{ID: 1, ProductID: 7, Rating: 5})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	err = events.NewRedisPublisher("").Publish(context.Background(), event)
+	assert.NoError(t, err)
+	assert.Len(t, mockClient.published, 1)
+	assert.Equal(t, "events:review.created", mockClient.published[0].channel)
+
+	var published events.Event
+	err = json.Unmarshal(mockClient.published[0].message.([]byte), &published)
+	assert.NoError(t, err)
+	assert.Equal(t, event.ID, published.ID)
+	assert.Equal(t, uint(7), published.ProductID)
+}
+
+// TestDispatchOutboxRetries tests that failed deliveries are retried later and not lost
+func TestDispatchOutboxRetries(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+
+	product := models.Product{Name: "Test Product", Price: 10}
+	db.Create(&product)
+	_, err = service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 5})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// The first delivery fails
+	failing := events.NewInProcessPublisher()
+	failing.Subscribe(events.AllEvents, func(ctx context.Context, event events.Event) error {
+		return errors.New("subscriber unavailable")
+	})
+	delivered, err := service.DispatchOutbox(db, failing, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+
+	var pending []models.OutboxEvent
+	db.Where("delivered_at IS NULL").Find(&pending)
+	assert.Len(t, pending, 2)
+	for _, outboxEvent := range pending {
+		assert.Equal(t, 1, outboxEvent.Attempts)
+		assert.Equal(t, "subscriber unavailable", outboxEvent.LastError)
+		assert.True(t, outboxEvent.NextAttemptAt.After(time.Now()))
+	}
+
+	// Events are not retried before their backoff expires
+	working := events.NewInProcessPublisher()
+	delivered, err = service.DispatchOutbox(db, working, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+
+	// Once due, the events are delivered and marked as such
+	db.Model(&models.OutboxEvent{}).Update("next_attempt_at", time.Now().UTC().Add(-time.Second))
+	delivered, err = service.DispatchOutbox(db, working, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, delivered)
+
+	var remaining int
+	db.Model(&models.OutboxEvent{}).Where("delivered_at IS NULL").Count(&remaining)
+	assert.Equal(t, 0, remaining)
+}
+
+// TestDispatchOutboxClaimed tests that an event claimed by another dispatcher is not relayed again
+func TestDispatchOutboxClaimed(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+
+	product := models.Product{Name: "Test Product", Price: 10}
+	db.Create(&product)
+	_, err = service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 5})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// Another dispatcher claims the events right after they were read as pending
+	claimedMeanwhile := false
+	db.Callback().Query().After("gorm:query").Register("test:concurrent_relay", func(scope *gorm.Scope) {
+		if _, ok := scope.Value.(*[]models.OutboxEvent); ok && !claimedMeanwhile {
+			claimedMeanwhile = true
+			scope.NewDB().Model(&models.OutboxEvent{}).UpdateColumn("next_attempt_at", time.Now().UTC().Add(time.Minute))
+		}
+	})
+
+	relayed := 0
+	publisher := events.NewInProcessPublisher()
+	publisher.Subscribe(events.AllEvents, func(ctx context.Context, event events.Event) error {
+		relayed++
+		return nil
+	})
+	delivered, err := service.DispatchOutbox(db, publisher, 100)
+	assert.NoError(t, err)
+	assert.True(t, claimedMeanwhile)
+	assert.Equal(t, 0, delivered)
+	assert.Equal(t, 0, relayed)
+}
+
+// TestCreateReviewRollsBack tests that a failed review creation leaves neither the review nor its events
+func TestCreateReviewRollsBack(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+
+	product := models.Product{Name: "Test Product", Price: 10}
+	db.Create(&product)
+
+	// Drop the outbox so the transaction fails after the review is inserted
+	db.DropTable(&models.OutboxEvent{})
+	_, err = service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 5})
+	assert.Error(t, err)
+
+	var count int
+	db.Model(&models.Review{}).Count(&count)
+	assert.Equal(t, 0, count)
+}
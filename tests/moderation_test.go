@@ -0,0 +1,274 @@
+package servicetester
+
+import (
+	"encoding/json"
+	"go_api_product_review/cache"
+	"go_api_product_review/db"
+	"go_api_product_review/middleware"
+	"go_api_product_review/models"
+	"go_api_product_review/service"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestModerateReview tests that only approved reviews count toward the ratings and appear in public reads
+func TestModerateReview(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+
+	product := models.Product{Name: "Bananas", Price: 2.5}
+	db.Create(&product)
+	_, err = service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 5})
+	assert.NoError(t, err)
+	pending, err := service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 1, Status: models.ReviewPending})
+	assert.NoError(t, err)
+
+	// Pending reviews are left out of the ratings and the public reads
+	detail, err := service.GetProductByID(db, product.ID, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, detail.ReviewCount)
+	assert.Equal(t, 5.0, detail.AverageRating)
+	assert.Len(t, detail.Reviews, 1)
+	page, err := service.ListProductReviews(db, product.ID, models.ReviewQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+
+	approved, err := service.ModerateReview(db, pending.ID, models.ReviewApproved, "", "mod")
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReviewApproved, approved.Status)
+	assert.Equal(t, "mod", approved.ModeratedBy)
+	assert.NotNil(t, approved.ModeratedAt)
+
+	detail, err = service.GetProductByID(db, product.ID, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, detail.ReviewCount)
+	assert.Equal(t, 3.0, detail.AverageRating)
+	assert.Len(t, detail.Reviews, 2)
+
+	// Approving the review again does not count it twice
+	_, err = service.ModerateReview(db, pending.ID, models.ReviewApproved, "", "other-mod")
+	assert.ErrorIs(t, err, service.ErrInvalidTransition)
+	var stored models.Product
+	db.First(&stored, product.ID)
+	assert.Equal(t, 2, stored.ReviewCount)
+	assert.Equal(t, int64(6), stored.RatingSum)
+	page, err = service.ListProductReviews(db, product.ID, models.ReviewQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 2)
+
+	// Taking the review down removes it again
+	rejected, err := service.ModerateReview(db, pending.ID, models.ReviewRejected, "Spam", "mod")
+	assert.NoError(t, err)
+	assert.Equal(t, "Spam", rejected.ModerationReason)
+	detail, err = service.GetProductByID(db, product.ID, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, detail.ReviewCount)
+	assert.Equal(t, 5.0, detail.AverageRating)
+	cached, err := service.GetReview(db, pending.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReviewRejected, cached.Status)
+
+	// Rejected reviews can only be approved
+	_, err = service.ModerateReview(db, pending.ID, models.ReviewFlagged, "Spam", "mod")
+	assert.ErrorIs(t, err, service.ErrInvalidTransition)
+	_, err = service.ModerateReview(db, 404, models.ReviewApproved, "", "mod")
+	assert.ErrorIs(t, err, service.ErrNotFound)
+
+	// Changes of reviews that are not approved leave the ratings alone
+	_, err = service.UpdateReview(db, pending.ID, &models.Review{Rating: 2}, "")
+	assert.NoError(t, err)
+	assert.NoError(t, service.DeleteReview(db, pending.ID, ""))
+	stored = models.Product{}
+	db.First(&stored, product.ID)
+	assert.Equal(t, 1, stored.ReviewCount)
+	assert.Equal(t, int64(5), stored.RatingSum)
+
+	drifts, err := service.ReconcileRatingAggregates(db)
+	assert.NoError(t, err)
+	assert.Empty(t, drifts)
+}
+
+// TestModerateReviewConcurrently tests that a review moderated between its read and its update is not moved again
+func TestModerateReviewConcurrently(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+
+	product := models.Product{Name: "Bananas", Price: 2.5}
+	db.Create(&product)
+	pending, err := service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 4, Status: models.ReviewPending})
+	assert.NoError(t, err)
+
+	// Another moderator approves the review right after it was read as pending
+	approvedMeanwhile := false
+	db.Callback().Query().After("gorm:query").Register("test:concurrent_moderation", func(scope *gorm.Scope) {
+		if review, ok := scope.Value.(*models.Review); ok && review.ID == pending.ID && !approvedMeanwhile {
+			approvedMeanwhile = true
+			scope.NewDB().Model(&models.Review{}).Where("id = ?", pending.ID).UpdateColumn("status", models.ReviewApproved)
+		}
+	})
+
+	_, err = service.ModerateReview(db, pending.ID, models.ReviewApproved, "", "mod")
+	assert.ErrorIs(t, err, service.ErrInvalidTransition)
+	assert.True(t, approvedMeanwhile)
+	var stored models.Product
+	db.First(&stored, product.ID)
+	assert.Equal(t, 0, stored.ReviewCount)
+	assert.Equal(t, int64(0), stored.RatingSum)
+}
+
+// TestChangeReviewModeratedConcurrently tests that a review moderated between its read and an edit or delete is left alone
+func TestChangeReviewModeratedConcurrently(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+
+	product := models.Product{Name: "Bananas", Price: 2.5}
+	db.Create(&product)
+	approved, err := service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 4, ReviewText: "Sweet"})
+	assert.NoError(t, err)
+	pending, err := service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 2, Status: models.ReviewPending})
+	assert.NoError(t, err)
+
+	// A moderator moves the review right after it was read
+	moderations := map[uint]string{approved.ID: models.ReviewRejected, pending.ID: models.ReviewApproved}
+	db.Callback().Query().After("gorm:query").Register("test:concurrent_moderation", func(scope *gorm.Scope) {
+		review, ok := scope.Value.(*models.Review)
+		if !ok {
+			return
+		}
+		if status, found := moderations[review.ID]; found {
+			delete(moderations, review.ID)
+			scope.NewDB().Model(&models.Review{}).Where("id = ?", review.ID).UpdateColumn("status", status)
+		}
+	})
+
+	// The edit does not put the rejected review back, nor apply its rating change to the product
+	_, err = service.UpdateReview(db, approved.ID, &models.Review{Rating: 1, ReviewText: "Sweet"}, "author")
+	assert.ErrorIs(t, err, service.ErrReviewModerated)
+	var stored models.Review
+	db.First(&stored, approved.ID)
+	assert.Equal(t, 4, stored.Rating)
+	var storedProduct models.Product
+	db.First(&storedProduct, product.ID)
+	assert.Equal(t, 1, storedProduct.ReviewCount)
+	assert.Equal(t, int64(4), storedProduct.RatingSum)
+
+	// The delete does not remove the review approved meanwhile as if it was still pending
+	err = service.DeleteReview(db, pending.ID, "author")
+	assert.ErrorIs(t, err, service.ErrReviewModerated)
+	assert.NoError(t, db.First(&models.Review{}, pending.ID).Error)
+	assert.Empty(t, moderations)
+}
+
+// TestListModerationQueue tests the moderation queue, oldest reviews first
+func TestListModerationQueue(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+
+	product := models.Product{Name: "Bananas", Price: 2.5}
+	db.Create(&product)
+	var ids []uint
+	for _, status := range []string{models.ReviewPending, models.ReviewApproved, models.ReviewPending, models.ReviewPending} {
+		review, err := service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 3, Status: status})
+		assert.NoError(t, err)
+		ids = append(ids, review.ID)
+	}
+	_, err = service.ModerateReview(db, ids[3], models.ReviewFlagged, "Offensive", "mod")
+	assert.NoError(t, err)
+
+	page, err := service.ListModerationQueue(db, models.ModerationQuery{Limit: 1})
+	assert.NoError(t, err)
+	if assert.Len(t, page.Items, 1) {
+		assert.Equal(t, ids[0], page.Items[0].ID)
+	}
+	assert.NotEmpty(t, page.NextCursor)
+
+	page, err = service.ListModerationQueue(db, models.ModerationQuery{Limit: 1, Cursor: page.NextCursor})
+	assert.NoError(t, err)
+	if assert.Len(t, page.Items, 1) {
+		assert.Equal(t, ids[2], page.Items[0].ID)
+	}
+	assert.Empty(t, page.NextCursor)
+
+	page, err = service.ListModerationQueue(db, models.ModerationQuery{Status: models.ReviewFlagged})
+	assert.NoError(t, err)
+	if assert.Len(t, page.Items, 1) {
+		assert.Equal(t, "Offensive", page.Items[0].ModerationReason)
+	}
+
+	// Cursors are bound to the status they were issued for
+	first, err := service.ListModerationQueue(db, models.ModerationQuery{Limit: 1})
+	assert.NoError(t, err)
+	_, err = service.ListModerationQueue(db, models.ModerationQuery{Status: models.ReviewFlagged, Cursor: first.NextCursor})
+	assert.ErrorIs(t, err, service.ErrInvalidCursor)
+	_, err = service.ListModerationQueue(db, models.ModerationQuery{Status: "deleted"})
+	assert.Error(t, err)
+}
+
+// TestModerationEndpoints tests that reviews posted through the API wait for a moderator
+func TestModerationEndpoints(t *testing.T) {
+	router := newAPIRouter(t)
+	product := models.Product{Name: "Bananas", Price: 2.5}
+	db.DB.Create(&product)
+
+	author := tokenFor(t, "alice", middleware.RoleReviewer)
+	reader := tokenFor(t, "reader", middleware.RoleReadOnly)
+	moderator := tokenFor(t, "mod", middleware.RoleModerator)
+	review := map[string]interface{}{"product_id": product.ID, "rating": 4, "status": models.ReviewApproved}
+
+	// The status is set by the server, not from the body
+	recorder := callAPI(router, author, http.MethodPost, "/reviews/", review)
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	var created models.Review
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &created))
+	assert.Equal(t, models.ReviewPending, created.Status)
+	path := "/reviews/" + strconv.Itoa(int(created.ID))
+	moderationPath := "/moderation" + path
+
+	// Pending reviews are only visible to their author and moderators
+	assert.Equal(t, http.StatusNotFound, callAPI(router, reader, http.MethodGet, path, nil).Code)
+	assert.Equal(t, http.StatusOK, callAPI(router, author, http.MethodGet, path, nil).Code)
+	assert.Equal(t, http.StatusOK, callAPI(router, moderator, http.MethodGet, path, nil).Code)
+
+	assert.Equal(t, http.StatusForbidden, callAPI(router, author, http.MethodGet, "/moderation/reviews", nil).Code)
+	recorder = callAPI(router, moderator, http.MethodGet, "/moderation/reviews?status=pending", nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var queue models.ReviewPage
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &queue))
+	assert.Len(t, queue.Items, 1)
+
+	assert.Equal(t, http.StatusForbidden, callAPI(router, author, http.MethodPost, moderationPath+"/approve", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, callAPI(router, moderator, http.MethodPost, moderationPath+"/reject", nil).Code)
+	assert.Equal(t, http.StatusOK, callAPI(router, moderator, http.MethodPost, moderationPath+"/approve", nil).Code)
+	assert.Equal(t, http.StatusConflict, callAPI(router, moderator, http.MethodPost, moderationPath+"/approve", nil).Code)
+	assert.Equal(t, http.StatusNotFound, callAPI(router, moderator, http.MethodPost, "/moderation/reviews/404/approve", nil).Code)
+
+	recorder = callAPI(router, reader, http.MethodGet, path, nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var published models.ReviewResponse
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &published))
+	assert.Equal(t, models.ReviewApproved, published.Status)
+
+	recorder = callAPI(router, moderator, http.MethodPost, moderationPath+"/flag", models.ModerationRequest{Reason: "Reported by customers"})
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, http.StatusNotFound, callAPI(router, reader, http.MethodGet, path, nil).Code)
+}
@@ -0,0 +1,121 @@
+package servicetester
+
+import (
+	"go_api_product_review/cache"
+	"go_api_product_review/models"
+	"go_api_product_review/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestListProductsPagination tests walking through all products page by page
+func TestListProductsPagination(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+
+	// Several products share the same price to exercise the ID tie-breaker
+	prices := []float64{10, 20, 20, 20, 30, 40, 50}
+	for i, price := range prices {
+		product := models.Product{Name: "Product " + string(rune('A'+i)), Price: price}
+		if err := db.Create(&product).Error; err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+	}
+
+	query := models.ProductQuery{Limit: 3, Sort: "price", IncludeTotal: true}
+	var seen []float64
+	var pages int
+	for {
+		page, err := service.ListProducts(db, query)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		pages++
+		assert.NotNil(t, page.Total)
+		assert.Equal(t, int64(len(prices)), *page.Total)
+		for _, product := range page.Items {
+			seen = append(seen, product.Price)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+
+	// Every product is returned exactly once, in ascending price order
+	assert.Equal(t, 3, pages)
+	assert.Equal(t, prices, seen)
+}
+
+// TestListProductsFiltersAndSort tests the price, rating and name filters with a descending sort
+func TestListProductsFiltersAndSort(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+
+	products := []models.Product{
+		{Name: "Green Bananas", Price: 5, AverageRating: 4.5},
+		{Name: "Yellow Bananas", Price: 15, AverageRating: 3},
+		{Name: "Ripe bananas", Price: 25, AverageRating: 4.8},
+		{Name: "Apples", Price: 20, AverageRating: 5},
+	}
+	for i := range products {
+		if err := db.Create(&products[i]).Error; err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+	}
+
+	minPrice, maxPrice, minRating := 1.0, 30.0, 4.0
+	page, err := service.ListProducts(db, models.ProductQuery{
+		MinPrice:  &minPrice,
+		MaxPrice:  &maxPrice,
+		MinRating: &minRating,
+		Name:      "BANANA",
+		Sort:      "-average_rating",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	assert.Len(t, page.Items, 2)
+	assert.Equal(t, "Ripe bananas", page.Items[0].Name)
+	assert.Equal(t, "Green Bananas", page.Items[1].Name)
+	assert.Empty(t, page.NextCursor)
+	assert.Nil(t, page.Total)
+}
+
+// TestListProductsInvalidCursor tests that a cursor issued for another sort order is rejected
+func TestListProductsInvalidCursor(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+
+	for _, price := range []float64{10, 20} {
+		if err := db.Create(&models.Product{Name: "Product", Price: price}).Error; err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+	}
+
+	page, err := service.ListProducts(db, models.ProductQuery{Limit: 1, Sort: "price"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	assert.NotEmpty(t, page.NextCursor)
+
+	_, err = service.ListProducts(db, models.ProductQuery{Limit: 1, Sort: "-created_at", Cursor: page.NextCursor})
+	assert.ErrorIs(t, err, service.ErrInvalidCursor)
+
+	_, err = service.ListProducts(db, models.ProductQuery{Cursor: "not-a-cursor"})
+	assert.ErrorIs(t, err, service.ErrInvalidCursor)
+}
@@ -21,13 +21,22 @@ import (
 
 // MockRedisClient simulates Redis operations in memory
 type MockRedisClient struct {
-	data map[string]interface{}
+	data      map[string]interface{}
+	ttls      map[string]time.Duration
+	published []publishedMessage
+}
+
+// publishedMessage records a message sent through the mock Publish
+type publishedMessage struct {
+	channel string
+	message interface{}
 }
 
 // NewMockRedisClient initializes a new MockRedisClient
 func NewMockRedisClient() *MockRedisClient {
 	return &MockRedisClient{
 		data: make(map[string]interface{}),
+		ttls: make(map[string]time.Duration),
 	}
 }
 
@@ -42,6 +51,15 @@ func (m *MockRedisClient) Set(ctx context.Context, key string, value interface{}
 	return redis.NewStatusResult("OK", nil) // Simulate success response
 }
 
+// SetNX simulates setting a value in Redis only if the key does not exist
+func (m *MockRedisClient) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd {
+	if _, exists := m.data[key]; exists {
+		return redis.NewBoolResult(false, nil)
+	}
+	m.data[key] = value
+	return redis.NewBoolResult(true, nil)
+}
+
 // Get simulates retrieving a value from Redis by key
 func (m *MockRedisClient) Get(ctx context.Context, key string) *redis.StringCmd {
 	value, exists := m.data[key]
@@ -54,9 +72,13 @@ func (m *MockRedisClient) Get(ctx context.Context, key string) *redis.StringCmd
 	case []byte:
 		// If the value is a byte slice, return it as a string
 		return redis.NewStringResult(string(v), nil)
+	case string:
+		return redis.NewStringResult(v, nil)
 	case float64:
 		// If the value is a float64, convert it to a string
 		return redis.NewStringResult(strconv.FormatFloat(v, 'f', -1, 64), nil)
+	case int64:
+		return redis.NewStringResult(strconv.FormatInt(v, 10), nil)
 	default:
 		// If the type is not recognized, return an empty result
 		return redis.NewStringResult("", fmt.Errorf("unsupported type: %T", v))
@@ -75,6 +97,83 @@ func (m *MockRedisClient) Del(ctx context.Context, keys ...string) *redis.IntCmd
 	return redis.NewIntResult(int64(deletedCount), nil) // Return the count of deleted keys
 }
 
+// Incr simulates incrementing a counter in Redis, missing keys start at 0
+func (m *MockRedisClient) Incr(ctx context.Context, key string) *redis.IntCmd {
+	count, _ := m.data[key].(int64)
+	count++
+	m.data[key] = count
+	return redis.NewIntResult(count, nil)
+}
+
+// IncrBy simulates incrementing a counter in Redis by a value, missing keys start at 0
+func (m *MockRedisClient) IncrBy(ctx context.Context, key string, value int64) *redis.IntCmd {
+	count, _ := m.data[key].(int64)
+	count += value
+	m.data[key] = count
+	return redis.NewIntResult(count, nil)
+}
+
+// SAdd simulates adding members to a Redis set
+func (m *MockRedisClient) SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	set, ok := m.data[key].(map[string]bool)
+	if !ok {
+		set = make(map[string]bool)
+		m.data[key] = set
+	}
+	added := 0
+	for _, member := range members {
+		name := fmt.Sprint(member)
+		if !set[name] {
+			set[name] = true
+			added++
+		}
+	}
+	return redis.NewIntResult(int64(added), nil)
+}
+
+// SPopN simulates removing and returning up to count members of a Redis set
+func (m *MockRedisClient) SPopN(ctx context.Context, key string, count int64) *redis.StringSliceCmd {
+	set, _ := m.data[key].(map[string]bool)
+	popped := []string{}
+	for member := range set {
+		if int64(len(popped)) == count {
+			break
+		}
+		popped = append(popped, member)
+		delete(set, member)
+	}
+	if len(set) == 0 {
+		delete(m.data, key)
+	}
+	return redis.NewStringSliceResult(popped, nil)
+}
+
+// Expire simulates setting the expiration of a key, the mock never expires keys
+func (m *MockRedisClient) Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd {
+	_, exists := m.data[key]
+	return redis.NewBoolResult(exists, nil)
+}
+
+// Eval simulates running the Lua scripts of the cache package, other scripts fail
+func (m *MockRedisClient) Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd {
+	switch script {
+	case cache.IncrWithExpireScript:
+		count := m.Incr(ctx, keys[0]).Val()
+		if count == 1 {
+			m.ttls[keys[0]] = time.Duration(args[0].(int64)) * time.Millisecond
+		}
+		return redis.NewCmdResult(count, nil)
+	default:
+		return redis.NewCmdResult(nil, fmt.Errorf("unsupported script: %s", script))
+	}
+}
+
+// Publish simulates publishing a message on a Redis channel
+func (m *MockRedisClient) Publish(ctx context.Context, channel string, message interface{}) *redis.IntCmd {
+	m.published = append(m.published, publishedMessage{channel: channel, message: message})
+	return redis.NewIntResult(0, nil) // No subscribers in the mock
+}
+
 // setupTestDB sets up an in-memory SQLite database for testing
 func setupTestDB() (*gorm.DB, error) {
 	// Open an in-memory SQLite database
@@ -86,6 +185,11 @@ func setupTestDB() (*gorm.DB, error) {
 	// Auto-migrate models to create tables
 	db.AutoMigrate(&models.Product{})
 	db.AutoMigrate(&models.Review{})
+	db.AutoMigrate(&models.ReviewVote{}, &models.ReviewReply{}, &models.ReviewRevision{}, &models.ReviewMedia{}, &models.ReviewTextBand{}, &models.Purchase{},
+		&models.Category{}, &models.ProductCategory{}, &models.CategoryAspect{}, &models.ReviewAspectRating{}, &models.ProductAspectRating{})
+	db.AutoMigrate(&models.OutboxEvent{})
+	db.AutoMigrate(&models.WebhookSubscription{}, &models.WebhookDelivery{})
+	db.AutoMigrate(&models.APIKey{})
 	if err != nil {
 		return nil, err
 	}
@@ -112,21 +216,25 @@ func TestGetProductByID(t *testing.T) {
 		t.Fatalf("failed to create product: %v", err)
 	}
 
-	// Add reviews for the product
-	review1 := models.Review{ProductID: product.ID, Rating: 4}
-	review2 := models.Review{ProductID: product.ID, Rating: 5}
-	if err := db.Create(&review1).Error; err != nil {
+	// Add reviews for the product, which maintain its rating aggregates
+	review1 := models.Review{ProductID: product.ID, Rating: 4, ReviewText: "Good"}
+	review2 := models.Review{ProductID: product.ID, Rating: 5, ReviewText: "Excellent"}
+	if _, err := service.CreateReview(db, &review1); err != nil {
 		t.Fatalf("failed to create review1: %v", err)
 	}
-	if err := db.Create(&review2).Error; err != nil {
+	if _, err := service.CreateReview(db, &review2); err != nil {
 		t.Fatalf("failed to create review2: %v", err)
 	}
 
-	// Call the function under test (calculating average rating)
-	avgRating, err := service.GetProductByID(db, product.ID)
+	// Call the function under test with nothing cached (reading the average rating)
+	mockClient.Del(cache.Ctx, "product:"+strconv.Itoa(int(product.ID))+":average_rating", "product:"+strconv.Itoa(int(product.ID)))
+	summary, err := service.GetProductByID(db, product.ID, false)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
+	avgRating := summary.AverageRating
+	assert.Equal(t, 2, summary.ReviewCount)
+	assert.Nil(t, summary.Reviews)
 
 	// Calculate the expected average rating manually
 	expectedAverageRating := float64(review1.Rating+review2.Rating) / float64(2)
@@ -147,6 +255,66 @@ func TestGetProductByID(t *testing.T) {
 	}
 }
 
+// TestGetProductByIDReadThroughCache tests that product details are served from the cache once loaded
+func TestGetProductByIDReadThroughCache(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+
+	mockClient := NewMockRedisClient()
+	cache.InitRedis(mockClient)
+
+	product := models.Product{Name: "Cached Product", Description: "Original", Price: 10}
+	if err := db.Create(&product).Error; err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+
+	// The first read loads the product from the database and caches it
+	detail, err := service.GetProductByID(db, product.ID, false)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	assert.Equal(t, "Cached Product", detail.Name)
+	assert.Equal(t, product.CreatedAt.Unix(), detail.CreatedAt.Unix())
+	_, exists := mockClient.data["product:"+strconv.Itoa(int(product.ID))]
+	assert.True(t, exists, "Expected product to be cached")
+
+	// A change made behind the service's back is not visible while cached
+	db.Model(&product).Update("description", "Changed")
+	detail, err = service.GetProductByID(db, product.ID, false)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	assert.Equal(t, "Original", detail.Description)
+
+	// Updating through the service invalidates the cached product
+	_, err = service.UpdateProduct(db, product.ID, &models.Product{Name: "Cached Product", Description: "Updated", Price: 12})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	detail, err = service.GetProductByID(db, product.ID, false)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	assert.Equal(t, "Updated", detail.Description)
+	assert.Equal(t, 12.0, detail.Price)
+}
+
+// TestGetProductByIDNotFound tests that a missing product returns ErrNotFound
+func TestGetProductByIDNotFound(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+
+	_, err = service.GetProductByID(db, 404, false)
+	assert.ErrorIs(t, err, service.ErrNotFound)
+}
+
 // TestUpdateProductAverageRating tests the updating of a product's average rating
 func TestUpdateProductAverageRating(t *testing.T) {
 	// Set up the test database and Redis client
@@ -227,23 +395,33 @@ func TestListProducts(t *testing.T) {
 		t.Fatalf("failed to create product: %v", err)
 	}
 
-	// Add a review for the product
+	// Add a review for the product, which updates its review count
 	review := &models.Review{ProductID: product.ID, FirstName: "Rob", LastName: "Me", ReviewText: "Great product!", Rating: 5.0}
-	if err := db.Create(review).Error; err != nil {
+	if _, err := service.CreateReview(db, review); err != nil {
 		t.Fatalf("failed to create review: %v", err)
 	}
 
 	// Call the function under test (listing products)
-	products, err := service.ListProducts(db)
+	page, err := service.ListProducts(db, models.ProductQuery{})
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
+	products := page.Items
 
-	// Verify that the product was listed and has the expected reviews
+	// Verify that the product was listed with its review count but without reviews
 	assert.NotNil(t, products)
 	assert.Len(t, products, 1) // One product should be returned
 	assert.Equal(t, product.ID, products[0].ID)
 	assert.Equal(t, "Test Product", products[0].Name)
+	assert.Equal(t, 1, products[0].ReviewCount)
+	assert.Nil(t, products[0].Reviews)
+
+	// Reviews are embedded only when requested
+	page, err = service.ListProducts(db, models.ProductQuery{Include: models.IncludeReviews})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	products = page.Items
 	assert.NotNil(t, products[0].Reviews)
 	assert.Len(t, products[0].Reviews, 1) // One review should be associated with the product
 	assert.Equal(t, review.Rating, products[0].Reviews[0].Rating)
@@ -284,7 +462,7 @@ func TestUpdateReview(t *testing.T) {
 	}
 
 	// Call the UpdateReview function to update the review
-	updated, err := service.UpdateReview(db, initialReview.ID, updatedReview)
+	updated, err := service.UpdateReview(db, initialReview.ID, updatedReview, "")
 
 	// Ensure no error occurred during the update
 	if err != nil {
@@ -370,7 +548,7 @@ func TestDeleteReview(t *testing.T) {
 	}
 
 	// Call DeleteReview function to delete the review
-	err = service.DeleteReview(db, review.ID)
+	err = service.DeleteReview(db, review.ID, "")
 
 	// Ensure no error occurred during deletion
 	if err != nil {
@@ -449,8 +627,8 @@ func TestUpdateProduct(t *testing.T) {
 	}
 	defer db.Close()
 
-	// Create the necessary table in the database
-	db.AutoMigrate(&models.Product{})
+	// Create the necessary tables in the database
+	db.AutoMigrate(&models.Product{}, &models.ProductCategory{})
 
 	// Create a sample product and save it to the database
 	product := &models.Product{
@@ -0,0 +1,132 @@
+package servicetester
+
+import (
+	"encoding/json"
+	"go_api_product_review/cache"
+	"go_api_product_review/middleware"
+	"go_api_product_review/models"
+	"go_api_product_review/service"
+	"net/http"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestVerifiedPurchases tests that reviews of bought products are verified, before and after the purchase is imported
+func TestVerifiedPurchases(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+
+	product := models.Product{Name: "Bananas", Price: 2.5}
+	db.Create(&product)
+	early, err := service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 5, AuthorSubject: "alice"})
+	assert.NoError(t, err)
+	assert.False(t, early.Verified)
+	_, err = service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 3, AuthorSubject: "bob"})
+	assert.NoError(t, err)
+
+	// The existing review of alice is verified by the import
+	result, err := service.ImportPurchases(db, []models.Purchase{
+		{CustomerID: "alice", ProductID: product.ID, OrderID: "order-1"},
+		{CustomerID: "alice", ProductID: product.ID, OrderID: "order-1"},
+		{CustomerID: "carol", ProductID: product.ID, OrderID: "order-2"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, models.PurchaseImportResult{Recorded: 2, Skipped: 1, VerifiedReviews: 1}, *result)
+	cached, err := service.GetReview(db, early.ID)
+	assert.NoError(t, err)
+	assert.True(t, cached.Verified)
+	detail, err := service.GetProductByID(db, product.ID, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, detail.VerifiedReviewCount)
+	assert.Equal(t, 5.0, detail.VerifiedAverageRating)
+	assert.Equal(t, 4.0, detail.AverageRating)
+
+	// New reviews of customers who bought the product are verified right away
+	late, err := service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 1, AuthorSubject: "carol"})
+	assert.NoError(t, err)
+	assert.True(t, late.Verified)
+	var stored models.Product
+	db.First(&stored, product.ID)
+	assert.Equal(t, 2, stored.VerifiedReviewCount)
+	assert.Equal(t, 3.0, stored.VerifiedAverageRating)
+
+	page, err := service.ListProductReviews(db, product.ID, models.ReviewQuery{Verified: true})
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 2)
+	for _, item := range page.Items {
+		assert.True(t, item.Verified)
+	}
+	page, err = service.ListProductReviews(db, product.ID, models.ReviewQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 3)
+
+	// Removing a verified review updates the verified aggregates, which match the reviews
+	assert.NoError(t, service.DeleteReview(db, late.ID, "carol"))
+	db.First(&stored, product.ID)
+	assert.Equal(t, 1, stored.VerifiedReviewCount)
+	assert.Equal(t, 5.0, stored.VerifiedAverageRating)
+	drifts, err := service.ReconcileRatingAggregates(db)
+	assert.NoError(t, err)
+	assert.Empty(t, drifts)
+
+	result, err = service.ImportPurchases(db, []models.Purchase{{CustomerID: "alice", ProductID: product.ID, OrderID: "order-1"}})
+	assert.NoError(t, err)
+	assert.Equal(t, models.PurchaseImportResult{Skipped: 1}, *result)
+}
+
+// TestVerifiedPurchaseDuringEdit tests that a review verified while it is being edited keeps its badge
+func TestVerifiedPurchaseDuringEdit(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+
+	product := models.Product{Name: "Bananas", Price: 2.5}
+	db.Create(&product)
+	review, err := service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 5, AuthorSubject: "alice"})
+	assert.NoError(t, err)
+
+	// The purchase of the author is imported right after the review was read for the edit
+	importedMeanwhile := false
+	db.Callback().Query().After("gorm:query").Register("test:concurrent_import", func(scope *gorm.Scope) {
+		if stored, ok := scope.Value.(*models.Review); ok && stored.ID == review.ID && !importedMeanwhile {
+			importedMeanwhile = true
+			scope.NewDB().Model(&models.Review{}).Where("id = ?", review.ID).UpdateColumn("verified", true)
+		}
+	})
+
+	_, err = service.UpdateReview(db, review.ID, &models.Review{Rating: 4, ReviewText: "Still good"}, "alice")
+	assert.NoError(t, err)
+	assert.True(t, importedMeanwhile)
+	var stored models.Review
+	db.First(&stored, review.ID)
+	assert.True(t, stored.Verified)
+	assert.Equal(t, 4, stored.Rating)
+}
+
+// TestPurchaseEndpoints tests that only administrators import purchases and that imports are validated
+func TestPurchaseEndpoints(t *testing.T) {
+	router := newAPIRouter(t)
+	admin := tokenFor(t, "admin", middleware.RoleAdmin)
+	reviewer := tokenFor(t, "alice", middleware.RoleReviewer)
+	purchases := models.PurchaseImport{Purchases: []models.Purchase{{CustomerID: "alice", ProductID: 1, OrderID: "order-1"}}}
+
+	assert.Equal(t, http.StatusForbidden, callAPI(router, reviewer, http.MethodPost, "/purchases/", purchases).Code)
+	assert.Equal(t, http.StatusBadRequest, callAPI(router, admin, http.MethodPost, "/purchases/", models.PurchaseImport{}).Code)
+	invalid := models.PurchaseImport{Purchases: []models.Purchase{{ProductID: 1, OrderID: "order-1"}}}
+	assert.Equal(t, http.StatusBadRequest, callAPI(router, admin, http.MethodPost, "/purchases/", invalid).Code)
+
+	recorder := callAPI(router, admin, http.MethodPost, "/purchases/", purchases)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var result models.PurchaseImportResult
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+	assert.Equal(t, 1, result.Recorded)
+}
@@ -0,0 +1,130 @@
+package servicetester
+
+import (
+	"go_api_product_review/cache"
+	"go_api_product_review/models"
+	"go_api_product_review/service"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+// createRankedProducts creates a product with a single 5 star review, one with ten reviews
+// averaging 4.8 and one without reviews
+func createRankedProducts(t *testing.T, db *gorm.DB) []models.Product {
+	ratings := [][]int{{5}, {5, 5, 5, 5, 5, 5, 5, 5, 4, 4}, {}}
+	products := []models.Product{{Name: "Single review"}, {Name: "Many reviews"}, {Name: "No review"}}
+	for i := range products {
+		products[i].Price = 10
+		if _, err := service.CreateProduct(db, &products[i]); err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+		for _, rating := range ratings[i] {
+			if _, err := service.CreateReview(db, &models.Review{ProductID: products[i].ID, Rating: rating}); err != nil {
+				t.Fatalf("failed to create review: %v", err)
+			}
+		}
+	}
+	return products
+}
+
+// TestRankingScoreSort tests that products with few reviews rank below well reviewed ones
+func TestRankingScoreSort(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+	config := service.DefaultRankingConfig()
+	config.PriorMean = 3
+	service.InitRanking(config)
+	t.Cleanup(func() { service.InitRanking(service.DefaultRankingConfig()) })
+
+	products := createRankedProducts(t, db)
+
+	product, err := service.GetProductByID(db, products[0].ID, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 5.0, product.AverageRating)
+	assert.InDelta(t, 35.0/11, product.RankingScore, 1e-9)
+
+	page, err := service.ListProducts(db, models.ProductQuery{Sort: "-ranking_score"})
+	assert.NoError(t, err)
+	if assert.Len(t, page.Items, 3) {
+		assert.Equal(t, "Many reviews", page.Items[0].Name)
+		assert.InDelta(t, 3.9, page.Items[0].RankingScore, 1e-9)
+		assert.Equal(t, "Single review", page.Items[1].Name)
+		assert.Equal(t, "No review", page.Items[2].Name)
+		assert.Equal(t, 3.0, page.Items[2].RankingScore)
+	}
+
+	// The plain average puts the single review first
+	page, err = service.ListProducts(db, models.ProductQuery{Sort: "-average_rating"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Single review", page.Items[0].Name)
+
+	// Deleting reviews keeps the score up to date
+	reviews, err := service.ListProductReviews(db, products[1].ID, models.ReviewQuery{Limit: 100})
+	assert.NoError(t, err)
+	for _, review := range reviews.Items {
+		assert.NoError(t, service.DeleteReview(db, review.ID, ""))
+	}
+	var stored models.Product
+	db.First(&stored, products[1].ID)
+	assert.Equal(t, 3.0, stored.RankingScore)
+}
+
+// TestRefreshRankingScores tests recomputing the scores after a configuration change
+func TestRefreshRankingScores(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+	config := service.DefaultRankingConfig()
+	config.PriorMean = 3
+	service.InitRanking(config)
+	t.Cleanup(func() { service.InitRanking(service.DefaultRankingConfig()) })
+
+	products := createRankedProducts(t, db)
+
+	config = service.DefaultRankingConfig()
+	config.Method = service.RankingWilson
+	service.InitRanking(config)
+	updated, err := service.RefreshRankingScores(db)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, updated)
+
+	var stored []models.Product
+	db.Order("ranking_score DESC").Find(&stored)
+	assert.Equal(t, products[1].ID, stored[0].ID)
+	assert.InDelta(t, 0.7224, stored[0].RankingScore, 1e-4)
+	assert.InDelta(t, 0.2066, stored[1].RankingScore, 1e-4)
+	assert.Equal(t, 0.0, stored[2].RankingScore)
+
+	// Nothing changes on the next run
+	updated, err = service.RefreshRankingScores(db)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, updated)
+}
+
+// TestLoadRankingConfigFromEnv tests the ranking configuration from environment variables
+func TestLoadRankingConfigFromEnv(t *testing.T) {
+	t.Setenv("RANKING_METHOD", "wilson")
+	t.Setenv("RANKING_CONFIDENCE", "1.64")
+	config, err := service.LoadRankingConfigFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, service.RankingWilson, config.Method)
+	assert.Equal(t, 1.64, config.Confidence)
+
+	t.Setenv("RANKING_METHOD", "popularity")
+	_, err = service.LoadRankingConfigFromEnv()
+	assert.Error(t, err)
+
+	t.Setenv("RANKING_METHOD", "bayesian")
+	t.Setenv("RANKING_PRIOR_MEAN", "7")
+	_, err = service.LoadRankingConfigFromEnv()
+	assert.Error(t, err)
+}
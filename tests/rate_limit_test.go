@@ -0,0 +1,209 @@
+package servicetester
+
+import (
+	"context"
+	"errors"
+	"go_api_product_review/cache"
+	"go_api_product_review/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingRateLimitStore is a rate limit store whose backend is down
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return 0, errors.New("connection refused")
+}
+
+func (failingRateLimitStore) Count(ctx context.Context, key string) (int64, error) {
+	return 0, errors.New("connection refused")
+}
+
+// newRateLimitedRouter creates a router authenticated with HS256 tokens with a single rate limited route
+func newRateLimitedRouter(t *testing.T, limiter *middleware.RateLimiter, policy middleware.RateLimitPolicy) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	verifier, err := middleware.NewJWTVerifier(middleware.JWTConfig{HMACSecret: []byte(testHMACSecret)})
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+	router := gin.New()
+	router.Use(middleware.AuthMiddleware(verifier, nil))
+	group := router.Group("/limited", limiter.Limit("limited", policy))
+	group.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+// TestRateLimiterSlidingWindow tests the sliding window counters and the rate limit headers
+func TestRateLimiterSlidingWindow(t *testing.T) {
+	now := time.Unix(1700000040, 0) // Start of a one minute window
+	store := middleware.NewMemoryRateLimitStore()
+	store.Now = func() time.Time { return now }
+	limiter := middleware.NewRateLimiter(store)
+	limiter.Now = func() time.Time { return now }
+	router := newRateLimitedRouter(t, limiter, middleware.RateLimitPolicy{
+		Default:  middleware.RateLimit{Requests: 3, Window: time.Minute},
+		Subjects: map[string]middleware.RateLimit{"partner": {Requests: 10, Window: time.Minute}},
+	})
+	alice := tokenFor(t, "alice")
+	call := func(token string) *httptest.ResponseRecorder {
+		return callAPI(router, token, http.MethodGet, "/limited/", nil)
+	}
+
+	for _, remaining := range []string{"2", "1", "0"} {
+		recorder := call(alice)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "3", recorder.Header().Get(middleware.RateLimitLimitHeader))
+		assert.Equal(t, remaining, recorder.Header().Get(middleware.RateLimitRemainingHeader))
+		assert.Equal(t, "60", recorder.Header().Get(middleware.RateLimitResetHeader))
+	}
+	recorder := call(alice)
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, "90", recorder.Header().Get("Retry-After"))
+
+	// Other callers have their own counters, and overrides apply per subject
+	assert.Equal(t, http.StatusOK, call(tokenFor(t, "bob")).Code)
+	recorder = call(tokenFor(t, "partner"))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "10", recorder.Header().Get(middleware.RateLimitLimitHeader))
+
+	// Half way through the next window, the 4 requests of the previous one weigh 2
+	now = now.Add(90 * time.Second)
+	recorder = call(alice)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "0", recorder.Header().Get(middleware.RateLimitRemainingHeader))
+	recorder = call(alice)
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, "30", recorder.Header().Get("Retry-After"))
+
+	now = now.Add(30 * time.Second)
+	assert.Equal(t, http.StatusOK, call(alice).Code)
+}
+
+// TestRateLimitFailedAuthentication tests that clients failing to authenticate are throttled by IP address
+func TestRateLimitFailedAuthentication(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	verifier, err := middleware.NewJWTVerifier(middleware.JWTConfig{HMACSecret: []byte(testHMACSecret)})
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+	limiter := middleware.NewRateLimiter(middleware.NewMemoryRateLimitStore())
+	router := gin.New()
+	router.Use(limiter.LimitByIP("ip", middleware.RateLimitPolicy{
+		Default: middleware.RateLimit{Requests: 3, Window: time.Minute},
+	}))
+	router.Use(middleware.AuthMiddleware(verifier, nil))
+	router.GET("/limited/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusUnauthorized, callAPI(router, "guess", http.MethodGet, "/limited/", nil).Code)
+	}
+	recorder := callAPI(router, "guess", http.MethodGet, "/limited/", nil)
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.NotEmpty(t, recorder.Header().Get("Retry-After"))
+
+	// Valid tokens from the same address are throttled along
+	assert.Equal(t, http.StatusTooManyRequests, callAPI(router, tokenFor(t, "alice"), http.MethodGet, "/limited/", nil).Code)
+}
+
+// TestRateLimitSpoofedForwardedFor tests that clients cannot reset their IP address counter with X-Forwarded-For
+func TestRateLimitSpoofedForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limiter := middleware.NewRateLimiter(middleware.NewMemoryRateLimitStore())
+	router := gin.New()
+	assert.NoError(t, router.SetTrustedProxies(nil))
+	router.Use(limiter.LimitByIP("ip", middleware.RateLimitPolicy{
+		Default: middleware.RateLimit{Requests: 2, Window: time.Minute},
+	}))
+	router.GET("/limited/", func(c *gin.Context) { c.Status(http.StatusOK) })
+	call := func(remoteAddr string, forwardedFor string) int {
+		request := httptest.NewRequest(http.MethodGet, "/limited/", nil)
+		request.RemoteAddr = remoteAddr
+		request.Header.Set("X-Forwarded-For", forwardedFor)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	// A new fake address on every request still counts against the address of the connection
+	assert.Equal(t, http.StatusOK, call("198.51.100.7:4000", "203.0.113.1"))
+	assert.Equal(t, http.StatusOK, call("198.51.100.7:4001", "203.0.113.2"))
+	assert.Equal(t, http.StatusTooManyRequests, call("198.51.100.7:4002", "203.0.113.3"))
+
+	// Behind a trusted proxy, the forwarded addresses are counted
+	assert.NoError(t, router.SetTrustedProxies([]string{"192.0.2.1"}))
+	assert.Equal(t, http.StatusOK, call("192.0.2.1:4000", "203.0.113.4"))
+	assert.Equal(t, http.StatusOK, call("192.0.2.1:4001", "203.0.113.5"))
+	assert.Equal(t, http.StatusOK, call("192.0.2.1:4002", "203.0.113.6"))
+}
+
+// TestRateLimitStores tests the Redis store and the fallback to the in-memory store
+func TestRateLimitStores(t *testing.T) {
+	mockRedis := NewMockRedisClient()
+	cache.InitRedis(mockRedis)
+	redisStore := middleware.NewRedisRateLimitStore()
+
+	count, err := redisStore.Increment(context.Background(), "ratelimit:test", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	count, _ = redisStore.Increment(context.Background(), "ratelimit:test", time.Minute)
+	assert.Equal(t, int64(2), count)
+	assert.Equal(t, time.Minute, mockRedis.ttls["ratelimit:test"])
+	count, err = redisStore.Count(context.Background(), "ratelimit:test")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	count, err = redisStore.Count(context.Background(), "ratelimit:missing")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+
+	// In-memory counters restart once expired, even between two sweeps
+	now := time.Unix(1700000000, 0)
+	memoryStore := middleware.NewMemoryRateLimitStore()
+	memoryStore.Now = func() time.Time { return now }
+	count, _ = memoryStore.Increment(context.Background(), "ratelimit:test", 10*time.Second)
+	assert.Equal(t, int64(1), count)
+	count, _ = memoryStore.Increment(context.Background(), "ratelimit:test", 10*time.Second)
+	assert.Equal(t, int64(2), count)
+	now = now.Add(10 * time.Second)
+	count, _ = memoryStore.Count(context.Background(), "ratelimit:test")
+	assert.Equal(t, int64(0), count)
+	count, _ = memoryStore.Increment(context.Background(), "ratelimit:test", 10*time.Second)
+	assert.Equal(t, int64(1), count)
+
+	// Callers are still limited, per node, while Redis is down
+	memoryStore = middleware.NewMemoryRateLimitStore()
+	limiter := middleware.NewRateLimiter(middleware.NewFallbackRateLimitStore(failingRateLimitStore{}, memoryStore))
+	router := newRateLimitedRouter(t, limiter, middleware.RateLimitPolicy{
+		Default: middleware.RateLimit{Requests: 1, Window: time.Hour},
+	})
+	token := tokenFor(t, "alice")
+	assert.Equal(t, http.StatusOK, callAPI(router, token, http.MethodGet, "/limited/", nil).Code)
+	assert.Equal(t, http.StatusTooManyRequests, callAPI(router, token, http.MethodGet, "/limited/", nil).Code)
+}
+
+// TestLoadRateLimitPolicyFromEnv tests the rate limit configuration of route groups
+func TestLoadRateLimitPolicyFromEnv(t *testing.T) {
+	defaultLimit := middleware.RateLimit{Requests: 60, Window: time.Minute}
+
+	policy, err := middleware.LoadRateLimitPolicyFromEnv("api-keys", defaultLimit)
+	assert.NoError(t, err)
+	assert.Equal(t, defaultLimit, policy.Default)
+
+	t.Setenv("RATE_LIMIT_API_KEYS", "10/30s")
+	t.Setenv("RATE_LIMIT_API_KEYS_SUBJECTS", "api-key:3=1000/1h, alice=off")
+	policy, err = middleware.LoadRateLimitPolicyFromEnv("api-keys", defaultLimit)
+	assert.NoError(t, err)
+	assert.Equal(t, middleware.RateLimit{Requests: 10, Window: 30 * time.Second}, policy.Default)
+	assert.Equal(t, middleware.RateLimit{Requests: 1000, Window: time.Hour}, policy.Subjects["api-key:3"])
+	assert.Equal(t, middleware.RateLimit{}, policy.Subjects["alice"])
+
+	for _, invalid := range []string{"10", "ten/1m", "0/1m", "10/soon"} {
+		_, err := middleware.ParseRateLimit(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,113 @@
+package servicetester
+
+import (
+	"go_api_product_review/cache"
+	"go_api_product_review/events"
+	"go_api_product_review/models"
+	"go_api_product_review/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRatingAggregatesFollowReviewChanges tests that review changes maintain the rating aggregates of the product
+func TestRatingAggregatesFollowReviewChanges(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+
+	product := models.Product{Name: "Bananas", Price: 2.5}
+	db.Create(&product)
+	assertAggregates := func(count int, sum int64, average float64) {
+		t.Helper()
+		var stored models.Product
+		assert.NoError(t, db.First(&stored, product.ID).Error)
+		assert.Equal(t, count, stored.ReviewCount)
+		assert.Equal(t, sum, stored.RatingSum)
+		assert.InDelta(t, average, stored.AverageRating, 1e-9)
+	}
+
+	first := &models.Review{ProductID: product.ID, Rating: 4}
+	second := &models.Review{ProductID: product.ID, Rating: 5}
+	_, err = service.CreateReview(db, first)
+	assert.NoError(t, err)
+	_, err = service.CreateReview(db, second)
+	assert.NoError(t, err)
+	assertAggregates(2, 9, 4.5)
+
+	_, err = service.UpdateReview(db, first.ID, &models.Review{Rating: 1}, "")
+	assert.NoError(t, err)
+	assertAggregates(2, 6, 3)
+
+	// Product updates leave the aggregates alone
+	_, err = service.UpdateProduct(db, product.ID, &models.Product{Name: "Red bananas", Price: 3, ReviewCount: 9, AverageRating: 5})
+	assert.NoError(t, err)
+	assertAggregates(2, 6, 3)
+
+	assert.NoError(t, service.DeleteReview(db, second.ID, ""))
+	assertAggregates(1, 1, 1)
+	// Deleting the review again does not remove it from the aggregates twice
+	assert.ErrorIs(t, service.DeleteReview(db, second.ID, ""), service.ErrNotFound)
+	assertAggregates(1, 1, 1)
+	assert.NoError(t, service.DeleteReview(db, first.ID, ""))
+	assertAggregates(0, 0, 0)
+
+	// Every change of the average was announced
+	var changes int
+	db.Model(&models.OutboxEvent{}).Where("event_type = ?", events.ProductRatingChanged).Count(&changes)
+	assert.Equal(t, 5, changes)
+}
+
+// TestReconcileRatingAggregates tests that drifted aggregates are detected, reported and fixed
+func TestReconcileRatingAggregates(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+
+	healthy := models.Product{Name: "Apples", Price: 1}
+	drifted := models.Product{Name: "Bananas", Price: 2.5}
+	db.Create(&healthy)
+	db.Create(&drifted)
+	_, err = service.CreateReview(db, &models.Review{ProductID: healthy.ID, Rating: 3})
+	assert.NoError(t, err)
+
+	// Reviews written behind the service's back are missing from the aggregates
+	db.Create(&models.Review{ProductID: drifted.ID, Rating: 2})
+	db.Create(&models.Review{ProductID: drifted.ID, Rating: 5})
+
+	drifts, err := service.ReconcileRatingAggregates(db)
+	assert.NoError(t, err)
+	assert.Equal(t, []service.RatingDrift{{
+		ProductID:       drifted.ID,
+		StoredCount:     0,
+		ActualCount:     2,
+		StoredSum:       0,
+		ActualSum:       7,
+		ActualRatings:   [5]int{0, 1, 0, 0, 1},
+		PreviousAverage: 0,
+		AverageRating:   3.5,
+
+		ActualRecentCount: 2,
+	}}, drifts)
+
+	var fixed models.Product
+	db.First(&fixed, drifted.ID)
+	assert.Equal(t, 2, fixed.ReviewCount)
+	assert.Equal(t, int64(7), fixed.RatingSum)
+	assert.Equal(t, 3.5, fixed.AverageRating)
+
+	rating, err := service.GetCachedProductAverageRating(drifted.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 3.5, rating)
+
+	// Nothing is left to fix
+	drifts, err = service.ReconcileRatingAggregates(db)
+	assert.NoError(t, err)
+	assert.Empty(t, drifts)
+}
@@ -0,0 +1,99 @@
+package servicetester
+
+import (
+	"encoding/json"
+	"go_api_product_review/cache"
+	"go_api_product_review/db"
+	"go_api_product_review/middleware"
+	"go_api_product_review/models"
+	"go_api_product_review/service"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGetRatingSummary tests the rating distribution of a product and its cache
+func TestGetRatingSummary(t *testing.T) {
+	testDB, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer testDB.Close()
+	mockClient := NewMockRedisClient()
+	cache.InitRedis(mockClient)
+
+	product := models.Product{Name: "Bananas", Price: 2.5}
+	testDB.Create(&product)
+	var lowest *models.Review
+	for _, rating := range []int{5, 2, 4, 5} {
+		review, err := service.CreateReview(testDB, &models.Review{ProductID: product.ID, Rating: rating})
+		assert.NoError(t, err)
+		if rating == 2 {
+			lowest = review
+		}
+	}
+
+	summary, err := service.GetRatingSummary(testDB, product.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.RatingSummary{
+		ProductID:     product.ID,
+		Total:         4,
+		AverageRating: 4,
+		Median:        4.5,
+		Distribution: []models.RatingBucket{
+			{Rating: 5, Count: 2, Percentage: 50},
+			{Rating: 4, Count: 1, Percentage: 25},
+			{Rating: 3, Count: 0, Percentage: 0},
+			{Rating: 2, Count: 1, Percentage: 25},
+			{Rating: 1, Count: 0, Percentage: 0},
+		},
+	}, summary)
+	_, cached := mockClient.data["product:"+strconv.Itoa(int(product.ID))+":rating_summary"]
+	assert.True(t, cached)
+
+	// Review changes drop the cached summary
+	_, err = service.UpdateReview(testDB, lowest.ID, &models.Review{Rating: 3}, "")
+	assert.NoError(t, err)
+	summary, err = service.GetRatingSummary(testDB, product.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, summary.Distribution[2].Rating)
+	assert.Equal(t, 1, summary.Distribution[2].Count)
+	assert.Equal(t, 0, summary.Distribution[3].Count)
+	assert.Equal(t, 4.5, summary.Median)
+
+	_, err = service.GetRatingSummary(testDB, 404)
+	assert.ErrorIs(t, err, service.ErrNotFound)
+}
+
+// TestNewRatingSummary tests the median and the rounding of the percentages
+func TestNewRatingSummary(t *testing.T) {
+	summary := models.NewRatingSummary(models.Product{ReviewCount: 3, RatingSum: 9, AverageRating: 3, Rating1Count: 1, Rating3Count: 1, Rating5Count: 1})
+	assert.Equal(t, 3.0, summary.Median)
+	assert.Equal(t, 33.3, summary.Distribution[0].Percentage)
+
+	empty := models.NewRatingSummary(models.Product{})
+	assert.Equal(t, 0, empty.Total)
+	assert.Equal(t, 0.0, empty.Median)
+	assert.Len(t, empty.Distribution, 5)
+}
+
+// TestRatingSummaryEndpoint tests the rating summary route
+func TestRatingSummaryEndpoint(t *testing.T) {
+	router := newAPIRouter(t)
+	product := models.Product{Name: "Bananas", Price: 2.5}
+	db.DB.Create(&product)
+	_, err := service.CreateReview(db.DB, &models.Review{ProductID: product.ID, Rating: 4})
+	assert.NoError(t, err)
+	reader := tokenFor(t, "reader", middleware.RoleReadOnly)
+
+	recorder := callAPI(router, reader, http.MethodGet, "/products/"+strconv.Itoa(int(product.ID))+"/rating-summary", nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var summary models.RatingSummary
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &summary))
+	assert.Equal(t, 1, summary.Total)
+	assert.Equal(t, 4.0, summary.Median)
+
+	assert.Equal(t, http.StatusNotFound, callAPI(router, reader, http.MethodGet, "/products/404/rating-summary", nil).Code)
+}
@@ -0,0 +1,260 @@
+package servicetester
+
+import (
+	"encoding/json"
+	"go_api_product_review/cache"
+	"go_api_product_review/db"
+	"go_api_product_review/middleware"
+	"go_api_product_review/models"
+	"go_api_product_review/service"
+	"math"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+// gormModelAt returns the record fields of a record created at the given time
+func gormModelAt(createdAt time.Time) gorm.Model {
+	return gorm.Model{CreatedAt: createdAt, UpdatedAt: createdAt}
+}
+
+// TestRecentAndDecayedRatings tests the recent and decayed averages along review changes
+func TestRecentAndDecayedRatings(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+
+	product := models.Product{Name: "Bananas", Price: 2.5}
+	db.Create(&product)
+	now := time.Now()
+	old, err := service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 1, Model: gormModelAt(now.AddDate(0, 0, -180))})
+	assert.NoError(t, err)
+	_, err = service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 5, Model: gormModelAt(now.AddDate(0, 0, -1))})
+	assert.NoError(t, err)
+	_, err = service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 4})
+	assert.NoError(t, err)
+
+	// The old review weighs a quarter after two half-lives of 90 days
+	var stored models.Product
+	db.First(&stored, product.ID)
+	assert.InDelta(t, 10.0/3, stored.AverageRating, 1e-9)
+	assert.Equal(t, 2, stored.RecentReviewCount)
+	assert.Equal(t, 4.5, stored.RecentAverageRating)
+	oldWeight, dayWeight := 0.25, math.Exp2(-1.0/90)
+	assert.InDelta(t, (1*oldWeight+5*dayWeight+4)/(oldWeight+dayWeight+1), stored.DecayedAverageRating, 1e-3)
+
+	// Rating changes of old reviews leave the recent average alone
+	_, err = service.UpdateReview(db, old.ID, &models.Review{Rating: 5}, "")
+	assert.NoError(t, err)
+	db.First(&stored, product.ID)
+	assert.Equal(t, 4.5, stored.RecentAverageRating)
+	assert.InDelta(t, (5*oldWeight+5*dayWeight+4)/(oldWeight+dayWeight+1), stored.DecayedAverageRating, 1e-3)
+
+	summary := models.NewProductSummary(stored)
+	assert.Equal(t, 2, summary.RecentReviewCount)
+	assert.Equal(t, stored.DecayedAverageRating, summary.DecayedAverageRating)
+
+	// The aggregates match what the reconciler computes from the reviews
+	drifts, err := service.ReconcileRatingAggregates(db)
+	assert.NoError(t, err)
+	assert.Empty(t, drifts)
+	updated, err := service.RefreshRecentRatings(db)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, updated)
+
+	var reviews []models.Review
+	db.Where("product_id = ?", product.ID).Find(&reviews)
+	for _, review := range reviews {
+		assert.NoError(t, service.DeleteReview(db, review.ID, ""))
+	}
+	db.First(&stored, product.ID)
+	assert.Equal(t, 0, stored.RecentReviewCount)
+	assert.Equal(t, 0.0, stored.RecentAverageRating)
+	assert.Equal(t, 0.0, stored.DecayedAverageRating)
+	assert.Equal(t, 0.0, stored.DecayedWeight)
+}
+
+// TestShortDecayHalfLife tests that short half-lives keep the decayed sums finite and that stale decay references are moved
+func TestShortDecayHalfLife(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+	t.Cleanup(func() { service.InitRatingTrends(service.DefaultRatingTrendConfig()) })
+
+	t.Setenv("RATING_DECAY_HALF_LIFE_DAYS", "1")
+	config, err := service.LoadRatingTrendConfigFromEnv()
+	assert.NoError(t, err)
+	service.InitRatingTrends(config)
+	assert.Error(t, service.RatingTrendConfig{RecentWindow: time.Hour, DecayHalfLife: time.Hour}.Validate())
+
+	product := models.Product{Name: "Bananas", Price: 2.5}
+	db.Create(&product)
+	_, err = service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 1, Model: gormModelAt(time.Now().AddDate(-5, 0, 0))})
+	assert.NoError(t, err)
+	_, err = service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 5})
+	assert.NoError(t, err)
+
+	// The five years old review weighs next to nothing but the sums stay finite
+	var stored models.Product
+	db.First(&stored, product.ID)
+	assert.False(t, math.IsInf(stored.DecayedWeight, 0) || math.IsNaN(stored.DecayedWeight))
+	assert.InDelta(t, 5.0, stored.DecayedAverageRating, 1e-9)
+	if assert.NotNil(t, stored.DecayedReference) {
+		assert.WithinDuration(t, time.Now(), *stored.DecayedReference, time.Minute)
+	}
+	detail, err := service.GetProductByID(db, product.ID, false)
+	assert.NoError(t, err)
+	_, err = json.Marshal(detail)
+	assert.NoError(t, err)
+
+	// The reconciler moves stale references without reporting a drift
+	staleReference := time.Now().AddDate(0, 0, -30)
+	db.Model(&stored).UpdateColumns(map[string]interface{}{
+		"decayed_reference":  &staleReference,
+		"decayed_weight":     stored.DecayedWeight * math.Exp2(30),
+		"decayed_rating_sum": stored.DecayedRatingSum * math.Exp2(30),
+	})
+	drifts, err := service.ReconcileRatingAggregates(db)
+	assert.NoError(t, err)
+	assert.Empty(t, drifts)
+	db.First(&stored, product.ID)
+	assert.WithinDuration(t, time.Now(), *stored.DecayedReference, time.Minute)
+	assert.InDelta(t, 1.0, stored.DecayedWeight, 1e-3)
+	assert.InDelta(t, 5.0, stored.DecayedAverageRating, 1e-9)
+}
+
+// TestRefreshRecentRatings tests that reviews leaving the recent window are dropped from the recent average
+func TestRefreshRecentRatings(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	mockClient := NewMockRedisClient()
+	cache.InitRedis(mockClient)
+	t.Cleanup(func() { service.InitRatingTrends(service.DefaultRatingTrendConfig()) })
+
+	product := models.Product{Name: "Bananas", Price: 2.5}
+	db.Create(&product)
+	_, err = service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 2, Model: gormModelAt(time.Now().AddDate(0, 0, -10))})
+	assert.NoError(t, err)
+	_, err = service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 4})
+	assert.NoError(t, err)
+	_, err = service.GetProductByID(db, product.ID, false)
+	assert.NoError(t, err)
+
+	config := service.DefaultRatingTrendConfig()
+	config.RecentWindow = 7 * 24 * time.Hour
+	service.InitRatingTrends(config)
+	updated, err := service.RefreshRecentRatings(db)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, updated)
+
+	var stored models.Product
+	db.First(&stored, product.ID)
+	assert.Equal(t, 1, stored.RecentReviewCount)
+	assert.Equal(t, 4.0, stored.RecentAverageRating)
+	assert.Equal(t, 3.0, stored.AverageRating)
+	_, cached := mockClient.data["product:"+strconv.Itoa(int(product.ID))]
+	assert.False(t, cached)
+}
+
+// TestGetRatingTrend tests the weekly and monthly buckets of the reviews of a product
+func TestGetRatingTrend(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	mockClient := NewMockRedisClient()
+	cache.InitRedis(mockClient)
+
+	product := models.Product{Name: "Bananas", Price: 2.5}
+	db.Create(&product)
+	now := time.Now()
+	twoWeeksAgo := now.AddDate(0, 0, -14)
+	for _, review := range []models.Review{
+		{ProductID: product.ID, Rating: 2, Model: gormModelAt(twoWeeksAgo)},
+		{ProductID: product.ID, Rating: 5, Model: gormModelAt(twoWeeksAgo)},
+		{ProductID: product.ID, Rating: 4, Model: gormModelAt(now.AddDate(-2, 0, 0))},
+		{ProductID: product.ID, Rating: 3},
+	} {
+		_, err := service.CreateReview(db, &review)
+		assert.NoError(t, err)
+	}
+
+	trend, err := service.GetRatingTrend(db, product.ID, models.RatingTrendQuery{Periods: 4})
+	assert.NoError(t, err)
+	assert.Equal(t, models.TrendWeekly, trend.Interval)
+	assert.Equal(t, 3.5, trend.AverageRating)
+	if assert.Len(t, trend.Points, 4) {
+		assert.Equal(t, models.PeriodStart(now, models.TrendWeekly), trend.Points[3].PeriodStart)
+		assert.Equal(t, time.Monday, trend.Points[3].PeriodStart.Weekday())
+		assert.Equal(t, models.RatingTrendPoint{PeriodStart: trend.Points[1].PeriodStart, ReviewCount: 2, AverageRating: 3.5}, trend.Points[1])
+		assert.Equal(t, 0, trend.Points[2].ReviewCount)
+		assert.Equal(t, 1, trend.Points[3].ReviewCount)
+		assert.Equal(t, 3.0, trend.Points[3].AverageRating)
+	}
+	_, cached := mockClient.data["product:"+strconv.Itoa(int(product.ID))+":rating_trend:week"]
+	assert.True(t, cached)
+
+	// The review from two years ago is out of the monthly trend
+	trend, err = service.GetRatingTrend(db, product.ID, models.RatingTrendQuery{Interval: models.TrendMonthly, Periods: 24})
+	assert.NoError(t, err)
+	assert.Len(t, trend.Points, 24)
+	total := 0
+	for _, point := range trend.Points {
+		assert.Equal(t, 1, point.PeriodStart.Day())
+		total += point.ReviewCount
+	}
+	assert.Equal(t, 3, total)
+
+	// Review changes drop the cached trends
+	_, err = service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 5})
+	assert.NoError(t, err)
+	trend, err = service.GetRatingTrend(db, product.ID, models.RatingTrendQuery{Periods: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, trend.Points[0].ReviewCount)
+
+	_, err = service.GetRatingTrend(db, product.ID, models.RatingTrendQuery{Interval: "day"})
+	assert.Error(t, err)
+	_, err = service.GetRatingTrend(db, 404, models.RatingTrendQuery{})
+	assert.ErrorIs(t, err, service.ErrNotFound)
+}
+
+// TestRatingTrendEndpoint tests the rating trend route
+func TestRatingTrendEndpoint(t *testing.T) {
+	router := newAPIRouter(t)
+	product := models.Product{Name: "Bananas", Price: 2.5}
+	db.DB.Create(&product)
+	reader := tokenFor(t, "reader", middleware.RoleReadOnly)
+	path := "/products/" + strconv.Itoa(int(product.ID)) + "/rating-trend"
+
+	recorder := callAPI(router, reader, http.MethodGet, path+"?interval=month&periods=6", nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var trend models.RatingTrend
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &trend))
+	assert.Equal(t, models.TrendMonthly, trend.Interval)
+	assert.Len(t, trend.Points, 6)
+
+	assert.Equal(t, http.StatusBadRequest, callAPI(router, reader, http.MethodGet, path+"?periods=100", nil).Code)
+	assert.Equal(t, http.StatusNotFound, callAPI(router, reader, http.MethodGet, "/products/404/rating-trend", nil).Code)
+}
+
+// TestPeriodStart tests the start of the weekly and monthly periods
+func TestPeriodStart(t *testing.T) {
+	sunday := time.Date(2024, time.June, 2, 23, 30, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, time.May, 27, 0, 0, 0, 0, time.UTC), models.PeriodStart(sunday, models.TrendWeekly))
+	assert.Equal(t, time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC), models.PeriodStart(sunday, models.TrendMonthly))
+	assert.Equal(t, time.Date(2024, time.June, 3, 0, 0, 0, 0, time.UTC), models.NextPeriod(models.PeriodStart(sunday, models.TrendWeekly), models.TrendWeekly))
+}
@@ -0,0 +1,128 @@
+package servicetester
+
+import (
+	"encoding/json"
+	"go_api_product_review/cache"
+	"go_api_product_review/db"
+	"go_api_product_review/middleware"
+	"go_api_product_review/models"
+	"go_api_product_review/service"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestReviewHistory tests that updates and deletes are recorded and that restoring a revision recomputes the ratings
+func TestReviewHistory(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+
+	product := models.Product{Name: "Bananas", Price: 2.5}
+	db.Create(&product)
+	review, err := service.CreateReview(db, &models.Review{ProductID: product.ID, FirstName: "Alice", Rating: 2, ReviewText: "Too green"})
+	assert.NoError(t, err)
+	_, err = service.CreateReply(db, review.ID, nil, "shop", "They ripen in a few days")
+	assert.NoError(t, err)
+
+	_, err = service.UpdateReview(db, review.ID, &models.Review{FirstName: "Alice", Rating: 5, ReviewText: "Ripe now"}, "alice")
+	assert.NoError(t, err)
+	history, err := service.GetReviewHistory(db, review.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, history.Revisions, 1) {
+		revision := history.Revisions[0]
+		assert.Equal(t, 1, revision.Version)
+		assert.Equal(t, models.RevisionUpdate, revision.Action)
+		assert.Equal(t, "alice", revision.ChangedBy)
+		assert.Equal(t, "Too green", revision.ReviewText)
+		assert.Equal(t, 2, revision.Rating)
+	}
+
+	// The history of a deleted review is kept
+	assert.NoError(t, service.DeleteReview(db, review.ID, "alice"))
+	history, err = service.GetReviewHistory(db, review.ID)
+	assert.NoError(t, err)
+	assert.True(t, history.Deleted)
+	if assert.Len(t, history.Revisions, 2) {
+		assert.Equal(t, models.RevisionDelete, history.Revisions[1].Action)
+		assert.Equal(t, "Ripe now", history.Revisions[1].ReviewText)
+	}
+	var stored models.Product
+	db.First(&stored, product.ID)
+	assert.Equal(t, 0, stored.ReviewCount)
+
+	// Restoring the first version undeletes the review with its replies and recomputes the ratings
+	restored, err := service.RestoreReviewRevision(db, review.ID, 1, "admin")
+	assert.NoError(t, err)
+	assert.Equal(t, "Too green", restored.ReviewText)
+	assert.Equal(t, 2, restored.Rating)
+	assert.Len(t, restored.Replies, 1)
+	cached, err := service.GetReview(db, review.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Too green", cached.ReviewText)
+	db.First(&stored, product.ID)
+	assert.Equal(t, 1, stored.ReviewCount)
+	assert.Equal(t, 2.0, stored.AverageRating)
+	detail, err := service.GetProductByID(db, product.ID, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2.0, detail.AverageRating)
+
+	history, err = service.GetReviewHistory(db, review.ID)
+	assert.NoError(t, err)
+	assert.False(t, history.Deleted)
+	if assert.Len(t, history.Revisions, 3) {
+		assert.Equal(t, 3, history.Revisions[2].Version)
+		assert.Equal(t, models.RevisionRestore, history.Revisions[2].Action)
+		assert.Equal(t, "admin", history.Revisions[2].ChangedBy)
+		assert.Equal(t, 5, history.Revisions[2].Rating)
+	}
+
+	_, err = service.RestoreReviewRevision(db, review.ID, 9, "admin")
+	assert.ErrorIs(t, err, service.ErrNotFound)
+	_, err = service.GetReviewHistory(db, 404)
+	assert.ErrorIs(t, err, service.ErrNotFound)
+}
+
+// TestReviewHistoryEndpoints tests that only the author and moderators read the history and only administrators restore it
+func TestReviewHistoryEndpoints(t *testing.T) {
+	router := newAPIRouter(t)
+	product := models.Product{Name: "Bananas", Price: 2.5}
+	db.DB.Create(&product)
+	review := models.Review{ProductID: product.ID, Rating: 2, ReviewText: "Too green", AuthorSubject: "alice"}
+	_, err := service.CreateReview(db.DB, &review)
+	assert.NoError(t, err)
+
+	author := tokenFor(t, "alice", middleware.RoleReviewer)
+	other := tokenFor(t, "bob", middleware.RoleReviewer)
+	moderator := tokenFor(t, "mod", middleware.RoleModerator)
+	admin := tokenFor(t, "admin", middleware.RoleAdmin)
+	path := "/reviews/" + strconv.Itoa(int(review.ID))
+
+	update := models.Review{ProductID: product.ID, Rating: 4, ReviewText: "Ripe now"}
+	assert.Equal(t, http.StatusOK, callAPI(router, author, http.MethodPut, path, update).Code)
+
+	assert.Equal(t, http.StatusForbidden, callAPI(router, other, http.MethodGet, path+"/history", nil).Code)
+	assert.Equal(t, http.StatusOK, callAPI(router, moderator, http.MethodGet, path+"/history", nil).Code)
+	recorder := callAPI(router, author, http.MethodGet, path+"/history", nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var history models.ReviewHistory
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &history))
+	if assert.Len(t, history.Revisions, 1) {
+		assert.Equal(t, "alice", history.Revisions[0].ChangedBy)
+		assert.Equal(t, "Too green", history.Revisions[0].ReviewText)
+	}
+
+	assert.Equal(t, http.StatusForbidden, callAPI(router, moderator, http.MethodPost, path+"/history/1/restore", nil).Code)
+	assert.Equal(t, http.StatusNotFound, callAPI(router, admin, http.MethodPost, path+"/history/9/restore", nil).Code)
+	recorder = callAPI(router, admin, http.MethodPost, path+"/history/1/restore", nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var restored models.ReviewResponse
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &restored))
+	assert.Equal(t, "Too green", restored.ReviewText)
+	assert.Equal(t, 2, restored.Rating)
+}
@@ -0,0 +1,331 @@
+package servicetester
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"go_api_product_review/cache"
+	"go_api_product_review/db"
+	"go_api_product_review/media"
+	"go_api_product_review/middleware"
+	"go_api_product_review/models"
+	"go_api_product_review/service"
+	"go_api_product_review/storage"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// encodeTestImage returns a width x height gradient encoded as a PNG or a JPEG
+func encodeTestImage(t *testing.T, width int, height int, format string) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var encoded bytes.Buffer
+	var err error
+	if format == "jpeg" {
+		err = jpeg.Encode(&encoded, img, nil)
+	} else {
+		err = png.Encode(&encoded, img)
+	}
+	if err != nil {
+		t.Fatalf("failed to encode image: %v", err)
+	}
+	return encoded.Bytes()
+}
+
+// useMediaStore stores the photos in a temporary directory for the duration of the test
+func useMediaStore(t *testing.T, config media.Config) storage.Store {
+	store, err := storage.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	service.InitMedia(store, config)
+	t.Cleanup(func() { service.InitMedia(nil, media.DefaultConfig()) })
+	return store
+}
+
+// TestProcessMedia tests the upload limits, the sniffed types and the thumbnails
+func TestProcessMedia(t *testing.T) {
+	config := media.DefaultConfig()
+
+	processed, err := media.Process(encodeTestImage(t, 640, 480, "png"), config)
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", processed.ContentType)
+	assert.Equal(t, ".png", processed.Extension)
+	assert.Equal(t, 640, processed.Width)
+	assert.Equal(t, 480, processed.Height)
+	assert.Equal(t, 320, processed.ThumbnailWidth)
+	assert.Equal(t, 240, processed.ThumbnailHeight)
+	assert.Equal(t, "image/png", processed.ThumbnailContentType)
+
+	// JPEG photos get JPEG thumbnails, small photos keep their size
+	processed, err = media.Process(encodeTestImage(t, 100, 200, "jpeg"), config)
+	assert.NoError(t, err)
+	assert.Equal(t, "image/jpeg", processed.ContentType)
+	assert.Equal(t, ".jpg", processed.ThumbnailExtension)
+	assert.Equal(t, 100, processed.ThumbnailWidth)
+	assert.Equal(t, 200, processed.ThumbnailHeight)
+	thumbnail, err := jpeg.Decode(bytes.NewReader(processed.Thumbnail))
+	assert.NoError(t, err)
+	assert.Equal(t, 100, thumbnail.Bounds().Dx())
+
+	// Tall photos fit the thumbnail by their height
+	processed, err = media.Process(encodeTestImage(t, 300, 900, "png"), config)
+	assert.NoError(t, err)
+	assert.Equal(t, 106, processed.ThumbnailWidth)
+	assert.Equal(t, 320, processed.ThumbnailHeight)
+
+	// The type is sniffed from the content, not from the claims of the upload
+	_, err = media.Process([]byte("<html><body>not a photo</body></html>"), config)
+	assert.ErrorIs(t, err, media.ErrUnsupportedType)
+	_, err = media.Process(append([]byte("\x89PNG\r\n\x1a\n"), "truncated"...), config)
+	assert.ErrorIs(t, err, media.ErrUnsupportedType)
+
+	config.MaxBytes = 100
+	_, err = media.Process(encodeTestImage(t, 64, 64, "png"), config)
+	assert.ErrorIs(t, err, media.ErrTooLarge)
+
+	config = media.DefaultConfig()
+	config.MaxPixels = 1000
+	_, err = media.Process(encodeTestImage(t, 40, 40, "png"), config)
+	assert.ErrorIs(t, err, media.ErrTooManyPixels)
+}
+
+// TestReviewMedia tests attaching photos to a review, embedding them in the review and removing them
+func TestReviewMedia(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+
+	product := models.Product{Name: "Bananas", Price: 2.5}
+	db.Create(&product)
+	review, err := service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 5, AuthorSubject: "alice"})
+	assert.NoError(t, err)
+
+	// Without a store the uploads are turned off
+	_, err = service.AddReviewMedia(db, review.ID, "alice", bytes.NewReader(encodeTestImage(t, 64, 64, "png")))
+	assert.ErrorIs(t, err, service.ErrMediaStorageDisabled)
+
+	config := media.DefaultConfig()
+	config.MaxPerReview = 2
+	store := useMediaStore(t, config)
+
+	photo := encodeTestImage(t, 800, 400, "jpeg")
+	item, err := service.AddReviewMedia(db, review.ID, "alice", bytes.NewReader(photo))
+	assert.NoError(t, err)
+	assert.Equal(t, "image/jpeg", item.ContentType)
+	assert.Equal(t, int64(len(photo)), item.Size)
+	assert.Equal(t, 800, item.Width)
+	assert.Equal(t, 160, item.ThumbnailHeight)
+	assert.True(t, strings.HasSuffix(item.StorageKey, ".jpg"))
+
+	file, err := service.OpenReviewMedia(item, false)
+	assert.NoError(t, err)
+	stored, _ := io.ReadAll(file)
+	file.Close()
+	assert.Equal(t, photo, stored)
+
+	// The review embeds its photos, cached or not
+	cached, err := service.GetReview(db, review.ID)
+	assert.NoError(t, err)
+	assert.Len(t, cached.Media, 1)
+	response := models.NewReviewResponse(*cached)
+	assert.Equal(t, "/reviews/"+strconv.Itoa(int(review.ID))+"/media/"+strconv.Itoa(int(item.ID)), response.Media[0].URL)
+	assert.Equal(t, response.Media[0].URL+"/thumbnail", response.Media[0].ThumbnailURL)
+	page, err := service.ListProductReviews(db, product.ID, models.ReviewQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+	assert.Len(t, page.Items[0].Media, 1)
+
+	_, err = service.AddReviewMedia(db, review.ID, "alice", strings.NewReader("plain text"))
+	assert.ErrorIs(t, err, media.ErrUnsupportedType)
+	_, err = service.AddReviewMedia(db, review.ID+100, "alice", bytes.NewReader(photo))
+	assert.ErrorIs(t, err, service.ErrNotFound)
+	_, err = service.AddReviewMedia(db, review.ID, "alice", bytes.NewReader(encodeTestImage(t, 32, 32, "png")))
+	assert.NoError(t, err)
+	_, err = service.AddReviewMedia(db, review.ID, "alice", bytes.NewReader(photo))
+	assert.ErrorIs(t, err, service.ErrTooManyMedia)
+
+	// Removing a photo removes its files and its entry from the review
+	assert.NoError(t, service.DeleteReviewMedia(db, review.ID, item.ID))
+	_, err = store.Get(context.Background(), item.StorageKey)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	_, err = store.Get(context.Background(), item.ThumbnailKey)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	cached, err = service.GetReview(db, review.ID)
+	assert.NoError(t, err)
+	assert.Len(t, cached.Media, 1)
+	assert.ErrorIs(t, service.DeleteReviewMedia(db, review.ID, item.ID), service.ErrNotFound)
+}
+
+// fakeS3 is an in-memory stand-in for an S3-compatible service, checking the signature headers of the requests
+type fakeS3 struct {
+	sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+	body, _ := io.ReadAll(r.Body)
+	hash := sha256.Sum256(body)
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key-id/") ||
+		r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(hash[:]) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		s.objects[r.URL.Path] = body
+		s.types[r.URL.Path] = r.Header.Get("Content-Type")
+	case http.MethodGet:
+		object, ok := s.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(object)
+	case http.MethodDelete:
+		delete(s.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// TestS3Store tests the S3 backend against a local stand-in
+func TestS3Store(t *testing.T) {
+	fake := &fakeS3{objects: map[string][]byte{}, types: map[string]string{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	store := storage.NewS3Store(storage.S3Config{
+		Endpoint:        server.URL,
+		Bucket:          "photos",
+		Region:          "us-east-1",
+		AccessKeyID:     "key-id",
+		SecretAccessKey: "secret",
+	}, server.Client())
+	ctx := context.Background()
+
+	assert.NoError(t, store.Put(ctx, "reviews/1/a.png", []byte("data"), "image/png"))
+	assert.Equal(t, []byte("data"), fake.objects["/photos/reviews/1/a.png"])
+	assert.Equal(t, "image/png", fake.types["/photos/reviews/1/a.png"])
+
+	file, err := store.Get(ctx, "reviews/1/a.png")
+	assert.NoError(t, err)
+	data, _ := io.ReadAll(file)
+	file.Close()
+	assert.Equal(t, []byte("data"), data)
+
+	assert.NoError(t, store.Delete(ctx, "reviews/1/a.png"))
+	_, err = store.Get(ctx, "reviews/1/a.png")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	assert.NoError(t, store.Delete(ctx, "reviews/1/a.png"))
+
+	// Keys escaping the bucket are refused before any request
+	assert.Error(t, store.Put(ctx, "../other/a.png", []byte("data"), "image/png"))
+	assert.Len(t, fake.objects, 0)
+}
+
+// uploadMedia uploads the file as a multipart form against the router
+func uploadMedia(router *gin.Engine, token string, path string, data []byte) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("file", "photo.png")
+	part.Write(data)
+	writer.Close()
+
+	request := httptest.NewRequest(http.MethodPost, path, &body)
+	request.Header.Set("Authorization", "Bearer "+token)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// TestReviewMediaEndpoints tests that only authors attach photos and that the photos are served with their type
+func TestReviewMediaEndpoints(t *testing.T) {
+	router := newAPIRouter(t)
+	useMediaStore(t, media.DefaultConfig())
+	alice := tokenFor(t, "alice", middleware.RoleReviewer)
+	bob := tokenFor(t, "bob", middleware.RoleReviewer)
+	moderator := tokenFor(t, "moderator", middleware.RoleModerator)
+	reader := tokenFor(t, "reader", middleware.RoleReadOnly)
+
+	recorder := callAPI(router, tokenFor(t, "admin", middleware.RoleAdmin), http.MethodPost, "/products/",
+		map[string]interface{}{"name": "Bananas", "price": 2.5})
+	var product models.Product
+	json.Unmarshal(recorder.Body.Bytes(), &product)
+	recorder = callAPI(router, alice, http.MethodPost, "/reviews/", map[string]interface{}{"product_id": product.ID, "rating": 4})
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	var review models.ReviewResponse
+	json.Unmarshal(recorder.Body.Bytes(), &review)
+	path := "/reviews/" + strconv.Itoa(int(review.ID)) + "/media"
+
+	photo := encodeTestImage(t, 640, 480, "png")
+	assert.Equal(t, http.StatusForbidden, uploadMedia(router, bob, path, photo).Code)
+	assert.Equal(t, http.StatusUnsupportedMediaType, uploadMedia(router, alice, path, []byte("GIF? no")).Code)
+	assert.Equal(t, http.StatusBadRequest, callAPI(router, alice, http.MethodPost, path, nil).Code)
+
+	recorder = uploadMedia(router, alice, path, photo)
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	var item models.ReviewMediaResponse
+	json.Unmarshal(recorder.Body.Bytes(), &item)
+	assert.Equal(t, 640, item.Width)
+	assert.Equal(t, 320, item.ThumbnailWidth)
+
+	recorder = callAPI(router, alice, http.MethodGet, "/reviews/"+strconv.Itoa(int(review.ID)), nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	json.Unmarshal(recorder.Body.Bytes(), &review)
+	assert.Len(t, review.Media, 1)
+
+	recorder = callAPI(router, alice, http.MethodGet, item.URL, nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "image/png", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "private, no-store", recorder.Header().Get("Cache-Control"))
+	assert.Equal(t, photo, recorder.Body.Bytes())
+	recorder = callAPI(router, moderator, http.MethodGet, item.ThumbnailURL, nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	thumbnail, err := png.DecodeConfig(recorder.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, 320, thumbnail.Width)
+
+	// The photos of pending reviews are hidden like the reviews
+	assert.Equal(t, http.StatusNotFound, callAPI(router, reader, http.MethodGet, item.URL, nil).Code)
+
+	// The photos of approved reviews are public
+	assert.NoError(t, db.DB.Model(&models.Review{}).Where("id = ?", review.ID).Update("status", models.ReviewApproved).Error)
+	assert.NoError(t, cache.Rdb.Del(cache.Ctx, "review:"+strconv.Itoa(int(review.ID))).Err())
+	recorder = callAPI(router, reader, http.MethodGet, item.URL, nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "public, max-age=300", recorder.Header().Get("Cache-Control"))
+
+	// Bodies past the limit are refused before the photo is read
+	oversized := make([]byte, media.DefaultConfig().MaxBytes+128<<10)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, uploadMedia(router, alice, path, oversized).Code)
+
+	assert.Equal(t, http.StatusForbidden, callAPI(router, bob, http.MethodDelete, item.URL, nil).Code)
+	assert.Equal(t, http.StatusNoContent, callAPI(router, alice, http.MethodDelete, item.URL, nil).Code)
+	assert.Equal(t, http.StatusNotFound, callAPI(router, alice, http.MethodGet, item.URL, nil).Code)
+}
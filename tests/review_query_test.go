@@ -0,0 +1,131 @@
+package servicetester
+
+import (
+	"go_api_product_review/cache"
+	"go_api_product_review/models"
+	"go_api_product_review/service"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestListProductReviews tests paging through the reviews of a product sorted by rating
+func TestListProductReviews(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+
+	product := models.Product{Name: "Test Product", Price: 10}
+	other := models.Product{Name: "Other Product", Price: 10}
+	db.Create(&product)
+	db.Create(&other)
+
+	for _, rating := range []int{3, 5, 1, 5, 4} {
+		if err := db.Create(&models.Review{ProductID: product.ID, Rating: rating}).Error; err != nil {
+			t.Fatalf("failed to create review: %v", err)
+		}
+	}
+	db.Create(&models.Review{ProductID: other.ID, Rating: 2})
+
+	query := models.ReviewQuery{Limit: 2, Sort: "highest"}
+	var ratings []int
+	for {
+		page, err := service.ListProductReviews(db, product.ID, query)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		for _, review := range page.Items {
+			assert.Equal(t, product.ID, review.ProductID)
+			ratings = append(ratings, review.Rating)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	assert.Equal(t, []int{5, 5, 4, 3, 1}, ratings)
+
+	// Filter by rating
+	five := 5
+	page, err := service.ListProductReviews(db, product.ID, models.ReviewQuery{Rating: &five})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	assert.Len(t, page.Items, 2)
+
+	// Unknown products are reported as not found
+	_, err = service.ListProductReviews(db, 404, models.ReviewQuery{})
+	assert.ErrorIs(t, err, service.ErrNotFound)
+}
+
+// TestListProductReviewsCacheInvalidation tests that cached pages are dropped when a review changes
+func TestListProductReviewsCacheInvalidation(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+
+	product := models.Product{Name: "Test Product", Price: 10}
+	db.Create(&product)
+	_, err = service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 4})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	page, err := service.ListProductReviews(db, product.ID, models.ReviewQuery{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	assert.Len(t, page.Items, 1)
+
+	// A review written behind the service's back is not visible while the page is cached
+	db.Create(&models.Review{ProductID: product.ID, Rating: 2})
+	page, err = service.ListProductReviews(db, product.ID, models.ReviewQuery{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	assert.Len(t, page.Items, 1)
+
+	// Creating a review through the service invalidates the cached pages
+	_, err = service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 5})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	page, err = service.ListProductReviews(db, product.ID, models.ReviewQuery{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	assert.Len(t, page.Items, 3)
+	assert.Equal(t, 5, page.Items[0].Rating) // Newest first
+}
+
+// TestGetReviewCacheMiss tests that a review missing from the cache is loaded and cached
+func TestGetReviewCacheMiss(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	mockClient := NewMockRedisClient()
+	cache.InitRedis(mockClient)
+
+	review := models.Review{ProductID: 1, FirstName: "Ana", Rating: 3}
+	db.Create(&review)
+
+	retrievedReview, err := service.GetReview(db, review.ID)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	assert.Equal(t, "Ana", retrievedReview.FirstName)
+	_, exists := mockClient.data["review:"+strconv.Itoa(int(review.ID))]
+	assert.True(t, exists, "Expected review to be cached")
+
+	_, err = service.GetReview(db, 404)
+	assert.ErrorIs(t, err, service.ErrNotFound)
+}
@@ -0,0 +1,129 @@
+package servicetester
+
+import (
+	"encoding/json"
+	"go_api_product_review/cache"
+	"go_api_product_review/db"
+	"go_api_product_review/middleware"
+	"go_api_product_review/models"
+	"go_api_product_review/service"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestReviewReplies tests that threaded replies are embedded in the review reads and their caches
+func TestReviewReplies(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+
+	product := models.Product{Name: "Bananas", Price: 2.5}
+	db.Create(&product)
+	review, err := service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 2, ReviewText: "Too green"})
+	assert.NoError(t, err)
+	other, err := service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 5})
+	assert.NoError(t, err)
+
+	// Cache a page of reviews before replying
+	page, err := service.ListProductReviews(db, product.ID, models.ReviewQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, page.Items[1].Replies)
+
+	reply, err := service.CreateReply(db, review.ID, nil, "shop", "Sorry, a replacement is on its way")
+	assert.NoError(t, err)
+	answer, err := service.CreateReply(db, review.ID, &reply.ID, "shop", "It was delivered this morning")
+	assert.NoError(t, err)
+	_, err = service.CreateReply(db, other.ID, &reply.ID, "shop", "Wrong thread")
+	assert.ErrorIs(t, err, service.ErrInvalidReplyParent)
+	_, err = service.CreateReply(db, 404, nil, "shop", "No review")
+	assert.ErrorIs(t, err, service.ErrNotFound)
+	pending, err := service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 1, Status: models.ReviewPending})
+	assert.NoError(t, err)
+	_, err = service.CreateReply(db, pending.ID, nil, "shop", "Not public yet")
+	assert.ErrorIs(t, err, service.ErrNotFound)
+
+	// The cached review and the cached pages hold the threads
+	cached, err := service.GetReview(db, review.ID)
+	assert.NoError(t, err)
+	threads := models.NewReviewResponse(*cached).Replies
+	if assert.Len(t, threads, 1) && assert.Len(t, threads[0].Replies, 1) {
+		assert.Equal(t, answer.ID, threads[0].Replies[0].ID)
+	}
+	page, err = service.ListProductReviews(db, product.ID, models.ReviewQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, page.Items[1].Replies, 1)
+	detail, err := service.GetProductByID(db, product.ID, true)
+	assert.NoError(t, err)
+	assert.Len(t, detail.Reviews[1].Replies, 1)
+
+	// Edits of the review keep its replies in the cache
+	_, err = service.UpdateReview(db, review.ID, &models.Review{Rating: 3, ReviewText: "Ripe now"}, "")
+	assert.NoError(t, err)
+	cached, err = service.GetReview(db, review.ID)
+	assert.NoError(t, err)
+	assert.Len(t, cached.Replies, 2)
+
+	updated, err := service.UpdateReply(db, review.ID, answer.ID, "Delivered this morning")
+	assert.NoError(t, err)
+	assert.Equal(t, "Delivered this morning", updated.Body)
+	cached, err = service.GetReview(db, review.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Delivered this morning", cached.Replies[1].Body)
+	_, err = service.UpdateReply(db, other.ID, answer.ID, "Wrong review")
+	assert.ErrorIs(t, err, service.ErrNotFound)
+
+	// Deleting a reply deletes its answers
+	assert.NoError(t, service.DeleteReply(db, review.ID, reply.ID))
+	cached, err = service.GetReview(db, review.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, cached.Replies)
+	var count int
+	db.Model(&models.ReviewReply{}).Count(&count)
+	assert.Equal(t, 0, count)
+	assert.ErrorIs(t, service.DeleteReply(db, review.ID, reply.ID), service.ErrNotFound)
+}
+
+// TestReplyEndpoints tests that only merchants reply and only to change their own replies
+func TestReplyEndpoints(t *testing.T) {
+	router := newAPIRouter(t)
+	product := models.Product{Name: "Bananas", Price: 2.5}
+	db.DB.Create(&product)
+	review := models.Review{ProductID: product.ID, Rating: 2}
+	_, err := service.CreateReview(db.DB, &review)
+	assert.NoError(t, err)
+
+	merchant := tokenFor(t, "shop", middleware.RoleMerchant)
+	competitor := tokenFor(t, "other-shop", middleware.RoleMerchant)
+	reviewer := tokenFor(t, "alice", middleware.RoleReviewer)
+	path := "/reviews/" + strconv.Itoa(int(review.ID))
+	body := models.ReplyRequest{Body: "Sorry, a replacement is on its way"}
+
+	assert.Equal(t, http.StatusForbidden, callAPI(router, reviewer, http.MethodPost, path+"/replies", body).Code)
+	assert.Equal(t, http.StatusBadRequest, callAPI(router, merchant, http.MethodPost, path+"/replies", models.ReplyRequest{Body: " "}).Code)
+	recorder := callAPI(router, merchant, http.MethodPost, path+"/replies", body)
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	var reply models.ReviewReplyResponse
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &reply))
+	assert.Equal(t, "shop", reply.AuthorSubject)
+	replyPath := path + "/replies/" + strconv.Itoa(int(reply.ID))
+
+	recorder = callAPI(router, reviewer, http.MethodGet, path, nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var read models.ReviewResponse
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &read))
+	if assert.Len(t, read.Replies, 1) {
+		assert.Equal(t, body.Body, read.Replies[0].Body)
+	}
+
+	assert.Equal(t, http.StatusForbidden, callAPI(router, competitor, http.MethodPut, replyPath, models.ReplyRequest{Body: "Buy ours"}).Code)
+	assert.Equal(t, http.StatusOK, callAPI(router, merchant, http.MethodPut, replyPath, models.ReplyRequest{Body: "Replacement sent"}).Code)
+	assert.Equal(t, http.StatusForbidden, callAPI(router, competitor, http.MethodDelete, replyPath, nil).Code)
+	assert.Equal(t, http.StatusNoContent, callAPI(router, merchant, http.MethodDelete, replyPath, nil).Code)
+	assert.Equal(t, http.StatusNotFound, callAPI(router, merchant, http.MethodDelete, replyPath, nil).Code)
+}
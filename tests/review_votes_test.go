@@ -0,0 +1,242 @@
+package servicetester
+
+import (
+	"encoding/json"
+	"go_api_product_review/cache"
+	"go_api_product_review/db"
+	"go_api_product_review/middleware"
+	"go_api_product_review/models"
+	"go_api_product_review/service"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+// voteTallyKey returns the Redis key of the helpful or unhelpful live tally of a review
+func voteTallyKey(reviewID uint, kind string) string {
+	return "review:" + strconv.Itoa(int(reviewID)) + ":votes:" + kind
+}
+
+// TestCastVote tests the vote tallies, with a single vote per caller and review
+func TestCastVote(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+
+	product := models.Product{Name: "Bananas", Price: 2.5}
+	db.Create(&product)
+	review, err := service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 5, AuthorSubject: "alice"})
+	assert.NoError(t, err)
+
+	for _, vote := range []struct {
+		voter   string
+		helpful bool
+	}{{"bob", true}, {"carol", false}, {"bob", true}, {"dave", true}} {
+		_, err := service.CastVote(db, review.ID, vote.voter, vote.helpful)
+		assert.NoError(t, err)
+	}
+	tally, err := service.GetVoteTally(db, review.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.VoteTally{ReviewID: review.ID, Helpful: 2, Unhelpful: 1}, *tally)
+
+	// Changing a vote moves it to the other tally
+	tally, err = service.CastVote(db, review.ID, "bob", false)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), tally.Helpful)
+	assert.Equal(t, int64(2), tally.Unhelpful)
+
+	tally, err = service.DeleteVote(db, review.ID, "carol")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), tally.Unhelpful)
+	_, err = service.DeleteVote(db, review.ID, "carol")
+	assert.ErrorIs(t, err, service.ErrNotFound)
+
+	// Tallies missing from Redis are counted from the votes
+	cache.InitRedis(NewMockRedisClient())
+	tally, err = service.GetVoteTally(db, review.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.VoteTally{ReviewID: review.ID, Helpful: 1, Unhelpful: 1}, *tally)
+
+	// A missing key is seeded from the votes, the other one keeps its live value
+	mock := NewMockRedisClient()
+	cache.InitRedis(mock)
+	mock.data[voteTallyKey(review.ID, "helpful")] = int64(5)
+	tally, err = service.CastVote(db, review.ID, "erin", false)
+	assert.NoError(t, err)
+	assert.Equal(t, models.VoteTally{ReviewID: review.ID, Helpful: 5, Unhelpful: 2}, *tally)
+
+	// The database holds a single vote per caller and review
+	assert.Error(t, db.Create(&models.ReviewVote{ReviewID: review.ID, Voter: "dave", Helpful: false}).Error)
+
+	_, err = service.CastVote(db, review.ID, "alice", true)
+	assert.ErrorIs(t, err, service.ErrSelfVote)
+	pending, err := service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 1, Status: models.ReviewPending})
+	assert.NoError(t, err)
+	_, err = service.CastVote(db, pending.ID, "bob", true)
+	assert.ErrorIs(t, err, service.ErrNotFound)
+
+	// Deleting the review drops its live tally
+	assert.NoError(t, service.DeleteReview(db, review.ID, "admin"))
+	assert.NotContains(t, mock.data, voteTallyKey(review.ID, "helpful"))
+	assert.NotContains(t, mock.data, voteTallyKey(review.ID, "unhelpful"))
+}
+
+// TestCastVoteConcurrently tests that a first vote racing another first vote of the caller replaces it
+func TestCastVoteConcurrently(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+
+	product := models.Product{Name: "Bananas", Price: 2.5}
+	db.Create(&product)
+	review, err := service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 5, AuthorSubject: "alice"})
+	assert.NoError(t, err)
+
+	// The other vote of the caller is stored right after the first vote was looked up
+	votedMeanwhile := false
+	db.Callback().Query().After("gorm:query").Register("test:concurrent_vote", func(scope *gorm.Scope) {
+		if _, ok := scope.Value.(*models.ReviewVote); ok && !votedMeanwhile {
+			votedMeanwhile = true
+			scope.NewDB().Create(&models.ReviewVote{ReviewID: review.ID, Voter: "bob", Helpful: false})
+		}
+	})
+
+	_, err = service.CastVote(db, review.ID, "bob", true)
+	assert.NoError(t, err)
+	assert.True(t, votedMeanwhile)
+	var votes []models.ReviewVote
+	db.Where("review_id = ?", review.ID).Find(&votes)
+	assert.Len(t, votes, 1)
+	assert.True(t, votes[0].Helpful)
+}
+
+// TestPersistVoteTallies tests that the tallies are persisted to the reviews, which can then be sorted by helpfulness
+func TestPersistVoteTallies(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	mock := NewMockRedisClient()
+	cache.InitRedis(mock)
+
+	product := models.Product{Name: "Bananas", Price: 2.5}
+	db.Create(&product)
+	votes := [][2]int{{1, 0}, {8, 1}, {3, 3}}
+	var ids []uint
+	for i, counts := range votes {
+		review, err := service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 4, AuthorSubject: "author" + strconv.Itoa(i)})
+		assert.NoError(t, err)
+		ids = append(ids, review.ID)
+		for v := 0; v < counts[0]+counts[1]; v++ {
+			_, err := service.CastVote(db, review.ID, "voter"+strconv.Itoa(v), v < counts[0])
+			assert.NoError(t, err)
+		}
+	}
+
+	// Sorting by helpfulness caches the page before the tallies are persisted
+	page, err := service.ListProductReviews(db, product.ID, models.ReviewQuery{Sort: "helpful"})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, page.Items[0].HelpfulCount)
+
+	persisted, err := service.PersistVoteTallies(db)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, persisted)
+	persisted, err = service.PersistVoteTallies(db)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, persisted)
+
+	var stored models.Review
+	db.First(&stored, ids[1])
+	assert.Equal(t, 8, stored.HelpfulCount)
+	assert.Equal(t, 1, stored.UnhelpfulCount)
+
+	// The persistence recounts the votes, a drifted live tally is dropped and seeded again
+	mock.data[voteTallyKey(ids[0], "helpful")] = int64(40)
+	_, err = service.CastVote(db, ids[0], "voter1", true)
+	assert.NoError(t, err)
+	_, err = service.PersistVoteTallies(db)
+	assert.NoError(t, err)
+	var recounted models.Review
+	db.First(&recounted, ids[0])
+	assert.Equal(t, 2, recounted.HelpfulCount)
+	tally, err := service.GetVoteTally(db, ids[0])
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), tally.Helpful)
+
+	// Many helpful votes come before a single one, the persistence dropped the cached pages
+	var order []uint
+	query := models.ReviewQuery{Sort: "helpful", Limit: 1}
+	for {
+		page, err := service.ListProductReviews(db, product.ID, query)
+		assert.NoError(t, err)
+		for _, item := range page.Items {
+			order = append(order, item.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	assert.Equal(t, []uint{ids[1], ids[0], ids[2]}, order)
+
+	cached, err := service.GetReview(db, ids[1])
+	assert.NoError(t, err)
+	assert.Equal(t, 8, cached.HelpfulCount)
+
+	// Edits of the review keep the tallies persisted after the review was read
+	persistedMeanwhile := false
+	db.Callback().Query().After("gorm:query").Register("test:concurrent_persistence", func(scope *gorm.Scope) {
+		if review, ok := scope.Value.(*models.Review); ok && review.ID == ids[1] && !persistedMeanwhile {
+			persistedMeanwhile = true
+			scope.NewDB().Model(&models.Review{}).Where("id = ?", ids[1]).UpdateColumn("helpful_count", 9)
+		}
+	})
+	_, err = service.UpdateReview(db, ids[1], &models.Review{Rating: 5, ReviewText: "Even better"}, "author1")
+	assert.NoError(t, err)
+	assert.True(t, persistedMeanwhile)
+	stored = models.Review{}
+	db.First(&stored, ids[1])
+	assert.Equal(t, 9, stored.HelpfulCount)
+	assert.Equal(t, 1, stored.UnhelpfulCount)
+}
+
+// TestVoteEndpoints tests voting on reviews through the API
+func TestVoteEndpoints(t *testing.T) {
+	router := newAPIRouter(t)
+	product := models.Product{Name: "Bananas", Price: 2.5}
+	db.DB.Create(&product)
+	review := models.Review{ProductID: product.ID, Rating: 4, AuthorSubject: "alice"}
+	_, err := service.CreateReview(db.DB, &review)
+	assert.NoError(t, err)
+
+	author := tokenFor(t, "alice", middleware.RoleReviewer)
+	voter := tokenFor(t, "bob", middleware.RoleReviewer)
+	reader := tokenFor(t, "reader", middleware.RoleReadOnly)
+	path := "/reviews/" + strconv.Itoa(int(review.ID)) + "/votes"
+	helpful := models.VoteRequest{Helpful: new(bool)}
+	*helpful.Helpful = true
+
+	recorder := callAPI(router, voter, http.MethodPost, path, helpful)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var tally models.VoteTally
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &tally))
+	assert.Equal(t, int64(1), tally.Helpful)
+
+	assert.Equal(t, http.StatusBadRequest, callAPI(router, voter, http.MethodPost, path, map[string]interface{}{}).Code)
+	assert.Equal(t, http.StatusForbidden, callAPI(router, author, http.MethodPost, path, helpful).Code)
+	assert.Equal(t, http.StatusForbidden, callAPI(router, reader, http.MethodPost, path, helpful).Code)
+	assert.Equal(t, http.StatusNotFound, callAPI(router, voter, http.MethodPost, "/reviews/404/votes", helpful).Code)
+
+	assert.Equal(t, http.StatusOK, callAPI(router, voter, http.MethodDelete, path, nil).Code)
+	assert.Equal(t, http.StatusNotFound, callAPI(router, voter, http.MethodDelete, path, nil).Code)
+}
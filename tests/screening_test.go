@@ -0,0 +1,191 @@
+package servicetester
+
+import (
+	"encoding/json"
+	"go_api_product_review/cache"
+	"go_api_product_review/db"
+	"go_api_product_review/middleware"
+	"go_api_product_review/models"
+	"go_api_product_review/screening"
+	"go_api_product_review/service"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// useScreening sets the screening pipeline for the test, restoring the default one afterwards
+func useScreening(t *testing.T, config screening.Config) {
+	service.InitScreening(screening.NewPipeline(config))
+	t.Cleanup(func() {
+		service.InitScreening(screening.NewPipeline(screening.DefaultConfig()))
+	})
+}
+
+// rulesOf returns the rules of the findings of a screening result
+func rulesOf(result screening.Result) []string {
+	rules := []string{}
+	for _, finding := range result.Findings {
+		rules = append(rules, finding.Rule)
+	}
+	return rules
+}
+
+// TestScreeningPipeline tests the verdicts of the standard screeners
+func TestScreeningPipeline(t *testing.T) {
+	config := screening.DefaultConfig()
+	config.BannedWords = []string{"spam", "scam"}
+	config.MinLength = 3
+	config.MaxLength = 100
+	pipeline := screening.NewPipeline(config)
+
+	tests := []struct {
+		name    string
+		text    string
+		verdict string
+		rules   []string
+	}{
+		{"clean", "These bananas are amazing, I will buy them again!", screening.Accept, []string{}},
+		{"banned word", "What a sc4aaam, avoid", screening.Flag, []string{screening.RuleBannedWord}},
+		{"two banned words", "$P@M and 5c@m.", screening.Reject, []string{screening.RuleBannedWord, screening.RuleBannedWord}},
+		{"banned words in punctuation", "Total scam,spam;avoid", screening.Reject, []string{screening.RuleBannedWord, screening.RuleBannedWord}},
+		{"link", "Cheaper on www.example.com", screening.Flag, []string{screening.RuleURL}},
+		{"email", "Write to bananas@example.com", screening.Flag, []string{screening.RuleEmail}},
+		{"phone", "Call me at +1 (555) 123-4567", screening.Flag, []string{screening.RulePhone}},
+		{"date", "Bought them on 2023-01-15, still fresh", screening.Accept, []string{}},
+		{"date range", "Ate them from 15/01/2023 to 2023-02-01 without trouble", screening.Accept, []string{}},
+		{"order number", "Order 1234567 arrived quickly", screening.Accept, []string{}},
+		{"sentence punctuation", "I love it.Me and my wife eat them daily", screening.Accept, []string{}},
+		{"caps and repeats", "THESE ARE THE BEST BANANAS!!!!!!!", screening.Flag, []string{screening.RuleCaps, screening.RuleRepeatedChars}},
+		{"too short", "ok", screening.Reject, []string{screening.RuleLength}},
+		{"too long", strings.Repeat("ab", 51), screening.Reject, []string{screening.RuleLength}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := pipeline.Screen(test.text)
+			assert.Equal(t, test.verdict, result.Verdict)
+			assert.Equal(t, test.rules, rulesOf(result))
+		})
+	}
+
+	assert.Equal(t, "banana", screening.Normalize("B@n4n4!"))
+	assert.Nil(t, screening.NewPipeline(screening.Config{}))
+}
+
+// TestScreenReviews tests that suspicious reviews are flagged and rejected ones are not saved
+func TestScreenReviews(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+	config := screening.DefaultConfig()
+	config.BannedWords = []string{"scam"}
+	useScreening(t, config)
+
+	product := models.Product{Name: "Bananas", Price: 2.5}
+	db.Create(&product)
+
+	review, err := service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 5, ReviewText: "Great bananas"})
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReviewApproved, review.Status)
+
+	flagged, err := service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 1, ReviewText: "Cheaper on www.example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReviewFlagged, flagged.Status)
+	assert.Equal(t, service.ScreeningModerator, flagged.ModeratedBy)
+	assert.Contains(t, flagged.ModerationReason, screening.RuleURL)
+
+	_, err = service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 1, ReviewText: "SC4M SC4M, call 555-123-4567"})
+	var screeningErr *service.ScreeningError
+	if assert.ErrorAs(t, err, &screeningErr) {
+		assert.Equal(t, screening.Reject, screeningErr.Result.Verdict)
+	}
+	var count int
+	db.Model(&models.Review{}).Count(&count)
+	assert.Equal(t, 2, count)
+
+	// Suspicious edits take approved reviews down from the ratings
+	updated, err := service.UpdateReview(db, review.ID, &models.Review{Rating: 4, ReviewText: "Email me at bananas@example.com"}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReviewFlagged, updated.Status)
+	var stored models.Product
+	db.First(&stored, product.ID)
+	assert.Equal(t, 0, stored.ReviewCount)
+
+	// Rejected edits leave the review as it was
+	_, err = service.UpdateReview(db, flagged.ID, &models.Review{Rating: 1, ReviewText: "scam at www.example.com"}, "")
+	assert.ErrorAs(t, err, &screeningErr)
+	var unchanged models.Review
+	db.First(&unchanged, flagged.ID)
+	assert.Equal(t, "Cheaper on www.example.com", unchanged.ReviewText)
+
+	drifts, err := service.ReconcileRatingAggregates(db)
+	assert.NoError(t, err)
+	assert.Empty(t, drifts)
+
+	// Turning the screening off lets everything through
+	service.InitScreening(nil)
+	review, err = service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 3, ReviewText: "scam"})
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReviewApproved, review.Status)
+}
+
+// TestScreeningEndpoint tests that rejected reviews are answered with the screening findings
+func TestScreeningEndpoint(t *testing.T) {
+	router := newAPIRouter(t)
+	config := screening.DefaultConfig()
+	config.BannedWords = []string{"scam"}
+	useScreening(t, config)
+	product := models.Product{Name: "Bananas", Price: 2.5}
+	db.DB.Create(&product)
+	author := tokenFor(t, "alice", middleware.RoleReviewer)
+
+	review := map[string]interface{}{"product_id": product.ID, "rating": 1, "review_text": "SC@M, write to scam@example.com"}
+	recorder := callAPI(router, author, http.MethodPost, "/reviews/", review)
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	var response models.ScreeningErrorResponse
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, "Review rejected by content screening", response.Message)
+	assert.GreaterOrEqual(t, response.Score, config.RejectScore)
+	assert.NotEmpty(t, response.Findings)
+
+	review["review_text"] = "Good bananas"
+	recorder = callAPI(router, author, http.MethodPost, "/reviews/", review)
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	var created models.Review
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &created))
+
+	review["review_text"] = "SC@M, write to scam@example.com"
+	path := "/reviews/" + strconv.Itoa(int(created.ID))
+	assert.Equal(t, http.StatusUnprocessableEntity, callAPI(router, author, http.MethodPut, path, review).Code)
+}
+
+// TestLoadScreeningConfigFromEnv tests the screening configuration read from the environment
+func TestLoadScreeningConfigFromEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "banned.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("# Banned words\nscam\n\nfake\n"), 0o600))
+	t.Setenv("SCREENING_BANNED_WORDS", "spam")
+	t.Setenv("SCREENING_BANNED_WORDS_FILE", path)
+	t.Setenv("SCREENING_MAX_LENGTH", "200")
+	t.Setenv("SCREENING_FLAG_SCORE", "3")
+	config, err := screening.LoadConfigFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"spam", "scam", "fake"}, config.BannedWords)
+	assert.Equal(t, 200, config.MaxLength)
+	assert.Equal(t, 3.0, config.FlagScore)
+
+	t.Setenv("SCREENING_ENABLED", "false")
+	config, err = screening.LoadConfigFromEnv()
+	assert.NoError(t, err)
+	assert.Nil(t, screening.NewPipeline(config))
+
+	t.Setenv("SCREENING_FLAG_SCORE", "9")
+	_, err = screening.LoadConfigFromEnv()
+	assert.Error(t, err)
+}
@@ -0,0 +1,268 @@
+package servicetester
+
+import (
+	"encoding/json"
+	"go_api_product_review/cache"
+	"go_api_product_review/events"
+	"go_api_product_review/models"
+	"go_api_product_review/service"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+// webhookReceiver is an httptest server recording the webhook deliveries it receives
+type webhookReceiver struct {
+	server   *httptest.Server
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+	failures int
+}
+
+// newWebhookReceiver starts a receiver answering 500 to the first failures requests
+func newWebhookReceiver(failures int) *webhookReceiver {
+	receiver := &webhookReceiver{failures: failures}
+	receiver.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receiver.mu.Lock()
+		defer receiver.mu.Unlock()
+		receiver.requests = append(receiver.requests, r)
+		receiver.bodies = append(receiver.bodies, body)
+		if receiver.failures > 0 {
+			receiver.failures--
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	return receiver
+}
+
+// TestWebhookDelivery tests that review events are delivered signed to the matching subscriptions
+func TestWebhookDelivery(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+
+	receiver := newWebhookReceiver(0)
+	defer receiver.server.Close()
+
+	product := models.Product{Name: "Test Product", Price: 10}
+	other := models.Product{Name: "Other Product", Price: 10}
+	db.Create(&product)
+	db.Create(&other)
+
+	secret := "0123456789abcdef0123456789abcdef"
+	_, err = service.CreateWebhook(db, &models.WebhookSubscription{
+		TargetURL:  receiver.server.URL,
+		EventTypes: events.ReviewCreated,
+		ProductID:  product.ID,
+		Secret:     secret,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// Only the review.created event of the subscribed product must be delivered
+	_, err = service.CreateReview(db, &models.Review{ProductID: product.ID, Rating: 5})
+	assert.NoError(t, err)
+	_, err = service.CreateReview(db, &models.Review{ProductID: other.ID, Rating: 5})
+	assert.NoError(t, err)
+
+	// Relaying the outbox twice must not schedule the same delivery twice
+	publisher := service.NewWebhookPublisher(db)
+	_, err = service.DispatchOutbox(db, publisher, 100)
+	assert.NoError(t, err)
+	var outboxEvents []models.OutboxEvent
+	db.Find(&outboxEvents)
+	for _, outboxEvent := range outboxEvents {
+		var event events.Event
+		assert.NoError(t, json.Unmarshal([]byte(outboxEvent.Payload), &event))
+		assert.NoError(t, publisher.Publish(cache.Ctx, event))
+	}
+
+	delivered, err := service.DeliverWebhooks(db, http.DefaultClient, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+
+	assert.Len(t, receiver.requests, 1)
+	request := receiver.requests[0]
+	assert.Equal(t, events.ReviewCreated, request.Header.Get(service.WebhookEventHeader))
+	expectedSignature := service.SignWebhookPayload(secret, request.Header.Get(service.WebhookTimestampHeader), receiver.bodies[0])
+	assert.Equal(t, expectedSignature, request.Header.Get(service.WebhookSignatureHeader))
+
+	var event events.Event
+	assert.NoError(t, json.Unmarshal(receiver.bodies[0], &event))
+	assert.Equal(t, product.ID, event.ProductID)
+	assert.Equal(t, request.Header.Get(service.WebhookIDHeader), event.ID)
+}
+
+// TestWebhookDeliveryRetries tests that failed deliveries are retried with a backoff and logged
+func TestWebhookDeliveryRetries(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+
+	receiver := newWebhookReceiver(1)
+	defer receiver.server.Close()
+
+	subscription, err := service.CreateWebhook(db, &models.WebhookSubscription{
+		TargetURL:  receiver.server.URL,
+		EventTypes: events.ReviewCreated + "," + events.ProductRatingChanged,
+		Secret:     "0123456789abcdef",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	event, _ := events.New(events.ReviewCreated, 1, models.ReviewResponse{ID: 1, ProductID: 1, Rating: 4})
+	assert.NoError(t, service.NewWebhookPublisher(db).Publish(cache.Ctx, event))
+
+	// The first attempt fails and is scheduled for later
+	delivered, err := service.DeliverWebhooks(db, http.DefaultClient, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+
+	deliveries, err := service.ListWebhookDeliveries(db, subscription.ID, 100)
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 1)
+	assert.Equal(t, 1, deliveries[0].Attempts)
+	assert.Equal(t, http.StatusInternalServerError, deliveries[0].StatusCode)
+	assert.True(t, deliveries[0].NextAttemptAt.After(time.Now()))
+
+	// Nothing is sent before the backoff expires
+	delivered, err = service.DeliverWebhooks(db, http.DefaultClient, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+	assert.Len(t, receiver.requests, 1)
+
+	// The retry succeeds once due
+	db.Model(&models.WebhookDelivery{}).Update("next_attempt_at", time.Now().UTC().Add(-time.Second))
+	delivered, err = service.DeliverWebhooks(db, http.DefaultClient, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+
+	deliveries, err = service.ListWebhookDeliveries(db, subscription.ID, 100)
+	assert.NoError(t, err)
+	response := models.NewWebhookDeliveryResponse(deliveries[0])
+	assert.Equal(t, "delivered", response.Status)
+	assert.Equal(t, 2, response.Attempts)
+	assert.Equal(t, http.StatusNoContent, response.StatusCode)
+}
+
+// TestWebhookDeliveryDeadPartner tests that a failing subscription does not hold up the deliveries of the others
+func TestWebhookDeliveryDeadPartner(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+
+	dead := newWebhookReceiver(100)
+	defer dead.server.Close()
+	healthy := newWebhookReceiver(0)
+	defer healthy.server.Close()
+
+	publisher := service.NewWebhookPublisher(db)
+	for _, receiver := range []*webhookReceiver{dead, healthy} {
+		_, err := service.CreateWebhook(db, &models.WebhookSubscription{
+			TargetURL:  receiver.server.URL,
+			EventTypes: events.ReviewCreated,
+			Secret:     "0123456789abcdef",
+		})
+		assert.NoError(t, err)
+	}
+	for i := 1; i <= 3; i++ {
+		event, _ := events.New(events.ReviewCreated, 1, models.ReviewResponse{ID: uint(i), ProductID: 1, Rating: 4})
+		assert.NoError(t, publisher.Publish(cache.Ctx, event))
+	}
+
+	// The dead partner is attempted once, its other deliveries wait for the retry
+	delivered, err := service.DeliverWebhooks(db, http.DefaultClient, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, delivered)
+	assert.Len(t, healthy.requests, 3)
+	assert.Len(t, dead.requests, 1)
+
+	var waiting []models.WebhookDelivery
+	db.Where("delivered_at IS NULL").Order("id ASC").Find(&waiting)
+	assert.Len(t, waiting, 3)
+	assert.Equal(t, 1, waiting[0].Attempts)
+	for _, delivery := range waiting {
+		assert.True(t, delivery.NextAttemptAt.After(time.Now()))
+	}
+	assert.Equal(t, 0, waiting[1].Attempts)
+}
+
+// TestWebhookDeliveryClaimed tests that a delivery claimed by another dispatcher is not sent again
+func TestWebhookDeliveryClaimed(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("failed to setup test db: %v", err)
+	}
+	defer db.Close()
+	cache.InitRedis(NewMockRedisClient())
+
+	receiver := newWebhookReceiver(0)
+	defer receiver.server.Close()
+
+	_, err = service.CreateWebhook(db, &models.WebhookSubscription{
+		TargetURL:  receiver.server.URL,
+		EventTypes: events.ReviewCreated,
+		Secret:     "0123456789abcdef",
+	})
+	assert.NoError(t, err)
+	event, _ := events.New(events.ReviewCreated, 1, models.ReviewResponse{ID: 1, ProductID: 1, Rating: 4})
+	assert.NoError(t, service.NewWebhookPublisher(db).Publish(cache.Ctx, event))
+
+	// Another dispatcher claims the delivery right after it was read as due
+	claimedMeanwhile := false
+	db.Callback().Query().After("gorm:query").Register("test:concurrent_dispatch", func(scope *gorm.Scope) {
+		if _, ok := scope.Value.(*[]models.WebhookDelivery); ok && !claimedMeanwhile {
+			claimedMeanwhile = true
+			scope.NewDB().Model(&models.WebhookDelivery{}).UpdateColumn("next_attempt_at", time.Now().UTC().Add(time.Minute))
+		}
+	})
+
+	delivered, err := service.DeliverWebhooks(db, http.DefaultClient, 100)
+	assert.NoError(t, err)
+	assert.True(t, claimedMeanwhile)
+	assert.Equal(t, 0, delivered)
+	assert.Empty(t, receiver.requests)
+}
+
+// TestWebhookRequestValidate tests the validation of webhook subscription requests
+func TestWebhookRequestValidate(t *testing.T) {
+	valid := models.WebhookRequest{
+		TargetURL:  "https://partner.example.com/hooks",
+		EventTypes: []string{events.ReviewCreated},
+		Secret:     "0123456789abcdef",
+	}
+	assert.NoError(t, valid.Validate())
+
+	invalidURL := valid
+	invalidURL.TargetURL = "ftp://partner.example.com"
+	assert.Error(t, invalidURL.Validate())
+
+	unknownType := valid
+	unknownType.EventTypes = []string{"order.created"}
+	assert.Error(t, unknownType.Validate())
+
+	shortSecret := valid
+	shortSecret.Secret = "short"
+	assert.Error(t, shortSecret.Validate())
+}